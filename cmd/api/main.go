@@ -9,17 +9,21 @@ import (
 	"time"
 
 	"zemdocs/internal/api/handlers"
+	"zemdocs/internal/api/middleware"
 	"zemdocs/internal/api/router"
 	"zemdocs/internal/clientes/documents"
 	"zemdocs/internal/clientes/documents/ma/imperatriz"
 	"zemdocs/internal/config"
 	"zemdocs/internal/database"
+	"zemdocs/internal/database/model"
 	"zemdocs/internal/database/repository"
 	"zemdocs/internal/jobs"
 	"zemdocs/internal/logger"
+	"zemdocs/internal/pdf"
 	"zemdocs/internal/scheduler"
 	"zemdocs/internal/service"
 	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
 )
 
 func main() {
@@ -39,31 +43,50 @@ func main() {
 	logger.Info("Iniciando aplicação zemdocs-api")
 
 	// Inicializar banco de dados
-	if err := database.InitDB(cfg); err != nil {
+	db, err := database.Open(cfg)
+	if err != nil {
 		logger.Fatal(err, "Erro ao inicializar banco de dados")
 	}
-	defer database.CloseDB()
+	defer db.Close()
 
 	// Executar migrações automáticas
-	if err := database.RunMigrations(context.Background()); err != nil {
+	if err := database.RunMigrations(context.Background(), db); err != nil {
 		logger.Fatal(err, "Erro ao executar migrações")
 	}
 
-	// Inicializar MinIO
-	minioClient, err := storage.NewMinIOClient(
-		cfg.MinIO.Endpoint,
-		cfg.MinIO.AccessKey,
-		cfg.MinIO.SecretKey,
-		cfg.MinIO.BucketName,
-		cfg.MinIO.UseSSL,
-	)
+	// Inicializar armazenamento de XMLs (MinIO ou sistema de arquivos local)
+	store, err := storage.NewStore(cfg, repository.NewEncryptionKeyRepository(db))
 	if err != nil {
-		logger.Fatal(err, "Erro ao conectar com MinIO")
+		logger.Fatal(err, "Erro ao inicializar armazenamento")
 	}
 
+	// Verificar o bucket já na inicialização; se o MinIO estiver fora, o outbox reenvia depois
+	if err := storage.EnsureBucket(context.Background(), store); err != nil {
+		logger.Error(err, "MinIO indisponível na inicialização")
+	}
+
+	// Inicialização, carga de agendamentos e jobs acessam os dados de todos os escritórios
+	systemCtx := tenant.WithSystem(context.Background())
+
 	// Inicializar repositórios
-	nfseRepo := repository.NewDocumentRepository(database.DB)
-	empresaRepo := repository.NewEmpresaRepository(database.DB)
+	nfseRepo := repository.NewDocumentRepository(db)
+	empresaRepo := repository.NewEmpresaRepository(db)
+	outboxRepo := repository.NewStorageOutboxRepository(db)
+	orphanRepo := repository.NewStorageOrphanRepository(db)
+	deadLetterRepo := repository.NewDeadLetterRepository(db)
+	jobLockRepo := repository.NewJobLockRepository(db)
+	scheduleRepo := repository.NewJobScheduleRepository(db)
+	dashboardRepo := repository.NewDashboardRepository(db)
+	escritorioRepo := repository.NewEscritorioRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	partitionRepo := repository.NewDocumentPartitionRepository(db)
+	exportRepo := repository.NewExportRepository(db)
+
+	// Retenção fiscal aplicada aos XMLs (object lock no MinIO, somente leitura no backend local)
+	retentionPolicy := service.NewRetentionPolicy(cfg.Retention)
+
+	// Outbox garante que o XML de todo documento salvo chegue ao MinIO
+	outboxService := service.NewStorageOutboxService(outboxRepo, store, retentionPolicy)
 
 	// Inicializar registry de clientes de documentos
 	documentsRegistry := documents.NewRegistry()
@@ -73,28 +96,106 @@ func main() {
 		cfg.NFSe.ImperatrizBaseURL,
 		cfg.NFSe.ImperatrizToken,
 	)
-	documentsRegistry.Register("2105302", imperatrizClient)
+	documentsRegistry.Register(imperatriz.CodigoIBGE, imperatrizClient)
+
+	// Inicializar serviços de documentos
+	nfseService := service.NewNFSeService(documentsRegistry, nfseRepo, store, outboxService)
+
+	// Documentos que falharem na conversão ou persistência vão para a fila de falhas
+	deadLetterService := service.NewDeadLetterService(deadLetterRepo, nfseService)
+	imperatrizClient.SetFailureHandler(deadLetterService.Record)
+	nfseService.SetFailureHandler(deadLetterService.Record)
+
+	// Trilha de auditoria gravada junto com as alterações de documentos, empresas e usuários
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(db))
+	nfseService.SetAudit(auditService)
+
+	// Empresas cadastradas e vínculo dos documentos com emitente e tomador
+	cnpjaService := service.NewCNPJAService()
+	empresaService := service.NewEmpresaService(empresaRepo, nfseRepo, cnpjaService, auditService)
+	empresaLinker := service.NewEmpresaLinkService(empresaRepo, nfseRepo, empresaService, cfg.Scheduler.AutoRegisterEmpresas)
+	empresaService.SetCreatedHandler(empresaLinker.VincularDocumentos)
+
+	// Certificados digitais só são aceitos com criptografia em repouso
+	var certificadoService *service.CertificadoService
+	if encrypted, ok := store.(*storage.EncryptedStore); ok {
+		certificadoService = service.NewCertificadoService(empresaRepo, encrypted, auditService)
+		empresaService.SetPurgedHandler(certificadoService.RemoverObjeto)
+	}
+	nfseService.SetEmpresaLinker(empresaLinker)
+
+	// Agregados do dashboard, recalculados pouco depois de cada sincronização ou exclusão
+	dashboardService := service.NewDashboardService(dashboardRepo, time.Duration(cfg.Dashboard.RefreshDelay)*time.Second)
+	nfseService.SetDashboard(dashboardService)
+
+	// O scheduler sempre existe para execuções manuais; o agendamento só ocorre se habilitado
+	jobScheduler := scheduler.NewScheduler()
+	jobScheduler.SetRunRepository(repository.NewJobRunRepository(db), cfg.Scheduler.InstanceID)
+
+	// Com várias réplicas, cada job só roda onde o advisory lock for adquirido
+	if cfg.Scheduler.LockEnabled {
+		locker := scheduler.NewPostgresLocker(db, jobLockRepo, cfg.Scheduler.InstanceID)
+		jobScheduler.SetLocker(locker, time.Duration(cfg.Scheduler.LockTimeout)*time.Minute)
+	}
+
+	// Agendamentos de sincronização ficam no banco, por município e empresa
+	jobScheduler.SetScheduleRepository(scheduleRepo)
+	jobScheduleService := service.NewJobScheduleService(scheduleRepo, empresaRepo, documentsRegistry)
 
-	// Inicializar serviços (NFSe service removido temporariamente)
-	// nfseService := service.NewNFSeService(documentsRegistry, nfseRepo, minioClient)
+	// Escritórios de contabilidade (tenants) e suas credenciais de API
+	escritorioService := service.NewEscritorioService(escritorioRepo, apiTokenRepo)
 
-	// Inicializar scheduler se habilitado
-	var jobScheduler *scheduler.Scheduler
+	// Na primeira inicialização, o job antes definido por variáveis de ambiente vira um
+	// agendamento do escritório padrão
+	var escritorioPadraoID *int64
+	if escritorio, err := escritorioService.ConsultarPadrao(systemCtx); err == nil {
+		escritorioPadraoID = &escritorio.ID
+	}
+	if err := jobScheduleService.CriarPadrao(systemCtx, &model.JobScheduleRequest{
+		Name:         "nfse-sync-imperatriz",
+		Type:         model.JobScheduleTypeNFSeSync,
+		CodigoIBGE:   imperatriz.CodigoIBGE,
+		Cron:         cfg.Scheduler.SyncInterval,
+		Params:       map[string]string{model.JobParamCompetencia: cfg.Scheduler.CompetenciaAtual},
+		Description:  "Sincronização de NFS-e de Imperatriz-MA",
+		EscritorioID: escritorioPadraoID,
+	}); err != nil {
+		logger.Error(err, "Erro ao criar agendamento padrão de sincronização")
+	}
+
+	// Jobs de sistema também ficam na tabela de agendamentos: um único registro de
+	// jobs, pausável em todas as réplicas e com um lock por tipo de job. As
+	// variáveis *_INTERVAL definem apenas o cron do agendamento criado aqui.
+	for _, req := range []*model.JobScheduleRequest{
+		{Name: "storage-outbox", Type: model.JobScheduleTypeStorageOutbox, Cron: cfg.Scheduler.OutboxInterval, Description: "Reenvio de uploads pendentes do outbox"},
+		{Name: "storage-reconcile", Type: model.JobScheduleTypeStorageReconcile, Cron: cfg.Scheduler.ReconcileInterval, Description: "Reconciliação entre banco e armazenamento"},
+		{Name: "storage-scrub", Type: model.JobScheduleTypeStorageScrub, Cron: cfg.Scheduler.ScrubInterval, Description: "Verificação do hash dos XMLs armazenados"},
+		{Name: "document-partition", Type: model.JobScheduleTypeDocumentPartition, Cron: cfg.Scheduler.PartitionInterval, Description: "Partições anuais de documents: criação antecipada e arquivamento"},
+	} {
+		if err := jobScheduleService.GarantirSistema(systemCtx, req); err != nil {
+			logger.Error(err, "Erro ao criar agendamento "+req.Name)
+		}
+	}
+
+	var scheduleLoader *scheduler.ScheduleLoader
 	if cfg.Scheduler.Enabled {
-		jobScheduler = scheduler.NewScheduler()
-
-		// Criar job de sincronização
-		syncJob := jobs.NewNFSeSyncJob(
-			imperatrizClient,
-			nfseRepo,
-			minioClient,
-			cfg.Scheduler.CompetenciaAtual,
-		)
+		scheduleFactory := jobs.NewScheduleFactory(documentsRegistry, nfseRepo, outboxRepo, orphanRepo, empresaRepo, store, outboxService)
+		scheduleFactory.SetFailureHandler(deadLetterService.Record)
+		scheduleFactory.SetEmpresaLinker(empresaLinker)
+		scheduleFactory.SetDashboard(dashboardService)
+		scheduleFactory.SetAudit(auditService)
+		scheduleFactory.SetPartitionService(service.NewDocumentPartitionService(partitionRepo, cfg.Partition))
 
-		// Agendar job
-		if err := jobScheduler.AddJob(cfg.Scheduler.SyncInterval, syncJob); err != nil {
-			logger.Fatal(err, "Erro ao agendar job de sincronização")
+		scheduleLoader = scheduler.NewScheduleLoader(
+			jobScheduler,
+			scheduleRepo,
+			scheduleFactory.Build,
+			time.Duration(cfg.Scheduler.ReloadInterval)*time.Second,
+		)
+		if err := scheduleLoader.Start(systemCtx); err != nil {
+			logger.Fatal(err, "Erro ao carregar agendamentos")
 		}
+		jobScheduleService.SetChangeHandler(scheduleLoader.Reload)
 
 		// Iniciar scheduler
 		jobScheduler.Start()
@@ -102,15 +203,59 @@ func main() {
 	}
 
 	// Inicializar serviços adicionais
-	cnpjaService := service.NewCNPJAService()
-	empresaService := service.NewEmpresaService(empresaRepo, cnpjaService)
+	retentionService := service.NewRetentionService(nfseRepo, store, retentionPolicy, outboxService, auditService)
+	retentionService.SetDashboard(dashboardService)
+	documentXMLService := service.NewDocumentXMLService(
+		nfseRepo,
+		repository.NewDocumentDownloadRepository(db),
+		store,
+		time.Duration(cfg.Storage.PresignExpiry)*time.Minute,
+	)
+
+	// DANFSE/DANFE gerados a partir do XML, com cache no armazenamento
+	pdfRenderer, err := pdf.NewRenderer(cfg.PDF)
+	if err != nil {
+		logger.Fatal(err, "Erro ao inicializar gerador de PDF")
+	}
+	documentPDFService := service.NewDocumentPDFService(documentXMLService, store, pdfRenderer)
+	exportService := service.NewExportService(
+		nfseRepo,
+		empresaRepo,
+		exportRepo,
+		documentPDFService,
+		store,
+		cfg.Export.SyncLimit,
+		time.Duration(cfg.Export.URLExpiry)*time.Hour,
+	)
 
 	// Inicializar handlers
-	documentHandler := handlers.NewDocumentHandler()
+	documentHandler := handlers.NewDocumentHandler(retentionService, documentXMLService, documentPDFService, service.NewDocumentSearchService(nfseRepo), service.NewDocumentListService(nfseRepo), dashboardService)
+	nfseHandler := handlers.NewNFSeHandler(nfseService, jobScheduler)
 	empresaHandler := handlers.NewEmpresaHandler(empresaService)
+	empresaHandler.SetCertificadoService(certificadoService)
+	deadLetterHandler := handlers.NewDeadLetterHandler(deadLetterService)
+	jobHandler := handlers.NewJobHandler(jobLockRepo, jobScheduler)
+	jobScheduleHandler := handlers.NewJobScheduleHandler(jobScheduleService)
+	storageHandler := handlers.NewStorageHandler(store, orphanRepo)
+	exportHandler := handlers.NewExportHandler(exportService, jobScheduler)
+	escritorioHandler := handlers.NewEscritorioHandler(escritorioService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+
+	// Credenciais dos escritórios gravadas no banco, além dos tokens de API_TOKENS
+	tokenLookup := func(ctx context.Context, token string) (*middleware.Principal, error) {
+		apiToken, err := escritorioService.AutenticarToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return &middleware.Principal{
+			Name:         apiToken.Nome,
+			Permissions:  apiToken.Permissions,
+			EscritorioID: &apiToken.EscritorioID,
+		}, nil
+	}
 
 	// Configurar router
-	r := router.SetupRouter(documentHandler, empresaHandler)
+	r := router.SetupRouter(documentHandler, nfseHandler, empresaHandler, deadLetterHandler, jobHandler, jobScheduleHandler, storageHandler, exportHandler, escritorioHandler, auditHandler, cfg.Auth, tokenLookup, db)
 
 	// Configurar servidor
 	srv := &http.Server{
@@ -136,11 +281,12 @@ func main() {
 
 	logger.Info("Desligando servidor...")
 
-	// Parar scheduler se estiver rodando
-	if jobScheduler != nil {
-		logger.Info("Parando scheduler...")
-		jobScheduler.Stop()
+	// Parar scheduler e aguardar execuções em andamento
+	logger.Info("Parando scheduler...")
+	if scheduleLoader != nil {
+		scheduleLoader.Stop()
 	}
+	jobScheduler.Stop()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -150,5 +296,10 @@ func main() {
 		logger.Fatal(err, "Erro ao desligar servidor")
 	}
 
+	// Atualização do dashboard ainda adiada pelas últimas alterações
+	if err := dashboardService.Encerrar(ctx); err != nil {
+		logger.Error(err, "Erro ao atualizar agregados do dashboard")
+	}
+
 	logger.Info("Servidor desligado com sucesso")
 }
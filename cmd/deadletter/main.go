@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/config"
+	"zemdocs/internal/database"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+
+	"github.com/uptrace/bun"
+)
+
+const usage = `Uso: deadletter <comando> [opções]
+
+Comandos:
+  list [-status pendente] [-stage etapa] [-source origem] [-competencia AAAAMM] [-limit 50] [-offset 0]
+  show <id>        Exibe o registro com o payload bruto
+  reprocess <id>   Tenta salvar novamente o documento
+  discard <id>     Descarta o registro
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail("Erro ao carregar configurações: %v", err)
+	}
+	logger.Init(cfg)
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		fail("Erro ao inicializar banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	deadLetterService, err := newDeadLetterService(cfg, db)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	// Comando administrativo: a fila de falhas é do sistema
+	ctx := tenant.WithSystem(context.Background())
+	switch os.Args[1] {
+	case "list":
+		list(ctx, deadLetterService, os.Args[2:])
+	case "show":
+		deadLetter, err := deadLetterService.Consultar(ctx, parseID(os.Args[2:]))
+		if err != nil {
+			fail("%v", err)
+		}
+		printJSON(deadLetter)
+	case "reprocess":
+		deadLetter, err := deadLetterService.Reprocessar(ctx, parseID(os.Args[2:]))
+		if err != nil {
+			fail("%v", err)
+		}
+		fmt.Printf("Documento %s reprocessado com sucesso\n", deadLetter.NumeroDocumento)
+	case "discard":
+		id := parseID(os.Args[2:])
+		if err := deadLetterService.Descartar(ctx, id); err != nil {
+			fail("%v", err)
+		}
+		fmt.Printf("Registro %d descartado\n", id)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// newDeadLetterService monta as dependências necessárias para reprocessar documentos
+func newDeadLetterService(cfg *config.Config, db *bun.DB) (*service.DeadLetterService, error) {
+	store, err := storage.NewStore(cfg, repository.NewEncryptionKeyRepository(db))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inicializar armazenamento: %w", err)
+	}
+
+	nfseRepo := repository.NewDocumentRepository(db)
+	outboxService := service.NewStorageOutboxService(repository.NewStorageOutboxRepository(db), store, service.NewRetentionPolicy(cfg.Retention))
+	nfseService := service.NewNFSeService(documents.NewRegistry(), nfseRepo, store, outboxService)
+
+	return service.NewDeadLetterService(repository.NewDeadLetterRepository(db), nfseService), nil
+}
+
+func list(ctx context.Context, deadLetterService *service.DeadLetterService, args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	status := fs.String("status", string(model.DeadLetterStatusPendente), "status (pendente, reprocessado, descartado)")
+	stage := fs.String("stage", "", "etapa da falha")
+	source := fs.String("source", "", "cliente de origem")
+	competencia := fs.String("competencia", "", "competência (AAAAMM)")
+	limit := fs.Int("limit", 50, "quantidade de registros")
+	offset := fs.Int("offset", 0, "deslocamento")
+	fs.Parse(args)
+
+	deadLetters, total, err := deadLetterService.Listar(ctx, model.DeadLetterFilter{
+		Status:      model.DeadLetterStatus(*status),
+		Stage:       *stage,
+		Source:      *source,
+		Competencia: *competencia,
+		Limit:       *limit,
+		Offset:      *offset,
+	})
+	if err != nil {
+		fail("%v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tORIGEM\tETAPA\tNÚMERO\tCOMPETÊNCIA\tSTATUS\tTENTATIVAS\tERRO")
+	for _, d := range deadLetters {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			d.ID, d.Source, d.Stage, d.NumeroDocumento, d.Competencia, d.Status, d.Attempts, truncate(d.Error, 80))
+	}
+	w.Flush()
+	fmt.Printf("\n%d de %d registros\n", len(deadLetters), total)
+}
+
+func parseID(args []string) int64 {
+	if len(args) == 0 {
+		fail("Informe o ID do registro")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fail("ID inválido: %s", args[0])
+	}
+	return id
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/pdf"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+)
+
+const usage = `Uso: export [opções]
+
+Gera um ZIP com os XMLs de uma empresa e o manifesto (manifest.csv e manifest.json).
+
+Exemplo:
+  export -cnpj 12345678000190 -competencia 08/2024 -direcao emitidos
+
+Opções:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	empresaID := flag.Int("empresa-id", 0, "ID da empresa (alternativa ao CNPJ)")
+	cnpj := flag.String("cnpj", "", "CNPJ da empresa")
+	competencia := flag.String("competencia", "", "competência inicial (AAAAMM ou MM/AAAA)")
+	competenciaFim := flag.String("competencia-fim", "", "competência final (padrão: a inicial)")
+	tipos := flag.String("tipos", "", "tipos de documento separados por vírgula (padrão: todos)")
+	direcao := flag.String("direcao", model.ExportDirecaoAmbos, "emitidos, recebidos ou ambos")
+	incluirPDF := flag.Bool("pdf", false, "inclui o DANFSE/DANFE ao lado de cada XML")
+	output := flag.String("o", "", "arquivo de saída (padrão: xmls_{cnpj}_{competência}.zip; - para stdout)")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail("Erro ao carregar configurações: %v", err)
+	}
+	logger.Init(cfg)
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		fail("Erro ao inicializar banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	store, err := storage.NewStore(cfg, repository.NewEncryptionKeyRepository(db))
+	if err != nil {
+		fail("Erro ao inicializar armazenamento: %v", err)
+	}
+
+	filter := &model.ExportFilter{
+		CNPJ:              *cnpj,
+		CompetenciaInicio: *competencia,
+		CompetenciaFim:    *competenciaFim,
+		Direcao:           *direcao,
+		IncluirPDF:        *incluirPDF,
+	}
+	if *empresaID != 0 {
+		filter.EmpresaID = empresaID
+	}
+	types, err := service.ParseDocumentTypes(*tipos)
+	if err != nil {
+		fail("%v", err)
+	}
+	for _, documentType := range types {
+		filter.DocumentTypes = append(filter.DocumentTypes, model.DocumentType(documentType))
+	}
+
+	nfseRepo := repository.NewDocumentRepository(db)
+	pdfRenderer, err := pdf.NewRenderer(cfg.PDF)
+	if err != nil {
+		fail("Erro ao inicializar gerador de PDF: %v", err)
+	}
+	xmlService := service.NewDocumentXMLService(
+		nfseRepo,
+		repository.NewDocumentDownloadRepository(db),
+		store,
+		time.Duration(cfg.Storage.PresignExpiry)*time.Minute,
+	)
+
+	exportService := service.NewExportService(
+		nfseRepo,
+		repository.NewEmpresaRepository(db),
+		repository.NewExportRepository(db),
+		service.NewDocumentPDFService(xmlService, store, pdfRenderer),
+		store,
+		cfg.Export.SyncLimit,
+		time.Duration(cfg.Export.URLExpiry)*time.Hour,
+	)
+
+	// Comando administrativo: acesso aos documentos de todos os escritórios
+	ctx := tenant.WithSystem(context.Background())
+	if err := exportService.PrepararFiltro(ctx, filter); err != nil {
+		fail("%v", err)
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		path := *output
+		if path == "" {
+			path = service.ExportFileName(filter)
+		}
+		out, err = os.Create(path)
+		if err != nil {
+			fail("Erro ao criar %s: %v", path, err)
+		}
+		defer out.Close()
+	}
+
+	manifest, err := exportService.GerarZIP(ctx, filter, out)
+	if err != nil {
+		fail("Erro ao gerar exportação: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d documentos, %d XMLs exportados, %d com problema (ver manifest.csv)\n",
+		manifest.Documents, manifest.Files, manifest.Failed)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
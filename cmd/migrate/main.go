@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database"
+	"zemdocs/internal/logger"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+const usage = `Uso: migrate <comando> [argumentos]
+
+Aplica as migrações SQL versionadas de internal/database/migrations.
+
+Comandos:
+  up              aplica as migrações pendentes
+  down            desfaz o último grupo de migrações aplicado
+  status          lista as migrações aplicadas e pendentes
+  create <nome>   cria um novo par de arquivos .tx.up.sql/.tx.down.sql
+  unlock          libera o lock deixado por uma execução interrompida
+
+Bancos criados pelas migrações automáticas antigas recebem a migração base
+normalmente: ela só cria o que ainda não existe.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail("Erro ao carregar configurações: %v", err)
+	}
+	logger.Init(cfg)
+
+	ctx := context.Background()
+
+	// create só gera arquivos; não precisa de banco
+	if command == "create" {
+		if len(os.Args) < 3 {
+			fail("Informe o nome da migração, por exemplo: create add_documents_empresa_id")
+		}
+		files, err := database.NewMigrator(nil).CreateTxSQLMigrations(ctx, os.Args[2])
+		if err != nil {
+			fail("Erro ao criar migração: %v", err)
+		}
+		for _, file := range files {
+			fmt.Printf("Criado %s\n", file.Path)
+		}
+		return
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		fail("Erro ao inicializar banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	migrator := database.NewMigrator(db)
+	if err := migrator.Init(ctx); err != nil {
+		fail("Erro ao criar tabelas de controle das migrações: %v", err)
+	}
+
+	switch command {
+	case "up":
+		if err := database.RunMigrations(ctx, db); err != nil {
+			fail("%v", err)
+		}
+		fmt.Println("Migrações aplicadas")
+
+	case "down":
+		if err := migrator.Lock(ctx); err != nil {
+			fail("Erro ao obter lock das migrações: %v", err)
+		}
+		group, err := migrator.Rollback(ctx)
+		migrator.Unlock(ctx)
+		if err != nil {
+			fail("Erro ao desfazer migrações: %v", err)
+		}
+		if group.IsZero() {
+			fmt.Println("Nenhuma migração para desfazer")
+			return
+		}
+		fmt.Printf("Desfeito o grupo %d: %s\n", group.ID, group.Migrations)
+
+	case "status":
+		ms, err := migrator.MigrationsWithStatus(ctx)
+		if err != nil {
+			fail("Erro ao consultar migrações: %v", err)
+		}
+		printStatus(ms)
+
+	case "unlock":
+		if err := migrator.Unlock(ctx); err != nil {
+			fail("Erro ao liberar lock das migrações: %v", err)
+		}
+		fmt.Println("Lock liberado")
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func printStatus(ms migrate.MigrationSlice) {
+	for _, m := range ms {
+		status := "pendente"
+		if m.IsApplied() {
+			status = fmt.Sprintf("aplicada em %s (grupo %d)", m.MigratedAt.Format("02/01/2006 15:04"), m.GroupID)
+		}
+		fmt.Printf("%-16s %-40s %s\n", m.Name, strings.ReplaceAll(m.Comment, "_", " "), status)
+	}
+
+	pending := len(ms.Unapplied())
+	fmt.Printf("%d migrações, %d aplicadas, %d pendentes\n", len(ms), len(ms)-pending, pending)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
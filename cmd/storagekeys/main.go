@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+
+	"github.com/uptrace/bun"
+)
+
+const usage = `Uso: storagekeys <comando> [opções]
+
+Administra as chaves da criptografia em repouso (STORAGE_ENCRYPTION=true).
+
+Comandos:
+  status                      lista as chaves de dados e a chave mestra de cada uma
+  rotate-master               recifra as chaves de dados com a chave mestra atual
+  rotate-data -tenant ID      cria uma nova versão da chave de dados do tenant
+  reencrypt [-prefix XML/]    regrava os objetos com a chave atual (cifra os legados)
+
+O tenant é o ID do escritório dono do objeto. No reencrypt, os objetos legados
+em texto puro recebem o escritório do documento que os referencia (ou -tenant).
+
+Para trocar a chave mestra, adicione a nova em STORAGE_MASTER_KEY_FILE, aponte
+STORAGE_MASTER_KEY_ID para ela, rode rotate-master e só então remova a antiga.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	if len(os.Args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	tenantFlag := flags.String("tenant", "", "tenant (ID do escritório)")
+	prefix := flags.String("prefix", "", "prefixo das chaves a regravar")
+	flags.Parse(os.Args[2:])
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail("Erro ao carregar configurações: %v", err)
+	}
+	logger.Init(cfg)
+
+	if !cfg.Storage.Encryption {
+		fail("Criptografia em repouso desabilitada (STORAGE_ENCRYPTION=false)")
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		fail("Erro ao inicializar banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	keyRepo := repository.NewEncryptionKeyRepository(db)
+	store, err := storage.NewStore(cfg, keyRepo)
+	if err != nil {
+		fail("Erro ao inicializar armazenamento: %v", err)
+	}
+	encrypted := store.(*storage.EncryptedStore)
+
+	ctx := context.Background()
+	switch command {
+	case "status":
+		keys, err := keyRepo.List(ctx)
+		if err != nil {
+			fail("Erro ao listar chaves: %v", err)
+		}
+		for _, key := range keys {
+			fmt.Printf("%-20s v%-4d mestra=%-10s criada=%s\n",
+				key.Tenant, key.Version, key.MasterKeyID, key.CreatedAt.Format("02/01/2006 15:04"))
+		}
+		fmt.Printf("%d chaves de dados\n", len(keys))
+
+	case "rotate-master":
+		rewrapped, err := encrypted.RotateMasterKey(ctx)
+		if err != nil {
+			fail("Erro ao rotacionar chave mestra (%d recifradas): %v", rewrapped, err)
+		}
+		fmt.Printf("%d chaves de dados recifradas\n", rewrapped)
+
+	case "rotate-data":
+		if *tenantFlag == "" {
+			fail("Informe -tenant")
+		}
+		version, err := encrypted.RotateDataKey(ctx, *tenantFlag)
+		if err != nil {
+			fail("Erro ao rotacionar chave de dados: %v", err)
+		}
+		fmt.Printf("Chave de %s agora na versão %d\n", *tenantFlag, version)
+
+	case "reencrypt":
+		keys, err := store.List(ctx, *prefix)
+		if err != nil {
+			fail("Erro ao listar objetos: %v", err)
+		}
+		tenants, shared, err := documentTenants(ctx, db)
+		if err != nil {
+			fail("Erro ao listar documentos: %v", err)
+		}
+		updated, failed := 0, 0
+		for _, key := range keys {
+			objectTenant := *tenantFlag
+			if objectTenant == "" {
+				// Objeto de mais de um escritório ainda em texto puro: cifrá-lo com a
+				// chave de um deles deixaria o outro dependente dela
+				if shared[key] && !encryptedObject(ctx, encrypted, key) {
+					fmt.Fprintf(os.Stderr, "%s: chave usada por mais de um escritório; rode storagemigrate antes\n", key)
+					failed++
+					continue
+				}
+				objectTenant = tenants[key]
+			}
+			changed, err := encrypted.Reencrypt(ctx, key, objectTenant)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", key, err)
+				failed++
+				continue
+			}
+			if changed {
+				updated++
+			}
+		}
+		fmt.Printf("%d objetos, %d regravados, %d com erro\n", len(keys), updated, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// documentTenants tenant (escritório) do XML e do PDF em cache de cada documento,
+// pela chave do objeto. Chaves dos layouts anteriores ao separado por escritório
+// podem ser usadas por documentos de mais de um escritório; essas não têm um
+// tenant único e voltam em shared.
+func documentTenants(ctx context.Context, db *bun.DB) (tenants map[string]string, shared map[string]bool, err error) {
+	ctx = tenant.WithSystem(ctx)
+	documentRepo := repository.NewDocumentRepository(db)
+
+	tenants = make(map[string]string)
+	shared = make(map[string]bool)
+	afterID := 0
+	for {
+		documents, err := documentRepo.ListWithXMLKeyAfterID(ctx, afterID, 1000)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(documents) == 0 {
+			return tenants, shared, nil
+		}
+		for _, document := range documents {
+			documentTenant := storage.EscritorioTenant(document.EscritorioID)
+			for _, key := range []string{document.XMLKey, service.PDFObjectName(document)} {
+				if current, ok := tenants[key]; ok && current != documentTenant {
+					shared[key] = true
+				}
+				tenants[key] = documentTenant
+			}
+		}
+		afterID = documents[len(documents)-1].ID
+	}
+}
+
+// encryptedObject indica se o objeto já está cifrado
+func encryptedObject(ctx context.Context, store *storage.EncryptedStore, key string) bool {
+	info, err := store.Stat(ctx, key)
+	return err == nil && info.MetadataValue(storage.MetadataEncryption) != ""
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+)
+
+const usage = `Uso: storagemigrate [opções]
+
+Copia os XMLs do layout legado nfse/{ano}/{mês}/{número}.xml e do layout
+XML/NFS/{ano}/{MMAAAA}/{cnpj}/{número}.xml para a chave do escritório dono do
+documento, XML/{escritório}/NFS/{ano}/{MMAAAA}/{cnpj}/{número}.xml, e registra
+a chave no documento. Nos layouts anteriores a mesma NFS-e sincronizada por dois
+escritórios compartilhava o objeto; cada documento passa a ter a sua cópia,
+cifrada com a chave do seu escritório. Com -delete-legacy, o objeto anterior e o
+PDF em cache só são removidos quando nenhum documento usa mais a chave.
+
+As sincronizações atuais já gravam o XML pelo outbox com a chave registrada no
+documento (xml_key). Rode este comando uma vez, depois da atualização, para os
+documentos sincronizados antes disso: sem xml_key a análise do XML e os links
+de download não encontram o objeto. Com STORAGE_ENCRYPTION=true, defina
+STORAGE_ALLOW_PLAINTEXT=true durante a migração, para ler os objetos legados,
+e rode "storagekeys reencrypt" antes de voltar a desativá-lo.
+
+Opções:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	dryRun := flag.Bool("dry-run", false, "apenas relata o que seria feito")
+	resume := flag.Bool("resume", false, "continua a partir do checkpoint")
+	checkpoint := flag.String("checkpoint", ".storagemigrate.checkpoint", "arquivo de checkpoint")
+	deleteLegacy := flag.Bool("delete-legacy", false, "remove o objeto legado após copiá-lo e conferi-lo")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail("Erro ao carregar configurações: %v", err)
+	}
+	logger.Init(cfg)
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		fail("Erro ao inicializar banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	store, err := storage.NewStore(cfg, repository.NewEncryptionKeyRepository(db))
+	if err != nil {
+		fail("Erro ao inicializar armazenamento: %v", err)
+	}
+
+	layoutService := service.NewStorageLayoutService(repository.NewDocumentRepository(db), store, service.NewRetentionPolicy(cfg.Retention))
+	report, err := layoutService.Migrate(tenant.WithSystem(context.Background()), service.LayoutMigrationOptions{
+		DryRun:         *dryRun,
+		CheckpointFile: *checkpoint,
+		Resume:         *resume,
+		DeleteLegacy:   *deleteLegacy && !*dryRun,
+	})
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		fail("Migração interrompida: %v (use -resume para continuar)", err)
+	}
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
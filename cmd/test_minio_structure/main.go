@@ -10,19 +10,22 @@ func main() {
 	numeroNfse := "240000093"
 	competencia := "202408"
 	cnpjPrestador := "32800353000162"
-
-	// Criar cliente MinIO (apenas para demonstrar a geração de paths)
-	minioClient := &storage.MinIOClient{}
+	escritorioID := int64(7)
 
 	// Estrutura antiga
-	oldPath := minioClient.GenerateObjectName(numeroNfse, competencia)
+	oldPath := storage.GenerateObjectName(numeroNfse, competencia)
 	fmt.Printf("📁 Estrutura ANTIGA: %s\n", oldPath)
 
-	// Nova estrutura com CNPJ
-	newPath := minioClient.GenerateObjectNameWithCNPJ(numeroNfse, competencia, cnpjPrestador)
+	// Estrutura com CNPJ, sem escritório
+	cnpjPath := storage.GenerateObjectNameWithCNPJ(numeroNfse, competencia, cnpjPrestador)
+	fmt.Printf("📁 Estrutura CNPJ:   %s\n", cnpjPath)
+
+	// Nova estrutura com escritório e CNPJ
+	newPath := storage.GenerateEscritorioObjectName(&escritorioID, numeroNfse, competencia, cnpjPrestador)
 	fmt.Printf("📁 Estrutura NOVA:   %s\n", newPath)
 
 	fmt.Println("\n🔍 Detalhes da nova estrutura:")
+	fmt.Printf("   - Escritório: %d\n", escritorioID)
 	fmt.Printf("   - Ano: %s\n", competencia[:4])
 	fmt.Printf("   - Mês/Ano: %s%s\n", competencia[4:6], competencia[:4])
 	fmt.Printf("   - CNPJ Prestador: %s\n", cnpjPrestador)
@@ -30,9 +33,10 @@ func main() {
 
 	fmt.Println("\n📋 Exemplo de estrutura de diretórios no MinIO:")
 	fmt.Println("XML/")
-	fmt.Println("└── NFS/")
-	fmt.Println("    └── 2024/")
-	fmt.Println("        └── 082024/")
-	fmt.Println("            └── 32800353000162/")
-	fmt.Println("                └── 240000093.xml")
+	fmt.Println("└── 7/")
+	fmt.Println("    └── NFS/")
+	fmt.Println("        └── 2024/")
+	fmt.Println("            └── 082024/")
+	fmt.Println("                └── 32800353000162/")
+	fmt.Println("                    └── 240000093.xml")
 }
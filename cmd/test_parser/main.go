@@ -37,11 +37,11 @@ func main() {
 	fmt.Printf("Tipo RPS: %d\n", data.TipoRps)
 	
 	fmt.Printf("\n=== VALORES ===\n")
-	fmt.Printf("Valor Serviços: R$ %.2f\n", data.ValorServico)
-	fmt.Printf("Valor ISS: R$ %.2f\n", data.ValorIss)
-	fmt.Printf("Base Cálculo: R$ %.2f\n", data.BaseCalculo)
-	fmt.Printf("Alíquota: %.4f%%\n", data.Aliquota)
-	fmt.Printf("Valor Deduções: R$ %.2f\n", data.ValorDeducoes)
+	fmt.Printf("Valor Serviços: R$ %s\n", data.ValorServico.StringFixed(2))
+	fmt.Printf("Valor ISS: R$ %s\n", data.ValorIss.StringFixed(2))
+	fmt.Printf("Base Cálculo: R$ %s\n", data.BaseCalculo.StringFixed(2))
+	fmt.Printf("Alíquota: %s%%\n", data.Aliquota.StringFixed(4))
+	fmt.Printf("Valor Deduções: R$ %s\n", data.ValorDeducoes.StringFixed(2))
 	
 	fmt.Printf("\n=== PRESTADOR ===\n")
 	fmt.Printf("CNPJ: %s\n", data.CNPJPrestador)
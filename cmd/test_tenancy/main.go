@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/service"
+	"zemdocs/internal/tenant"
+
+	"github.com/uptrace/bun"
+)
+
+// Verifica o isolamento entre escritórios com dois tenants reais no banco
+// configurado: filtro dos repositórios e, como segunda barreira, row-level
+// security. Os registros criados são removidos ao final.
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		panic("Erro ao carregar configurações: " + err.Error())
+	}
+	logger.Init(cfg)
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		panic("Erro ao inicializar banco de dados: " + err.Error())
+	}
+	defer db.Close()
+
+	systemCtx := tenant.WithSystem(context.Background())
+	if err := database.RunMigrations(systemCtx, db); err != nil {
+		panic("Erro ao executar migrações: " + err.Error())
+	}
+
+	escritorioService := service.NewEscritorioService(
+		repository.NewEscritorioRepository(db),
+		repository.NewAPITokenRepository(db),
+	)
+	empresaRepo := repository.NewEmpresaRepository(db)
+	documentRepo := repository.NewDocumentRepository(db)
+
+	suffix := time.Now().Format("150405.000")
+	escritorioA, err := escritorioService.CriarEscritorio(systemCtx, &model.EscritorioRequest{Nome: "Teste A " + suffix})
+	if err != nil {
+		panic(err)
+	}
+	escritorioB, err := escritorioService.CriarEscritorio(systemCtx, &model.EscritorioRequest{Nome: "Teste B " + suffix})
+	if err != nil {
+		panic(err)
+	}
+	defer cleanup(db, escritorioA.ID, escritorioB.ID)
+
+	ctxA := tenant.WithEscritorio(context.Background(), escritorioA.ID)
+	ctxB := tenant.WithEscritorio(context.Background(), escritorioB.ID)
+
+	fmt.Printf("🏢 Escritórios de teste: A=%d, B=%d\n", escritorioA.ID, escritorioB.ID)
+
+	// Dados de cada escritório
+	seed := time.Now().UnixNano() % 1e12
+	empresaA := &model.Empresa{CNPJ: fmt.Sprintf("%014d", seed), RazaoSocial: "Empresa A"}
+	empresaB := &model.Empresa{CNPJ: fmt.Sprintf("%014d", seed+1), RazaoSocial: "Empresa B"}
+	must(empresaRepo.Create(ctxA, empresaA))
+	must(empresaRepo.Create(ctxB, empresaB))
+
+	documentA := newDocument(fmt.Sprintf("TEN-A-%d", seed), empresaA)
+	documentB := newDocument(fmt.Sprintf("TEN-B-%d", seed), empresaB)
+	must(documentRepo.Create(ctxA, documentA))
+	must(documentRepo.Create(ctxB, documentB))
+
+	failures := 0
+	check := func(name string, ok bool) {
+		if ok {
+			fmt.Printf("✅ %s\n", name)
+			return
+		}
+		fmt.Printf("❌ %s\n", name)
+		failures++
+	}
+
+	fmt.Println("\n🔍 Filtro dos repositórios:")
+
+	check("registro recebe o escritório do contexto",
+		documentA.EscritorioID != nil && *documentA.EscritorioID == escritorioA.ID)
+
+	_, err = documentRepo.GetByID(ctxA, documentA.ID)
+	check("A lê o próprio documento", err == nil)
+
+	_, err = documentRepo.GetByID(ctxA, documentB.ID)
+	check("A não lê documento de B", err != nil)
+
+	_, err = empresaRepo.GetByCNPJ(ctxB, empresaA.CNPJ)
+	check("B não encontra empresa de A pelo CNPJ", err != nil)
+
+	empresas, err := empresaRepo.Search(ctxA, "Empresa ", 100, 0)
+	check("busca de empresas de A não traz empresas de B", err == nil && !containsEmpresa(empresas, empresaB.ID))
+
+	err = empresaRepo.CreateTelefone(ctxA, &model.EmpresaTelefone{EmpresaID: empresaB.ID, Numero: "999999999"})
+	check("A não grava telefone em empresa de B", err != nil)
+
+	must(documentRepo.Delete(ctxA, documentB.ID))
+	_, err = documentRepo.GetByID(ctxB, documentB.ID)
+	check("exclusão por A não remove documento de B", err == nil)
+
+	forged := newDocument(fmt.Sprintf("TEN-F-%d", seed), empresaA)
+	forged.EscritorioID = &escritorioB.ID
+	must(documentRepo.Create(ctxA, forged))
+	check("A não grava documento em nome de B", forged.EscritorioID != nil && *forged.EscritorioID == escritorioA.ID)
+
+	_, err = documentRepo.GetByID(context.Background(), documentA.ID)
+	check("consulta sem escritório é recusada", errors.Is(err, tenant.ErrNoScope))
+
+	_, err = documentRepo.GetByID(systemCtx, documentB.ID)
+	check("acesso de sistema lê qualquer escritório", err == nil)
+
+	fmt.Println("\n🔍 Row-level security:")
+	checkRLS(db, escritorioA.ID, documentA.ID, documentB.ID, check)
+
+	fmt.Println()
+	if failures > 0 {
+		fmt.Printf("❌ %d verificações falharam\n", failures)
+		cleanup(db, escritorioA.ID, escritorioB.ID)
+		os.Exit(1)
+	}
+	fmt.Println("✅ Isolamento entre escritórios verificado")
+}
+
+// checkRLS consulta sem o filtro dos repositórios, dentro de uma transação com
+// app.system desligado e app.escritorio_id definido, como faz o middleware da
+// API. Sem nenhuma das variáveis nada deve ser visível.
+func checkRLS(db *bun.DB, escritorioID int64, own, other int, check func(string, bool)) {
+	ctx := context.Background()
+
+	var bypass bool
+	if err := db.NewRaw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(ctx, &bypass); err != nil {
+		panic(err)
+	}
+	if bypass {
+		fmt.Println("⚠️  Usuário do banco ignora RLS (superusuário ou BYPASSRLS); verificação pulada")
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		panic(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('app.system', 'off', true)"); err != nil {
+		panic(err)
+	}
+
+	var visible int
+	must(tx.NewRaw("SELECT count(*) FROM documents WHERE id IN (?, ?)", own, other).Scan(ctx, &visible))
+	check("sem escritório nem acesso de sistema nenhum documento é visível", visible == 0)
+
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('app.escritorio_id', ?, true)", fmt.Sprint(escritorioID)); err != nil {
+		panic(err)
+	}
+
+	must(tx.NewRaw("SELECT count(*) FROM documents WHERE id IN (?, ?)", own, other).Scan(ctx, &visible))
+	check("consulta sem filtro só enxerga o próprio documento", visible == 1)
+
+	result, err := tx.ExecContext(ctx, "UPDATE documents SET status = status WHERE id = ?", other)
+	if err != nil {
+		panic(err)
+	}
+	updated, _ := result.RowsAffected()
+	check("atualização sem filtro não alcança documento de outro escritório", updated == 0)
+
+	_, err = tx.ExecContext(ctx, "UPDATE documents SET escritorio_id = NULL WHERE id = ?", own)
+	check("documento não pode ser movido para fora do escritório", err != nil)
+}
+
+func newDocument(numero string, empresa *model.Empresa) *model.Document {
+	return &model.Document{
+		DocumentType:        model.DocumentTypeNFSe,
+		NumeroDocumento:     numero,
+		CodigoVerificacao:   "TESTE",
+		DataEmissao:         time.Now(),
+		Competencia:         time.Now().Format("200601"),
+		Status:              "Emitida",
+		CNPJEmitente:        empresa.CNPJ,
+		RazaoSocialEmitente: empresa.RazaoSocial,
+	}
+}
+
+func containsEmpresa(empresas []*model.Empresa, id int) bool {
+	for _, empresa := range empresas {
+		if empresa.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanup remove os dados dos escritórios de teste
+func cleanup(db *bun.DB, ids ...int64) {
+	ctx := context.Background()
+	queries := []string{
+		"DELETE FROM documents WHERE escritorio_id IN (?)",
+		"DELETE FROM empresa_telefones WHERE empresa_id IN (SELECT id FROM empresas WHERE escritorio_id IN (?))",
+		"DELETE FROM empresas WHERE escritorio_id IN (?)",
+		"DELETE FROM escritorios WHERE id IN (?)",
+	}
+	for _, query := range queries {
+		if _, err := db.ExecContext(ctx, query, bun.In(ids)); err != nil {
+			fmt.Printf("⚠️  Erro ao limpar dados de teste: %v\n", err)
+		}
+	}
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
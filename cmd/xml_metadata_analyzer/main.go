@@ -48,8 +48,8 @@ func main() {
 	fmt.Printf("Série RPS: %s\n", metadata.SerieRps)
 	fmt.Printf("Data Emissão: %s\n", metadata.DataEmissao.Format("2006-01-02 15:04:05"))
 	fmt.Printf("Código Verificação: %s\n", metadata.CodigoVerificacao)
-	fmt.Printf("Valor Serviço: R$ %.2f\n", metadata.ValorServico)
-	fmt.Printf("Valor ISS: R$ %.2f\n", metadata.ValorIss)
+	fmt.Printf("Valor Serviço: R$ %s\n", metadata.ValorServico.StringFixed(2))
+	fmt.Printf("Valor ISS: R$ %s\n", metadata.ValorIss.StringFixed(2))
 	fmt.Printf("Discriminação: %s\n", metadata.Discriminacao)
 	fmt.Printf("CNPJ Prestador: %s\n", metadata.CNPJPrestador)
 	fmt.Printf("Razão Social Prestador: %s\n", metadata.RazaoSocialPrestador)
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handler para a consulta da trilha de auditoria
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler cria uma nova instância do handler de auditoria
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// ListarAuditoria lista as alterações registradas, mais recentes primeiro, com
+// paginação por cursor (next_cursor da página anterior)
+func (h *AuditHandler) ListarAuditoria(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	response, err := h.auditService.Listar(c.Request.Context(), filter, c.Query("cursor"), limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportarAuditoria envia em CSV todas as alterações que atendem aos filtros
+func (h *AuditHandler) ExportarAuditoria(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "auditoria_"+time.Now().Format("20060102")+".csv"))
+
+	total, err := h.auditService.Exportar(c.Request.Context(), filter, c.Writer)
+	if err != nil {
+		// Com o CSV já em envio não há como trocar o status; o cliente recebe um arquivo truncado
+		logger.Error(err, "Erro ao exportar auditoria")
+		if !c.Writer.Written() {
+			h.respondError(c, err)
+		}
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Auditoria exportada: %d registros", total))
+}
+
+func (h *AuditHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// auditFilterFromQuery monta os filtros da auditoria a partir da query string:
+// entity, entity_id, action, actor e data_inicio/data_fim (AAAA-MM-DD, inclusivas)
+func auditFilterFromQuery(c *gin.Context) (*model.AuditFilter, error) {
+	filter := &model.AuditFilter{
+		Entity: c.Query("entity"),
+		Action: c.Query("action"),
+		Actor:  c.Query("actor"),
+	}
+
+	if value := c.Query("entity_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("entity_id inválido")
+		}
+		filter.EntityID = &id
+	}
+
+	var err error
+	if value := c.Query("data_inicio"); value != "" {
+		if filter.DataInicio, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			return nil, fmt.Errorf("data_inicio inválida (use AAAA-MM-DD)")
+		}
+	}
+	if value := c.Query("data_fim"); value != "" {
+		dataFim, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("data_fim inválida (use AAAA-MM-DD)")
+		}
+		// A data final é inclusiva
+		filter.DataFim = dataFim.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
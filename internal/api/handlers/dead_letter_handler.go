@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterHandler handler para a fila de documentos com falha
+type DeadLetterHandler struct {
+	deadLetterService *service.DeadLetterService
+}
+
+// NewDeadLetterHandler cria uma nova instância do handler da fila de falhas
+func NewDeadLetterHandler(deadLetterService *service.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterService: deadLetterService,
+	}
+}
+
+// ListarFalhas lista documentos da fila de falhas com filtros e paginação
+func (h *DeadLetterHandler) ListarFalhas(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filter := model.DeadLetterFilter{
+		Status:      model.DeadLetterStatus(c.Query("status")),
+		Stage:       c.Query("stage"),
+		Source:      c.Query("source"),
+		Competencia: c.Query("competencia"),
+		Limit:       limit,
+		Offset:      offset,
+	}
+
+	deadLetters, total, err := h.deadLetterService.Listar(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar falhas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"falhas": deadLetters,
+		"limit":  limit,
+		"offset": offset,
+		"total":  total,
+	})
+}
+
+// ConsultarFalha retorna um registro da fila de falhas com o payload bruto
+func (h *DeadLetterHandler) ConsultarFalha(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	deadLetter, err := h.deadLetterService.Consultar(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registro não encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deadLetter)
+}
+
+// ReprocessarFalha tenta novamente salvar o documento a partir do payload preservado
+func (h *DeadLetterHandler) ReprocessarFalha(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	deadLetter, err := h.deadLetterService.Reprocessar(c.Request.Context(), id)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, service.ErrDeadLetterResolved) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deadLetter)
+}
+
+// DescartarFalha descarta um registro da fila de falhas
+func (h *DeadLetterHandler) DescartarFalha(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.deadLetterService.Descartar(c.Request.Context(), id); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, service.ErrDeadLetterResolved) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Registro descartado com sucesso",
+	})
+}
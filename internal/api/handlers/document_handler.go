@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"zemdocs/internal/audit"
 	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/scheduler"
 	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
 	"zemdocs/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +20,13 @@ import (
 
 type NFSeHandler struct {
 	nfseService *service.NFSeService
+	scheduler   *scheduler.Scheduler
 }
 
-func NewNFSeHandler(nfseService *service.NFSeService) *NFSeHandler {
+func NewNFSeHandler(nfseService *service.NFSeService, jobScheduler *scheduler.Scheduler) *NFSeHandler {
 	return &NFSeHandler{
 		nfseService: nfseService,
+		scheduler:   jobScheduler,
 	}
 }
 
@@ -153,27 +161,32 @@ func (h *NFSeHandler) TestarAPIExterna(c *gin.Context) {
 	})
 }
 
-// SincronizarManual executa sincronização manual das NFS-e
+// SincronizarManual dispara a sincronização das NFS-e em segundo plano.
+// O andamento pode ser acompanhado em /api/v1/jobs/runs/:id
 func (h *NFSeHandler) SincronizarManual(c *gin.Context) {
 	competencia := c.DefaultQuery("competencia", "202408")
 
-	ctx := c.Request.Context()
-
-	// Executar sincronização
-	err := h.nfseService.SincronizarNFSe(ctx, competencia)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":       "Erro na sincronização",
-			"details":     err.Error(),
-			"competencia": competencia,
-		})
-		return
+	// Rota de sistema: os documentos sincronizados pertencem ao escritório
+	// informado e são registrados na auditoria em nome de quem disparou
+	requestCtx := c.Request.Context()
+	if value := c.Query("escritorio_id"); value != "" {
+		escritorioID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || escritorioID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "escritorio_id inválido"})
+			return
+		}
+		requestCtx = tenant.WithEscritorio(requestCtx, escritorioID)
 	}
+	job := scheduler.NewFuncJob("nfse-sync-manual-"+competencia, func(ctx context.Context) error {
+		return h.nfseService.SincronizarNFSe(audit.Inherit(tenant.Inherit(ctx, requestCtx), requestCtx), competencia)
+	})
+	runID := h.scheduler.RunOnce(job)
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusAccepted, gin.H{
 		"success":     true,
-		"message":     "Sincronização executada com sucesso",
+		"message":     "Sincronização iniciada",
 		"competencia": competencia,
+		"run_id":      runID,
 	})
 }
 
@@ -187,16 +200,19 @@ func (h *NFSeHandler) AnalisarXMLMetadados(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// Primeiro buscar a NFS-e no banco para obter a competência
-	nfse, err := h.nfseService.ConsultarPorNumero(ctx, numeroNfse)
-	if err != nil {
+	// Primeiro verificar se a NFS-e existe no banco
+	if _, err := h.nfseService.ConsultarPorNumero(ctx, numeroNfse); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "NFS-e não encontrada: " + err.Error()})
 		return
 	}
 
-	// Buscar o XML do MinIO
-	xmlContent, err := h.nfseService.GetXMLContent(ctx, numeroNfse, nfse.Competencia)
+	// Buscar o XML pela chave registrada no documento
+	xmlContent, err := h.nfseService.GetXMLContent(ctx, numeroNfse)
 	if err != nil {
+		if errors.Is(err, storage.ErrHashMismatch) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "XML corrompido no armazenamento: " + err.Error()})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "XML não encontrado: " + err.Error()})
 		return
 	}
@@ -1,24 +1,81 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zemdocs/internal/api/middleware"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 // DocumentHandler handler básico para documentos (compatibilidade)
-type DocumentHandler struct{}
+type DocumentHandler struct {
+	retentionService *service.RetentionService
+	xmlService       *service.DocumentXMLService
+	pdfService       *service.DocumentPDFService
+	searchService    *service.DocumentSearchService
+	listService      *service.DocumentListService
+	dashboardService *service.DashboardService
+}
 
 // NewDocumentHandler cria uma nova instância do handler de documentos
-func NewDocumentHandler() *DocumentHandler {
-	return &DocumentHandler{}
+func NewDocumentHandler(retentionService *service.RetentionService, xmlService *service.DocumentXMLService, pdfService *service.DocumentPDFService, searchService *service.DocumentSearchService, listService *service.DocumentListService, dashboardService *service.DashboardService) *DocumentHandler {
+	return &DocumentHandler{
+		retentionService: retentionService,
+		xmlService:       xmlService,
+		pdfService:       pdfService,
+		searchService:    searchService,
+		listService:      listService,
+		dashboardService: dashboardService,
+	}
 }
 
-// ListarDocumentos lista documentos (placeholder)
+// ListarDocumentos lista documentos com filtros, ordenação e paginação por cursor
+// (next_cursor da resposta no parâmetro cursor da próxima requisição)
 func (h *DocumentHandler) ListarDocumentos(c *gin.Context) {
+	req, err := listRequestFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.listService.Listar(c.Request.Context(), req, c.Query("cursor"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// BuscarDocumentos busca textual nos documentos, em ordem de relevância, com trechos destacados
+func (h *DocumentHandler) BuscarDocumentos(c *gin.Context) {
+	filter, err := searchFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, total, err := h.searchService.Buscar(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
-		"data":    []interface{}{},
+		"documents": results,
+		"q":         filter.Query,
+		"limit":     filter.Limit,
+		"offset":    filter.Offset,
+		"total":     total,
 	})
 }
 
@@ -43,44 +100,516 @@ func (h *DocumentHandler) AtualizarDocumento(c *gin.Context) {
 	})
 }
 
-// ExcluirDocumento exclui um documento (placeholder)
+// ExcluirDocumento exclui logicamente um documento; o XML é mantido até a purga
 func (h *DocumentHandler) ExcluirDocumento(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.retentionService.ExcluirDocumento(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Documento excluído com sucesso"})
+}
+
+// RestaurarDocumento restaura um documento excluído
+func (h *DocumentHandler) RestaurarDocumento(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	document, err := h.retentionService.RestaurarDocumento(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// PurgarDocumento remove definitivamente um documento excluído e seu XML;
+// recusado enquanto houver retenção fiscal
+func (h *DocumentHandler) PurgarDocumento(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.retentionService.PurgarDocumento(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Documento removido definitivamente"})
+}
+
+// ListarExcluidos lista os documentos excluídos, que podem ser restaurados ou purgados
+func (h *DocumentHandler) ListarExcluidos(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	documents, total, err := h.retentionService.ListarExcluidos(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
+		"documents": documents,
+		"limit":     limit,
+		"offset":    offset,
+		"total":     total,
 	})
 }
 
-// DadosGrafico retorna dados para gráfico (placeholder)
+// ConsultarRetencao retorna a situação da retenção fiscal do documento
+func (h *DocumentHandler) ConsultarRetencao(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	status, err := h.retentionService.ConsultarRetencao(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// BaixarXML entrega o XML do documento (inline, ou como anexo com download=true).
+// Responde 304 quando o If-None-Match confere com o hash do XML.
+func (h *DocumentHandler) BaixarXML(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	xml, err := h.xmlService.ConsultarXML(ctx, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Sem hash registrado não há ETag: uma ETag vazia faria todos os XMLs parecerem iguais
+	etag := ""
+	if xml.ETag != "" {
+		etag = `"` + xml.ETag + `"`
+		c.Header("ETag", etag)
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	if match := c.GetHeader("If-None-Match"); match != "" && etag != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if err := h.xmlService.BaixarXML(ctx, xml, downloadAccess(c)); err != nil {
+		c.Header("ETag", "")
+		h.respondError(c, err)
+		return
+	}
+
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, xml.FileName))
+	c.Header("X-Content-Type-Options", "nosniff")
+	if !xml.LastModified.IsZero() {
+		c.Header("Last-Modified", xml.LastModified.UTC().Format(http.TimeFormat))
+	}
+	c.Data(http.StatusOK, xml.ContentType, xml.Content)
+}
+
+// GerarLinkXML retorna um link pré-assinado de curta duração para o XML do documento
+func (h *DocumentHandler) GerarLinkXML(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	link, err := h.xmlService.GerarURL(c.Request.Context(), id, downloadAccess(c))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// BaixarPDF entrega o DANFSE/DANFE do documento, gerado a partir do XML e mantido em cache
+func (h *DocumentHandler) BaixarPDF(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	documentPDF, err := h.pdfService.ConsultarPDF(ctx, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	etag := ""
+	if documentPDF.ETag != "" {
+		etag = `"` + documentPDF.ETag + `"`
+		c.Header("ETag", etag)
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	if match := c.GetHeader("If-None-Match"); match != "" && etag != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if err := h.pdfService.BaixarPDF(ctx, documentPDF, downloadAccess(c)); err != nil {
+		c.Header("ETag", "")
+		h.respondError(c, err)
+		return
+	}
+
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, documentPDF.FileName))
+	c.Data(http.StatusOK, "application/pdf", documentPDF.Content)
+}
+
+// DadosGrafico retorna documentos emitidos, faturamento e ISS de cada mês do período
 func (h *DocumentHandler) DadosGrafico(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
-	})
+	filter, err := dashboardFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	points, err := h.dashboardService.Grafico(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, points)
+}
+
+// EstatisticasDocumentos retorna a quantidade de documentos emitidos no período e no mês
+func (h *DocumentHandler) EstatisticasDocumentos(c *gin.Context) {
+	filter, err := dashboardFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.dashboardService.Estatisticas(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
 }
 
-// DocumentosRecentes retorna documentos recentes (placeholder)
+// DocumentosRecentes retorna os últimos documentos emitidos, com os filtros da listagem
 func (h *DocumentHandler) DocumentosRecentes(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
-	})
+	req, err := listRequestFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Sort = service.DefaultDocumentSort
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+
+	page, err := h.listService.Listar(c.Request.Context(), req, "")
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": page.Documents})
 }
 
-// DadosReceita retorna dados de receita (placeholder)
+// DadosReceita retorna o faturamento do período e do mês, com a variação mensal
 func (h *DocumentHandler) DadosReceita(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
-	})
+	filter, err := dashboardFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revenue, err := h.dashboardService.Receita(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revenue)
 }
 
-// DadosCrescimento retorna dados de crescimento (placeholder)
+// DadosCrescimento retorna a variação do faturamento do mês sobre o mês anterior
 func (h *DocumentHandler) DadosCrescimento(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
-	})
+	filter, err := dashboardFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	growth, err := h.dashboardService.Crescimento(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, growth)
 }
 
-// MetricasISS retorna métricas de ISS (placeholder)
+// MetricasISS retorna o ISS devido, retido e a recolher. A meta opcional
+// (meta_iss) é o ISS esperado no período.
 func (h *DocumentHandler) MetricasISS(c *gin.Context) {
+	filter, err := dashboardFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var meta *decimal.Decimal
+	if value := c.Query("meta_iss"); value != "" {
+		parsed, err := decimal.NewFromString(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "meta_iss inválida"})
+			return
+		}
+		meta = &parsed
+	}
+
+	metrics, err := h.dashboardService.MetricasISS(c.Request.Context(), filter, meta)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// MaioresTomadores retorna os tomadores com maior faturamento no período
+func (h *DocumentHandler) MaioresTomadores(c *gin.Context) {
+	filter, err := dashboardFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+		return
+	}
+
+	tomadores, err := h.dashboardService.TopTomadores(c.Request.Context(), filter, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Endpoint em desenvolvimento",
+		"tomadores":          tomadores,
+		"competencia_inicio": filter.CompetenciaInicio,
+		"competencia_fim":    filter.CompetenciaFim,
 	})
 }
+
+func (h *DocumentHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentRetained):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrXMLNotAvailable):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrXMLCorrupted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPDFNotSupported):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// downloadAccess identifica o acesso para a auditoria dos downloads
+func downloadAccess(c *gin.Context) service.DownloadAccess {
+	return service.DownloadAccess{
+		Principal: middleware.GetPrincipal(c).Name,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// etagMatches verifica o If-None-Match, que pode trazer várias ETags ou "*"
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// dashboardFilterFromQuery monta o recorte do dashboard a partir da query string:
+// empresa_id (emitente) e competencia ou competencia_inicio/competencia_fim
+func dashboardFilterFromQuery(c *gin.Context) (*model.DashboardFilter, error) {
+	filter := &model.DashboardFilter{
+		CompetenciaInicio: c.DefaultQuery("competencia_inicio", c.Query("competencia")),
+		CompetenciaFim:    c.DefaultQuery("competencia_fim", c.Query("competencia")),
+	}
+	if value := c.Query("empresa_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("empresa_id inválido")
+		}
+		filter.EmpresaID = &id
+	}
+	return filter, nil
+}
+
+// searchFilterFromQuery monta a busca a partir da query string: q, os filtros
+// de documentFilterFromQuery, limit e offset
+func searchFilterFromQuery(c *gin.Context) (*model.DocumentSearchFilter, error) {
+	documentFilter, err := documentFilterFromQuery(c)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &model.DocumentSearchFilter{
+		Query:          c.Query("q"),
+		DocumentFilter: *documentFilter,
+	}
+	if filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "0")); err != nil {
+		return nil, fmt.Errorf("limit inválido")
+	}
+	if filter.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+		return nil, fmt.Errorf("offset inválido")
+	}
+
+	return filter, nil
+}
+
+// listRequestFromQuery monta a listagem a partir da query string: os filtros de
+// documentFilterFromQuery, sort (coluna, com "-" para decrescente) e limit
+func listRequestFromQuery(c *gin.Context) (*model.DocumentListRequest, error) {
+	documentFilter, err := documentFilterFromQuery(c)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &model.DocumentListRequest{DocumentFilter: *documentFilter}
+	if sort := c.Query("sort"); sort != "" {
+		req.Sort = model.DocumentSort{
+			Column: strings.TrimPrefix(sort, "-"),
+			Desc:   strings.HasPrefix(sort, "-"),
+		}
+	}
+	if req.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "0")); err != nil {
+		return nil, fmt.Errorf("limit inválido")
+	}
+
+	return req, nil
+}
+
+// documentFilterFromQuery monta os filtros de documentos a partir da query string:
+// tipos e status (separados por vírgula), competencia ou competencia_inicio/competencia_fim,
+// data_inicio/data_fim (AAAA-MM-DD, inclusivas), cnpj, cnpj_emitente, cnpj_destinatario,
+// empresa_id, emitente_empresa_id, destinatario_empresa_id, valor_min, valor_max,
+// item_lista_servico e codigo_municipio
+func documentFilterFromQuery(c *gin.Context) (*model.DocumentFilter, error) {
+	filter := &model.DocumentFilter{
+		CompetenciaInicio: c.DefaultQuery("competencia_inicio", c.Query("competencia")),
+		CompetenciaFim:    c.DefaultQuery("competencia_fim", c.Query("competencia")),
+		CNPJ:              c.Query("cnpj"),
+		CNPJEmitente:      c.Query("cnpj_emitente"),
+		CNPJDestinatario:  c.Query("cnpj_destinatario"),
+		ItemListaServico:  c.Query("item_lista_servico"),
+		CodigoMunicipio:   c.Query("codigo_municipio"),
+	}
+
+	types, err := service.ParseDocumentTypes(c.Query("tipos"))
+	if err != nil {
+		return nil, err
+	}
+	for _, documentType := range types {
+		filter.DocumentTypes = append(filter.DocumentTypes, model.DocumentType(documentType))
+	}
+
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Status = append(filter.Status, status)
+		}
+	}
+
+	if value := c.Query("data_inicio"); value != "" {
+		if filter.DataInicio, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			return nil, fmt.Errorf("data_inicio inválida (use AAAA-MM-DD)")
+		}
+	}
+	if value := c.Query("data_fim"); value != "" {
+		dataFim, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("data_fim inválida (use AAAA-MM-DD)")
+		}
+		// A data final é inclusiva
+		filter.DataFim = dataFim.AddDate(0, 0, 1)
+	}
+
+	ids := map[string]**int{
+		"empresa_id":              &filter.EmpresaID,
+		"emitente_empresa_id":     &filter.EmitenteEmpresaID,
+		"destinatario_empresa_id": &filter.DestinatarioEmpresaID,
+	}
+	for param, target := range ids {
+		if value := c.Query(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s inválido", param)
+			}
+			*target = &id
+		}
+	}
+
+	valores := map[string]**decimal.Decimal{
+		"valor_min": &filter.ValorMin,
+		"valor_max": &filter.ValorMax,
+	}
+	for param, target := range valores {
+		if value := c.Query(param); value != "" {
+			valor, err := decimal.NewFromString(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s inválido", param)
+			}
+			*target = &valor
+		}
+	}
+
+	return filter, nil
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -12,7 +15,8 @@ import (
 
 // EmpresaHandler handler para operações de empresas
 type EmpresaHandler struct {
-	empresaService *service.EmpresaService
+	empresaService     *service.EmpresaService
+	certificadoService *service.CertificadoService
 }
 
 // NewEmpresaHandler cria uma nova instância do handler de empresas
@@ -22,6 +26,12 @@ func NewEmpresaHandler(empresaService *service.EmpresaService) *EmpresaHandler {
 	}
 }
 
+// SetCertificadoService habilita o envio de certificados; sem ele (criptografia
+// em repouso desabilitada) as rotas de certificado respondem 503
+func (h *EmpresaHandler) SetCertificadoService(certificadoService *service.CertificadoService) {
+	h.certificadoService = certificadoService
+}
+
 // ConsultarCNPJAPI consulta dados de CNPJ na API CNPJA
 func (h *EmpresaHandler) ConsultarCNPJAPI(c *gin.Context) {
 	cnpj := c.Param("cnpj")
@@ -167,6 +177,55 @@ func (h *EmpresaHandler) ConsultarEmpresaPorID(c *gin.Context) {
 	c.JSON(http.StatusOK, empresa)
 }
 
+// ListarDocumentosEmitidos lista os documentos em que a empresa é a emitente
+func (h *EmpresaHandler) ListarDocumentosEmitidos(c *gin.Context) {
+	h.listarDocumentos(c, model.ExportDirecaoEmitidos)
+}
+
+// ListarDocumentosRecebidos lista os documentos em que a empresa é a tomadora
+func (h *EmpresaHandler) ListarDocumentosRecebidos(c *gin.Context) {
+	h.listarDocumentos(c, model.ExportDirecaoRecebidos)
+}
+
+// listarDocumentos lista com paginação os documentos vinculados à empresa na direção informada
+func (h *EmpresaHandler) listarDocumentos(c *gin.Context, direcao string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	documents, total, err := h.empresaService.ListarDocumentos(c.Request.Context(), id, direcao, limit, offset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Empresa não encontrada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar documentos da empresa"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents": documents,
+		"direcao":   direcao,
+		"limit":     limit,
+		"offset":    offset,
+		"total":     total,
+		"page":      (offset / limit) + 1,
+		"pages":     (total + limit - 1) / limit,
+	})
+}
+
 // ConsultarEmpresaPorCNPJ consulta uma empresa por CNPJ
 func (h *EmpresaHandler) ConsultarEmpresaPorCNPJ(c *gin.Context) {
 	cnpj := c.Param("cnpj")
@@ -230,6 +289,132 @@ func (h *EmpresaHandler) ExcluirEmpresa(c *gin.Context) {
 	})
 }
 
+// RestaurarEmpresa restaura uma empresa excluída
+func (h *EmpresaHandler) RestaurarEmpresa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	empresa, err := h.empresaService.RestaurarEmpresa(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, empresa)
+}
+
+// PurgarEmpresa remove definitivamente uma empresa excluída e seus dados relacionados
+func (h *EmpresaHandler) PurgarEmpresa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.empresaService.PurgarEmpresa(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Empresa removida definitivamente",
+	})
+}
+
+// ListarEmpresasExcluidas lista as empresas excluídas, que podem ser restauradas ou purgadas
+func (h *EmpresaHandler) ListarEmpresasExcluidas(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	empresas, total, err := h.empresaService.ListarExcluidas(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"empresas": empresas,
+		"limit":    limit,
+		"offset":   offset,
+		"total":    total,
+	})
+}
+
+// EnviarCertificado grava o certificado A1 (campo "certificado" do formulário),
+// cifrado com a chave do escritório
+func (h *EmpresaHandler) EnviarCertificado(c *gin.Context) {
+	if h.certificadoService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Certificados exigem criptografia em repouso (STORAGE_ENCRYPTION=true)"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	file, err := c.FormFile("certificado")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Arquivo do certificado não informado"})
+		return
+	}
+	reader, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler certificado"})
+		return
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler certificado"})
+		return
+	}
+
+	enviadoEm, err := h.certificadoService.Enviar(c.Request.Context(), id, content)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                "Certificado gravado com sucesso",
+		"certificado_enviado_em": enviadoEm,
+	})
+}
+
+// RemoverCertificado remove o certificado da empresa
+func (h *EmpresaHandler) RemoverCertificado(c *gin.Context) {
+	if h.certificadoService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Certificados exigem criptografia em repouso (STORAGE_ENCRYPTION=true)"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.certificadoService.Remover(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Certificado removido com sucesso",
+	})
+}
+
 // EstatisticasEmpresas retorna estatísticas das empresas
 func (h *EmpresaHandler) EstatisticasEmpresas(c *gin.Context) {
 	stats, err := h.empresaService.ObterEstatisticas(c.Request.Context())
@@ -240,3 +425,14 @@ func (h *EmpresaHandler) EstatisticasEmpresas(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+func (h *EmpresaHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmpresaNotFound), errors.Is(err, service.ErrCertificadoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
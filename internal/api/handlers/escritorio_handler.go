@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EscritorioHandler handler para a administração dos escritórios e de suas credenciais
+type EscritorioHandler struct {
+	escritorioService *service.EscritorioService
+}
+
+// NewEscritorioHandler cria uma nova instância do handler de escritórios
+func NewEscritorioHandler(escritorioService *service.EscritorioService) *EscritorioHandler {
+	return &EscritorioHandler{
+		escritorioService: escritorioService,
+	}
+}
+
+// ListarEscritorios lista os escritórios cadastrados
+func (h *EscritorioHandler) ListarEscritorios(c *gin.Context) {
+	escritorios, err := h.escritorioService.ListarEscritorios(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar escritórios"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"escritorios": escritorios,
+	})
+}
+
+// ConsultarEscritorio retorna um escritório por ID
+func (h *EscritorioHandler) ConsultarEscritorio(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	escritorio, err := h.escritorioService.ConsultarEscritorio(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, escritorio)
+}
+
+// CriarEscritorio cadastra um novo escritório
+func (h *EscritorioHandler) CriarEscritorio(c *gin.Context) {
+	var req model.EscritorioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	escritorio, err := h.escritorioService.CriarEscritorio(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, escritorio)
+}
+
+// AtualizarEscritorio altera um escritório; com ativo=false suas credenciais deixam de ser aceitas
+func (h *EscritorioHandler) AtualizarEscritorio(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req model.EscritorioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	escritorio, err := h.escritorioService.AtualizarEscritorio(c.Request.Context(), id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, escritorio)
+}
+
+// ListarTokens lista as credenciais de API do escritório
+func (h *EscritorioHandler) ListarTokens(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	tokens, err := h.escritorioService.ListarTokens(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// CriarToken gera uma credencial para o escritório; o token só aparece nesta resposta
+func (h *EscritorioHandler) CriarToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req model.APITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	token, err := h.escritorioService.CriarToken(c.Request.Context(), id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// RevogarToken revoga uma credencial do escritório
+func (h *EscritorioHandler) RevogarToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+	tokenID, err := strconv.ParseInt(c.Param("token_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da credencial inválido"})
+		return
+	}
+
+	if err := h.escritorioService.RevogarToken(c.Request.Context(), id, tokenID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Credencial revogada com sucesso"})
+}
+
+func (h *EscritorioHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEscritorioNotFound), errors.Is(err, service.ErrAPITokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEscritorioExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
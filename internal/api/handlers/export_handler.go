@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
+	"zemdocs/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handler para a exportação em lote de XMLs
+type ExportHandler struct {
+	exportService *service.ExportService
+	scheduler     *scheduler.Scheduler
+}
+
+// NewExportHandler cria uma nova instância do handler de exportação
+func NewExportHandler(exportService *service.ExportService, jobScheduler *scheduler.Scheduler) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		scheduler:     jobScheduler,
+	}
+}
+
+// ExportarDocumentos envia um ZIP com os XMLs e o manifesto. Exportações grandes
+// (ou com async=true) rodam em segundo plano e são acompanhadas em /api/v1/exports/:id
+func (h *ExportHandler) ExportarDocumentos(c *gin.Context) {
+	filter, err := exportFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.exportService.PrepararFiltro(ctx, filter); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	background, count, err := h.exportService.EmSegundoPlano(ctx, filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if background || c.Query("async") == "true" {
+		h.agendar(c, filter, count)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", service.ExportFileName(filter)))
+	c.Status(http.StatusOK)
+
+	manifest, err := h.exportService.GerarZIP(ctx, filter, c.Writer)
+	if err != nil {
+		// Com o ZIP já em envio não há como trocar o status; o cliente recebe um arquivo truncado
+		logger.Error(err, "Erro ao gerar exportação de XMLs")
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Exportação enviada: CNPJ %s, %d documentos (%d sem XML)", filter.CNPJ, manifest.Documents, manifest.Failed))
+}
+
+// CriarExportacao agenda uma exportação em segundo plano a partir dos filtros no corpo
+func (h *ExportHandler) CriarExportacao(c *gin.Context) {
+	var filter model.ExportFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.exportService.PrepararFiltro(ctx, &filter); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	_, count, err := h.exportService.EmSegundoPlano(ctx, &filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	h.agendar(c, &filter, count)
+}
+
+// ConsultarExportacao retorna a situação da exportação e o link de download quando concluída
+func (h *ExportHandler) ConsultarExportacao(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	export, err := h.exportService.Consultar(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// agendar registra a exportação e a executa pelo scheduler. O registro é gravado
+// fora da transação da requisição para que o job já o encontre ao iniciar.
+func (h *ExportHandler) agendar(c *gin.Context, filter *model.ExportFilter, count int) {
+	ctx := tenant.WithoutConn(c.Request.Context())
+	export, err := h.exportService.Agendar(ctx, filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	exportID := export.ID
+	job := scheduler.NewFuncJob(fmt.Sprintf("export-%d", exportID), func(ctx context.Context) error {
+		return h.exportService.Executar(ctx, exportID)
+	})
+	runID := h.scheduler.RunOnce(job)
+	if err := h.exportService.RegistrarExecucao(ctx, export, runID); err != nil {
+		logger.Error(err, fmt.Sprintf("Erro ao associar exportação %d à execução %s", exportID, runID))
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Exportação iniciada",
+		"export_id":  exportID,
+		"run_id":     runID,
+		"documents":  count,
+		"status_url": fmt.Sprintf("/api/v1/exports/%d", exportID),
+	})
+}
+
+func (h *ExportHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// exportFilterFromQuery monta os filtros a partir da query string
+// (empresa_id ou cnpj, competencia ou competencia_inicio/competencia_fim, tipos, direcao, incluir_pdf)
+func exportFilterFromQuery(c *gin.Context) (*model.ExportFilter, error) {
+	filter := &model.ExportFilter{
+		CNPJ:              c.Query("cnpj"),
+		CompetenciaInicio: c.DefaultQuery("competencia_inicio", c.Query("competencia")),
+		CompetenciaFim:    c.Query("competencia_fim"),
+		Direcao:           c.Query("direcao"),
+		IncluirPDF:        c.Query("incluir_pdf") == "true",
+	}
+
+	if empresaID := c.Query("empresa_id"); empresaID != "" {
+		id, err := strconv.Atoi(empresaID)
+		if err != nil {
+			return nil, fmt.Errorf("empresa_id inválido")
+		}
+		filter.EmpresaID = &id
+	}
+
+	types, err := service.ParseDocumentTypes(c.Query("tipos"))
+	if err != nil {
+		return nil, err
+	}
+	for _, documentType := range types {
+		filter.DocumentTypes = append(filter.DocumentTypes, model.DocumentType(documentType))
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler handler para consulta e gerenciamento de jobs agendados
+type JobHandler struct {
+	lockRepo  *repository.JobLockRepository
+	scheduler *scheduler.Scheduler
+}
+
+// NewJobHandler cria uma nova instância do handler de jobs
+func NewJobHandler(lockRepo *repository.JobLockRepository, jobScheduler *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{
+		lockRepo:  lockRepo,
+		scheduler: jobScheduler,
+	}
+}
+
+// ListarJobs lista os jobs agendados com próxima e última execução
+func (h *JobHandler) ListarJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": h.scheduler.ListJobs(),
+	})
+}
+
+// ExecutarJob dispara uma execução imediata e retorna o ID da execução
+func (h *JobHandler) ExecutarJob(c *gin.Context) {
+	runID, err := h.scheduler.TriggerJob(c.Param("name"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"run_id": runID,
+	})
+}
+
+// PausarJob suspende as execuções agendadas de um job
+func (h *JobHandler) PausarJob(c *gin.Context) {
+	if err := h.scheduler.PauseJob(c.Request.Context(), c.Param("name")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job pausado"})
+}
+
+// RetomarJob retoma as execuções agendadas de um job
+func (h *JobHandler) RetomarJob(c *gin.Context) {
+	if err := h.scheduler.ResumeJob(c.Request.Context(), c.Param("name")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job retomado"})
+}
+
+// ListarExecucoes lista as execuções mais recentes de um job
+func (h *JobHandler) ListarExecucoes(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	runs, err := h.scheduler.ListRuns(c.Request.Context(), c.Param("name"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar execuções"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs": runs,
+	})
+}
+
+// ConsultarExecucao retorna status, contadores e logs de uma execução
+func (h *JobHandler) ConsultarExecucao(c *gin.Context) {
+	run, err := h.scheduler.GetRun(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// CancelarExecucao cancela uma execução em andamento
+func (h *JobHandler) CancelarExecucao(c *gin.Context) {
+	if err := h.scheduler.CancelRun(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Cancelamento solicitado"})
+}
+
+// ListarLocks lista os locks de jobs e a réplica que detém cada um
+func (h *JobHandler) ListarLocks(c *gin.Context) {
+	locks, err := h.lockRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar locks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"locks": locks,
+	})
+}
+
+func (h *JobHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound), errors.Is(err, scheduler.ErrRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, scheduler.ErrRunNotActive), errors.Is(err, scheduler.ErrJobNotPersistent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
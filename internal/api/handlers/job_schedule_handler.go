@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobScheduleHandler handler para os agendamentos de jobs armazenados no banco
+type JobScheduleHandler struct {
+	scheduleService *service.JobScheduleService
+}
+
+// NewJobScheduleHandler cria uma nova instância do handler de agendamentos
+func NewJobScheduleHandler(scheduleService *service.JobScheduleService) *JobScheduleHandler {
+	return &JobScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// ListarAgendamentos lista todos os agendamentos
+func (h *JobScheduleHandler) ListarAgendamentos(c *gin.Context) {
+	schedules, err := h.scheduleService.Listar(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar agendamentos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agendamentos": schedules,
+	})
+}
+
+// ConsultarAgendamento retorna um agendamento por ID
+func (h *JobScheduleHandler) ConsultarAgendamento(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	schedule, err := h.scheduleService.Consultar(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// CriarAgendamento cadastra um novo agendamento; passa a valer sem reiniciar a aplicação
+func (h *JobScheduleHandler) CriarAgendamento(c *gin.Context) {
+	var req model.JobScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	schedule, err := h.scheduleService.Criar(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// AtualizarAgendamento altera um agendamento existente
+func (h *JobScheduleHandler) AtualizarAgendamento(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req model.JobScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	schedule, err := h.scheduleService.Atualizar(c.Request.Context(), id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// ExcluirAgendamento remove um agendamento
+func (h *JobScheduleHandler) ExcluirAgendamento(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.scheduleService.Excluir(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Agendamento excluído com sucesso"})
+}
+
+func (h *JobScheduleHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrJobScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrJobScheduleExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
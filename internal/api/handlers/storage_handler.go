@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageHandler serve os links assinados do armazenamento local e do armazenamento
+// criptografado (que entrega o conteúdo decifrado). Com MinIO sem criptografia os
+// links apontam direto para o bucket e só a consulta de órfãos é usada.
+type StorageHandler struct {
+	store      storage.Store
+	orphanRepo *repository.StorageOrphanRepository
+}
+
+// NewStorageHandler cria uma nova instância do handler de armazenamento
+func NewStorageHandler(store storage.Store, orphanRepo *repository.StorageOrphanRepository) *StorageHandler {
+	return &StorageHandler{
+		store:      store,
+		orphanRepo: orphanRepo,
+	}
+}
+
+// ListarOrfaos lista os objetos sem documento encontrados pela reconciliação
+func (h *StorageHandler) ListarOrfaos(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx := c.Request.Context()
+	orphans, err := h.orphanRepo.List(ctx, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar objetos órfãos"})
+		return
+	}
+	total, err := h.orphanRepo.Count(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao contar objetos órfãos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"orphans": orphans,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// DownloadLocal entrega um objeto a partir de um link gerado por PresignGet
+func (h *StorageHandler) DownloadLocal(c *gin.Context) {
+	verifier, ok := h.store.(storage.PresignVerifier)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Armazenamento local não está habilitado"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := verifier.VerifyPresigned(key, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	info, err := h.store.Stat(ctx, key)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	data, err := h.store.Get(ctx, key)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("ETag", `"`+info.ETag+`"`)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func (h *StorageHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrObjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Objeto não encontrado"})
+	case errors.Is(err, storage.ErrInvalidKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, storage.ErrDecryption), errors.Is(err, storage.ErrMasterKeyNotFound):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao decifrar objeto"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ler objeto"})
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"zemdocs/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit identifica no contexto quem faz a requisição (principal, IP e user agent),
+// registrado pelos serviços na trilha de auditoria. Deve vir depois de Auth.
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithActor(c.Request.Context(), audit.Actor{
+			Name:      GetPrincipal(c).Name,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
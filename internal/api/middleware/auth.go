@@ -1,17 +1,62 @@
 package middleware
 
 import (
+	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
+	"zemdocs/internal/config"
+
 	"github.com/gin-gonic/gin"
 )
 
-// Auth middleware para validar o token de autorização
-func Auth() gin.HandlerFunc {
+// Permissões verificadas pelas rotas
+const (
+	PermissionAll              = "*"
+	PermissionDocumentDownload = "documentos.baixar"   // XML dos documentos
+	PermissionAuditRead        = "auditoria.consultar" // Trilha de auditoria
+	PermissionPurge            = "registros.purgar"    // Remoção definitiva de empresas e documentos excluídos
+)
+
+const principalKey = "principal"
+
+// Principal identifica quem fez a requisição e o que pode fazer. Sem
+// escritório o principal é de sistema e enxerga os dados de todos os escritórios.
+type Principal struct {
+	Name         string
+	Permissions  []string
+	EscritorioID *int64
+}
+
+// TokenLookup resolve as credenciais de escritório gravadas no banco; retorna
+// erro se o token não corresponder a nenhuma credencial ativa
+type TokenLookup func(ctx context.Context, token string) (*Principal, error)
+
+// Can verifica se o principal tem a permissão
+func (p *Principal) Can(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == PermissionAll || granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymous principal usado com a autenticação desabilitada (desenvolvimento)
+var anonymous = &Principal{Name: "anonimo", Permissions: []string{PermissionAll}}
+
+// Auth middleware para validar o token de autorização e identificar o principal.
+// Os tokens de API_TOKENS são verificados primeiro; depois, as credenciais dos
+// escritórios (lookup pode ser nil). Com AUTH_ENABLED=false todas as requisições
+// são aceitas como anônimas, com acesso de sistema.
+func Auth(cfg config.AuthConfig, lookup TokenLookup) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		// Token de produção
-		expectedToken := "69415f14b56ccabe8cc5ec8cf5d5a2d2dc2ac66f0bb9859484dd5f8ce7ae2d2a"
+		if !cfg.Enabled {
+			c.Set(principalKey, anonymous)
+			c.Next()
+			return
+		}
 
 		// Obter token do header Authorization
 		authHeader := c.GetHeader("Authorization")
@@ -26,12 +71,46 @@ func Auth() gin.HandlerFunc {
 		token = strings.TrimSpace(token)
 
 		// Validar token
-		if token != expectedToken {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de autorização inválido"})
+		for _, apiToken := range cfg.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(apiToken.Token)) == 1 {
+				c.Set(principalKey, &Principal{Name: apiToken.Name, Permissions: apiToken.Permissions, EscritorioID: apiToken.EscritorioID})
+				c.Next()
+				return
+			}
+		}
+
+		if lookup != nil && token != "" {
+			if principal, err := lookup(c.Request.Context(), token); err == nil {
+				c.Set(principalKey, principal)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de autorização inválido"})
+		c.Abort()
+	})
+}
+
+// RequirePermission recusa a requisição se o principal não tiver a permissão
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GetPrincipal(c).Can(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permissão negada: " + permission})
 			c.Abort()
 			return
 		}
-
 		c.Next()
-	})
+	}
+}
+
+// GetPrincipal retorna o principal da requisição. Sem o middleware Auth não há
+// principal e nenhuma permissão é concedida.
+func GetPrincipal(c *gin.Context) *Principal {
+	if value, ok := c.Get(principalKey); ok {
+		if principal, ok := value.(*Principal); ok {
+			return principal
+		}
+	}
+	return &Principal{Name: "desconhecido"}
 }
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"strconv"
+
+	"zemdocs/internal/logger"
+	"zemdocs/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+// scopeQuery restringe a sessão ou transação ao escritório e desliga o acesso de
+// sistema que database.Open liga nas conexões do pool. O último argumento indica
+// se os valores valem só até o fim da transação.
+const scopeQuery = "SELECT set_config('app.escritorio_id', ?, ?), set_config('app.system', 'off', ?)"
+
+// resetScopeQuery devolve a sessão aos valores com que a conexão foi aberta
+const resetScopeQuery = "RESET ALL"
+
+// Tenant define o escopo de dados da requisição a partir do principal. Para um
+// escritório, a requisição roda em uma transação com app.escritorio_id definido,
+// de modo que o row-level security do Postgres valha mesmo se um repositório
+// esquecer o filtro. A resposta fica em memória até a transação ser confirmada:
+// se o commit falhar o cliente recebe 500, nunca um sucesso de uma alteração
+// desfeita. Respostas de erro ou pânico desfazem a transação. O que foi agendado
+// com tenant.AfterCommit executa depois do commit e do envio da resposta.
+//
+// As rotas em streaming (ZIP, XML, PDF, CSV) não são retidas em memória nem
+// seguram uma transação enquanto escrevem: usam uma conexão própria com o
+// escritório definido na sessão, valendo para cada consulta feita nela.
+// Deve vir depois de Auth.
+func Tenant(db *bun.DB, streamingRoutes ...string) gin.HandlerFunc {
+	streaming := make(map[string]bool, len(streamingRoutes))
+	for _, route := range streamingRoutes {
+		streaming[route] = true
+	}
+
+	return func(c *gin.Context) {
+		// Sem principal (Auth ausente) não há como decidir o escopo
+		if _, ok := c.Get(principalKey); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de autorização requerido"})
+			c.Abort()
+			return
+		}
+		principal := GetPrincipal(c)
+		ctx := c.Request.Context()
+
+		if principal.EscritorioID == nil {
+			c.Request = c.Request.WithContext(tenant.WithSystem(ctx))
+			c.Next()
+			return
+		}
+
+		escritorioID := *principal.EscritorioID
+		if streaming[c.FullPath()] {
+			tenantStreaming(c, db, escritorioID)
+			return
+		}
+		tenantTx(c, db, escritorioID)
+	}
+}
+
+// tenantTx executa a requisição em uma transação do escritório e só envia a
+// resposta depois do commit
+func tenantTx(c *gin.Context, db *bun.DB, escritorioID int64) {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error(err, "Erro ao iniciar transação da requisição")
+		respondDatabaseError(c)
+		return
+	}
+
+	id := strconv.FormatInt(escritorioID, 10)
+	if _, err := tx.ExecContext(ctx, scopeQuery, id, true, true); err != nil {
+		_ = tx.Rollback()
+		logger.Error(err, "Erro ao definir o escritório da transação")
+		respondDatabaseError(c)
+		return
+	}
+
+	writer := newBufferedWriter(c.Writer)
+	committed := false
+	defer func() {
+		// Em pânico o gin.Recovery precisa escrever no writer original
+		c.Writer = writer.ResponseWriter
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	txCtx, runAfterCommit := tenant.WithAfterCommit(tenant.WithConn(tenant.WithEscritorio(ctx, escritorioID), tx))
+	c.Writer = writer
+	c.Request = c.Request.WithContext(txCtx)
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	if writer.Status() >= http.StatusBadRequest {
+		writer.flush()
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error(err, "Erro ao confirmar transação da requisição")
+		respondDatabaseError(c)
+		return
+	}
+	committed = true
+	writer.flush()
+	runAfterCommit()
+}
+
+// tenantStreaming executa a requisição em uma conexão exclusiva com o escritório
+// definido na sessão. Sem transação, cada gravação é confirmada na hora e a
+// resposta é escrita diretamente ao cliente.
+func tenantStreaming(c *gin.Context, db *bun.DB, escritorioID int64) {
+	ctx := c.Request.Context()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		logger.Error(err, "Erro ao obter conexão da requisição")
+		respondDatabaseError(c)
+		return
+	}
+	defer releaseConn(conn)
+
+	if _, err := conn.ExecContext(ctx, scopeQuery, strconv.FormatInt(escritorioID, 10), false, false); err != nil {
+		logger.Error(err, "Erro ao definir o escritório da conexão")
+		respondDatabaseError(c)
+		return
+	}
+
+	c.Request = c.Request.WithContext(tenant.WithConn(tenant.WithEscritorio(ctx, escritorioID), conn))
+	c.Next()
+}
+
+// releaseConn devolve a conexão ao pool sem o escritório da sessão. Se não for
+// possível limpar a sessão, a conexão é descartada para que nenhuma outra
+// operação herde o escopo.
+func releaseConn(conn bun.Conn) {
+	if _, err := conn.ExecContext(context.Background(), resetScopeQuery); err != nil {
+		logger.Error(err, "Erro ao limpar o escritório da conexão; conexão descartada")
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	_ = conn.Close()
+}
+
+func respondDatabaseError(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao acessar o banco de dados"})
+	c.Abort()
+}
+
+// bufferedWriter retém status, cabeçalhos e corpo da resposta até flush
+type bufferedWriter struct {
+	gin.ResponseWriter
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func newBufferedWriter(w gin.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		status:         http.StatusOK,
+	}
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush é ignorado: a resposta só sai depois do commit
+func (w *bufferedWriter) Flush() {}
+
+// flush envia ao cliente a resposta retida
+func (w *bufferedWriter) flush() {
+	header := w.ResponseWriter.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	} else if w.written {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// RequireSystem restringe a rota aos principais de sistema (sem escritório):
+// administração de escritórios, jobs e fila de falhas
+func RequireSystem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(principalKey); !ok || GetPrincipal(c).EscritorioID != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Rota restrita à administração do sistema"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
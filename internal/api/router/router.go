@@ -3,12 +3,14 @@ package router
 import (
 	"zemdocs/internal/api/handlers"
 	"zemdocs/internal/api/middleware"
+	"zemdocs/internal/config"
 
 	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
 )
 
 // SetupRouter configura as rotas da API
-func SetupRouter(documentHandler *handlers.DocumentHandler, empresaHandler *handlers.EmpresaHandler) *gin.Engine {
+func SetupRouter(documentHandler *handlers.DocumentHandler, nfseHandler *handlers.NFSeHandler, empresaHandler *handlers.EmpresaHandler, deadLetterHandler *handlers.DeadLetterHandler, jobHandler *handlers.JobHandler, jobScheduleHandler *handlers.JobScheduleHandler, storageHandler *handlers.StorageHandler, exportHandler *handlers.ExportHandler, escritorioHandler *handlers.EscritorioHandler, auditHandler *handlers.AuditHandler, authConfig config.AuthConfig, tokenLookup middleware.TokenLookup, db *bun.DB) *gin.Engine {
 	// Configurar modo do Gin
 	gin.SetMode(gin.ReleaseMode)
 
@@ -28,23 +30,55 @@ func SetupRouter(documentHandler *handlers.DocumentHandler, empresaHandler *hand
 		})
 	})
 
-	// Grupo de rotas da API (sem autenticação para desenvolvimento)
+	// Links assinados do armazenamento local ou criptografado; a assinatura
+	// substitui o token, pois o link é entregue a quem não tem acesso à API
+	router.GET("/api/v1/storage/local/*key", storageHandler.DownloadLocal)
+
+	// Rotas que escrevem a resposta aos poucos ou entregam arquivos: rodam fora da
+	// transação da requisição (ver middleware.Tenant)
+	streamingRoutes := []string{
+		"/api/v1/documents/export",
+		"/api/v1/documents/:id/xml",
+		"/api/v1/documents/:id/pdf",
+		"/api/v1/audit/export",
+	}
+
+	// Grupo de rotas da API (AUTH_ENABLED=false libera tudo para desenvolvimento).
+	// Cada requisição enxerga apenas os dados do escritório do token.
 	api := router.Group("/api/v1")
-	// api.Use(middleware.Auth()) // Comentado para desenvolvimento
+	api.Use(middleware.Auth(authConfig, tokenLookup), middleware.Tenant(db, streamingRoutes...), middleware.Audit())
 	{
 		// Rotas de documentos (antigo NFS-e)
 		documents := api.Group("/documents")
 		{
 			documents.GET("/", documentHandler.ListarDocumentos)
+			documents.GET("/search", documentHandler.BuscarDocumentos) // Busca textual com relevância e destaques
 			documents.GET("/:id", documentHandler.ConsultarDocumento)
 			documents.POST("/", documentHandler.CriarDocumento)
 			documents.PUT("/:id", documentHandler.AtualizarDocumento)
-			documents.DELETE("/:id", documentHandler.ExcluirDocumento)
+			documents.DELETE("/:id", documentHandler.ExcluirDocumento) // Exclusão lógica
+			documents.GET("/excluidos", documentHandler.ListarExcluidos)
+			documents.POST("/:id/restore", documentHandler.RestaurarDocumento)
+			documents.DELETE("/:id/purge", middleware.RequirePermission(middleware.PermissionPurge), documentHandler.PurgarDocumento) // Remove o XML; respeita a retenção
+			documents.GET("/:id/retention", documentHandler.ConsultarRetencao)
+			documents.GET("/:id/xml", middleware.RequirePermission(middleware.PermissionDocumentDownload), documentHandler.BaixarXML)        // XML com ETag
+			documents.GET("/:id/xml-url", middleware.RequirePermission(middleware.PermissionDocumentDownload), documentHandler.GerarLinkXML) // Link pré-assinado
+			documents.GET("/:id/pdf", middleware.RequirePermission(middleware.PermissionDocumentDownload), documentHandler.BaixarPDF)        // DANFSE/DANFE
 			documents.GET("/chart-data", documentHandler.DadosGrafico)
+			documents.GET("/stats", documentHandler.EstatisticasDocumentos)
 			documents.GET("/recent", documentHandler.DocumentosRecentes)
 			documents.GET("/revenue", documentHandler.DadosReceita)
 			documents.GET("/growth", documentHandler.DadosCrescimento)
 			documents.GET("/iss-metrics", documentHandler.MetricasISS)
+			documents.GET("/top-tomadores", documentHandler.MaioresTomadores)
+			documents.GET("/export", exportHandler.ExportarDocumentos) // ZIP com XMLs e manifesto
+		}
+
+		// Exportações em lote executadas em segundo plano
+		exports := api.Group("/exports")
+		{
+			exports.POST("/", exportHandler.CriarExportacao)
+			exports.GET("/:id", exportHandler.ConsultarExportacao) // Situação e link de download
 		}
 
 		// Rotas de empresas
@@ -55,7 +89,20 @@ func SetupRouter(documentHandler *handlers.DocumentHandler, empresaHandler *hand
 			empresas.GET("/:id", empresaHandler.ConsultarEmpresaPorID) // Buscar empresa por ID
 			empresas.POST("/", empresaHandler.CriarEmpresaCompleta)    // Criar empresa com dados completos
 			empresas.PUT("/:id", empresaHandler.AtualizarEmpresa)      // Atualizar empresa existente
-			empresas.DELETE("/:id", empresaHandler.ExcluirEmpresa)     // Excluir empresa
+			empresas.DELETE("/:id", empresaHandler.ExcluirEmpresa)     // Excluir empresa (exclusão lógica)
+
+			// Empresas excluídas: restauração e remoção definitiva
+			empresas.GET("/excluidas", empresaHandler.ListarEmpresasExcluidas)
+			empresas.POST("/:id/restore", empresaHandler.RestaurarEmpresa)
+			empresas.DELETE("/:id/purge", middleware.RequirePermission(middleware.PermissionPurge), empresaHandler.PurgarEmpresa)
+
+			// Certificado digital A1, guardado cifrado
+			empresas.PUT("/:id/certificado", empresaHandler.EnviarCertificado)
+			empresas.DELETE("/:id/certificado", empresaHandler.RemoverCertificado)
+
+			// Documentos vinculados à empresa como emitente ou tomadora
+			empresas.GET("/:id/documentos/emitidos", empresaHandler.ListarDocumentosEmitidos)
+			empresas.GET("/:id/documentos/recebidos", empresaHandler.ListarDocumentosRecebidos)
 
 			// Consultas especializadas
 			empresas.GET("/cnpj/:cnpj", empresaHandler.ConsultarEmpresaPorCNPJ) // Buscar empresa por CNPJ (banco local)
@@ -66,11 +113,69 @@ func SetupRouter(documentHandler *handlers.DocumentHandler, empresaHandler *hand
 			empresas.POST("/cnpj-api/:cnpj", empresaHandler.CriarEmpresaPorCNPJ) // Criar empresa direto da API CNPJ
 		}
 
+		// Trilha de auditoria das alterações de documentos, empresas e usuários
+		auditLogs := api.Group("/audit", middleware.RequirePermission(middleware.PermissionAuditRead))
+		{
+			auditLogs.GET("/", auditHandler.ListarAuditoria)
+			auditLogs.GET("/export", auditHandler.ExportarAuditoria) // CSV para revisões de conformidade
+		}
+
+		// Escritórios de contabilidade e suas credenciais (somente sistema)
+		escritorios := api.Group("/escritorios", middleware.RequireSystem())
+		{
+			escritorios.GET("/", escritorioHandler.ListarEscritorios)
+			escritorios.GET("/:id", escritorioHandler.ConsultarEscritorio)
+			escritorios.POST("/", escritorioHandler.CriarEscritorio)
+			escritorios.PUT("/:id", escritorioHandler.AtualizarEscritorio)
+			escritorios.GET("/:id/tokens", escritorioHandler.ListarTokens)
+			escritorios.POST("/:id/tokens", escritorioHandler.CriarToken)               // Token exibido uma única vez
+			escritorios.DELETE("/:id/tokens/:token_id", escritorioHandler.RevogarToken) // Revogar credencial
+		}
+
+		// Fila de documentos que falharam na conversão ou persistência (somente sistema)
+		deadLetters := api.Group("/dead-letters", middleware.RequireSystem())
+		{
+			deadLetters.GET("/", deadLetterHandler.ListarFalhas)
+			deadLetters.GET("/:id", deadLetterHandler.ConsultarFalha)
+			deadLetters.POST("/:id/reprocess", deadLetterHandler.ReprocessarFalha)
+			deadLetters.DELETE("/:id", deadLetterHandler.DescartarFalha)
+		}
+
+		// Jobs agendados (somente sistema)
+		jobs := api.Group("/jobs", middleware.RequireSystem())
+		{
+			jobs.GET("/", jobHandler.ListarJobs)                       // Jobs com próxima/última execução
+			jobs.GET("/locks", jobHandler.ListarLocks)                 // Réplica que detém cada job
+			jobs.GET("/runs/:id", jobHandler.ConsultarExecucao)        // Status, contadores e logs
+			jobs.POST("/runs/:id/cancel", jobHandler.CancelarExecucao) // Cancelar execução em andamento
+			jobs.POST("/:name/run", jobHandler.ExecutarJob)            // Disparar execução imediata
+			jobs.POST("/:name/pause", jobHandler.PausarJob)            // Pausar agendamento
+			jobs.POST("/:name/resume", jobHandler.RetomarJob)          // Retomar agendamento
+			jobs.GET("/:name/runs", jobHandler.ListarExecucoes)        // Histórico de execuções
+		}
+
+		// Objetos do armazenamento sem documento, registrados pela reconciliação (somente sistema)
+		storageAdmin := api.Group("/storage", middleware.RequireSystem())
+		{
+			storageAdmin.GET("/orphans", storageHandler.ListarOrfaos)
+		}
+
+		// Agendamentos de jobs armazenados no banco (aplicados sem reiniciar)
+		jobSchedules := api.Group("/job-schedules")
+		{
+			jobSchedules.GET("/", jobScheduleHandler.ListarAgendamentos)
+			jobSchedules.GET("/:id", jobScheduleHandler.ConsultarAgendamento)
+			jobSchedules.POST("/", jobScheduleHandler.CriarAgendamento)
+			jobSchedules.PUT("/:id", jobScheduleHandler.AtualizarAgendamento)
+			jobSchedules.DELETE("/:id", jobScheduleHandler.ExcluirAgendamento)
+		}
+
 		// Manter compatibilidade com rotas antigas de NFS-e
 		nfse := api.Group("/nfse")
 		{
 			nfse.GET("/consultar", documentHandler.ConsultarDocumento)
 			nfse.GET("/recent", documentHandler.DocumentosRecentes)
+			nfse.POST("/sincronizar", middleware.RequireSystem(), nfseHandler.SincronizarManual) // Somente sistema; escritorio_id define o dono dos documentos
 		}
 	}
 
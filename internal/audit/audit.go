@@ -0,0 +1,40 @@
+// Package audit carrega no contexto quem executa a operação, registrado pelos
+// serviços na trilha de auditoria. As requisições recebem o principal do token;
+// jobs e comandos, sem ator no contexto, aparecem como o sistema.
+package audit
+
+import "context"
+
+// ActorSistema ator das operações sem requisição (jobs, comandos, inicialização)
+const ActorSistema = "sistema"
+
+// Actor quem executa a operação e de onde
+type Actor struct {
+	Name      string
+	IP        string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor associa ao contexto o ator das operações
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext retorna o ator do contexto ou, sem ele, o sistema
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok && actor.Name != "" {
+		return actor
+	}
+	return Actor{Name: ActorSistema}
+}
+
+// Inherit aplica a ctx o ator de from. Usado pelos jobs disparados por uma
+// requisição, cujas alterações continuam sendo de quem os disparou.
+func Inherit(ctx, from context.Context) context.Context {
+	if actor, ok := from.Value(actorKey{}).(Actor); ok {
+		return WithActor(ctx, actor)
+	}
+	return ctx
+}
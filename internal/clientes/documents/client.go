@@ -3,6 +3,8 @@ package documents
 import (
 	"context"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // DocumentType representa os tipos de documentos fiscais suportados
@@ -44,18 +46,18 @@ type ConsultarXMLRequest struct {
 
 // Response resposta padrão de documentos fiscais (estrutura unificada)
 type Response struct {
-	DocumentType      DocumentType `json:"document_type"`
-	NumeroDocumento   string       `json:"numero_documento"`
-	NumeroNfse        string       `json:"numero_nfse"` // Para compatibilidade com NFS-e
-	NumeroRps         string       `json:"numero_rps"`
-	SerieRps          string       `json:"serie_rps"`
-	DataEmissao       time.Time    `json:"data_emissao"`
-	Status            string       `json:"status"`
-	CodigoVerificacao string       `json:"codigo_verificacao"`
-	ValorServico      float64      `json:"valor_servico"`
-	ValorIss          float64      `json:"valor_iss"`
-	Competencia       string       `json:"competencia"`
-	XMLContent        string       `json:"xml_content,omitempty"`
+	DocumentType      DocumentType    `json:"document_type"`
+	NumeroDocumento   string          `json:"numero_documento"`
+	NumeroNfse        string          `json:"numero_nfse"` // Para compatibilidade com NFS-e
+	NumeroRps         string          `json:"numero_rps"`
+	SerieRps          string          `json:"serie_rps"`
+	DataEmissao       time.Time       `json:"data_emissao"`
+	Status            string          `json:"status"`
+	CodigoVerificacao string          `json:"codigo_verificacao"`
+	ValorServico      decimal.Decimal `json:"valor_servico"`
+	ValorIss          decimal.Decimal `json:"valor_iss"`
+	Competencia       string          `json:"competencia"`
+	XMLContent        string          `json:"xml_content,omitempty"`
 }
 
 // ImperatrizAPIResponse estrutura da resposta da API de Imperatriz
@@ -75,6 +77,35 @@ type ImperatrizNFSeRecord struct {
 	XmlCompactado string `json:"XmlCompactado"`
 }
 
+// Etapas em que a conversão ou persistência de um documento pode falhar
+const (
+	FailureStageDecompress = "descompactar"
+	FailureStageParse      = "parse"
+	FailureStageDate       = "data_emissao"
+	FailureStagePersist    = "persistencia"
+)
+
+// Formatos do payload bruto preservado em uma falha
+const (
+	PayloadFormatBase64Zip = "base64-zip"
+	PayloadFormatXML       = "xml"
+)
+
+// Failure representa um documento que não pôde ser convertido ou salvo
+type Failure struct {
+	Source          string // Nome do cliente de origem (ex.: imperatriz)
+	CodigoIBGE      string
+	Stage           string
+	NumeroDocumento string
+	Competencia     string
+	Payload         string
+	PayloadFormat   string
+	Err             error
+}
+
+// FailureHandler recebe documentos que falharam para que não sejam perdidos
+type FailureHandler func(ctx context.Context, failure Failure)
+
 // Registry registro de clientes por município
 type Registry struct {
 	clients map[string]Client
@@ -19,8 +19,12 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	token      string
+	onFailure  documents.FailureHandler
 }
 
+// CodigoIBGE código IBGE de Imperatriz-MA
+const CodigoIBGE = "2105302"
+
 // NewClient cria uma nova instância do cliente
 func NewClient(baseURL, token string) *Client {
 	return &Client{
@@ -32,6 +36,11 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
+// SetFailureHandler define quem recebe os registros que falharem na conversão
+func (c *Client) SetFailureHandler(handler documents.FailureHandler) {
+	c.onFailure = handler
+}
+
 // ConsultarDocuments implementa documents.Client
 func (c *Client) ConsultarDocuments(ctx context.Context, req documents.ConsultarRequest) (*documents.Response, error) {
 	url := fmt.Sprintf("%s/consultar", c.baseURL)
@@ -95,11 +104,11 @@ func (c *Client) ConsultarXMLDocuments(ctx context.Context, req documents.Consul
 	}
 
 	// Converter para formato padrão
-	return c.convertToStandardFormat(apiResp.Dados)
+	return c.convertToStandardFormat(ctx, apiResp.Dados)
 }
 
 // convertToStandardFormat converte dados da API de Imperatriz para formato padrão
-func (c *Client) convertToStandardFormat(dados []documents.ImperatrizNFSeRecord) ([]documents.Response, error) {
+func (c *Client) convertToStandardFormat(ctx context.Context, dados []documents.ImperatrizNFSeRecord) ([]documents.Response, error) {
 	var responses []documents.Response
 
 	for _, record := range dados {
@@ -107,6 +116,7 @@ func (c *Client) convertToStandardFormat(dados []documents.ImperatrizNFSeRecord)
 		xmlContent, err := utils.DecompressXML(record.XmlCompactado)
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("Erro ao descompactar XML da NFS-e %d", record.NrNfse))
+			c.reportFailure(ctx, record, documents.FailureStageDecompress, record.XmlCompactado, documents.PayloadFormatBase64Zip, err)
 			continue
 		}
 
@@ -114,6 +124,7 @@ func (c *Client) convertToStandardFormat(dados []documents.ImperatrizNFSeRecord)
 		xmlData, err := utils.ParseNFSeXML(xmlContent)
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("Erro ao fazer parse do XML da NFS-e %d", record.NrNfse))
+			c.reportFailure(ctx, record, documents.FailureStageParse, xmlContent, documents.PayloadFormatXML, err)
 			continue
 		}
 
@@ -121,7 +132,8 @@ func (c *Client) convertToStandardFormat(dados []documents.ImperatrizNFSeRecord)
 		dataEmissao, err := time.Parse("2006-01-02 15:04:05", record.DtEmissao)
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("Erro ao converter data de emissão da NFS-e %d", record.NrNfse))
-			dataEmissao = time.Now() // Fallback
+			c.reportFailure(ctx, record, documents.FailureStageDate, xmlContent, documents.PayloadFormatXML, err)
+			continue
 		}
 
 		// Criar resposta padrão
@@ -146,6 +158,24 @@ func (c *Client) convertToStandardFormat(dados []documents.ImperatrizNFSeRecord)
 	return responses, nil
 }
 
+// reportFailure encaminha um registro que falhou ao handler configurado
+func (c *Client) reportFailure(ctx context.Context, record documents.ImperatrizNFSeRecord, stage, payload, format string, err error) {
+	if c.onFailure == nil {
+		return
+	}
+
+	c.onFailure(ctx, documents.Failure{
+		Source:          "imperatriz",
+		CodigoIBGE:      CodigoIBGE,
+		Stage:           stage,
+		NumeroDocumento: strconv.Itoa(record.NrNfse),
+		Competencia:     strconv.Itoa(record.NrCompetencia),
+		Payload:         payload,
+		PayloadFormat:   format,
+		Err:             err,
+	})
+}
+
 // UltimoRPSEnviado implementa nfse.Client
 func (c *Client) UltimoRPSEnviado(ctx context.Context) (string, error) {
 	url := fmt.Sprintf("%s/ultimorpsenviado", c.baseURL)
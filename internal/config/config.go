@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,8 +14,15 @@ type Config struct {
 	Database  DatabaseConfig
 	Redis     RedisConfig
 	Server    ServerConfig
+	Auth      AuthConfig
 	App       AppConfig
 	MinIO     MinIOConfig
+	Storage   StorageConfig
+	Retention RetentionConfig
+	Export    ExportConfig
+	PDF       PDFConfig
+	Partition PartitionConfig
+	Dashboard DashboardConfig
 	Scheduler SchedulerConfig
 	NFSe      NFSeConfig
 }
@@ -41,6 +49,21 @@ type ServerConfig struct {
 	Host string
 }
 
+// AuthConfig configurações de autenticação da API
+type AuthConfig struct {
+	Enabled bool       // Sem autenticação (desenvolvimento) todas as permissões são concedidas
+	Tokens  []APIToken // Tokens aceitos e suas permissões
+}
+
+// APIToken token de acesso à API com as permissões concedidas ("*" para todas).
+// Sem escritório o token é de sistema e enxerga os dados de todos os escritórios.
+type APIToken struct {
+	Name         string
+	Token        string
+	Permissions  []string
+	EscritorioID *int64
+}
+
 // AppConfig configurações da aplicação
 type AppConfig struct {
 	Environment string
@@ -58,11 +81,90 @@ type MinIOConfig struct {
 	UseSSL     bool
 }
 
+// Backends de armazenamento de objetos
+const (
+	StorageBackendMinIO = "minio"
+	StorageBackendLocal = "local"
+)
+
+// StorageConfig configurações do armazenamento de XMLs
+type StorageConfig struct {
+	Backend             string // minio ou local
+	LocalPath           string // Diretório raiz do backend local
+	LocalBaseURL        string // URL pública usada nos links assinados do backend local
+	LocalSigningKey     string // Chave HMAC dos links assinados do backend local
+	EphemeralSigningKey bool   // Chave aleatória por processo se LocalSigningKey estiver vazia; só em desenvolvimento
+	PresignExpiry       int    // Validade, em minutos, dos links de download de XML
+
+	// Criptografia em repouso (envelope encryption)
+	Encryption    bool   // Cifra os objetos com uma chave de dados por tenant
+	MasterKey     string // Chave mestra em base64 (32 bytes)
+	MasterKeyFile string // Arquivo com chaves mestras, uma por linha: id:base64
+	MasterKeyID   string // ID da chave mestra atual
+
+	// Aceita na leitura objetos legados em texto puro; apenas durante a migração
+	AllowPlaintext bool
+}
+
+// ServesSignedLinks indica se os links assinados são servidos pela própria API
+// (/api/v1/storage/local), o que exige a mesma chave de assinatura em todas as réplicas
+func (s StorageConfig) ServesSignedLinks() bool {
+	return s.Backend == StorageBackendLocal || s.Encryption
+}
+
+// MinRetentionYears prazo mínimo de guarda dos documentos fiscais (CTN, art. 173)
+const MinRetentionYears = 5
+
+// RetentionConfig configurações de retenção dos XMLs arquivados
+type RetentionConfig struct {
+	DefaultYears int            // Prazo aplicado aos tipos sem regra própria
+	Years        map[string]int // Prazo por tipo de documento (ex.: "NF-e" -> 5)
+	LockMode     string         // Modo do object lock no MinIO: GOVERNANCE ou COMPLIANCE
+	LegalHold    bool           // Aplica também bloqueio legal (sem prazo) aos novos XMLs
+}
+
+// ExportConfig configurações da exportação em lote de XMLs
+type ExportConfig struct {
+	SyncLimit int // Acima deste número de documentos a exportação roda em segundo plano
+	URLExpiry int // Validade, em horas, do link de download das exportações
+}
+
+// PDFConfig configurações do DANFSE/DANFE gerados a partir dos XMLs
+type PDFConfig struct {
+	Municipio           string // Nome exibido no cabeçalho do DANFSE
+	LogoPath            string // Logo do município (PNG ou JPEG), opcional
+	NFSeVerificationURL string // Link de verificação do QR code; aceita {numero}, {codigo} e {cnpj}
+}
+
+// PartitionConfig configurações das partições anuais da tabela documents
+type PartitionConfig struct {
+	AheadYears        int    // Anos futuros com partição criada antecipadamente
+	ArchiveTablespace string // Tablespace de armazenamento mais barato; vazio desativa o arquivamento
+	ArchiveAfterYears int    // Anos de competência mantidos no tablespace padrão, além do atual
+}
+
+// DashboardConfig configurações dos agregados do dashboard
+type DashboardConfig struct {
+	RefreshDelay int // Em segundos; alterações nesse intervalo são agrupadas em um só recálculo
+}
+
 // SchedulerConfig configurações do scheduler
 type SchedulerConfig struct {
-	Enabled          bool
-	SyncInterval     string
-	CompetenciaAtual string
+	Enabled           bool
+	SyncInterval      string
+	CompetenciaAtual  string
+	OutboxInterval    string
+	ReconcileInterval string
+	ScrubInterval     string
+	PartitionInterval string
+	LockEnabled       bool
+	LockTimeout       int // Em minutos
+	InstanceID        string
+	ReloadInterval    int // Em segundos; verificação de alterações nos agendamentos do banco
+
+	// Cadastra pela API CNPJA as empresas emitentes/tomadoras ainda desconhecidas
+	// encontradas na sincronização, para vincular os documentos a elas
+	AutoRegisterEmpresas bool
 }
 
 // NFSeConfig configurações dos clientes NFS-e
@@ -98,6 +200,10 @@ func Load() (*Config, error) {
 			Port: getEnv("PORT", "8080"),
 			Host: getEnv("HOST", "0.0.0.0"),
 		},
+		Auth: AuthConfig{
+			Enabled: getEnvBool("AUTH_ENABLED", false),
+			Tokens:  parseAPITokens(getEnv("API_TOKENS", "")),
+		},
 		App: AppConfig{
 			Environment: getEnv("GIN_MODE", "debug"),
 			Debug:       getEnvBool("DEBUG", true),
@@ -111,10 +217,56 @@ func Load() (*Config, error) {
 			BucketName: getEnv("MINIO_BUCKET_NAME", "zemdocs"),
 			UseSSL:     getEnvBool("MINIO_USE_SSL", false),
 		},
+		Storage: StorageConfig{
+			Backend:             getEnv("STORAGE_BACKEND", StorageBackendMinIO),
+			LocalPath:           getEnv("STORAGE_LOCAL_PATH", "./data/storage"),
+			LocalBaseURL:        getEnv("STORAGE_LOCAL_BASE_URL", "http://localhost:8080/api/v1/storage/local"),
+			LocalSigningKey:     getEnv("STORAGE_LOCAL_SIGNING_KEY", ""),
+			EphemeralSigningKey: getEnvBool("STORAGE_LOCAL_SIGNING_KEY_EPHEMERAL", false),
+			PresignExpiry:       getEnvInt("STORAGE_PRESIGN_EXPIRY", 15),
+			Encryption:          getEnvBool("STORAGE_ENCRYPTION", false),
+			MasterKey:           getEnv("STORAGE_MASTER_KEY", ""),
+			MasterKeyFile:       getEnv("STORAGE_MASTER_KEY_FILE", ""),
+			MasterKeyID:         getEnv("STORAGE_MASTER_KEY_ID", ""),
+			AllowPlaintext:      getEnvBool("STORAGE_ALLOW_PLAINTEXT", false),
+		},
+		Retention: RetentionConfig{
+			DefaultYears: getEnvInt("RETENTION_DEFAULT_YEARS", MinRetentionYears),
+			Years:        parseRetentionRules(getEnv("RETENTION_RULES", "")),
+			LockMode:     getEnv("RETENTION_LOCK_MODE", "GOVERNANCE"),
+			LegalHold:    getEnvBool("RETENTION_LEGAL_HOLD", false),
+		},
+		Export: ExportConfig{
+			SyncLimit: getEnvInt("EXPORT_SYNC_LIMIT", 500),
+			URLExpiry: getEnvInt("EXPORT_URL_EXPIRY", 24),
+		},
+		PDF: PDFConfig{
+			Municipio:           getEnv("PDF_MUNICIPIO", "Prefeitura Municipal de Imperatriz - MA"),
+			LogoPath:            getEnv("PDF_LOGO_PATH", ""),
+			NFSeVerificationURL: getEnv("PDF_NFSE_VERIFICATION_URL", ""),
+		},
+		Partition: PartitionConfig{
+			AheadYears:        getEnvInt("DOCUMENTS_PARTITION_AHEAD_YEARS", 1),
+			ArchiveTablespace: getEnv("DOCUMENTS_ARCHIVE_TABLESPACE", ""),
+			ArchiveAfterYears: getEnvInt("DOCUMENTS_ARCHIVE_AFTER_YEARS", 2),
+		},
+		Dashboard: DashboardConfig{
+			RefreshDelay: getEnvInt("DASHBOARD_REFRESH_DELAY", 60),
+		},
 		Scheduler: SchedulerConfig{
-			Enabled:          getEnvBool("SCHEDULER_ENABLED", true),
-			SyncInterval:     getEnv("SYNC_INTERVAL", "0 */6 * * *"), // A cada 6 horas
-			CompetenciaAtual: getEnv("COMPETENCIA_ATUAL", "202408"),
+			Enabled:           getEnvBool("SCHEDULER_ENABLED", true),
+			SyncInterval:      getEnv("SYNC_INTERVAL", "0 */6 * * *"), // A cada 6 horas
+			CompetenciaAtual:  getEnv("COMPETENCIA_ATUAL", "202408"),
+			OutboxInterval:    getEnv("OUTBOX_INTERVAL", "0 */5 * * * *"),   // A cada 5 minutos
+			ReconcileInterval: getEnv("RECONCILE_INTERVAL", "0 0 3 * * *"),  // Diariamente às 3h
+			ScrubInterval:     getEnv("SCRUB_INTERVAL", "0 0 4 * * 0"),      // Domingos às 4h
+			PartitionInterval: getEnv("PARTITION_INTERVAL", "0 30 2 * * *"), // Diariamente às 2h30
+			LockEnabled:       getEnvBool("SCHEDULER_LOCK_ENABLED", true),
+			LockTimeout:       getEnvInt("SCHEDULER_LOCK_TIMEOUT", 60),
+			InstanceID:        getEnv("INSTANCE_ID", defaultInstanceID()),
+			ReloadInterval:    getEnvInt("SCHEDULE_RELOAD_INTERVAL", 30),
+
+			AutoRegisterEmpresas: getEnvBool("SYNC_AUTO_REGISTER_EMPRESAS", false),
 		},
 		NFSe: NFSeConfig{
 			ImperatrizBaseURL: getEnv("IMPERATRIZ_BASE_URL", "https://nfse.imperatriz.ma.gov.br/api/v1/nfse"),
@@ -143,6 +295,65 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// defaultInstanceID identifica a réplica atual pelo hostname e PID
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "zemdocs"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// parseRetentionRules interpreta "NFS-e=5,NF-e=6" em prazos (anos) por tipo de documento.
+// Entradas malformadas são ignoradas aqui e o prazo padrão é aplicado ao tipo.
+func parseRetentionRules(value string) map[string]int {
+	rules := make(map[string]int)
+	for _, rule := range strings.Split(value, ",") {
+		docType, years, ok := strings.Cut(strings.TrimSpace(rule), "=")
+		if !ok {
+			continue
+		}
+		if parsed, err := strconv.Atoi(strings.TrimSpace(years)); err == nil {
+			rules[strings.TrimSpace(docType)] = parsed
+		}
+	}
+	return rules
+}
+
+// parseAPITokens interpreta "nome:token:perm1|perm2,nome2:token2:*:escritorio_id" em tokens da API.
+// Entradas sem token são ignoradas; sem permissões o token só é aceito nas rotas abertas.
+// O quarto campo, opcional, restringe o token a um escritório.
+func parseAPITokens(value string) []APIToken {
+	var tokens []APIToken
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 4)
+		if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+			continue
+		}
+		token := APIToken{
+			Name:  strings.TrimSpace(parts[0]),
+			Token: strings.TrimSpace(parts[1]),
+		}
+		if len(parts) >= 3 {
+			for _, permission := range strings.Split(parts[2], "|") {
+				if permission = strings.TrimSpace(permission); permission != "" {
+					token.Permissions = append(token.Permissions, permission)
+				}
+			}
+		}
+		if len(parts) == 4 {
+			// Escritório inválido descarta o token em vez de transformá-lo em token de sistema
+			id, err := strconv.ParseInt(strings.TrimSpace(parts[3]), 10, 64)
+			if err != nil || id <= 0 {
+				continue
+			}
+			token.EscritorioID = &id
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
 // getEnvBool obtém uma variável de ambiente como boolean ou retorna um valor padrão
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -177,6 +388,48 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("PORT é obrigatório")
 	}
+	switch c.Storage.Backend {
+	case StorageBackendMinIO:
+	case StorageBackendLocal:
+		if c.Storage.LocalPath == "" {
+			return fmt.Errorf("STORAGE_LOCAL_PATH é obrigatório para o backend local")
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND inválido: %s (use minio ou local)", c.Storage.Backend)
+	}
+	if c.Auth.Enabled && len(c.Auth.Tokens) == 0 {
+		return fmt.Errorf("API_TOKENS é obrigatório com AUTH_ENABLED=true")
+	}
+	if c.Dashboard.RefreshDelay < 0 {
+		return fmt.Errorf("DASHBOARD_REFRESH_DELAY não pode ser negativo")
+	}
+	if c.Storage.PresignExpiry <= 0 {
+		return fmt.Errorf("STORAGE_PRESIGN_EXPIRY deve ser maior que zero")
+	}
+	if c.Storage.Encryption && c.Storage.MasterKey == "" && c.Storage.MasterKeyFile == "" {
+		return fmt.Errorf("STORAGE_MASTER_KEY ou STORAGE_MASTER_KEY_FILE é obrigatório com STORAGE_ENCRYPTION=true")
+	}
+	if c.Storage.ServesSignedLinks() && c.Storage.LocalSigningKey == "" {
+		if !c.Storage.EphemeralSigningKey {
+			return fmt.Errorf("STORAGE_LOCAL_SIGNING_KEY é obrigatório com STORAGE_BACKEND=local ou STORAGE_ENCRYPTION=true")
+		}
+		if !c.IsDevelopment() {
+			return fmt.Errorf("STORAGE_LOCAL_SIGNING_KEY_EPHEMERAL é permitido apenas em desenvolvimento")
+		}
+	}
+	if c.Retention.DefaultYears < MinRetentionYears {
+		return fmt.Errorf("RETENTION_DEFAULT_YEARS deve ser de pelo menos %d anos", MinRetentionYears)
+	}
+	for docType, years := range c.Retention.Years {
+		if years < MinRetentionYears {
+			return fmt.Errorf("RETENTION_RULES: prazo de %s deve ser de pelo menos %d anos", docType, MinRetentionYears)
+		}
+	}
+	switch strings.ToUpper(c.Retention.LockMode) {
+	case "GOVERNANCE", "COMPLIANCE":
+	default:
+		return fmt.Errorf("RETENTION_LOCK_MODE inválido: %s (use GOVERNANCE ou COMPLIANCE)", c.Retention.LockMode)
+	}
 	return nil
 }
 
@@ -13,38 +13,44 @@ import (
 	"github.com/uptrace/bun/driver/pgdriver"
 )
 
-// DB é a instância global do banco de dados
-var DB *bun.DB
-
-// InitDB inicializa a conexão com o banco de dados
-func InitDB(cfg *config.Config) error {
+// Open abre a conexão com o banco de dados. O *bun.DB retornado é repassado aos
+// repositórios por quem o abriu, que deve fechá-lo ao terminar.
+//
+// As conexões abrem com app.system = 'on', o acesso de sistema do row-level
+// security: jobs, comandos e migrações veem todos os escritórios, com o filtro
+// dos repositórios definindo o escopo. O middleware Tenant desliga a variável
+// nas requisições de escritório.
+func Open(cfg *config.Config) (*bun.DB, error) {
 	dsn := cfg.Database.DSN
 
 	// Conectar ao PostgreSQL
-	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
+	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn), withSystemAccess))
 
 	// Criar instância do Bun
-	DB = bun.NewDB(sqldb, pgdialect.New())
+	db := bun.NewDB(sqldb, pgdialect.New())
 
 	// Adicionar hook de logging personalizado
 	if cfg.App.BunDebug > 0 {
-		DB.AddQueryHook(NewBunLogger())
+		db.AddQueryHook(NewBunLogger())
 	}
 
 	// Testar conexão
 	ctx := context.Background()
-	if err := DB.PingContext(ctx); err != nil {
-		return fmt.Errorf("erro ao conectar com o banco de dados: %w", err)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("erro ao conectar com o banco de dados: %w", err)
 	}
 
 	logger.Database().Info().Msg("Conexão com o banco de dados estabelecida com sucesso")
-	return nil
+	return db, nil
 }
 
-// CloseDB fecha a conexão com o banco de dados
-func CloseDB() error {
-	if DB != nil {
-		return DB.Close()
+// withSystemAccess acrescenta app.system aos parâmetros de conexão do DSN
+func withSystemAccess(cfg *pgdriver.Config) {
+	params := make(map[string]interface{}, len(cfg.ConnParams)+1)
+	for key, value := range cfg.ConnParams {
+		params[key] = value
 	}
-	return nil
+	params["app.system"] = "on"
+	cfg.ConnParams = params
 }
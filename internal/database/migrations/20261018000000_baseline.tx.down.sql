@@ -0,0 +1,3 @@
+-- O esquema base não é desfeito: removê-lo apagaria todos os dados.
+-- Para recriar o banco do zero, apague o schema e rode "migrate up".
+DO $$ BEGIN RAISE EXCEPTION 'a migração base não pode ser desfeita'; END $$;
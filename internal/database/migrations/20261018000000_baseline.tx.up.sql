@@ -0,0 +1,431 @@
+-- Esquema base: tudo o que RunMigrations criava com CreateTable IfNotExists,
+-- as colunas adicionadas depois e a tabela documents, usada por DocumentRepository.
+-- Idempotente, para que bancos criados pelas migrações automáticas possam aplicá-la.
+
+CREATE TABLE IF NOT EXISTS nfse (
+    id BIGSERIAL NOT NULL,
+    numero_nfse VARCHAR NOT NULL,
+    codigo_verificacao VARCHAR NOT NULL,
+    numero_rps VARCHAR,
+    serie_rps VARCHAR,
+    tipo_rps BIGINT,
+    data_emissao TIMESTAMPTZ NOT NULL,
+    competencia VARCHAR NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pendente',
+    valor_nota DECIMAL(15,2) NOT NULL DEFAULT 0,
+    aliquota_iss DECIMAL(8,4) NOT NULL DEFAULT 0,
+    valor_iss DECIMAL(15,2) NOT NULL DEFAULT 0,
+    cnpj_prestador VARCHAR NOT NULL,
+    razao_social_prestador VARCHAR NOT NULL,
+    inscricao_municipal_prestador VARCHAR,
+    cnpj_tomador VARCHAR,
+    razao_social_tomador VARCHAR,
+    discriminacao TEXT,
+    codigo_servico VARCHAR,
+    item_lista_servico VARCHAR,
+    codigo_municipio VARCHAR,
+    codigo_ibge VARCHAR,
+    xml_content TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (numero_nfse)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS documents (
+    id BIGSERIAL NOT NULL,
+    document_type VARCHAR NOT NULL,
+    numero_documento VARCHAR NOT NULL,
+    codigo_verificacao VARCHAR NOT NULL,
+    numero_rps VARCHAR,
+    serie_rps VARCHAR,
+    tipo_rps BIGINT,
+    data_emissao TIMESTAMPTZ NOT NULL,
+    competencia VARCHAR NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pendente',
+    valor_nota DECIMAL(15,2) NOT NULL DEFAULT 0,
+    aliquota_iss DECIMAL(8,4) NOT NULL DEFAULT 0,
+    valor_iss DECIMAL(15,2) NOT NULL DEFAULT 0,
+    cnpj_emitente VARCHAR NOT NULL,
+    razao_social_emitente VARCHAR NOT NULL,
+    inscricao_municipal_emitente VARCHAR,
+    cnpj_destinatario VARCHAR,
+    razao_social_destinatario VARCHAR,
+    discriminacao TEXT,
+    codigo_servico VARCHAR,
+    item_lista_servico VARCHAR,
+    codigo_municipio VARCHAR,
+    codigo_ibge VARCHAR,
+    xml_content TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (numero_documento)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL NOT NULL,
+    name VARCHAR NOT NULL,
+    email VARCHAR NOT NULL,
+    password VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (email)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS empresas (
+    id BIGSERIAL NOT NULL,
+    cnpj VARCHAR NOT NULL,
+    inscricao_estadual VARCHAR,
+    inscricao_municipal VARCHAR,
+    razao_social VARCHAR NOT NULL,
+    nome_fantasia VARCHAR,
+    data_abertura TIMESTAMPTZ,
+    porte VARCHAR,
+    natureza_juridica VARCHAR,
+    codigo_natureza VARCHAR,
+    atividade_principal VARCHAR,
+    codigo_ativ_principal VARCHAR,
+    situacao_cadastral VARCHAR,
+    data_situacao TIMESTAMPTZ,
+    motivo_situacao VARCHAR,
+    situacao_especial VARCHAR,
+    data_situacao_especial TIMESTAMPTZ,
+    logradouro VARCHAR,
+    numero VARCHAR,
+    complemento VARCHAR,
+    cep VARCHAR,
+    bairro VARCHAR,
+    municipio VARCHAR,
+    uf VARCHAR,
+    email VARCHAR,
+    telefone VARCHAR,
+    capital_social DOUBLE PRECISION,
+    simples_nacional BOOLEAN,
+    mei BOOLEAN,
+    ativa BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (cnpj)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS atividades_secundarias (
+    id BIGSERIAL NOT NULL,
+    empresa_id BIGINT NOT NULL,
+    codigo VARCHAR NOT NULL,
+    descricao VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS empresa_membros (
+    id BIGSERIAL NOT NULL,
+    empresa_id BIGINT NOT NULL,
+    tipo_documento VARCHAR,
+    numero_documento VARCHAR,
+    nome VARCHAR NOT NULL,
+    idade VARCHAR,
+    cargo_id BIGINT,
+    cargo_nome VARCHAR NOT NULL,
+    data_inicio TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS empresa_inscricoes_estaduais (
+    id BIGSERIAL NOT NULL,
+    empresa_id BIGINT NOT NULL,
+    numero VARCHAR NOT NULL,
+    estado VARCHAR NOT NULL,
+    ativa BOOLEAN DEFAULT true,
+    data_status TIMESTAMPTZ,
+    status_id BIGINT,
+    status_nome VARCHAR,
+    tipo_id BIGINT,
+    tipo_nome VARCHAR,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS empresa_suframa (
+    id BIGSERIAL NOT NULL,
+    empresa_id BIGINT NOT NULL,
+    numero VARCHAR NOT NULL,
+    data_cadastro TIMESTAMPTZ,
+    data_vencimento TIMESTAMPTZ,
+    ativa BOOLEAN DEFAULT true,
+    incentivos_ativos BOOLEAN,
+    tipo_incentivo VARCHAR,
+    descricao_incentivo VARCHAR,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS empresa_telefones (
+    id BIGSERIAL NOT NULL,
+    empresa_id BIGINT NOT NULL,
+    tipo VARCHAR,
+    ddd VARCHAR,
+    numero VARCHAR NOT NULL,
+    principal BOOLEAN DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS empresa_emails (
+    id BIGSERIAL NOT NULL,
+    empresa_id BIGINT NOT NULL,
+    email VARCHAR NOT NULL,
+    dominio VARCHAR,
+    tipo VARCHAR,
+    principal BOOLEAN DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS storage_outbox (
+    id BIGSERIAL NOT NULL,
+    document_id BIGINT NOT NULL,
+    object_name VARCHAR NOT NULL,
+    content_type VARCHAR NOT NULL DEFAULT 'application/xml',
+    payload BYTEA NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pendente',
+    attempts BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    processed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL NOT NULL,
+    source VARCHAR NOT NULL,
+    codigo_ibge VARCHAR,
+    stage VARCHAR NOT NULL,
+    numero_documento VARCHAR,
+    competencia VARCHAR,
+    payload TEXT NOT NULL,
+    payload_format VARCHAR NOT NULL,
+    error TEXT NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pendente',
+    attempts BIGINT NOT NULL DEFAULT 0,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS job_locks (
+    name VARCHAR NOT NULL,
+    lock_key BIGINT NOT NULL,
+    holder VARCHAR NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (name)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id VARCHAR NOT NULL,
+    job_name VARCHAR NOT NULL,
+    trigger VARCHAR NOT NULL,
+    status VARCHAR NOT NULL,
+    holder VARCHAR,
+    error TEXT,
+    counters JSONB,
+    logs JSONB,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS job_schedules (
+    id BIGSERIAL NOT NULL,
+    name VARCHAR NOT NULL,
+    type VARCHAR NOT NULL,
+    empresa_id BIGINT,
+    codigo_ibge VARCHAR,
+    cron VARCHAR NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL,
+    description VARCHAR,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (name)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS exports (
+    id BIGSERIAL NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pendente',
+    filter JSONB NOT NULL,
+    run_id VARCHAR,
+    object_key VARCHAR,
+    documents BIGINT NOT NULL DEFAULT 0,
+    size BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS encryption_keys (
+    id BIGSERIAL NOT NULL,
+    tenant VARCHAR NOT NULL,
+    version BIGINT NOT NULL,
+    master_key_id VARCHAR NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    CONSTRAINT encryption_keys_tenant_version UNIQUE (tenant, version)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS document_downloads (
+    id BIGSERIAL NOT NULL,
+    document_id BIGINT NOT NULL,
+    kind VARCHAR NOT NULL,
+    principal VARCHAR NOT NULL,
+    object_key VARCHAR,
+    ip VARCHAR,
+    user_agent VARCHAR,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+--bun:split
+
+-- Integridade do XML dos documentos
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_key VARCHAR;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_sha256 VARCHAR(64);
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_integrity VARCHAR;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_verified_at TIMESTAMPTZ;
+ALTER TABLE storage_outbox ADD COLUMN IF NOT EXISTS content_sha256 VARCHAR(64);
+
+-- Localização do XML dos documentos
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_bucket VARCHAR;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_size BIGINT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_content_type VARCHAR;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_uploaded_at TIMESTAMPTZ;
+
+-- Retenção fiscal dos XMLs
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_retain_until TIMESTAMPTZ;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS xml_legal_hold BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE storage_outbox ADD COLUMN IF NOT EXISTS retain_until TIMESTAMPTZ;
+ALTER TABLE storage_outbox ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT false;
+
+-- Criptografia em repouso
+ALTER TABLE storage_outbox ADD COLUMN IF NOT EXISTS tenant VARCHAR;
+
+--bun:split
+
+-- Índices para NFSe
+CREATE INDEX IF NOT EXISTS idx_nfse_numero_nfse ON nfse (numero_nfse);
+CREATE INDEX IF NOT EXISTS idx_nfse_numero_rps ON nfse (numero_rps);
+CREATE INDEX IF NOT EXISTS idx_nfse_data_emissao ON nfse (data_emissao);
+CREATE INDEX IF NOT EXISTS idx_nfse_competencia ON nfse (competencia);
+CREATE INDEX IF NOT EXISTS idx_nfse_status ON nfse (status);
+
+-- Índices para Document
+CREATE INDEX IF NOT EXISTS idx_documents_xml_sha256 ON documents (xml_sha256);
+CREATE INDEX IF NOT EXISTS idx_documents_emitente_competencia ON documents (cnpj_emitente, competencia);
+CREATE INDEX IF NOT EXISTS idx_documents_destinatario_competencia ON documents (cnpj_destinatario, competencia);
+
+-- Índices para Empresa
+CREATE INDEX IF NOT EXISTS idx_empresa_cnpj ON empresas (cnpj);
+CREATE INDEX IF NOT EXISTS idx_empresa_razao_social ON empresas (razao_social);
+CREATE INDEX IF NOT EXISTS idx_empresa_nome_fantasia ON empresas (nome_fantasia);
+CREATE INDEX IF NOT EXISTS idx_empresa_situacao ON empresas (situacao_cadastral);
+
+-- Índices para User
+CREATE INDEX IF NOT EXISTS idx_user_email ON users (email);
+
+-- Índices para AtividadeSecundaria
+CREATE INDEX IF NOT EXISTS idx_atividade_empresa_id ON atividades_secundarias (empresa_id);
+CREATE INDEX IF NOT EXISTS idx_atividade_codigo ON atividades_secundarias (codigo);
+
+-- Índices para EmpresaMembro
+CREATE INDEX IF NOT EXISTS idx_empresa_membro_empresa_id ON empresa_membros (empresa_id);
+CREATE INDEX IF NOT EXISTS idx_empresa_membro_documento ON empresa_membros (numero_documento);
+CREATE INDEX IF NOT EXISTS idx_empresa_membro_nome ON empresa_membros (nome);
+
+-- Índices para EmpresaInscricaoEstadual
+CREATE INDEX IF NOT EXISTS idx_empresa_inscricao_empresa_id ON empresa_inscricoes_estaduais (empresa_id);
+CREATE INDEX IF NOT EXISTS idx_empresa_inscricao_numero ON empresa_inscricoes_estaduais (numero);
+CREATE INDEX IF NOT EXISTS idx_empresa_inscricao_estado ON empresa_inscricoes_estaduais (estado);
+
+-- Índices para EmpresaSuframa
+CREATE INDEX IF NOT EXISTS idx_empresa_suframa_empresa_id ON empresa_suframa (empresa_id);
+CREATE INDEX IF NOT EXISTS idx_empresa_suframa_numero ON empresa_suframa (numero);
+
+-- Índices para EmpresaTelefone
+CREATE INDEX IF NOT EXISTS idx_empresa_telefone_empresa_id ON empresa_telefones (empresa_id);
+CREATE INDEX IF NOT EXISTS idx_empresa_telefone_principal ON empresa_telefones (principal);
+
+-- Índices para EmpresaEmail
+CREATE INDEX IF NOT EXISTS idx_empresa_email_empresa_id ON empresa_emails (empresa_id);
+CREATE INDEX IF NOT EXISTS idx_empresa_email_principal ON empresa_emails (principal);
+CREATE INDEX IF NOT EXISTS idx_empresa_email_email ON empresa_emails (email);
+
+-- Índices para StorageOutbox
+CREATE INDEX IF NOT EXISTS idx_storage_outbox_pending ON storage_outbox (next_attempt_at) WHERE status = 'pendente';
+CREATE INDEX IF NOT EXISTS idx_storage_outbox_document_id ON storage_outbox (document_id);
+
+-- Índices para DeadLetter
+CREATE INDEX IF NOT EXISTS idx_dead_letters_status ON dead_letters (status);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_competencia ON dead_letters (competencia);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_numero ON dead_letters (numero_documento);
+
+-- Índices para DocumentDownload
+CREATE INDEX IF NOT EXISTS idx_document_downloads_document_id ON document_downloads (document_id, created_at DESC);
+
+-- Índices para JobRun
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started ON job_runs (job_name, started_at DESC);
+
+-- Índices para JobSchedule
+CREATE INDEX IF NOT EXISTS idx_job_schedules_enabled ON job_schedules (enabled);
+CREATE INDEX IF NOT EXISTS idx_job_schedules_empresa_id ON job_schedules (empresa_id);
@@ -0,0 +1,4 @@
+ALTER TABLE empresas
+    ALTER COLUMN capital_social DROP NOT NULL,
+    ALTER COLUMN capital_social DROP DEFAULT,
+    ALTER COLUMN capital_social TYPE DOUBLE PRECISION;
@@ -0,0 +1,8 @@
+-- Capital social em decimal exato, como os valores dos documentos.
+-- Sem informação o capital fica 0, como já era lido pela aplicação.
+UPDATE empresas SET capital_social = 0 WHERE capital_social IS NULL;
+
+ALTER TABLE empresas
+    ALTER COLUMN capital_social TYPE DECIMAL(18,2) USING round(capital_social::numeric, 2),
+    ALTER COLUMN capital_social SET DEFAULT 0,
+    ALTER COLUMN capital_social SET NOT NULL;
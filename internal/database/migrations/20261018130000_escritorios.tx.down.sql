@@ -0,0 +1,38 @@
+DROP POLICY IF EXISTS escritorio_isolation ON document_downloads;
+DROP POLICY IF EXISTS escritorio_isolation ON empresa_emails;
+DROP POLICY IF EXISTS escritorio_isolation ON empresa_telefones;
+DROP POLICY IF EXISTS escritorio_isolation ON empresa_suframa;
+DROP POLICY IF EXISTS escritorio_isolation ON empresa_inscricoes_estaduais;
+DROP POLICY IF EXISTS escritorio_isolation ON empresa_membros;
+DROP POLICY IF EXISTS escritorio_isolation ON atividades_secundarias;
+DROP POLICY IF EXISTS escritorio_isolation ON exports;
+DROP POLICY IF EXISTS escritorio_isolation ON job_schedules;
+DROP POLICY IF EXISTS escritorio_isolation ON documents;
+DROP POLICY IF EXISTS escritorio_isolation ON users;
+DROP POLICY IF EXISTS escritorio_isolation ON empresas;
+DROP POLICY IF EXISTS escritorio_isolation ON api_tokens;
+DROP POLICY IF EXISTS escritorio_isolation ON escritorios;
+
+ALTER TABLE document_downloads NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_emails NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_telefones NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_suframa NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_inscricoes_estaduais NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_membros NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE atividades_secundarias NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE exports NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE job_schedules NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE documents NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+ALTER TABLE empresas NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE dead_letters DROP COLUMN escritorio_id;
+ALTER TABLE exports DROP COLUMN escritorio_id;
+ALTER TABLE job_schedules DROP COLUMN escritorio_id;
+ALTER TABLE documents DROP COLUMN escritorio_id;
+ALTER TABLE users DROP COLUMN escritorio_id;
+ALTER TABLE empresas DROP COLUMN escritorio_id;
+
+DROP TABLE api_tokens;
+DROP TABLE escritorios;
+DROP FUNCTION app_escritorio_id();
@@ -0,0 +1,146 @@
+-- Multi-tenancy: escritórios de contabilidade donos de empresas, usuários,
+-- credenciais, agendamentos, exportações e documentos.
+
+CREATE TABLE escritorios (
+    id BIGSERIAL NOT NULL,
+    nome VARCHAR NOT NULL,
+    cnpj VARCHAR,
+    ativo BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (cnpj)
+);
+
+--bun:split
+
+-- Os dados existentes passam a pertencer a um escritório padrão
+INSERT INTO escritorios (nome) VALUES ('Escritório padrão');
+
+--bun:split
+
+CREATE TABLE api_tokens (
+    id BIGSERIAL NOT NULL,
+    escritorio_id BIGINT NOT NULL REFERENCES escritorios (id) ON DELETE CASCADE,
+    nome VARCHAR NOT NULL,
+    token_hash VARCHAR NOT NULL,
+    permissions VARCHAR[],
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id),
+    UNIQUE (token_hash)
+);
+
+--bun:split
+
+ALTER TABLE empresas ADD COLUMN escritorio_id BIGINT REFERENCES escritorios (id);
+ALTER TABLE users ADD COLUMN escritorio_id BIGINT REFERENCES escritorios (id);
+ALTER TABLE documents ADD COLUMN escritorio_id BIGINT REFERENCES escritorios (id);
+ALTER TABLE job_schedules ADD COLUMN escritorio_id BIGINT REFERENCES escritorios (id);
+ALTER TABLE exports ADD COLUMN escritorio_id BIGINT REFERENCES escritorios (id);
+-- A fila de falhas é do sistema; o escritório indica onde o reprocessamento grava o documento
+ALTER TABLE dead_letters ADD COLUMN escritorio_id BIGINT REFERENCES escritorios (id) ON DELETE SET NULL;
+
+UPDATE empresas SET escritorio_id = (SELECT min(id) FROM escritorios);
+UPDATE users SET escritorio_id = (SELECT min(id) FROM escritorios);
+UPDATE documents SET escritorio_id = (SELECT min(id) FROM escritorios);
+UPDATE exports SET escritorio_id = (SELECT min(id) FROM escritorios);
+UPDATE dead_letters SET escritorio_id = (SELECT min(id) FROM escritorios);
+-- Só a sincronização de documentos pertence a um escritório; os jobs de armazenamento são do sistema
+UPDATE job_schedules SET escritorio_id = (SELECT min(id) FROM escritorios) WHERE type = 'nfse-sync';
+
+CREATE INDEX idx_empresas_escritorio_id ON empresas (escritorio_id);
+CREATE INDEX idx_users_escritorio_id ON users (escritorio_id);
+CREATE INDEX idx_documents_escritorio_id ON documents (escritorio_id);
+CREATE INDEX idx_job_schedules_escritorio_id ON job_schedules (escritorio_id);
+CREATE INDEX idx_exports_escritorio_id ON exports (escritorio_id);
+CREATE INDEX idx_api_tokens_escritorio_id ON api_tokens (escritorio_id);
+
+--bun:split
+
+-- Row-level security como segunda barreira ao filtro dos repositórios. A API define
+-- app.escritorio_id na transação de cada requisição de um escritório; sem a
+-- variável (jobs, comandos, administração) as linhas de todos os escritórios ficam
+-- visíveis. Superusuários ignoram RLS: conecte a API com um papel comum.
+CREATE FUNCTION app_escritorio_id() RETURNS BIGINT
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('app.escritorio_id', true), '')::BIGINT $$;
+
+ALTER TABLE escritorios ENABLE ROW LEVEL SECURITY;
+ALTER TABLE escritorios FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON escritorios
+    USING (app_escritorio_id() IS NULL OR id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR id = app_escritorio_id());
+
+ALTER TABLE api_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_tokens FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON api_tokens
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+ALTER TABLE empresas ENABLE ROW LEVEL SECURITY;
+ALTER TABLE empresas FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON empresas
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON users
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+ALTER TABLE documents ENABLE ROW LEVEL SECURITY;
+ALTER TABLE documents FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON documents
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+ALTER TABLE job_schedules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE job_schedules FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON job_schedules
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+ALTER TABLE exports ENABLE ROW LEVEL SECURITY;
+ALTER TABLE exports FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON exports
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+-- Tabelas filhas seguem a visibilidade da empresa ou do documento
+ALTER TABLE atividades_secundarias ENABLE ROW LEVEL SECURITY;
+ALTER TABLE atividades_secundarias FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON atividades_secundarias
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+ALTER TABLE empresa_membros ENABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_membros FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON empresa_membros
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+ALTER TABLE empresa_inscricoes_estaduais ENABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_inscricoes_estaduais FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON empresa_inscricoes_estaduais
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+ALTER TABLE empresa_suframa ENABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_suframa FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON empresa_suframa
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+ALTER TABLE empresa_telefones ENABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_telefones FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON empresa_telefones
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+ALTER TABLE empresa_emails ENABLE ROW LEVEL SECURITY;
+ALTER TABLE empresa_emails FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON empresa_emails
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+ALTER TABLE document_downloads ENABLE ROW LEVEL SECURITY;
+ALTER TABLE document_downloads FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON document_downloads
+    USING (app_escritorio_id() IS NULL OR document_id IN (SELECT id FROM documents));
@@ -0,0 +1,2 @@
+ALTER TABLE documents DROP COLUMN destinatario_empresa_id;
+ALTER TABLE documents DROP COLUMN emitente_empresa_id;
@@ -0,0 +1,21 @@
+-- Vínculo dos documentos com as empresas cadastradas, como emitente e como tomador.
+-- Os CNPJs continuam gravados no documento; o vínculo é resolvido na sincronização
+-- e quando a empresa é cadastrada depois dos documentos.
+
+ALTER TABLE documents ADD COLUMN emitente_empresa_id BIGINT REFERENCES empresas (id) ON DELETE SET NULL;
+ALTER TABLE documents ADD COLUMN destinatario_empresa_id BIGINT REFERENCES empresas (id) ON DELETE SET NULL;
+
+--bun:split
+
+UPDATE documents d SET emitente_empresa_id = e.id
+FROM empresas e
+WHERE e.cnpj = d.cnpj_emitente AND e.escritorio_id IS NOT DISTINCT FROM d.escritorio_id;
+
+UPDATE documents d SET destinatario_empresa_id = e.id
+FROM empresas e
+WHERE e.cnpj = d.cnpj_destinatario AND e.escritorio_id IS NOT DISTINCT FROM d.escritorio_id;
+
+--bun:split
+
+CREATE INDEX idx_documents_emitente_empresa_id ON documents (emitente_empresa_id, data_emissao DESC);
+CREATE INDEX idx_documents_destinatario_empresa_id ON documents (destinatario_empresa_id, data_emissao DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_empresas_nome_fantasia_trgm;
+DROP INDEX IF EXISTS idx_empresas_razao_social_trgm;
+DROP INDEX IF EXISTS idx_empresas_cnpj_trgm;
+
+ALTER TABLE documents DROP COLUMN search_vector;
+
+DROP TEXT SEARCH CONFIGURATION zemdocs_portuguese;
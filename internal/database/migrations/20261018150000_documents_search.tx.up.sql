@@ -0,0 +1,33 @@
+-- Busca textual nos documentos: português com remoção de acentos, sobre a
+-- discriminação, razão social das partes, CNPJ/CPF, número e código de verificação.
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+--bun:split
+
+-- Configuração portuguesa que ignora acentos ("serviço" encontra "servico")
+CREATE TEXT SEARCH CONFIGURATION zemdocs_portuguese (COPY = portuguese);
+ALTER TEXT SEARCH CONFIGURATION zemdocs_portuguese
+    ALTER MAPPING FOR hword, hword_part, word WITH unaccent, portuguese_stem;
+
+--bun:split
+
+-- Identificadores pesam mais que as partes, que pesam mais que a discriminação
+ALTER TABLE documents ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('zemdocs_portuguese',
+        coalesce(numero_documento, '') || ' ' || coalesce(codigo_verificacao, '') || ' ' ||
+        coalesce(cnpj_emitente, '') || ' ' || coalesce(cnpj_destinatario, '')), 'A') ||
+    setweight(to_tsvector('zemdocs_portuguese',
+        coalesce(razao_social_emitente, '') || ' ' || coalesce(razao_social_destinatario, '')), 'B') ||
+    setweight(to_tsvector('zemdocs_portuguese', coalesce(discriminacao, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_documents_search_vector ON documents USING GIN (search_vector);
+
+--bun:split
+
+-- A busca de empresas continua por trecho (ILIKE), agora atendida por índices de trigramas
+CREATE INDEX idx_empresas_cnpj_trgm ON empresas USING GIN (cnpj gin_trgm_ops);
+CREATE INDEX idx_empresas_razao_social_trgm ON empresas USING GIN (razao_social gin_trgm_ops);
+CREATE INDEX idx_empresas_nome_fantasia_trgm ON empresas USING GIN (nome_fantasia gin_trgm_ops);
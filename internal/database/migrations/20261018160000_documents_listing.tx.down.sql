@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_documents_escritorio_competencia;
+DROP INDEX IF EXISTS idx_documents_escritorio_data_emissao;
@@ -0,0 +1,4 @@
+-- Paginação por chave da listagem de documentos na ordenação padrão (emissão
+-- mais recente primeiro) e nas consultas por competência do escritório
+CREATE INDEX idx_documents_escritorio_data_emissao ON documents (escritorio_id, data_emissao DESC, id DESC);
+CREATE INDEX idx_documents_escritorio_competencia ON documents (escritorio_id, competencia, id);
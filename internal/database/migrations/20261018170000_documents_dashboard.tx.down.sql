@@ -0,0 +1,4 @@
+DROP MATERIALIZED VIEW IF EXISTS documents_resumo_tomadores;
+DROP MATERIALIZED VIEW IF EXISTS documents_resumo_mensal;
+
+ALTER TABLE documents DROP COLUMN valor_iss_retido;
@@ -0,0 +1,41 @@
+-- ISS retido pelo tomador, extraído do XML (IssRetido/ValorIssRetido do ABRASF)
+ALTER TABLE documents ADD COLUMN valor_iss_retido DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+--bun:split
+
+-- Agregados do dashboard por escritório, empresa emitente e competência, atualizados
+-- ao fim de cada sincronização. Views materializadas não passam pelo row-level
+-- security: as consultas filtram escritorio_id no repositório. Chaves nulas viram 0
+-- para que o índice único permita o REFRESH ... CONCURRENTLY.
+CREATE MATERIALIZED VIEW documents_resumo_mensal AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota,
+    sum(valor_iss) AS valor_iss,
+    sum(valor_iss_retido) AS valor_iss_retido
+FROM documents
+GROUP BY 1, 2, 3;
+
+CREATE UNIQUE INDEX idx_documents_resumo_mensal_key ON documents_resumo_mensal (escritorio_id, emitente_empresa_id, competencia);
+
+--bun:split
+
+-- Faturamento por tomador, base do ranking de maiores tomadores
+CREATE MATERIALIZED VIEW documents_resumo_tomadores AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    cnpj_destinatario,
+    max(razao_social_destinatario) AS razao_social_destinatario,
+    max(destinatario_empresa_id) AS destinatario_empresa_id,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota
+FROM documents
+WHERE cnpj_destinatario <> ''
+GROUP BY 1, 2, 3, 4;
+
+CREATE UNIQUE INDEX idx_documents_resumo_tomadores_key ON documents_resumo_tomadores (escritorio_id, emitente_empresa_id, competencia, cnpj_destinatario);
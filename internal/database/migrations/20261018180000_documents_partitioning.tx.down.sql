@@ -0,0 +1,87 @@
+-- Volta documents a uma tabela comum. As partições arquivadas precisam estar
+-- acessíveis (o tablespace montado) para a cópia.
+
+DROP MATERIALIZED VIEW documents_resumo_tomadores;
+DROP MATERIALIZED VIEW documents_resumo_mensal;
+DROP POLICY escritorio_isolation ON document_downloads;
+
+ALTER TABLE documents RENAME TO documents_partitioned;
+ALTER SEQUENCE documents_id_seq OWNED BY NONE;
+
+CREATE TABLE documents (LIKE documents_partitioned INCLUDING DEFAULTS INCLUDING GENERATED);
+ALTER SEQUENCE documents_id_seq OWNED BY documents.id;
+
+DO $$
+DECLARE
+    colunas TEXT;
+BEGIN
+    SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position) INTO colunas
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'documents_partitioned' AND is_generated = 'NEVER';
+    EXECUTE format('INSERT INTO documents (%s) SELECT %s FROM documents_partitioned', colunas, colunas);
+END $$;
+
+DROP TABLE documents_partitioned;
+DROP FUNCTION documents_mover_particao(TEXT, TEXT);
+DROP FUNCTION documents_criar_particao(INT);
+
+--bun:split
+
+ALTER TABLE documents ADD PRIMARY KEY (id);
+ALTER TABLE documents ADD CONSTRAINT documents_numero_documento_key UNIQUE (numero_documento);
+ALTER TABLE documents ADD FOREIGN KEY (escritorio_id) REFERENCES escritorios (id);
+ALTER TABLE documents ADD FOREIGN KEY (emitente_empresa_id) REFERENCES empresas (id) ON DELETE SET NULL;
+ALTER TABLE documents ADD FOREIGN KEY (destinatario_empresa_id) REFERENCES empresas (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_documents_escritorio_id ON documents (escritorio_id);
+CREATE INDEX idx_documents_escritorio_data_emissao ON documents (escritorio_id, data_emissao DESC, id DESC);
+CREATE INDEX idx_documents_escritorio_competencia ON documents (escritorio_id, competencia, id);
+CREATE INDEX idx_documents_emitente_empresa_id ON documents (emitente_empresa_id, data_emissao DESC);
+CREATE INDEX idx_documents_destinatario_empresa_id ON documents (destinatario_empresa_id, data_emissao DESC);
+CREATE INDEX idx_documents_emitente_competencia ON documents (cnpj_emitente, competencia);
+CREATE INDEX idx_documents_destinatario_competencia ON documents (cnpj_destinatario, competencia);
+CREATE INDEX idx_documents_search_vector ON documents USING GIN (search_vector);
+CREATE INDEX idx_documents_xml_sha256 ON documents (xml_sha256);
+
+--bun:split
+
+ALTER TABLE documents ENABLE ROW LEVEL SECURITY;
+ALTER TABLE documents FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON documents
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+CREATE POLICY escritorio_isolation ON document_downloads
+    USING (app_escritorio_id() IS NULL OR document_id IN (SELECT id FROM documents));
+
+--bun:split
+
+CREATE MATERIALIZED VIEW documents_resumo_mensal AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota,
+    sum(valor_iss) AS valor_iss,
+    sum(valor_iss_retido) AS valor_iss_retido
+FROM documents
+GROUP BY 1, 2, 3;
+
+CREATE UNIQUE INDEX idx_documents_resumo_mensal_key ON documents_resumo_mensal (escritorio_id, emitente_empresa_id, competencia);
+
+CREATE MATERIALIZED VIEW documents_resumo_tomadores AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    cnpj_destinatario,
+    max(razao_social_destinatario) AS razao_social_destinatario,
+    max(destinatario_empresa_id) AS destinatario_empresa_id,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota
+FROM documents
+WHERE cnpj_destinatario <> ''
+GROUP BY 1, 2, 3, 4;
+
+CREATE UNIQUE INDEX idx_documents_resumo_tomadores_key ON documents_resumo_tomadores (escritorio_id, emitente_empresa_id, competencia, cnpj_destinatario);
@@ -0,0 +1,165 @@
+-- Particionamento de documents por ano de competência (RANGE sobre AAAAMM). A tabela
+-- é recriada como particionada e os dados são copiados; a chave primária e a unicidade
+-- do número passam a incluir a competência, como exige o particionamento. Competências
+-- fora das partições existentes caem em documents_default até a partição do ano ser
+-- criada pelo job de manutenção, que move essas linhas para ela.
+
+-- As views do dashboard e a política de document_downloads dependem da tabela antiga
+DROP MATERIALIZED VIEW documents_resumo_tomadores;
+DROP MATERIALIZED VIEW documents_resumo_mensal;
+DROP POLICY escritorio_isolation ON document_downloads;
+
+ALTER TABLE documents RENAME TO documents_legacy;
+ALTER SEQUENCE documents_id_seq OWNED BY NONE;
+
+CREATE TABLE documents (LIKE documents_legacy INCLUDING DEFAULTS INCLUDING GENERATED)
+    PARTITION BY RANGE (competencia);
+ALTER SEQUENCE documents_id_seq OWNED BY documents.id;
+
+CREATE TABLE documents_default PARTITION OF documents DEFAULT;
+
+--bun:split
+
+-- Cria a partição do ano, movendo para ela as linhas do ano que estavam na partição
+-- padrão. Roda sem app.escritorio_id para enxergar os documentos de todos os
+-- escritórios. Retorna false se a partição já existia.
+CREATE FUNCTION documents_criar_particao(ano INT) RETURNS BOOLEAN
+    LANGUAGE plpgsql
+    SET app.escritorio_id = ''
+    AS $$
+DECLARE
+    nome TEXT := 'documents_' || ano;
+    inicio TEXT := ano::TEXT || '01';
+    fim TEXT := (ano + 1)::TEXT || '01';
+    colunas TEXT;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('documents_criar_particao'));
+    IF to_regclass(nome) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+
+    CREATE TEMP TABLE documents_pendentes AS
+        SELECT * FROM documents_default WHERE competencia >= inicio AND competencia < fim;
+    DELETE FROM documents_default WHERE competencia >= inicio AND competencia < fim;
+
+    EXECUTE format('CREATE TABLE %I PARTITION OF documents FOR VALUES FROM (%L) TO (%L)', nome, inicio, fim);
+
+    -- Colunas geradas (search_vector) são recalculadas na inserção
+    SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position) INTO colunas
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'documents' AND is_generated = 'NEVER';
+    EXECUTE format('INSERT INTO documents (%s) SELECT %s FROM documents_pendentes', colunas, colunas);
+
+    DROP TABLE documents_pendentes;
+    RETURN true;
+END $$;
+
+-- Move a partição (tabela, TOAST e índices) para outro tablespace: o arquivamento em
+-- armazenamento mais barato e a volta para pg_default. A partição continua anexada,
+-- então as consultas históricas seguem lendo por documents.
+CREATE FUNCTION documents_mover_particao(nome TEXT, destino TEXT) RETURNS VOID
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    indice REGCLASS;
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_inherits
+        WHERE inhparent = 'documents'::regclass AND inhrelid = to_regclass(nome)
+    ) THEN
+        RAISE EXCEPTION 'partição de documents inexistente: %', nome;
+    END IF;
+
+    EXECUTE format('ALTER TABLE %I SET TABLESPACE %I', nome, destino);
+    FOR indice IN SELECT indexrelid::regclass FROM pg_index WHERE indrelid = to_regclass(nome) LOOP
+        EXECUTE format('ALTER INDEX %s SET TABLESPACE %I', indice, destino);
+    END LOOP;
+END $$;
+
+--bun:split
+
+-- Partições dos anos já sincronizados, do ano atual e do próximo
+SELECT documents_criar_particao(ano) FROM (
+    SELECT DISTINCT left(competencia, 4)::INT AS ano FROM documents_legacy WHERE competencia ~ '^[0-9]{6}$'
+    UNION SELECT extract(year FROM current_date)::INT
+    UNION SELECT extract(year FROM current_date)::INT + 1
+) anos;
+
+DO $$
+DECLARE
+    colunas TEXT;
+BEGIN
+    SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position) INTO colunas
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'documents_legacy' AND is_generated = 'NEVER';
+    EXECUTE format('INSERT INTO documents (%s) SELECT %s FROM documents_legacy', colunas, colunas);
+END $$;
+
+DROP TABLE documents_legacy;
+
+--bun:split
+
+ALTER TABLE documents ADD PRIMARY KEY (id, competencia);
+ALTER TABLE documents ADD CONSTRAINT documents_numero_documento_key UNIQUE (numero_documento, competencia);
+ALTER TABLE documents ADD FOREIGN KEY (escritorio_id) REFERENCES escritorios (id);
+ALTER TABLE documents ADD FOREIGN KEY (emitente_empresa_id) REFERENCES empresas (id) ON DELETE SET NULL;
+ALTER TABLE documents ADD FOREIGN KEY (destinatario_empresa_id) REFERENCES empresas (id) ON DELETE SET NULL;
+
+-- Índices (locais a cada partição) segundo as consultas do DocumentRepository:
+-- listagem do escritório por emissão ou criação, documentos da empresa, filtros e
+-- exportação por CNPJ e competência, busca textual, consulta por RPS e a varredura
+-- dos documentos ainda sem XML armazenado
+CREATE INDEX idx_documents_escritorio_data_emissao ON documents (escritorio_id, data_emissao DESC, id DESC);
+CREATE INDEX idx_documents_escritorio_competencia ON documents (escritorio_id, competencia, id);
+CREATE INDEX idx_documents_escritorio_created_at ON documents (escritorio_id, created_at DESC);
+CREATE INDEX idx_documents_emitente_empresa_id ON documents (emitente_empresa_id, data_emissao DESC, id DESC);
+CREATE INDEX idx_documents_destinatario_empresa_id ON documents (destinatario_empresa_id, data_emissao DESC, id DESC);
+CREATE INDEX idx_documents_emitente_competencia ON documents (cnpj_emitente, competencia);
+CREATE INDEX idx_documents_destinatario_competencia ON documents (cnpj_destinatario, competencia);
+CREATE INDEX idx_documents_search_vector ON documents USING GIN (search_vector);
+CREATE INDEX idx_documents_xml_sha256 ON documents (xml_sha256);
+CREATE INDEX idx_documents_numero_rps ON documents (numero_rps);
+CREATE INDEX idx_documents_sem_xml ON documents (id) WHERE xml_key IS NULL OR xml_key = '';
+
+--bun:split
+
+ALTER TABLE documents ENABLE ROW LEVEL SECURITY;
+ALTER TABLE documents FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON documents
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+CREATE POLICY escritorio_isolation ON document_downloads
+    USING (app_escritorio_id() IS NULL OR document_id IN (SELECT id FROM documents));
+
+--bun:split
+
+CREATE MATERIALIZED VIEW documents_resumo_mensal AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota,
+    sum(valor_iss) AS valor_iss,
+    sum(valor_iss_retido) AS valor_iss_retido
+FROM documents
+GROUP BY 1, 2, 3;
+
+CREATE UNIQUE INDEX idx_documents_resumo_mensal_key ON documents_resumo_mensal (escritorio_id, emitente_empresa_id, competencia);
+
+CREATE MATERIALIZED VIEW documents_resumo_tomadores AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    cnpj_destinatario,
+    max(razao_social_destinatario) AS razao_social_destinatario,
+    max(destinatario_empresa_id) AS destinatario_empresa_id,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota
+FROM documents
+WHERE cnpj_destinatario <> ''
+GROUP BY 1, 2, 3, 4;
+
+CREATE UNIQUE INDEX idx_documents_resumo_tomadores_key ON documents_resumo_tomadores (escritorio_id, emitente_empresa_id, competencia, cnpj_destinatario);
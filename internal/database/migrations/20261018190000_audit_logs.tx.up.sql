@@ -0,0 +1,48 @@
+-- Trilha de auditoria das alterações de documentos, empresas e usuários: quem
+-- alterou, de qual escritório e IP, e o registro antes e depois. Gravada na mesma
+-- transação da alteração e somente por inserção.
+
+CREATE TABLE audit_logs (
+    id BIGSERIAL NOT NULL,
+    escritorio_id BIGINT REFERENCES escritorios (id),
+    actor VARCHAR NOT NULL,
+    ip VARCHAR,
+    user_agent VARCHAR,
+    entity VARCHAR NOT NULL,
+    entity_id BIGINT NOT NULL,
+    action VARCHAR NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_audit_logs_escritorio_id ON audit_logs (escritorio_id, id DESC);
+CREATE INDEX idx_audit_logs_entity ON audit_logs (entity, entity_id, id DESC);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
+
+--bun:split
+
+-- Registros de auditoria não podem ser alterados nem removidos, nem pela aplicação
+CREATE FUNCTION audit_logs_somente_insercao() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs aceita apenas inserções (% recusado)', TG_OP;
+END $$;
+
+CREATE TRIGGER audit_logs_somente_insercao
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_somente_insercao();
+
+CREATE TRIGGER audit_logs_sem_truncate
+    BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_somente_insercao();
+
+--bun:split
+
+ALTER TABLE audit_logs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs FORCE ROW LEVEL SECURITY;
+CREATE POLICY escritorio_isolation ON audit_logs
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
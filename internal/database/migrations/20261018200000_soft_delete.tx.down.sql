@@ -0,0 +1,56 @@
+-- Registros excluídos logicamente são removidos de vez: sem deleted_at voltariam
+-- a aparecer nas consultas
+DROP MATERIALIZED VIEW documents_resumo_tomadores;
+DROP MATERIALIZED VIEW documents_resumo_mensal;
+
+DELETE FROM storage_outbox WHERE document_id IN (SELECT id FROM documents WHERE deleted_at IS NOT NULL);
+DELETE FROM documents WHERE deleted_at IS NOT NULL;
+DELETE FROM empresas WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE atividades_secundarias DROP CONSTRAINT atividades_secundarias_empresa_id_fkey;
+ALTER TABLE empresa_membros DROP CONSTRAINT empresa_membros_empresa_id_fkey;
+ALTER TABLE empresa_inscricoes_estaduais DROP CONSTRAINT empresa_inscricoes_estaduais_empresa_id_fkey;
+ALTER TABLE empresa_suframa DROP CONSTRAINT empresa_suframa_empresa_id_fkey;
+ALTER TABLE empresa_telefones DROP CONSTRAINT empresa_telefones_empresa_id_fkey;
+ALTER TABLE empresa_emails DROP CONSTRAINT empresa_emails_empresa_id_fkey;
+
+ALTER TABLE documents DROP COLUMN deleted_at;
+ALTER TABLE empresa_emails DROP COLUMN deleted_at;
+ALTER TABLE empresa_telefones DROP COLUMN deleted_at;
+ALTER TABLE empresa_suframa DROP COLUMN deleted_at;
+ALTER TABLE empresa_inscricoes_estaduais DROP COLUMN deleted_at;
+ALTER TABLE empresa_membros DROP COLUMN deleted_at;
+ALTER TABLE atividades_secundarias DROP COLUMN deleted_at;
+ALTER TABLE empresas DROP COLUMN deleted_at;
+
+--bun:split
+
+CREATE MATERIALIZED VIEW documents_resumo_mensal AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota,
+    sum(valor_iss) AS valor_iss,
+    sum(valor_iss_retido) AS valor_iss_retido
+FROM documents
+GROUP BY 1, 2, 3;
+
+CREATE UNIQUE INDEX idx_documents_resumo_mensal_key ON documents_resumo_mensal (escritorio_id, emitente_empresa_id, competencia);
+
+CREATE MATERIALIZED VIEW documents_resumo_tomadores AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    cnpj_destinatario,
+    max(razao_social_destinatario) AS razao_social_destinatario,
+    max(destinatario_empresa_id) AS destinatario_empresa_id,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota
+FROM documents
+WHERE cnpj_destinatario <> ''
+GROUP BY 1, 2, 3, 4;
+
+CREATE UNIQUE INDEX idx_documents_resumo_tomadores_key ON documents_resumo_tomadores (escritorio_id, emitente_empresa_id, competencia, cnpj_destinatario);
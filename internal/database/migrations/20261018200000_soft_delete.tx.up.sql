@@ -0,0 +1,75 @@
+-- Exclusão lógica de empresas e documentos: deleted_at preenchido esconde o registro
+-- das consultas, que pode ser restaurado. A remoção definitiva (purga) é explícita.
+-- As tabelas filhas da empresa são excluídas e restauradas junto com ela.
+ALTER TABLE empresas ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE atividades_secundarias ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE empresa_membros ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE empresa_inscricoes_estaduais ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE empresa_suframa ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE empresa_telefones ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE empresa_emails ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE documents ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_empresas_deleted_at ON empresas (escritorio_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_documents_deleted_at ON documents (escritorio_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+--bun:split
+
+-- Registros filhos deixados pela antiga exclusão física das empresas
+DELETE FROM atividades_secundarias WHERE empresa_id NOT IN (SELECT id FROM empresas);
+DELETE FROM empresa_membros WHERE empresa_id NOT IN (SELECT id FROM empresas);
+DELETE FROM empresa_inscricoes_estaduais WHERE empresa_id NOT IN (SELECT id FROM empresas);
+DELETE FROM empresa_suframa WHERE empresa_id NOT IN (SELECT id FROM empresas);
+DELETE FROM empresa_telefones WHERE empresa_id NOT IN (SELECT id FROM empresas);
+DELETE FROM empresa_emails WHERE empresa_id NOT IN (SELECT id FROM empresas);
+
+-- A purga da empresa remove os registros filhos
+ALTER TABLE atividades_secundarias ADD CONSTRAINT atividades_secundarias_empresa_id_fkey
+    FOREIGN KEY (empresa_id) REFERENCES empresas (id) ON DELETE CASCADE;
+ALTER TABLE empresa_membros ADD CONSTRAINT empresa_membros_empresa_id_fkey
+    FOREIGN KEY (empresa_id) REFERENCES empresas (id) ON DELETE CASCADE;
+ALTER TABLE empresa_inscricoes_estaduais ADD CONSTRAINT empresa_inscricoes_estaduais_empresa_id_fkey
+    FOREIGN KEY (empresa_id) REFERENCES empresas (id) ON DELETE CASCADE;
+ALTER TABLE empresa_suframa ADD CONSTRAINT empresa_suframa_empresa_id_fkey
+    FOREIGN KEY (empresa_id) REFERENCES empresas (id) ON DELETE CASCADE;
+ALTER TABLE empresa_telefones ADD CONSTRAINT empresa_telefones_empresa_id_fkey
+    FOREIGN KEY (empresa_id) REFERENCES empresas (id) ON DELETE CASCADE;
+ALTER TABLE empresa_emails ADD CONSTRAINT empresa_emails_empresa_id_fkey
+    FOREIGN KEY (empresa_id) REFERENCES empresas (id) ON DELETE CASCADE;
+
+--bun:split
+
+-- Os agregados do dashboard deixam de contar os documentos excluídos
+DROP MATERIALIZED VIEW documents_resumo_tomadores;
+DROP MATERIALIZED VIEW documents_resumo_mensal;
+
+CREATE MATERIALIZED VIEW documents_resumo_mensal AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota,
+    sum(valor_iss) AS valor_iss,
+    sum(valor_iss_retido) AS valor_iss_retido
+FROM documents
+WHERE deleted_at IS NULL
+GROUP BY 1, 2, 3;
+
+CREATE UNIQUE INDEX idx_documents_resumo_mensal_key ON documents_resumo_mensal (escritorio_id, emitente_empresa_id, competencia);
+
+CREATE MATERIALIZED VIEW documents_resumo_tomadores AS
+SELECT
+    COALESCE(escritorio_id, 0) AS escritorio_id,
+    COALESCE(emitente_empresa_id, 0) AS emitente_empresa_id,
+    competencia,
+    cnpj_destinatario,
+    max(razao_social_destinatario) AS razao_social_destinatario,
+    max(destinatario_empresa_id) AS destinatario_empresa_id,
+    count(*) AS documentos,
+    sum(valor_nota) AS valor_nota
+FROM documents
+WHERE cnpj_destinatario <> '' AND deleted_at IS NULL
+GROUP BY 1, 2, 3, 4;
+
+CREATE UNIQUE INDEX idx_documents_resumo_tomadores_key ON documents_resumo_tomadores (escritorio_id, emitente_empresa_id, competencia, cnpj_destinatario);
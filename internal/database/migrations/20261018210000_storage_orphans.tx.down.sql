@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_documents_xml_key;
+DROP TABLE storage_orphans;
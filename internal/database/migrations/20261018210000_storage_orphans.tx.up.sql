@@ -0,0 +1,18 @@
+-- Objetos do armazenamento sem documento correspondente, registrados pela
+-- reconciliação para que um operador decida entre remover o objeto ou
+-- reprocessar o documento.
+
+CREATE TABLE storage_orphans (
+    object_key VARCHAR NOT NULL,
+    bucket VARCHAR NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (object_key)
+);
+
+CREATE INDEX idx_storage_orphans_last_seen_at ON storage_orphans (last_seen_at);
+
+--bun:split
+
+-- Conferência de objetos órfãos em lote pela chave do XML
+CREATE INDEX idx_documents_xml_key ON documents (xml_key) WHERE xml_key IS NOT NULL;
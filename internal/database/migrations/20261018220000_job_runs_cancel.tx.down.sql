@@ -0,0 +1 @@
+ALTER TABLE job_runs DROP COLUMN cancel_requested_at;
@@ -0,0 +1,3 @@
+-- Pedido de cancelamento de uma execução, feito por qualquer réplica e
+-- atendido pela réplica que executa o job
+ALTER TABLE job_runs ADD COLUMN cancel_requested_at TIMESTAMPTZ;
@@ -0,0 +1 @@
+CREATE INDEX idx_documents_xml_sha256 ON documents (xml_sha256);
@@ -0,0 +1,3 @@
+-- O hash do XML é conferido contra o objeto (scrub e download), nunca buscado:
+-- documentos repetidos são descartados pelo número antes de qualquer gravação
+DROP INDEX idx_documents_xml_sha256;
@@ -0,0 +1 @@
+ALTER TABLE storage_outbox DROP COLUMN operation;
@@ -0,0 +1,3 @@
+-- Operação da entrada do outbox: gravação do XML ou remoção do objeto de um
+-- documento purgado, feita só depois que a purga é confirmada no banco
+ALTER TABLE storage_outbox ADD COLUMN operation VARCHAR NOT NULL DEFAULT 'put';
@@ -0,0 +1,2 @@
+ALTER TABLE empresas DROP COLUMN certificado_enviado_em;
+ALTER TABLE empresas DROP COLUMN certificado_key;
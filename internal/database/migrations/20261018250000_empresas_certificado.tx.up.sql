@@ -0,0 +1,3 @@
+-- Certificado digital A1 da empresa, guardado cifrado no armazenamento
+ALTER TABLE empresas ADD COLUMN certificado_key VARCHAR;
+ALTER TABLE empresas ADD COLUMN certificado_enviado_em TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+ALTER TABLE documents DROP CONSTRAINT documents_numero_documento_key;
+ALTER TABLE documents ADD CONSTRAINT documents_numero_documento_key UNIQUE (numero_documento, competencia);
@@ -0,0 +1,5 @@
+-- O mesmo número de documento pode existir em escritórios diferentes (uma
+-- empresa atendida por dois escritórios, ou números repetidos entre municípios);
+-- a unicidade vale dentro de cada escritório
+ALTER TABLE documents DROP CONSTRAINT documents_numero_documento_key;
+ALTER TABLE documents ADD CONSTRAINT documents_numero_documento_key UNIQUE NULLS NOT DISTINCT (escritorio_id, numero_documento, competencia);
@@ -0,0 +1,73 @@
+DROP POLICY escritorio_isolation ON escritorios;
+CREATE POLICY escritorio_isolation ON escritorios
+    USING (app_escritorio_id() IS NULL OR id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON api_tokens;
+CREATE POLICY escritorio_isolation ON api_tokens
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON empresas;
+CREATE POLICY escritorio_isolation ON empresas
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON users;
+CREATE POLICY escritorio_isolation ON users
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON documents;
+CREATE POLICY escritorio_isolation ON documents
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON job_schedules;
+CREATE POLICY escritorio_isolation ON job_schedules
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON exports;
+CREATE POLICY escritorio_isolation ON exports
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON audit_logs;
+CREATE POLICY escritorio_isolation ON audit_logs
+    USING (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_escritorio_id() IS NULL OR escritorio_id = app_escritorio_id());
+
+-- Tabelas filhas seguem a visibilidade da empresa ou do documento
+
+DROP POLICY escritorio_isolation ON atividades_secundarias;
+CREATE POLICY escritorio_isolation ON atividades_secundarias
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_membros;
+CREATE POLICY escritorio_isolation ON empresa_membros
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_inscricoes_estaduais;
+CREATE POLICY escritorio_isolation ON empresa_inscricoes_estaduais
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_suframa;
+CREATE POLICY escritorio_isolation ON empresa_suframa
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_telefones;
+CREATE POLICY escritorio_isolation ON empresa_telefones
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_emails;
+CREATE POLICY escritorio_isolation ON empresa_emails
+    USING (app_escritorio_id() IS NULL OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON document_downloads;
+CREATE POLICY escritorio_isolation ON document_downloads
+    USING (app_escritorio_id() IS NULL OR document_id IN (SELECT id FROM documents));
+
+--bun:split
+
+DROP FUNCTION app_system();
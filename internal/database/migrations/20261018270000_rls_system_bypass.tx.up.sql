@@ -0,0 +1,80 @@
+-- Row-level security sem acesso implícito: sem app.escritorio_id nenhuma linha
+-- fica visível. O acesso a todos os escritórios (jobs, comandos, administração)
+-- exige app.system = 'on', que database.Open liga nas conexões do pool e o
+-- middleware Tenant desliga nas requisições de escritório. Conexões de outras
+-- ferramentas, sem as variáveis, não enxergam dados dos escritórios.
+CREATE FUNCTION app_system() RETURNS BOOLEAN
+    LANGUAGE sql STABLE
+    AS $$ SELECT coalesce(current_setting('app.system', true), '') = 'on' $$;
+
+--bun:split
+
+DROP POLICY escritorio_isolation ON escritorios;
+CREATE POLICY escritorio_isolation ON escritorios
+    USING (app_system() OR id = app_escritorio_id())
+    WITH CHECK (app_system() OR id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON api_tokens;
+CREATE POLICY escritorio_isolation ON api_tokens
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON empresas;
+CREATE POLICY escritorio_isolation ON empresas
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON users;
+CREATE POLICY escritorio_isolation ON users
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON documents;
+CREATE POLICY escritorio_isolation ON documents
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON job_schedules;
+CREATE POLICY escritorio_isolation ON job_schedules
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON exports;
+CREATE POLICY escritorio_isolation ON exports
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+DROP POLICY escritorio_isolation ON audit_logs;
+CREATE POLICY escritorio_isolation ON audit_logs
+    USING (app_system() OR escritorio_id = app_escritorio_id())
+    WITH CHECK (app_system() OR escritorio_id = app_escritorio_id());
+
+-- Tabelas filhas seguem a visibilidade da empresa ou do documento
+
+DROP POLICY escritorio_isolation ON atividades_secundarias;
+CREATE POLICY escritorio_isolation ON atividades_secundarias
+    USING (app_system() OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_membros;
+CREATE POLICY escritorio_isolation ON empresa_membros
+    USING (app_system() OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_inscricoes_estaduais;
+CREATE POLICY escritorio_isolation ON empresa_inscricoes_estaduais
+    USING (app_system() OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_suframa;
+CREATE POLICY escritorio_isolation ON empresa_suframa
+    USING (app_system() OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_telefones;
+CREATE POLICY escritorio_isolation ON empresa_telefones
+    USING (app_system() OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON empresa_emails;
+CREATE POLICY escritorio_isolation ON empresa_emails
+    USING (app_system() OR empresa_id IN (SELECT id FROM empresas));
+
+DROP POLICY escritorio_isolation ON document_downloads;
+CREATE POLICY escritorio_isolation ON document_downloads
+    USING (app_system() OR document_id IN (SELECT id FROM documents));
@@ -0,0 +1,30 @@
+CREATE OR REPLACE FUNCTION documents_criar_particao(ano INT) RETURNS BOOLEAN
+    LANGUAGE plpgsql
+    SET app.escritorio_id = ''
+    AS $$
+DECLARE
+    nome TEXT := 'documents_' || ano;
+    inicio TEXT := ano::TEXT || '01';
+    fim TEXT := (ano + 1)::TEXT || '01';
+    colunas TEXT;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('documents_criar_particao'));
+    IF to_regclass(nome) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+
+    CREATE TEMP TABLE documents_pendentes AS
+        SELECT * FROM documents_default WHERE competencia >= inicio AND competencia < fim;
+    DELETE FROM documents_default WHERE competencia >= inicio AND competencia < fim;
+
+    EXECUTE format('CREATE TABLE %I PARTITION OF documents FOR VALUES FROM (%L) TO (%L)', nome, inicio, fim);
+
+    -- Colunas geradas (search_vector) são recalculadas na inserção
+    SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position) INTO colunas
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'documents' AND is_generated = 'NEVER';
+    EXECUTE format('INSERT INTO documents (%s) SELECT %s FROM documents_pendentes', colunas, colunas);
+
+    DROP TABLE documents_pendentes;
+    RETURN true;
+END $$;
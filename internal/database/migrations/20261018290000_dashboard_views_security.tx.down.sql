@@ -0,0 +1,5 @@
+DROP VIEW documents_resumo_tomadores;
+DROP VIEW documents_resumo_mensal;
+
+ALTER MATERIALIZED VIEW documents_resumo_tomadores_dados RENAME TO documents_resumo_tomadores;
+ALTER MATERIALIZED VIEW documents_resumo_mensal_dados RENAME TO documents_resumo_mensal;
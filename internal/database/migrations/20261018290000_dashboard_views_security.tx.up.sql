@@ -0,0 +1,17 @@
+-- Views materializadas não têm row-level security. Os agregados do dashboard passam a
+-- ser guardados nas materializadas *_dados, atualizadas pelo DashboardRepository e
+-- sem acesso para outros papéis, e lidos pelas views documents_resumo_mensal e
+-- documents_resumo_tomadores, que aplicam a regra das políticas de documents: acesso
+-- de sistema ou apenas o escritório da conexão. security_barrier impede que condições
+-- da consulta sejam avaliadas antes do filtro.
+ALTER MATERIALIZED VIEW documents_resumo_mensal RENAME TO documents_resumo_mensal_dados;
+ALTER MATERIALIZED VIEW documents_resumo_tomadores RENAME TO documents_resumo_tomadores_dados;
+REVOKE ALL ON documents_resumo_mensal_dados, documents_resumo_tomadores_dados FROM PUBLIC;
+
+CREATE VIEW documents_resumo_mensal WITH (security_barrier) AS
+SELECT * FROM documents_resumo_mensal_dados
+WHERE app_system() OR escritorio_id = app_escritorio_id();
+
+CREATE VIEW documents_resumo_tomadores WITH (security_barrier) AS
+SELECT * FROM documents_resumo_tomadores_dados
+WHERE app_system() OR escritorio_id = app_escritorio_id();
@@ -0,0 +1 @@
+DROP FUNCTION documents_xml_key_em_uso(TEXT);
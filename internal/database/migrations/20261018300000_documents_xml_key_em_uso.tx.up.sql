@@ -0,0 +1,13 @@
+-- Indica se algum documento, de qualquer escritório e mesmo excluído logicamente,
+-- ainda aponta para a chave de XML. Antes das chaves separadas por escritório, a
+-- mesma NFS-e sincronizada por dois escritórios compartilhava o objeto; a purga de
+-- um deles consulta esta função para não apagar o XML do outro. Roda com
+-- app.system = 'on' porque as políticas de documents esconderiam as linhas dos
+-- outros escritórios; devolve apenas o booleano.
+CREATE FUNCTION documents_xml_key_em_uso(chave TEXT) RETURNS BOOLEAN
+    LANGUAGE sql
+    STABLE
+    SET app.system = 'on'
+    AS $$
+    SELECT EXISTS (SELECT 1 FROM documents WHERE xml_key = chave)
+$$;
@@ -0,0 +1,23 @@
+// Package migrations contém as migrações SQL versionadas do banco.
+//
+// Cada migração é um par de arquivos {versão}_{nome}.tx.up.sql e .tx.down.sql,
+// executados em transação. Crie novas com "go run cmd/migrate/main.go create <nome>".
+package migrations
+
+import (
+	"embed"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+//go:embed *.sql
+var sqlMigrations embed.FS
+
+// Migrations registro das migrações embutidas no binário
+var Migrations = migrate.NewMigrations()
+
+func init() {
+	if err := Migrations.Discover(sqlMigrations); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Entidades registradas na auditoria
+const (
+	AuditEntityDocument = "documents"
+	AuditEntityEmpresa  = "empresas"
+	AuditEntityUser     = "users"
+)
+
+// Ações registradas na auditoria
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore" // Restauração de registro excluído logicamente
+	AuditActionPurge   = "purge"   // Remoção definitiva de registro excluído
+)
+
+// AuditLog registro da trilha de auditoria: quem alterou a entidade e o estado
+// antes e depois da alteração. A tabela aceita apenas inserções.
+type AuditLog struct {
+	bun.BaseModel `bun:"table:audit_logs,alias:al"`
+
+	ID           int64           `json:"id" bun:",pk,autoincrement"`
+	EscritorioID *int64          `json:"escritorio_id,omitempty"`
+	Actor        string          `json:"actor" bun:",notnull"`
+	IP           string          `json:"ip,omitempty"`
+	UserAgent    string          `json:"user_agent,omitempty"`
+	Entity       string          `json:"entity" bun:",notnull"`
+	EntityID     int64           `json:"entity_id" bun:",notnull"`
+	Action       string          `json:"action" bun:",notnull"`
+	Before       json.RawMessage `json:"before,omitempty" bun:"type:jsonb,nullzero"`
+	After        json.RawMessage `json:"after,omitempty" bun:"type:jsonb,nullzero"`
+	CreatedAt    time.Time       `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir
+func (a *AuditLog) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	if _, ok := query.(*bun.InsertQuery); ok {
+		a.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// AuditFilter filtros da consulta à auditoria. Campos vazios não restringem a consulta.
+type AuditFilter struct {
+	Entity     string    `json:"entity,omitempty"`
+	EntityID   *int64    `json:"entity_id,omitempty"`
+	Action     string    `json:"action,omitempty"`
+	Actor      string    `json:"actor,omitempty"`
+	DataInicio time.Time `json:"data_inicio,omitempty"` // Inclusiva
+	DataFim    time.Time `json:"data_fim,omitempty"`    // Exclusiva
+}
+
+// AuditListResponse página da auditoria, mais recentes primeiro; NextCursor vazio
+// indica a última página
+type AuditListResponse struct {
+	Logs       []*AuditLog `json:"logs"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Limit      int         `json:"limit"`
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+// DashboardFilter recorte dos agregados do dashboard. Os valores são sempre os
+// documentos emitidos (faturamento), da empresa informada ou de todo o escritório.
+type DashboardFilter struct {
+	EmpresaID         *int   `json:"empresa_id,omitempty"` // Empresa emitente
+	CompetenciaInicio string `json:"competencia_inicio"`   // AAAAMM
+	CompetenciaFim    string `json:"competencia_fim"`      // AAAAMM, o "mês atual" dos indicadores
+}
+
+// DocumentResumoMensal linha da view documents_resumo_mensal (sobre a view materializada
+// documents_resumo_mensal_dados)
+type DocumentResumoMensal struct {
+	bun.BaseModel `bun:"table:documents_resumo_mensal,alias:rm"`
+
+	EscritorioID      int64           `json:"-"`
+	EmitenteEmpresaID int             `json:"-"` // 0 quando o emitente não está cadastrado
+	Competencia       string          `json:"competencia"`
+	Documentos        int             `json:"documentos"`
+	ValorNota         decimal.Decimal `json:"valor_nota"`
+	ValorIss          decimal.Decimal `json:"valor_iss"`
+	ValorIssRetido    decimal.Decimal `json:"valor_iss_retido"`
+}
+
+// DocumentResumoTomador linha da view documents_resumo_tomadores (sobre a view
+// materializada documents_resumo_tomadores_dados)
+type DocumentResumoTomador struct {
+	bun.BaseModel `bun:"table:documents_resumo_tomadores,alias:rt"`
+
+	EscritorioID            int64           `json:"-"`
+	EmitenteEmpresaID       int             `json:"-"`
+	Competencia             string          `json:"-"`
+	CNPJDestinatario        string          `json:"cnpj"`
+	RazaoSocialDestinatario string          `json:"razao_social"`
+	DestinatarioEmpresaID   *int            `json:"empresa_id,omitempty"`
+	Documentos              int             `json:"documentos"`
+	ValorNota               decimal.Decimal `json:"valor_nota"`
+}
+
+// DashboardChartPoint ponto do gráfico de evolução mensal
+type DashboardChartPoint struct {
+	Month       string          `json:"month"` // Rótulo, ex.: "Jan/2026"
+	Competencia string          `json:"competencia"`
+	Documents   int             `json:"documents"`
+	Revenue     decimal.Decimal `json:"revenue"`
+	ISS         decimal.Decimal `json:"iss"`
+	ISSRetido   decimal.Decimal `json:"withheldISS"`
+}
+
+// DashboardDocumentStats quantidade de documentos emitidos no período e no mês
+type DashboardDocumentStats struct {
+	Total       int     `json:"total"`
+	ThisMonth   int     `json:"thisMonth"`
+	Growth      float64 `json:"growth"` // % sobre o mês anterior
+	Competencia string  `json:"competencia"`
+}
+
+// DashboardRevenue faturamento do período e do mês
+type DashboardRevenue struct {
+	Total       decimal.Decimal `json:"total"`
+	ThisMonth   decimal.Decimal `json:"thisMonth"`
+	Growth      float64         `json:"growth"` // % sobre o mês anterior
+	Competencia string          `json:"competencia"`
+}
+
+// DashboardGrowth variação do faturamento do mês sobre o mês anterior
+type DashboardGrowth struct {
+	Percentage  float64         `json:"percentage"`
+	IsPositive  bool            `json:"isPositive"`
+	Comparison  string          `json:"comparison"`
+	Competencia string          `json:"competencia"`
+	Atual       decimal.Decimal `json:"current"`
+	Anterior    decimal.Decimal `json:"previous"`
+}
+
+// DashboardISS ISS devido, retido pelos tomadores e a recolher pelo prestador
+type DashboardISS struct {
+	TotalISS          decimal.Decimal `json:"totalISS"`
+	WithheldISS       decimal.Decimal `json:"withheldISS"`
+	PayableISS        decimal.Decimal `json:"payableISS"`
+	MonthlyISS        decimal.Decimal `json:"monthlyISS"`
+	MonthlyWithheld   decimal.Decimal `json:"monthlyWithheldISS"`
+	MonthlyPayableISS decimal.Decimal `json:"monthlyPayableISS"`
+	AverageRate       float64         `json:"averageRate"`      // Alíquota efetiva (%) no período
+	ProgressToTarget  float64         `json:"progressToTarget"` // % da meta informada; 0 sem meta
+	Competencia       string          `json:"competencia"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// DeadLetterStatus representa o estado de um documento na fila de falhas
+type DeadLetterStatus string
+
+const (
+	DeadLetterStatusPendente     DeadLetterStatus = "pendente"
+	DeadLetterStatusReprocessado DeadLetterStatus = "reprocessado"
+	DeadLetterStatusDescartado   DeadLetterStatus = "descartado"
+)
+
+// DeadLetter representa um documento fiscal que falhou ao ser convertido ou salvo.
+// O payload bruto é preservado para que possa ser inspecionado e reprocessado.
+type DeadLetter struct {
+	bun.BaseModel `bun:"table:dead_letters,alias:dl"`
+
+	ID int64 `json:"id" bun:",pk,autoincrement"`
+
+	// Origem da falha
+	Source          string `json:"source" bun:",notnull"`
+	CodigoIBGE      string `json:"codigo_ibge"`
+	Stage           string `json:"stage" bun:",notnull"`
+	NumeroDocumento string `json:"numero_documento"`
+	Competencia     string `json:"competencia"`
+
+	// Escritório da sincronização que falhou; o reprocessamento grava o documento nele
+	EscritorioID *int64 `json:"escritorio_id,omitempty"`
+
+	// Conteúdo bruto e erro
+	Payload       string `json:"payload,omitempty" bun:",type:text,notnull"`
+	PayloadFormat string `json:"payload_format" bun:",notnull"`
+	Error         string `json:"error" bun:",type:text,notnull"`
+
+	// Controle
+	Status     DeadLetterStatus `json:"status" bun:",notnull,default:'pendente'"`
+	Attempts   int              `json:"attempts" bun:",notnull,default:0"`
+	ResolvedAt time.Time        `json:"resolved_at" bun:",nullzero"`
+	CreatedAt  time.Time        `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt  time.Time        `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir/atualizar
+func (d *DeadLetter) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		d.CreatedAt = time.Now()
+		d.UpdatedAt = time.Now()
+	case *bun.UpdateQuery:
+		d.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// DeadLetterFilter filtros para listagem da fila de falhas
+type DeadLetterFilter struct {
+	Status      DeadLetterStatus
+	Stage       string
+	Source      string
+	Competencia string
+	Limit       int
+	Offset      int
+}
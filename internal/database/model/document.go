@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/uptrace/bun"
 )
 
@@ -18,14 +19,22 @@ const (
 	DocumentTypeMDFe DocumentType = "MDF-e"
 )
 
+// Resultado da verificação de integridade do XML
+const (
+	XMLIntegrityOK         = "ok"
+	XMLIntegrityCorrompido = "corrompido"
+	XMLIntegrityAusente    = "ausente"
+)
+
 // Document representa o modelo genérico de documentos fiscais no banco de dados
 type Document struct {
 	bun.BaseModel `bun:"table:documents,alias:d"`
 
 	// Identificação única
 	ID                int          `json:"id" bun:",pk,autoincrement"`
+	EscritorioID      *int64       `json:"escritorio_id,omitempty"`
 	DocumentType      DocumentType `json:"document_type" bun:",notnull"`
-	NumeroDocumento   string       `json:"numero_documento" bun:",unique,notnull"`
+	NumeroDocumento   string       `json:"numero_documento" bun:",notnull"`
 	CodigoVerificacao string       `json:"codigo_verificacao" bun:",notnull"`
 
 	// Dados do RPS (específico para NFS-e)
@@ -35,15 +44,18 @@ type Document struct {
 
 	// Dados temporais
 	DataEmissao time.Time `json:"data_emissao" bun:",notnull"`
-	Competencia string    `json:"competencia" bun:",notnull"`
+	Competencia string    `json:"competencia" bun:",pk,notnull"` // Chave de partição (AAAAMM)
 
 	// Status e controle
 	Status string `json:"status" bun:",notnull,default:'pendente'"`
 
 	// Valores financeiros essenciais
-	ValorNota   float64 `json:"valor_nota" bun:",type:decimal(15,2),notnull,default:0"`
-	AliquotaIss float64 `json:"aliquota_iss" bun:",type:decimal(8,4),notnull,default:0"`
-	ValorIss    float64 `json:"valor_iss" bun:",type:decimal(15,2),notnull,default:0"`
+	ValorNota   decimal.Decimal `json:"valor_nota" bun:",type:decimal(15,2),notnull,default:0"`
+	AliquotaIss decimal.Decimal `json:"aliquota_iss" bun:",type:decimal(8,4),notnull,default:0"`
+	ValorIss    decimal.Decimal `json:"valor_iss" bun:",type:decimal(15,2),notnull,default:0"`
+
+	// ISS retido pelo tomador (zero quando o prestador recolhe)
+	ValorIssRetido decimal.Decimal `json:"valor_iss_retido" bun:",type:decimal(15,2),notnull,default:0"`
 
 	// Dados do prestador/emitente
 	CNPJEmitente               string `json:"cnpj_emitente" bun:",notnull"`
@@ -54,6 +66,10 @@ type Document struct {
 	CNPJDestinatario        string `json:"cnpj_destinatario"`
 	RazaoSocialDestinatario string `json:"razao_social_destinatario"`
 
+	// Empresas cadastradas correspondentes aos CNPJs, vinculadas na sincronização
+	EmitenteEmpresaID     *int `json:"emitente_empresa_id,omitempty"`
+	DestinatarioEmpresaID *int `json:"destinatario_empresa_id,omitempty"`
+
 	// Informações do serviço/produto
 	Discriminacao    string `json:"discriminacao" bun:",type:text"`
 	CodigoServico    string `json:"codigo_servico"`
@@ -63,12 +79,38 @@ type Document struct {
 	CodigoMunicipio string `json:"codigo_municipio"`
 	CodigoIBGE      string `json:"codigo_ibge"`
 
-	// XML completo (armazenado no MinIO, referência aqui)
+	// Legado: o XML não é mais gravado no banco, apenas no armazenamento (ver XMLKey)
 	XMLContent string `json:"xml_content" bun:",type:text"`
 
+	// Localização do XML no armazenamento
+	XMLBucket      string    `json:"xml_bucket"`
+	XMLKey         string    `json:"xml_key"`
+	XMLSize        int64     `json:"xml_size"`
+	XMLSha256      string    `json:"xml_sha256" bun:"xml_sha256"`
+	XMLContentType string    `json:"xml_content_type"`
+	XMLUploadedAt  time.Time `json:"xml_uploaded_at" bun:",nullzero"` // Preenchido quando o upload é confirmado
+
+	// Retenção fiscal aplicada ao XML: remoção bloqueada até XMLRetainUntil ou enquanto houver bloqueio legal
+	XMLRetainUntil time.Time `json:"xml_retain_until" bun:",nullzero"`
+	XMLLegalHold   bool      `json:"xml_legal_hold" bun:",notnull,default:false"`
+
+	// Resultado da última verificação de integridade do XML
+	XMLIntegrity  string    `json:"xml_integrity"`
+	XMLVerifiedAt time.Time `json:"xml_verified_at" bun:",nullzero"`
+
 	// Controle de auditoria
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"` // Exclusão lógica; o XML é mantido até a purga
+}
+
+// SetXMLStorage preenche a localização do XML a partir do conteúdo que será armazenado
+func (d *Document) SetXMLStorage(bucket, key string, content []byte, hash string) {
+	d.XMLBucket = bucket
+	d.XMLKey = key
+	d.XMLSize = int64(len(content))
+	d.XMLSha256 = hash
+	d.XMLContentType = "application/xml"
 }
 
 // BeforeAppendModel hook executado antes de inserir/atualizar
@@ -85,29 +127,33 @@ func (d *Document) BeforeAppendModel(ctx context.Context, query bun.Query) error
 
 // DocumentResponse representa a resposta de consulta para o frontend
 type DocumentResponse struct {
-	ID                         int          `json:"id"`
-	DocumentType               DocumentType `json:"document_type"`
-	NumeroDocumento            string       `json:"numero_documento"`
-	NumeroRps                  string       `json:"numero_rps"`
-	SerieRps                   string       `json:"serie_rps"`
-	DataEmissao                time.Time    `json:"data_emissao"`
-	Status                     string       `json:"status"`
-	CodigoVerificacao          string       `json:"codigo_verificacao"`
-	ValorNota                  float64      `json:"valor_nota"`
-	AliquotaIss                float64      `json:"aliquota_iss"`
-	ValorIss                   float64      `json:"valor_iss"`
-	Competencia                string       `json:"competencia"`
-	CNPJEmitente               string       `json:"cnpj_emitente,omitempty"`
-	RazaoSocialEmitente        string       `json:"razao_social_emitente,omitempty"`
-	InscricaoMunicipalEmitente string       `json:"inscricao_municipal_emitente,omitempty"`
-	CNPJDestinatario           string       `json:"cnpj_destinatario,omitempty"`
-	RazaoSocialDestinatario    string       `json:"razao_social_destinatario,omitempty"`
-	Discriminacao              string       `json:"discriminacao,omitempty"`
-	CodigoServico              string       `json:"codigo_servico,omitempty"`
-	ItemListaServico           string       `json:"item_lista_servico,omitempty"`
-	CodigoMunicipio            string       `json:"codigo_municipio,omitempty"`
-	CodigoIBGE                 string       `json:"codigo_ibge,omitempty"`
-	XMLContent                 string       `json:"xml_content,omitempty"`
+	ID                         int             `json:"id"`
+	DocumentType               DocumentType    `json:"document_type"`
+	NumeroDocumento            string          `json:"numero_documento"`
+	NumeroRps                  string          `json:"numero_rps"`
+	SerieRps                   string          `json:"serie_rps"`
+	DataEmissao                time.Time       `json:"data_emissao"`
+	Status                     string          `json:"status"`
+	CodigoVerificacao          string          `json:"codigo_verificacao"`
+	ValorNota                  decimal.Decimal `json:"valor_nota"`
+	AliquotaIss                decimal.Decimal `json:"aliquota_iss"`
+	ValorIss                   decimal.Decimal `json:"valor_iss"`
+	ValorIssRetido             decimal.Decimal `json:"valor_iss_retido"`
+	Competencia                string          `json:"competencia"`
+	CNPJEmitente               string          `json:"cnpj_emitente,omitempty"`
+	RazaoSocialEmitente        string          `json:"razao_social_emitente,omitempty"`
+	InscricaoMunicipalEmitente string          `json:"inscricao_municipal_emitente,omitempty"`
+	CNPJDestinatario           string          `json:"cnpj_destinatario,omitempty"`
+	RazaoSocialDestinatario    string          `json:"razao_social_destinatario,omitempty"`
+	EmitenteEmpresaID          *int            `json:"emitente_empresa_id,omitempty"`
+	DestinatarioEmpresaID      *int            `json:"destinatario_empresa_id,omitempty"`
+	Discriminacao              string          `json:"discriminacao,omitempty"`
+	CodigoServico              string          `json:"codigo_servico,omitempty"`
+	ItemListaServico           string          `json:"item_lista_servico,omitempty"`
+	CodigoMunicipio            string          `json:"codigo_municipio,omitempty"`
+	CodigoIBGE                 string          `json:"codigo_ibge,omitempty"`
+	XMLContent                 string          `json:"xml_content,omitempty"`
+	DeletedAt                  *time.Time      `json:"deleted_at,omitempty"`
 }
 
 // IsNFSe verifica se o documento é uma NFS-e
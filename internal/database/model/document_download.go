@@ -0,0 +1,41 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Formas de acesso ao XML registradas na auditoria
+const (
+	DocumentDownloadXML = "xml"     // Conteúdo entregue pela API
+	DocumentDownloadURL = "xml_url" // Link pré-assinado emitido
+	DocumentDownloadPDF = "pdf"     // DANFSE/DANFE entregue pela API
+)
+
+// DocumentDownload registro de auditoria de cada acesso ao XML de um documento
+type DocumentDownload struct {
+	bun.BaseModel `bun:"table:document_downloads,alias:dd"`
+
+	ID         int64  `json:"id" bun:",pk,autoincrement"`
+	DocumentID int    `json:"document_id" bun:",notnull"`
+	Kind       string `json:"kind" bun:",notnull"`
+	Principal  string `json:"principal" bun:",notnull"`
+	ObjectKey  string `json:"object_key"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+
+	// Validade do link emitido (apenas para xml_url)
+	ExpiresAt time.Time `json:"expires_at,omitempty" bun:",nullzero"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir
+func (d *DocumentDownload) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	if _, ok := query.(*bun.InsertQuery); ok {
+		d.CreatedAt = time.Now()
+	}
+	return nil
+}
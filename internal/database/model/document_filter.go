@@ -0,0 +1,98 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DocumentFilter filtros comuns às listagens e à busca de documentos. Campos
+// vazios não restringem a consulta.
+type DocumentFilter struct {
+	DocumentTypes         []DocumentType   `json:"document_types,omitempty"`
+	Status                []string         `json:"status,omitempty"`
+	CompetenciaInicio     string           `json:"competencia_inicio,omitempty"` // AAAAMM
+	CompetenciaFim        string           `json:"competencia_fim,omitempty"`    // AAAAMM
+	DataInicio            time.Time        `json:"data_inicio,omitempty"`        // Inclusiva
+	DataFim               time.Time        `json:"data_fim,omitempty"`           // Exclusiva
+	CNPJ                  string           `json:"cnpj,omitempty"`               // Emitente ou tomador
+	CNPJEmitente          string           `json:"cnpj_emitente,omitempty"`
+	CNPJDestinatario      string           `json:"cnpj_destinatario,omitempty"`
+	EmpresaID             *int             `json:"empresa_id,omitempty"` // Emitente ou tomadora
+	EmitenteEmpresaID     *int             `json:"emitente_empresa_id,omitempty"`
+	DestinatarioEmpresaID *int             `json:"destinatario_empresa_id,omitempty"`
+	ValorMin              *decimal.Decimal `json:"valor_min,omitempty"`
+	ValorMax              *decimal.Decimal `json:"valor_max,omitempty"`
+	ItemListaServico      string           `json:"item_lista_servico,omitempty"`
+	CodigoMunicipio       string           `json:"codigo_municipio,omitempty"`
+}
+
+// DocumentSort ordenação da listagem de documentos; o ID desempata
+type DocumentSort struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+}
+
+// DocumentSortColumns colunas aceitas na ordenação (todas NOT NULL, como exige
+// a paginação por chave)
+var DocumentSortColumns = []string{
+	"data_emissao",
+	"competencia",
+	"valor_nota",
+	"valor_iss",
+	"numero_documento",
+	"created_at",
+}
+
+// Valid verifica se a coluna de ordenação está entre as permitidas
+func (s DocumentSort) Valid() bool {
+	for _, column := range DocumentSortColumns {
+		if s.Column == column {
+			return true
+		}
+	}
+	return false
+}
+
+// SortValue valor da coluna de ordenação do documento, usado no cursor da próxima página
+func (d *Document) SortValue(column string) string {
+	switch column {
+	case "data_emissao":
+		return d.DataEmissao.Format(time.RFC3339Nano)
+	case "competencia":
+		return d.Competencia
+	case "valor_nota":
+		return d.ValorNota.String()
+	case "valor_iss":
+		return d.ValorIss.String()
+	case "numero_documento":
+		return d.NumeroDocumento
+	case "created_at":
+		return d.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+// DocumentCursor posição da página na paginação por chave (keyset): valor da
+// coluna de ordenação e ID do último documento entregue
+type DocumentCursor struct {
+	Sort  DocumentSort `json:"sort"`
+	Value string       `json:"value"`
+	ID    int          `json:"id"`
+}
+
+// DocumentListRequest filtros, ordenação e página da listagem de documentos
+type DocumentListRequest struct {
+	DocumentFilter
+	Sort   DocumentSort
+	Cursor *DocumentCursor
+	Limit  int
+}
+
+// DocumentListResponse página da listagem; NextCursor vazio indica a última página
+type DocumentListResponse struct {
+	Documents  []*DocumentResponse `json:"documents"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	Limit      int                 `json:"limit"`
+}
@@ -0,0 +1,19 @@
+package model
+
+// DocumentPartitionDefault partição que recebe as competências sem partição do ano
+const DocumentPartitionDefault = "documents_default"
+
+// DocumentPartition partição anual da tabela documents, lida do catálogo do Postgres
+type DocumentPartition struct {
+	Name       string `json:"name" bun:"name"`
+	Ano        int    `json:"ano,omitempty" bun:"-"`       // 0 na partição padrão
+	Tablespace string `json:"tablespace" bun:"tablespace"` // Vazio: tablespace padrão do banco
+	Size       int64  `json:"size" bun:"size"`             // Bytes, com índices e TOAST
+	Rows       int64  `json:"rows" bun:"rows"`             // Estimativa do planejador
+}
+
+// DocumentPartitionReport resultado da manutenção das partições
+type DocumentPartitionReport struct {
+	Created  []string `json:"created"`
+	Archived []string `json:"archived"`
+}
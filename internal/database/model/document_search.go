@@ -0,0 +1,28 @@
+package model
+
+// DocumentSearchFilter termo e filtros da busca textual de documentos
+type DocumentSearchFilter struct {
+	Query string `json:"q"`
+	DocumentFilter
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// DocumentSearchHit documento encontrado pela busca, com a relevância e os
+// trechos destacados calculados pelo banco
+type DocumentSearchHit struct {
+	Document `bun:",extend"`
+
+	Rank                  float64 `bun:"rank,scanonly"`
+	DestaqueDiscriminacao string  `bun:"destaque_discriminacao,scanonly"`
+	DestaqueEmitente      string  `bun:"destaque_emitente,scanonly"`
+	DestaqueDestinatario  string  `bun:"destaque_destinatario,scanonly"`
+}
+
+// DocumentSearchResult resultado da busca para o frontend. Os destaques trazem
+// o texto com HTML escapado e os termos encontrados entre <mark> e </mark>.
+type DocumentSearchResult struct {
+	*DocumentResponse
+	Rank      float64           `json:"rank"`
+	Destaques map[string]string `json:"destaques,omitempty"`
+}
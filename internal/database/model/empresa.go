@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/uptrace/bun"
 )
 
@@ -12,6 +13,7 @@ type Empresa struct {
 	bun.BaseModel `bun:"table:empresas,alias:e"`
 
 	ID                   int       `json:"id" bun:",pk,autoincrement"`
+	EscritorioID         *int64    `json:"escritorio_id,omitempty"`
 	CNPJ                 string    `json:"cnpj" bun:",unique,notnull"`
 	InscricaoEstadual    string    `json:"inscricao_estadual"`
 	InscricaoMunicipal   string    `json:"inscricao_municipal"`
@@ -42,15 +44,20 @@ type Empresa struct {
 	Email    string `json:"email"`
 	Telefone string `json:"telefone"`
 
+	// Certificado digital A1, guardado cifrado no armazenamento
+	CertificadoKey       string    `json:"-"`
+	CertificadoEnviadoEm time.Time `json:"certificado_enviado_em" bun:",nullzero"`
+
 	// Dados adicionais da API
-	CapitalSocial   float64 `json:"capital_social"`
-	SimplesNacional bool    `json:"simples_nacional"`
-	MEI             bool    `json:"mei"`
-	Ativa           bool    `json:"ativa" bun:",default:true"`
+	CapitalSocial   decimal.Decimal `json:"capital_social" bun:",type:decimal(18,2),notnull,default:0"`
+	SimplesNacional bool            `json:"simples_nacional"`
+	MEI             bool            `json:"mei"`
+	Ativa           bool            `json:"ativa" bun:",default:true"`
 
 	// Controle
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"` // Exclusão lógica; as tabelas filhas acompanham a empresa
 }
 
 // EmpresaMembro representa os sócios/administradores da empresa
@@ -75,6 +82,7 @@ type EmpresaMembro struct {
 	// Controle
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"`
 }
 
 // EmpresaInscricaoEstadual representa as inscrições estaduais da empresa
@@ -101,6 +109,7 @@ type EmpresaInscricaoEstadual struct {
 	// Controle
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"`
 }
 
 // EmpresaSuframa representa os dados SUFRAMA da empresa
@@ -124,6 +133,7 @@ type EmpresaSuframa struct {
 	// Controle
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"`
 }
 
 // AtividadeSecundaria representa as atividades econômicas secundárias da empresa
@@ -139,6 +149,7 @@ type AtividadeSecundaria struct {
 	Empresa *Empresa `json:"empresa,omitempty" bun:"rel:belongs-to,join:empresa_id=id"`
 
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"`
 }
 
 // BeforeAppendModel hook executado antes de inserir/atualizar
@@ -178,6 +189,7 @@ type EmpresaTelefone struct {
 	// Controle
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"`
 }
 
 // EmpresaEmail representa os emails da empresa
@@ -196,6 +208,7 @@ type EmpresaEmail struct {
 	// Controle
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	DeletedAt time.Time `json:"deleted_at" bun:",soft_delete,nullzero"`
 }
 
 // BeforeAppendModel hooks para os novos modelos
@@ -256,19 +269,19 @@ func (ee *EmpresaEmail) BeforeAppendModel(ctx context.Context, query bun.Query)
 
 // EmpresaResponse representa a resposta para o frontend
 type EmpresaResponse struct {
-	ID                 int       `json:"id"`
-	CNPJ               string    `json:"cnpj"`
-	RazaoSocial        string    `json:"razao_social"`
-	NomeFantasia       string    `json:"nome_fantasia"`
-	DataAbertura       time.Time `json:"data_abertura"`
-	Porte              string    `json:"porte"`
-	SituacaoCadastral  string    `json:"situacao_cadastral"`
-	AtividadePrincipal string    `json:"atividade_principal"`
-	NaturezaJuridica   string    `json:"natureza_juridica"`
-	CapitalSocial      float64   `json:"capital_social"`
-	SimplesNacional    bool      `json:"simples_nacional"`
-	MEI                bool      `json:"mei"`
-	Ativa              bool      `json:"ativa"`
+	ID                 int             `json:"id"`
+	CNPJ               string          `json:"cnpj"`
+	RazaoSocial        string          `json:"razao_social"`
+	NomeFantasia       string          `json:"nome_fantasia"`
+	DataAbertura       time.Time       `json:"data_abertura"`
+	Porte              string          `json:"porte"`
+	SituacaoCadastral  string          `json:"situacao_cadastral"`
+	AtividadePrincipal string          `json:"atividade_principal"`
+	NaturezaJuridica   string          `json:"natureza_juridica"`
+	CapitalSocial      decimal.Decimal `json:"capital_social"`
+	SimplesNacional    bool            `json:"simples_nacional"`
+	MEI                bool            `json:"mei"`
+	Ativa              bool            `json:"ativa"`
 
 	// Dados de contato (mantidos para compatibilidade)
 	Email    string `json:"email"`
@@ -277,6 +290,9 @@ type EmpresaResponse struct {
 	// Endereço
 	Endereco EnderecoResponse `json:"endereco"`
 
+	// Envio do certificado digital; ausente se a empresa não tem certificado
+	CertificadoEnviadoEm *time.Time `json:"certificado_enviado_em,omitempty"`
+
 	// Relacionamentos
 	AtividadesSecundarias []AtividadeResponse         `json:"atividades_secundarias,omitempty"`
 	Membros               []MembroResponse            `json:"membros,omitempty"`
@@ -286,8 +302,9 @@ type EmpresaResponse struct {
 	Emails                []EmailResponse             `json:"emails,omitempty"`
 
 	// Controle
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type EnderecoResponse struct {
@@ -383,10 +400,10 @@ type CNPJAFormResponse struct {
 	Telefone string `json:"telefone"`
 
 	// Dados adicionais
-	CapitalSocial   float64 `json:"capital_social"`
-	SimplesNacional bool    `json:"simples_nacional"`
-	MEI             bool    `json:"mei"`
-	Ativa           bool    `json:"ativa"`
+	CapitalSocial   decimal.Decimal `json:"capital_social"`
+	SimplesNacional bool            `json:"simples_nacional"`
+	MEI             bool            `json:"mei"`
+	Ativa           bool            `json:"ativa"`
 
 	// Listas de dados relacionados
 	AtividadesSecundarias []string             `json:"atividades_secundarias"`
@@ -452,14 +469,14 @@ type CNPJAResponse struct {
 
 // Tipos para a API CNPJA
 type CNPJACompany struct {
-	ID      int           `json:"id"`
-	Name    string        `json:"name"`
-	Equity  float64       `json:"equity"`
-	Size    CNPJASize     `json:"size"`
-	Nature  CNPJANature   `json:"nature"`
-	Simples CNPJASimples  `json:"simples"`
-	Simei   CNPJASimei    `json:"simei"`
-	Members []CNPJAMember `json:"members"`
+	ID      int             `json:"id"`
+	Name    string          `json:"name"`
+	Equity  decimal.Decimal `json:"equity"`
+	Size    CNPJASize       `json:"size"`
+	Nature  CNPJANature     `json:"nature"`
+	Simples CNPJASimples    `json:"simples"`
+	Simei   CNPJASimei      `json:"simei"`
+	Members []CNPJAMember   `json:"members"`
 }
 
 type CNPJAStatus struct {
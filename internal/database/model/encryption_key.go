@@ -0,0 +1,37 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// EncryptionKey chave de dados (DEK) de um tenant, guardada cifrada pela chave
+// mestra MasterKeyID. Cada rotação cria uma nova versão; as anteriores continuam
+// disponíveis para ler os objetos já gravados.
+type EncryptionKey struct {
+	bun.BaseModel `bun:"table:encryption_keys,alias:ek"`
+
+	ID          int64  `json:"id" bun:",pk,autoincrement"`
+	Tenant      string `json:"tenant" bun:",notnull,unique:encryption_keys_tenant_version"`
+	Version     int    `json:"version" bun:",notnull,unique:encryption_keys_tenant_version"`
+	MasterKeyID string `json:"master_key_id" bun:",notnull"`
+	WrappedKey  []byte `json:"-" bun:",type:bytea,notnull"`
+
+	// Controle de auditoria
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir/atualizar
+func (k *EncryptionKey) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		k.CreatedAt = time.Now()
+		k.UpdatedAt = time.Now()
+	case *bun.UpdateQuery:
+		k.UpdatedAt = time.Now()
+	}
+	return nil
+}
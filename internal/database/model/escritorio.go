@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Escritorio escritório de contabilidade (tenant). É dono das empresas, usuários,
+// credenciais de API, agendamentos e exportações; seus documentos só são vistos
+// por quem se autentica com uma credencial do escritório.
+type Escritorio struct {
+	bun.BaseModel `bun:"table:escritorios,alias:esc"`
+
+	ID    int64  `json:"id" bun:",pk,autoincrement"`
+	Nome  string `json:"nome" bun:",notnull"`
+	CNPJ  string `json:"cnpj" bun:",unique,nullzero"`
+	Ativo bool   `json:"ativo" bun:",notnull"`
+
+	// Controle de auditoria
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir/atualizar
+func (e *Escritorio) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		e.CreatedAt = time.Now()
+		e.UpdatedAt = time.Now()
+	case *bun.UpdateQuery:
+		e.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// EscritorioRequest estrutura para criação/atualização de escritórios
+type EscritorioRequest struct {
+	Nome  string `json:"nome" binding:"required"`
+	CNPJ  string `json:"cnpj"`
+	Ativo *bool  `json:"ativo"`
+}
+
+// APIToken credencial de acesso à API de um escritório. Só o hash SHA-256 do
+// token é gravado; o valor é exibido uma única vez, na criação.
+type APIToken struct {
+	bun.BaseModel `bun:"table:api_tokens,alias:tok"`
+
+	ID           int64     `json:"id" bun:",pk,autoincrement"`
+	EscritorioID int64     `json:"escritorio_id" bun:",notnull"`
+	Nome         string    `json:"nome" bun:",notnull"`
+	TokenHash    string    `json:"-" bun:",unique,notnull"`
+	Permissions  []string  `json:"permissions" bun:",array"`
+	LastUsedAt   time.Time `json:"last_used_at" bun:",nullzero"`
+	RevokedAt    time.Time `json:"revoked_at" bun:",nullzero"`
+	CreatedAt    time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir
+func (t *APIToken) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	if _, ok := query.(*bun.InsertQuery); ok {
+		t.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// APITokenRequest estrutura para criação de credenciais
+type APITokenRequest struct {
+	Nome        string   `json:"nome" binding:"required"`
+	Permissions []string `json:"permissions"`
+}
+
+// APITokenCreated credencial recém-criada, com o token em claro
+type APITokenCreated struct {
+	*APIToken
+	Token string `json:"token"`
+}
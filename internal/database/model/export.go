@@ -0,0 +1,76 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// ExportStatus representa o estado de uma exportação em lote
+type ExportStatus string
+
+const (
+	ExportStatusPendente    ExportStatus = "pendente"
+	ExportStatusProcessando ExportStatus = "processando"
+	ExportStatusConcluido   ExportStatus = "concluido"
+	ExportStatusFalha       ExportStatus = "falha"
+)
+
+// Direção dos documentos em relação ao CNPJ exportado
+const (
+	ExportDirecaoEmitidos  = "emitidos"
+	ExportDirecaoRecebidos = "recebidos"
+	ExportDirecaoAmbos     = "ambos"
+)
+
+// ExportFilter filtros de uma exportação de XMLs
+type ExportFilter struct {
+	EmpresaID         *int           `json:"empresa_id,omitempty"`
+	CNPJ              string         `json:"cnpj"`
+	CompetenciaInicio string         `json:"competencia_inicio"`       // AAAAMM
+	CompetenciaFim    string         `json:"competencia_fim"`          // AAAAMM
+	DocumentTypes     []DocumentType `json:"document_types,omitempty"` // Vazio: todos
+	Direcao           string         `json:"direcao"`                  // emitidos, recebidos ou ambos
+	IncluirPDF        bool           `json:"incluir_pdf,omitempty"`    // Inclui o DANFSE/DANFE ao lado de cada XML
+}
+
+// Export exportação em lote executada em segundo plano; o ZIP fica no
+// armazenamento em ObjectKey e é baixado por link pré-assinado
+type Export struct {
+	bun.BaseModel `bun:"table:exports,alias:ex"`
+
+	ID           int64        `json:"id" bun:",pk,autoincrement"`
+	EscritorioID *int64       `json:"escritorio_id,omitempty"`
+	Status       ExportStatus `json:"status" bun:",notnull,default:'pendente'"`
+	Filter       ExportFilter `json:"filter" bun:",type:jsonb,notnull"`
+	RunID        string       `json:"run_id"`
+	ObjectKey    string       `json:"object_key"`
+	Documents    int          `json:"documents" bun:",notnull,default:0"`
+	Size         int64        `json:"size" bun:",notnull,default:0"`
+	Error        string       `json:"error,omitempty" bun:",type:text"`
+	FinishedAt   time.Time    `json:"finished_at" bun:",nullzero"`
+
+	// Controle de auditoria
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir/atualizar
+func (e *Export) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		e.CreatedAt = time.Now()
+		e.UpdatedAt = time.Now()
+	case *bun.UpdateQuery:
+		e.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// ExportResponse exportação com o link de download, quando concluída
+type ExportResponse struct {
+	*Export
+	DownloadURL string    `json:"download_url,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// JobLock registra qual réplica detém o lock de execução de um job.
+// O lock em si é um advisory lock do Postgres, liberado automaticamente se a
+// conexão cair; esta tabela existe apenas para tornar o detentor visível.
+type JobLock struct {
+	bun.BaseModel `bun:"table:job_locks,alias:jl"`
+
+	Name       string    `json:"name" bun:",pk"`
+	LockKey    int64     `json:"lock_key" bun:",notnull"`
+	Holder     string    `json:"holder" bun:",notnull"`
+	AcquiredAt time.Time `json:"acquired_at" bun:",notnull"`
+	ExpiresAt  time.Time `json:"expires_at" bun:",notnull"`
+
+	// Active indica se o advisory lock ainda está de fato sendo mantido
+	Active bool `json:"active" bun:",scanonly"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// JobRunStatus representa o estado de uma execução de job
+type JobRunStatus string
+
+const (
+	JobRunStatusExecutando JobRunStatus = "executando"
+	JobRunStatusSucesso    JobRunStatus = "sucesso"
+	JobRunStatusErro       JobRunStatus = "erro"
+	JobRunStatusCancelado  JobRunStatus = "cancelado"
+	JobRunStatusIgnorado   JobRunStatus = "ignorado" // Lock mantido por outra réplica
+)
+
+// Origens de uma execução
+const (
+	JobRunTriggerAgendado = "agendado"
+	JobRunTriggerManual   = "manual"
+)
+
+// JobRun representa uma execução de um job agendado ou disparado manualmente
+type JobRun struct {
+	bun.BaseModel `bun:"table:job_runs,alias:jr"`
+
+	ID       string           `json:"id" bun:",pk"`
+	JobName  string           `json:"job_name" bun:",notnull"`
+	Trigger  string           `json:"trigger" bun:",notnull"`
+	Status   JobRunStatus     `json:"status" bun:",notnull"`
+	Holder   string           `json:"holder"` // Réplica que executou
+	Error    string           `json:"error,omitempty" bun:",type:text"`
+	Counters map[string]int64 `json:"counters" bun:",type:jsonb"`
+	Logs     []JobRunLog      `json:"logs,omitempty" bun:",type:jsonb"`
+
+	StartedAt  time.Time `json:"started_at" bun:",notnull"`
+	FinishedAt time.Time `json:"finished_at" bun:",nullzero"`
+
+	// Pedido de cancelamento; a réplica que executa o job o consulta periodicamente
+	CancelRequestedAt time.Time `json:"cancel_requested_at,omitempty" bun:",nullzero"`
+}
+
+// JobRunLog linha de log registrada durante uma execução
+type JobRunLog struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
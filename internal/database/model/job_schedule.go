@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Tipos de job que podem ser agendados pelo banco
+const (
+	JobScheduleTypeNFSeSync          = "nfse-sync"
+	JobScheduleTypeStorageOutbox     = "storage-outbox"
+	JobScheduleTypeStorageReconcile  = "storage-reconcile"
+	JobScheduleTypeStorageScrub      = "storage-scrub"
+	JobScheduleTypeDocumentPartition = "document-partition"
+)
+
+// Parâmetros conhecidos de um agendamento
+const (
+	// JobParamCompetencia competência a sincronizar: AAAAMM, "atual" ou "anterior" (padrão: atual)
+	JobParamCompetencia = "competencia"
+	// JobParamDocumentTypes tipos de documento aceitos, separados por vírgula (padrão: todos)
+	JobParamDocumentTypes = "document_types"
+)
+
+// JobSchedule define um job agendado armazenado no banco. O alvo é um
+// município (código IBGE) e, opcionalmente, uma empresa cujos documentos
+// (emitidos ou recebidos) devem ser sincronizados.
+type JobSchedule struct {
+	bun.BaseModel `bun:"table:job_schedules,alias:js"`
+
+	ID           int64             `json:"id" bun:",pk,autoincrement"`
+	EscritorioID *int64            `json:"escritorio_id,omitempty"`
+	Name         string            `json:"name" bun:",unique,notnull"`
+	Type         string            `json:"type" bun:",notnull"`
+	EmpresaID    *int              `json:"empresa_id,omitempty"`
+	CodigoIBGE   string            `json:"codigo_ibge"`
+	Cron         string            `json:"cron" bun:",notnull"`
+	Params       map[string]string `json:"params" bun:",type:jsonb,notnull,default:'{}'"`
+	Enabled      bool              `json:"enabled" bun:",notnull"`
+	Description  string            `json:"description"`
+	CreatedAt    time.Time         `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt    time.Time         `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir/atualizar
+func (j *JobSchedule) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		j.CreatedAt = time.Now()
+		j.UpdatedAt = time.Now()
+	case *bun.UpdateQuery:
+		j.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// JobName nome do job no scheduler; único por agendamento
+func (j *JobSchedule) JobName() string {
+	return "agenda-" + j.Name
+}
+
+// LockName nome do lock distribuído do job, que identifica o trabalho feito e não
+// o agendamento: os jobs de sistema têm um lock por tipo e as sincronizações, um
+// por escritório, município e empresa. Dois agendamentos do mesmo trabalho nunca
+// executam ao mesmo tempo.
+func (j *JobSchedule) LockName() string {
+	if j.Type != JobScheduleTypeNFSeSync {
+		return j.Type
+	}
+
+	escritorio, empresa := "-", "-"
+	if j.EscritorioID != nil {
+		escritorio = strconv.FormatInt(*j.EscritorioID, 10)
+	}
+	if j.EmpresaID != nil {
+		empresa = strconv.Itoa(*j.EmpresaID)
+	}
+	return fmt.Sprintf("%s:%s:%s:%s", j.Type, escritorio, j.CodigoIBGE, empresa)
+}
+
+// JobScheduleRequest estrutura para criação/atualização de agendamentos
+type JobScheduleRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Type        string            `json:"type" binding:"required"`
+	EmpresaID   *int              `json:"empresa_id"`
+	CodigoIBGE  string            `json:"codigo_ibge"`
+	Cron        string            `json:"cron" binding:"required"`
+	Params      map[string]string `json:"params"`
+	Enabled     *bool             `json:"enabled"`
+	Description string            `json:"description"`
+
+	// Escritório dono dos documentos sincronizados; informado só pelo sistema,
+	// pois o agendamento criado por um escritório é sempre dele
+	EscritorioID *int64 `json:"escritorio_id"`
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/uptrace/bun"
 )
 
@@ -29,9 +30,9 @@ type NFSe struct {
 	Status string `json:"status" bun:",notnull,default:'pendente'"`
 
 	// Valores financeiros essenciais
-	ValorNota   float64 `json:"valor_nota" bun:",type:decimal(15,2),notnull,default:0"`
-	AliquotaIss float64 `json:"aliquota_iss" bun:",type:decimal(8,4),notnull,default:0"`
-	ValorIss    float64 `json:"valor_iss" bun:",type:decimal(15,2),notnull,default:0"`
+	ValorNota   decimal.Decimal `json:"valor_nota" bun:",type:decimal(15,2),notnull,default:0"`
+	AliquotaIss decimal.Decimal `json:"aliquota_iss" bun:",type:decimal(8,4),notnull,default:0"`
+	ValorIss    decimal.Decimal `json:"valor_iss" bun:",type:decimal(15,2),notnull,default:0"`
 
 	// Dados do prestador
 	CNPJPrestador               string `json:"cnpj_prestador" bun:",notnull"`
@@ -73,25 +74,25 @@ func (n *NFSe) BeforeAppendModel(ctx context.Context, query bun.Query) error {
 
 // NFSeResponse representa a resposta de consulta para o frontend
 type NFSeResponse struct {
-	NumeroNfse                  string    `json:"numero_nfse"`
-	NumeroRps                   string    `json:"numero_rps"`
-	SerieRps                    string    `json:"serie_rps"`
-	DataEmissao                 time.Time `json:"data_emissao"`
-	Status                      string    `json:"status"`
-	CodigoVerificacao           string    `json:"codigo_verificacao"`
-	ValorNota                   float64   `json:"valor_nota"`
-	AliquotaIss                 float64   `json:"aliquota_iss"`
-	ValorIss                    float64   `json:"valor_iss"`
-	Competencia                 string    `json:"competencia"`
-	CNPJPrestador               string    `json:"cnpj_prestador,omitempty"`
-	RazaoSocialPrestador        string    `json:"razao_social_prestador,omitempty"`
-	InscricaoMunicipalPrestador string    `json:"inscricao_municipal_prestador,omitempty"`
-	CNPJTomador                 string    `json:"cnpj_tomador,omitempty"`
-	RazaoSocialTomador          string    `json:"razao_social_tomador,omitempty"`
-	Discriminacao               string    `json:"discriminacao,omitempty"`
-	CodigoServico               string    `json:"codigo_servico,omitempty"`
-	ItemListaServico            string    `json:"item_lista_servico,omitempty"`
-	CodigoMunicipio             string    `json:"codigo_municipio,omitempty"`
-	CodigoIBGE                  string    `json:"codigo_ibge,omitempty"`
-	XMLContent                  string    `json:"xml_content,omitempty"`
+	NumeroNfse                  string          `json:"numero_nfse"`
+	NumeroRps                   string          `json:"numero_rps"`
+	SerieRps                    string          `json:"serie_rps"`
+	DataEmissao                 time.Time       `json:"data_emissao"`
+	Status                      string          `json:"status"`
+	CodigoVerificacao           string          `json:"codigo_verificacao"`
+	ValorNota                   decimal.Decimal `json:"valor_nota"`
+	AliquotaIss                 decimal.Decimal `json:"aliquota_iss"`
+	ValorIss                    decimal.Decimal `json:"valor_iss"`
+	Competencia                 string          `json:"competencia"`
+	CNPJPrestador               string          `json:"cnpj_prestador,omitempty"`
+	RazaoSocialPrestador        string          `json:"razao_social_prestador,omitempty"`
+	InscricaoMunicipalPrestador string          `json:"inscricao_municipal_prestador,omitempty"`
+	CNPJTomador                 string          `json:"cnpj_tomador,omitempty"`
+	RazaoSocialTomador          string          `json:"razao_social_tomador,omitempty"`
+	Discriminacao               string          `json:"discriminacao,omitempty"`
+	CodigoServico               string          `json:"codigo_servico,omitempty"`
+	ItemListaServico            string          `json:"item_lista_servico,omitempty"`
+	CodigoMunicipio             string          `json:"codigo_municipio,omitempty"`
+	CodigoIBGE                  string          `json:"codigo_ibge,omitempty"`
+	XMLContent                  string          `json:"xml_content,omitempty"`
 }
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// StorageOrphan objeto do armazenamento sem documento correspondente, encontrado
+// pela reconciliação. O registro é removido quando uma reconciliação completa não
+// encontra mais o objeto órfão (documento gravado ou objeto removido).
+type StorageOrphan struct {
+	bun.BaseModel `bun:"table:storage_orphans,alias:sor"`
+
+	ObjectKey   string    `json:"object_key" bun:",pk"`
+	Bucket      string    `json:"bucket" bun:",notnull"`
+	FirstSeenAt time.Time `json:"first_seen_at" bun:",notnull"`
+	LastSeenAt  time.Time `json:"last_seen_at" bun:",notnull"`
+}
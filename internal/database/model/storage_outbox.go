@@ -0,0 +1,74 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// OutboxStatus representa o estado de uma entrada do outbox de armazenamento
+type OutboxStatus string
+
+const (
+	OutboxStatusPendente  OutboxStatus = "pendente"
+	OutboxStatusConcluido OutboxStatus = "concluido"
+	OutboxStatusFalha     OutboxStatus = "falha" // Excedeu o número máximo de tentativas
+)
+
+// OutboxOperation operação executada no armazenamento ao entregar a entrada
+type OutboxOperation string
+
+const (
+	OutboxOperationPut    OutboxOperation = "put"    // Gravar o payload na chave
+	OutboxOperationDelete OutboxOperation = "delete" // Remover o objeto (purga do documento)
+)
+
+// StorageOutbox representa um upload pendente para o armazenamento de objetos.
+// A entrada é gravada na mesma transação do documento, garantindo que todo
+// registro salvo no banco tenha seu XML enviado ao MinIO em algum momento.
+type StorageOutbox struct {
+	bun.BaseModel `bun:"table:storage_outbox,alias:so"`
+
+	ID          int64           `json:"id" bun:",pk,autoincrement"`
+	DocumentID  int             `json:"document_id" bun:",notnull"`
+	Operation   OutboxOperation `json:"operation" bun:",notnull,default:'put'"`
+	ObjectName  string          `json:"object_name" bun:",notnull"`
+	ContentType string          `json:"content_type" bun:",notnull,default:'application/xml'"`
+	Payload     []byte          `json:"-" bun:",type:bytea,notnull"`
+	ContentHash string          `json:"content_sha256" bun:"content_sha256"`
+
+	// Retenção aplicada ao objeto na entrega
+	RetainUntil time.Time `json:"retain_until" bun:",nullzero"`
+	LegalHold   bool      `json:"legal_hold" bun:",notnull,default:false"`
+
+	// Tenant dono do objeto (ID do escritório, ou o tenant padrão nos documentos sem
+	// escritório), usado na criptografia em repouso
+	Tenant string `json:"tenant"`
+
+	// Controle de entrega
+	Status        OutboxStatus `json:"status" bun:",notnull,default:'pendente'"`
+	Attempts      int          `json:"attempts" bun:",notnull,default:0"`
+	LastError     string       `json:"last_error" bun:",type:text"`
+	NextAttemptAt time.Time    `json:"next_attempt_at" bun:",nullzero,notnull,default:current_timestamp"`
+	ProcessedAt   time.Time    `json:"processed_at" bun:",nullzero"`
+
+	// Controle de auditoria
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// BeforeAppendModel hook executado antes de inserir/atualizar
+func (o *StorageOutbox) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		o.CreatedAt = time.Now()
+		o.UpdatedAt = time.Now()
+		if o.NextAttemptAt.IsZero() {
+			o.NextAttemptAt = o.CreatedAt
+		}
+	case *bun.UpdateQuery:
+		o.UpdatedAt = time.Now()
+	}
+	return nil
+}
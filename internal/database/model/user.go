@@ -11,12 +11,13 @@ import (
 type User struct {
 	bun.BaseModel `bun:"table:users,alias:u"`
 
-	ID        int       `json:"id" bun:",pk,autoincrement"`
-	Name      string    `json:"name" bun:",notnull"`
-	Email     string    `json:"email" bun:",unique,notnull"`
-	Password  string    `json:"-" bun:",notnull"`
-	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
-	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+	ID           int       `json:"id" bun:",pk,autoincrement"`
+	EscritorioID *int64    `json:"escritorio_id,omitempty"`
+	Name         string    `json:"name" bun:",notnull"`
+	Email        string    `json:"email" bun:",unique,notnull"`
+	Password     string    `json:"-" bun:",notnull"`
+	CreatedAt    time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt    time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
 }
 
 // BeforeAppendModel hook executado antes de inserir/atualizar
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+// APITokenRepository credenciais de API dos escritórios
+type APITokenRepository struct {
+	db *bun.DB
+}
+
+func NewAPITokenRepository(db *bun.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+const apiTokenEscritorio = "?TableAlias.escritorio_id"
+
+// Create grava uma credencial no escritório do contexto
+func (r *APITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	escritorioID := &token.EscritorioID
+	if err := stampEscritorio(ctx, &escritorioID); err != nil {
+		return err
+	}
+	token.EscritorioID = *escritorioID
+	_, err := conn(ctx, r.db).NewInsert().Model(token).Exec(ctx)
+	return err
+}
+
+// ListByEscritorio lista as credenciais de um escritório, inclusive as revogadas
+func (r *APITokenRepository) ListByEscritorio(ctx context.Context, escritorioID int64) ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), apiTokenEscritorio)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&tokens).
+		Where("escritorio_id = ?", escritorioID).
+		Order("id ASC").
+		Scan(ctx)
+	return tokens, err
+}
+
+// Revoke revoga a credencial do escritório; retorna false se ela não existir ou já estiver revogada
+func (r *APITokenRepository) Revoke(ctx context.Context, escritorioID, id int64) (bool, error) {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), apiTokenEscritorio)
+	if err != nil {
+		return false, err
+	}
+	result, err := query.
+		Model((*model.APIToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("escritorio_id = ?", escritorioID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	revoked, err := result.RowsAffected()
+	return revoked > 0, err
+}
+
+// GetActiveByHash busca a credencial ativa pelo hash do token. Usado na
+// autenticação, antes de o escritório ser conhecido, por isso não é restrito.
+func (r *APITokenRepository) GetActiveByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	token := &model.APIToken{}
+	err := r.db.NewSelect().
+		Model(token).
+		Join("JOIN escritorios AS esc ON esc.id = tok.escritorio_id").
+		Where("tok.token_hash = ?", hash).
+		Where("tok.revoked_at IS NULL").
+		Where("esc.ativo").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// TouchLastUsed registra o último uso da credencial
+func (r *APITokenRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	_, err := r.db.NewUpdate().
+		Model((*model.APIToken)(nil)).
+		Set("last_used_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
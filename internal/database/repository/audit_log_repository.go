@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/tenant"
+
+	"github.com/uptrace/bun"
+)
+
+// AuditLogRepository trilha de auditoria das alterações (somente inserção)
+type AuditLogRepository struct {
+	db *bun.DB
+}
+
+func NewAuditLogRepository(db *bun.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+const auditEscritorio = "?TableAlias.escritorio_id"
+
+// RunInTx executa fn em uma transação cujo contexto é usado pelos repositórios, de
+// modo que a alteração e seu registro de auditoria sejam gravados juntos. Dentro da
+// transação da requisição, usa um savepoint.
+func (r *AuditLogRepository) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return fn(tenant.WithConn(ctx, tx))
+	})
+}
+
+// Create grava o registro na transação do contexto, no escritório do contexto
+func (r *AuditLogRepository) Create(ctx context.Context, log *model.AuditLog) error {
+	if err := stampEscritorio(ctx, &log.EscritorioID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(log).Exec(ctx)
+	return err
+}
+
+// List lista os registros que atendem aos filtros, mais recentes primeiro, com ID
+// menor que beforeID (0 para a primeira página)
+func (r *AuditLogRepository) List(ctx context.Context, filter *model.AuditFilter, beforeID int64, limit int) ([]*model.AuditLog, error) {
+	var logs []*model.AuditLog
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), auditEscritorio)
+	if err != nil {
+		return nil, err
+	}
+	applyAuditFilter(query, filter)
+	if beforeID > 0 {
+		query.Where("?TableAlias.id < ?", beforeID)
+	}
+
+	err = query.
+		Model(&logs).
+		OrderExpr("?TableAlias.id DESC").
+		Limit(limit).
+		Scan(ctx)
+	return logs, err
+}
+
+// applyAuditFilter aplica os filtros da consulta à auditoria
+func applyAuditFilter(query *bun.SelectQuery, filter *model.AuditFilter) {
+	if filter.Entity != "" {
+		query.Where("?TableAlias.entity = ?", filter.Entity)
+	}
+	if filter.EntityID != nil {
+		query.Where("?TableAlias.entity_id = ?", *filter.EntityID)
+	}
+	if filter.Action != "" {
+		query.Where("?TableAlias.action = ?", filter.Action)
+	}
+	if filter.Actor != "" {
+		query.Where("?TableAlias.actor = ?", filter.Actor)
+	}
+	if !filter.DataInicio.IsZero() {
+		query.Where("?TableAlias.created_at >= ?", filter.DataInicio)
+	}
+	if !filter.DataFim.IsZero() {
+		query.Where("?TableAlias.created_at < ?", filter.DataFim)
+	}
+}
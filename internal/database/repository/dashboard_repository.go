@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+// dashboardViews views materializadas do dashboard, na ordem de atualização. São
+// lidas pelas views de mesmo nome sem o sufixo _dados, que filtram o escritório.
+var dashboardViews = []string{
+	"documents_resumo_mensal_dados",
+	"documents_resumo_tomadores_dados",
+}
+
+// DashboardRepository agregados do dashboard a partir das views materializadas.
+// Views materializadas não passam pelo row-level security: as consultas leem as
+// views com security_barrier que aplicam a regra das políticas e, além delas,
+// filtram o escritório do contexto.
+type DashboardRepository struct {
+	db *bun.DB
+}
+
+func NewDashboardRepository(db *bun.DB) *DashboardRepository {
+	return &DashboardRepository{db: db}
+}
+
+// newSelect inicia uma consulta às views restrita ao escritório do contexto
+func (r *DashboardRepository) newSelect(ctx context.Context) (*bun.SelectQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), "?TableAlias.escritorio_id")
+}
+
+// ResumoMensal totais por competência do período, somando as empresas do filtro.
+// Competências sem documentos não aparecem.
+func (r *DashboardRepository) ResumoMensal(ctx context.Context, filter *model.DashboardFilter) ([]*model.DocumentResumoMensal, error) {
+	var rows []*model.DocumentResumoMensal
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = applyDashboardFilter(query.Model(&rows), filter).
+		Column("competencia").
+		ColumnExpr("sum(documentos) AS documentos").
+		ColumnExpr("sum(valor_nota) AS valor_nota").
+		ColumnExpr("sum(valor_iss) AS valor_iss").
+		ColumnExpr("sum(valor_iss_retido) AS valor_iss_retido").
+		Group("competencia").
+		Order("competencia").
+		Scan(ctx)
+	return rows, err
+}
+
+// TopTomadores tomadores com maior faturamento no período
+func (r *DashboardRepository) TopTomadores(ctx context.Context, filter *model.DashboardFilter, limit int) ([]*model.DocumentResumoTomador, error) {
+	var rows []*model.DocumentResumoTomador
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = applyDashboardFilter(query.Model(&rows), filter).
+		Column("cnpj_destinatario").
+		ColumnExpr("max(razao_social_destinatario) AS razao_social_destinatario").
+		ColumnExpr("max(destinatario_empresa_id) AS destinatario_empresa_id").
+		ColumnExpr("sum(documentos) AS documentos").
+		ColumnExpr("sum(valor_nota) AS valor_nota").
+		Group("cnpj_destinatario").
+		OrderExpr("valor_nota DESC, cnpj_destinatario").
+		Limit(limit).
+		Scan(ctx)
+	return rows, err
+}
+
+// Refresh recalcula as views sem bloquear as leituras. Usa o banco diretamente,
+// fora da transação da requisição, para que app.escritorio_id não restrinja os
+// documentos agregados.
+func (r *DashboardRepository) Refresh(ctx context.Context) error {
+	for _, view := range dashboardViews {
+		if _, err := r.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY ?", bun.Ident(view)); err != nil {
+			return fmt.Errorf("erro ao atualizar %s: %w", view, err)
+		}
+	}
+	return nil
+}
+
+// applyDashboardFilter aplica o período e a empresa emitente às consultas das views
+func applyDashboardFilter(query *bun.SelectQuery, filter *model.DashboardFilter) *bun.SelectQuery {
+	if filter.CompetenciaInicio != "" {
+		query.Where("?TableAlias.competencia >= ?", filter.CompetenciaInicio)
+	}
+	if filter.CompetenciaFim != "" {
+		query.Where("?TableAlias.competencia <= ?", filter.CompetenciaFim)
+	}
+	if filter.EmpresaID != nil {
+		query.Where("?TableAlias.emitente_empresa_id = ?", *filter.EmpresaID)
+	}
+	return query
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type DeadLetterRepository struct {
+	db *bun.DB
+}
+
+func NewDeadLetterRepository(db *bun.DB) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
+
+// Create registra um novo documento com falha
+func (r *DeadLetterRepository) Create(ctx context.Context, deadLetter *model.DeadLetter) error {
+	_, err := r.db.NewInsert().Model(deadLetter).Exec(ctx)
+	return err
+}
+
+// GetByID busca um registro por ID
+func (r *DeadLetterRepository) GetByID(ctx context.Context, id int64) (*model.DeadLetter, error) {
+	deadLetter := &model.DeadLetter{}
+	err := r.db.NewSelect().
+		Model(deadLetter).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deadLetter, nil
+}
+
+// List lista registros aplicando os filtros informados (sem o payload)
+func (r *DeadLetterRepository) List(ctx context.Context, filter model.DeadLetterFilter) ([]*model.DeadLetter, error) {
+	var deadLetters []*model.DeadLetter
+	query := r.db.NewSelect().
+		Model(&deadLetters).
+		ExcludeColumn("payload")
+	applyDeadLetterFilter(query, filter)
+
+	err := query.
+		Order("id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(ctx)
+	return deadLetters, err
+}
+
+// Count conta registros aplicando os filtros informados
+func (r *DeadLetterRepository) Count(ctx context.Context, filter model.DeadLetterFilter) (int, error) {
+	query := r.db.NewSelect().Model((*model.DeadLetter)(nil))
+	applyDeadLetterFilter(query, filter)
+	return query.Count(ctx)
+}
+
+// RecordAttempt registra uma tentativa de reprocessamento sem sucesso
+func (r *DeadLetterRepository) RecordAttempt(ctx context.Context, id int64, stage, lastError string) error {
+	_, err := r.db.NewUpdate().
+		Model((*model.DeadLetter)(nil)).
+		Set("attempts = attempts + 1").
+		Set("stage = ?", stage).
+		Set("error = ?", lastError).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// Resolve marca um registro como reprocessado ou descartado
+func (r *DeadLetterRepository) Resolve(ctx context.Context, id int64, status model.DeadLetterStatus) error {
+	_, err := r.db.NewUpdate().
+		Model((*model.DeadLetter)(nil)).
+		Set("status = ?", status).
+		Set("resolved_at = ?", time.Now()).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// applyDeadLetterFilter aplica os filtros comuns às consultas da fila de falhas
+func applyDeadLetterFilter(query *bun.SelectQuery, filter model.DeadLetterFilter) {
+	if filter.Status != "" {
+		query.Where("status = ?", filter.Status)
+	}
+	if filter.Stage != "" {
+		query.Where("stage = ?", filter.Stage)
+	}
+	if filter.Source != "" {
+		query.Where("source = ?", filter.Source)
+	}
+	if filter.Competencia != "" {
+		query.Where("competencia = ?", filter.Competencia)
+	}
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+// DocumentDownloadRepository auditoria dos acessos ao XML dos documentos
+type DocumentDownloadRepository struct {
+	db *bun.DB
+}
+
+func NewDocumentDownloadRepository(db *bun.DB) *DocumentDownloadRepository {
+	return &DocumentDownloadRepository{db: db}
+}
+
+// Create registra um acesso
+func (r *DocumentDownloadRepository) Create(ctx context.Context, download *model.DocumentDownload) error {
+	_, err := conn(ctx, r.db).NewInsert().Model(download).Exec(ctx)
+	return err
+}
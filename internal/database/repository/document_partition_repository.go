@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+// DocumentPartitionRepository partições anuais da tabela documents. A estrutura
+// não pertence a um escritório: as operações usam o banco diretamente, fora da
+// transação da requisição.
+type DocumentPartitionRepository struct {
+	db *bun.DB
+}
+
+func NewDocumentPartitionRepository(db *bun.DB) *DocumentPartitionRepository {
+	return &DocumentPartitionRepository{db: db}
+}
+
+// List lista as partições de documents em ordem de nome (anos e, por fim, a padrão)
+func (r *DocumentPartitionRepository) List(ctx context.Context) ([]*model.DocumentPartition, error) {
+	var partitions []*model.DocumentPartition
+	err := r.db.NewRaw(`
+		SELECT c.relname AS name,
+			coalesce(t.spcname, '') AS tablespace,
+			pg_total_relation_size(c.oid) AS size,
+			greatest(c.reltuples, 0)::BIGINT AS rows
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		LEFT JOIN pg_tablespace t ON t.oid = c.reltablespace
+		WHERE i.inhparent = 'documents'::regclass
+		ORDER BY c.relname`).
+		Scan(ctx, &partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, partition := range partitions {
+		if ano, err := strconv.Atoi(strings.TrimPrefix(partition.Name, "documents_")); err == nil {
+			partition.Ano = ano
+		}
+	}
+	return partitions, nil
+}
+
+// DefaultYears anos de competência com documentos na partição padrão, ou seja,
+// sem partição própria ainda
+func (r *DocumentPartitionRepository) DefaultYears(ctx context.Context) ([]int, error) {
+	var anos []int
+	err := r.db.NewSelect().
+		TableExpr("?", bun.Ident(model.DocumentPartitionDefault)).
+		ColumnExpr("DISTINCT left(competencia, 4)::INT AS ano").
+		Where("competencia ~ '^[0-9]{6}$'").
+		OrderExpr("ano").
+		Scan(ctx, &anos)
+	return anos, err
+}
+
+// Create cria a partição do ano, movendo para ela os documentos do ano que estavam
+// na partição padrão. Retorna false se a partição já existia.
+func (r *DocumentPartitionRepository) Create(ctx context.Context, ano int) (bool, error) {
+	var created bool
+	err := r.db.NewRaw("SELECT documents_criar_particao(?)", ano).Scan(ctx, &created)
+	return created, err
+}
+
+// Move move a partição, com índices e TOAST, para o tablespace informado. Bloqueia a
+// partição enquanto os arquivos são copiados.
+func (r *DocumentPartitionRepository) Move(ctx context.Context, name, tablespace string) error {
+	_, err := r.db.ExecContext(ctx, "SELECT documents_mover_particao(?, ?)", name, tablespace)
+	return err
+}
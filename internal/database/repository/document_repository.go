@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"time"
 	"zemdocs/internal/database/model"
 
 	"github.com/uptrace/bun"
 )
 
+// documentEscritorio coluna do escritório dono do documento
+const documentEscritorio = "?TableAlias.escritorio_id"
+
 type DocumentRepository struct {
 	db *bun.DB
 }
@@ -15,10 +21,24 @@ func NewDocumentRepository(db *bun.DB) *DocumentRepository {
 	return &DocumentRepository{db: db}
 }
 
+// newSelect inicia uma consulta de documentos restrita ao escritório do contexto
+func (r *DocumentRepository) newSelect(ctx context.Context) (*bun.SelectQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), documentEscritorio)
+}
+
+// newUpdate inicia uma atualização de documentos restrita ao escritório do contexto
+func (r *DocumentRepository) newUpdate(ctx context.Context) (*bun.UpdateQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), documentEscritorio)
+}
+
 // GetByNumeroDocumento busca documento por número
 func (r *DocumentRepository) GetByNumeroDocumento(ctx context.Context, numeroDocumento string) (*model.Document, error) {
 	document := &model.Document{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(document).
 		Where("numero_documento = ?", numeroDocumento).
 		Scan(ctx)
@@ -28,6 +48,23 @@ func (r *DocumentRepository) GetByNumeroDocumento(ctx context.Context, numeroDoc
 	return document, nil
 }
 
+// GetByID busca documento por ID
+func (r *DocumentRepository) GetByID(ctx context.Context, id int) (*model.Document, error) {
+	document := &model.Document{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(document).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
 // GetByNumeroNfse busca NFS-e por número (compatibilidade)
 func (r *DocumentRepository) GetByNumeroNfse(ctx context.Context, numeroNfse string) (*model.Document, error) {
 	return r.GetByNumeroDocumento(ctx, numeroNfse)
@@ -36,7 +73,11 @@ func (r *DocumentRepository) GetByNumeroNfse(ctx context.Context, numeroNfse str
 // GetByNumeroRps busca documento por número do RPS
 func (r *DocumentRepository) GetByNumeroRps(ctx context.Context, numeroRps string) (*model.Document, error) {
 	document := &model.Document{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(document).
 		Where("numero_rps = ?", numeroRps).
 		Scan(ctx)
@@ -49,7 +90,11 @@ func (r *DocumentRepository) GetByNumeroRps(ctx context.Context, numeroRps strin
 // GetByIntervaloNumeros busca documentos por intervalo de números
 func (r *DocumentRepository) GetByIntervaloNumeros(ctx context.Context, nrInicial, nrFinal string) ([]*model.Document, error) {
 	var documents []*model.Document
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(&documents).
 		Where("numero_documento BETWEEN ? AND ?", nrInicial, nrFinal).
 		Order("numero_documento ASC").
@@ -57,37 +102,14 @@ func (r *DocumentRepository) GetByIntervaloNumeros(ctx context.Context, nrInicia
 	return documents, err
 }
 
-// GetByIntervaloDatas busca documentos por intervalo de datas com paginação
-func (r *DocumentRepository) GetByIntervaloDatas(ctx context.Context, dtInicial, dtFinal string, page int) ([]*model.Document, error) {
-	limit := 50 // Limite de registros por página
-	offset := (page - 1) * limit
-
-	var documents []*model.Document
-	err := r.db.NewSelect().
-		Model(&documents).
-		Where("data_emissao BETWEEN ? AND ?", dtInicial, dtFinal).
-		Order("data_emissao ASC").
-		Limit(limit).
-		Offset(offset).
-		Scan(ctx)
-	return documents, err
-}
-
-// GetByCompetencia busca documentos por competência
-func (r *DocumentRepository) GetByCompetencia(ctx context.Context, competencia string) ([]*model.Document, error) {
-	var documents []*model.Document
-	err := r.db.NewSelect().
-		Model(&documents).
-		Where("competencia = ?", competencia).
-		Order("numero_nfse ASC").
-		Scan(ctx)
-	return documents, err
-}
-
 // GetUltimoRPS retorna o número do último RPS enviado
 func (r *DocumentRepository) GetUltimoRPS(ctx context.Context) (string, error) {
 	var ultimoRps string
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = query.
 		Model((*model.Document)(nil)).
 		Column("numero_rps").
 		Order("created_at DESC").
@@ -100,9 +122,12 @@ func (r *DocumentRepository) GetUltimoRPS(ctx context.Context) (string, error) {
 	return ultimoRps, nil
 }
 
-// Create cria um novo documento
+// Create cria um novo documento no escritório do contexto
 func (r *DocumentRepository) Create(ctx context.Context, document *model.Document) error {
-	_, err := r.db.NewInsert().
+	if err := stampEscritorio(ctx, &document.EscritorioID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().
 		Model(document).
 		Exec(ctx)
 	return err
@@ -113,10 +138,16 @@ func (r *DocumentRepository) ExistsByNumeroNfse(ctx context.Context, numeroNfse
 	return r.ExistsByNumeroDocumento(ctx, numeroNfse)
 }
 
-// ExistsByNumeroDocumento verifica se um documento já existe pelo número
+// ExistsByNumeroDocumento verifica se um documento já existe pelo número, inclusive
+// entre os excluídos (a sincronização não os importa de novo)
 func (r *DocumentRepository) ExistsByNumeroDocumento(ctx context.Context, numeroDocumento string) (bool, error) {
-	count, err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return false, err
+	}
+	count, err := query.
 		Model((*model.Document)(nil)).
+		WhereAllWithDeleted().
 		Where("numero_documento = ?", numeroDocumento).
 		Count(ctx)
 
@@ -127,31 +158,550 @@ func (r *DocumentRepository) ExistsByNumeroDocumento(ctx context.Context, numero
 	return count > 0, nil
 }
 
-// GetByCompetenciaLocal busca documentos por competência no banco local
-func (r *DocumentRepository) GetByCompetenciaLocal(ctx context.Context, competencia string, limit, offset int) ([]*model.Document, error) {
+// CreateWithOutbox cria o documento e a entrada de upload no outbox na mesma transação
+func (r *DocumentRepository) CreateWithOutbox(ctx context.Context, document *model.Document, entry *model.StorageOutbox) error {
+	if err := stampEscritorio(ctx, &document.EscritorioID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(document).Exec(ctx); err != nil {
+			return err
+		}
+
+		entry.DocumentID = document.ID
+		_, err := tx.NewInsert().Model(entry).Exec(ctx)
+		return err
+	})
+}
+
+// Delete exclui logicamente o documento (deleted_at). O XML e as entradas de
+// outbox são mantidos para a restauração; a remoção definitiva é feita por Purge.
+func (r *DocumentRepository) Delete(ctx context.Context, id int) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewDelete(), documentEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model((*model.Document)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// Restore restaura o documento excluído logicamente; retorna sql.ErrNoRows se
+// não houver documento excluído com o ID no escritório do contexto
+func (r *DocumentRepository) Restore(ctx context.Context, id int) error {
+	query, err := r.newUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := query.
+		Model((*model.Document)(nil)).
+		WhereDeleted().
+		Set("deleted_at = NULL").
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if restored, _ := result.RowsAffected(); restored == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Purge remove definitivamente o documento já excluído logicamente e suas
+// entradas de outbox na mesma transação, gravando nela a entrada que remove o XML
+// (removal, nil se o documento não tem XML armazenado). As entradas só são
+// alteradas se o documento pertencer ao escritório do contexto. A remoção não é
+// gravada se outro documento, de qualquer escritório, ainda usar a mesma chave.
+func (r *DocumentRepository) Purge(ctx context.Context, id int, removal *model.StorageOutbox) error {
+	return conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query, err := whereEscritorio(ctx, tx.NewDelete(), documentEscritorio)
+		if err != nil {
+			return err
+		}
+		result, err := query.
+			Model((*model.Document)(nil)).
+			WhereDeleted().
+			ForceDelete().
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			return sql.ErrNoRows
+		}
+
+		_, err = tx.NewDelete().
+			Model((*model.StorageOutbox)(nil)).
+			Where("document_id = ?", id).
+			Exec(ctx)
+		if err != nil || removal == nil {
+			return err
+		}
+
+		// Chaves do layout anterior ao separado por escritório podem ser compartilhadas
+		var inUse bool
+		if err := tx.NewRaw("SELECT documents_xml_key_em_uso(?)", removal.ObjectName).Scan(ctx, &inUse); err != nil {
+			return err
+		}
+		if inUse {
+			return nil
+		}
+
+		// A remoção do XML só acontece depois que a purga for confirmada
+		removal.DocumentID = id
+		_, err = tx.NewInsert().Model(removal).Exec(ctx)
+		return err
+	})
+}
+
+// GetDeletedByID busca um documento excluído logicamente
+func (r *DocumentRepository) GetDeletedByID(ctx context.Context, id int) (*model.Document, error) {
+	document := &model.Document{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(document).
+		WhereDeleted().
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// ListDeleted lista os documentos excluídos logicamente, mais recentes primeiro
+func (r *DocumentRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*model.Document, error) {
+	var documents []*model.Document
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&documents).
+		WhereDeleted().
+		Order("deleted_at DESC", "id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+	return documents, err
+}
+
+// CountDeleted conta os documentos excluídos logicamente
+func (r *DocumentRepository) CountDeleted(ctx context.Context) (int, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return query.
+		Model((*model.Document)(nil)).
+		WhereDeleted().
+		Count(ctx)
+}
+
+// ListAfterID lista documentos em ordem de ID a partir de um cursor (usado em
+// varreduras do armazenamento; inclui os excluídos, cujo XML é mantido até a purga)
+func (r *DocumentRepository) ListAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error) {
+	var documents []*model.Document
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&documents).
+		WhereAllWithDeleted().
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+	return documents, err
+}
+
+// ListForExport lista, a partir de um cursor de ID, os documentos que atendem aos filtros da exportação
+func (r *DocumentRepository) ListForExport(ctx context.Context, filter *model.ExportFilter, afterID, limit int) ([]*model.Document, error) {
+	var documents []*model.Document
+	query, err := r.exportQuery(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&documents).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+	return documents, err
+}
+
+// CountForExport conta os documentos que atendem aos filtros da exportação
+func (r *DocumentRepository) CountForExport(ctx context.Context, filter *model.ExportFilter) (int, error) {
+	query, err := r.exportQuery(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return query.
+		Model((*model.Document)(nil)).
+		Count(ctx)
+}
+
+func (r *DocumentRepository) exportQuery(ctx context.Context, filter *model.ExportFilter) (*bun.SelectQuery, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filter.Direcao {
+	case model.ExportDirecaoEmitidos:
+		query.Where("cnpj_emitente = ?", filter.CNPJ)
+	case model.ExportDirecaoRecebidos:
+		query.Where("cnpj_destinatario = ?", filter.CNPJ)
+	default:
+		query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("cnpj_emitente = ?", filter.CNPJ).
+				WhereOr("cnpj_destinatario = ?", filter.CNPJ)
+		})
+	}
+
+	if filter.CompetenciaInicio != "" {
+		query.Where("competencia >= ?", filter.CompetenciaInicio)
+	}
+	if filter.CompetenciaFim != "" {
+		query.Where("competencia <= ?", filter.CompetenciaFim)
+	}
+	if len(filter.DocumentTypes) > 0 {
+		query.Where("document_type IN (?)", bun.In(filter.DocumentTypes))
+	}
+
+	return query, nil
+}
+
+// UpdateXMLIntegrity registra o resultado da verificação de integridade do XML
+func (r *DocumentRepository) UpdateXMLIntegrity(ctx context.Context, id int, status string, verifiedAt time.Time) error {
+	query, err := r.newUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model((*model.Document)(nil)).
+		WhereAllWithDeleted().
+		Set("xml_integrity = ?", status).
+		Set("xml_verified_at = ?", verifiedAt).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// UpdateXMLStorage grava as colunas de localização do XML a partir do documento
+func (r *DocumentRepository) UpdateXMLStorage(ctx context.Context, document *model.Document) error {
+	query, err := r.newUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model(document).
+		WhereAllWithDeleted().
+		Column("xml_bucket", "xml_key", "xml_size", "xml_sha256", "xml_content_type", "xml_uploaded_at",
+			"xml_retain_until", "xml_legal_hold", "updated_at").
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+// ListWithoutXMLKeyAfterID lista documentos sem chave de XML registrada a partir de
+// um cursor, inclusive os excluídos
+func (r *DocumentRepository) ListWithoutXMLKeyAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error) {
 	var documents []*model.Document
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&documents).
+		WhereAllWithDeleted().
+		Where("id > ?", afterID).
+		Where("xml_key IS NULL OR xml_key = ''").
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+	return documents, err
+}
+
+// ListWithXMLKeyAfterID lista documentos com chave de XML registrada a partir de
+// um cursor, inclusive os excluídos
+func (r *DocumentRepository) ListWithXMLKeyAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error) {
+	var documents []*model.Document
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&documents).
+		WhereAllWithDeleted().
+		Where("id > ?", afterID).
+		Where("xml_key IS NOT NULL AND xml_key <> ''").
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+	return documents, err
+}
+
+// ExistingXMLKeys informa quais das chaves estão registradas em algum documento,
+// inclusive entre os excluídos
+func (r *DocumentRepository) ExistingXMLKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(keys) == 0 {
+		return existing, nil
+	}
 
-	err := r.db.NewSelect().
+	var found []string
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model((*model.Document)(nil)).
+		WhereAllWithDeleted().
+		Column("xml_key").
+		Where("xml_key IN (?)", bun.In(keys)).
+		Scan(ctx, &found)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range found {
+		existing[key] = true
+	}
+	return existing, nil
+}
+
+// empresaColumn coluna de vínculo com a empresa conforme a direção: emitidos
+// (empresa como emitente) ou recebidos (empresa como tomadora)
+func empresaColumn(direcao string) string {
+	if direcao == model.ExportDirecaoRecebidos {
+		return "destinatario_empresa_id"
+	}
+	return "emitente_empresa_id"
+}
+
+// ListByEmpresa lista os documentos emitidos ou recebidos pela empresa
+func (r *DocumentRepository) ListByEmpresa(ctx context.Context, empresaID int, direcao string, limit, offset int) ([]*model.Document, error) {
+	var documents []*model.Document
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(&documents).
-		Where("competencia = ?", competencia).
-		Order("data_emissao DESC").
+		Where("?TableAlias.? = ?", bun.Ident(empresaColumn(direcao)), empresaID).
+		Order("data_emissao DESC", "id DESC").
 		Limit(limit).
 		Offset(offset).
 		Scan(ctx)
+	return documents, err
+}
+
+// CountByEmpresa conta os documentos emitidos ou recebidos pela empresa
+func (r *DocumentRepository) CountByEmpresa(ctx context.Context, empresaID int, direcao string) (int, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return query.
+		Model((*model.Document)(nil)).
+		Where("?TableAlias.? = ?", bun.Ident(empresaColumn(direcao)), empresaID).
+		Count(ctx)
+}
+
+// LinkEmpresa vincula à empresa os documentos do mesmo escritório ainda sem
+// vínculo em que o CNPJ dela aparece como emitente ou tomador. Retorna o total
+// de vínculos criados.
+func (r *DocumentRepository) LinkEmpresa(ctx context.Context, empresa *model.Empresa) (int, error) {
+	links := []struct{ cnpjColumn, empresaColumn string }{
+		{"cnpj_emitente", "emitente_empresa_id"},
+		{"cnpj_destinatario", "destinatario_empresa_id"},
+	}
+
+	total := 0
+	for _, link := range links {
+		query, err := r.newUpdate(ctx)
+		if err != nil {
+			return total, err
+		}
+		result, err := query.
+			Model((*model.Document)(nil)).
+			Set("? = ?", bun.Ident(link.empresaColumn), empresa.ID).
+			Where("? = ?", bun.Ident(link.cnpjColumn), empresa.CNPJ).
+			Where("? IS NULL", bun.Ident(link.empresaColumn)).
+			Where("escritorio_id IS NOT DISTINCT FROM ?", empresa.EscritorioID).
+			Exec(ctx)
+		if err != nil {
+			return total, err
+		}
+		affected, _ := result.RowsAffected()
+		total += int(affected)
+	}
+	return total, nil
+}
+
+// Delimitadores dos termos destacados por ts_headline; o serviço escapa o HTML
+// do trecho e os troca por <mark></mark>
+const (
+	SearchMarkStart = "\ue000"
+	SearchMarkStop  = "\ue001"
+)
+
+// searchConfig configuração de busca textual criada na migração (português sem acentos)
+const searchConfig = "zemdocs_portuguese"
 
+// Search busca documentos pelo termo, em ordem de relevância, com os trechos destacados
+func (r *DocumentRepository) Search(ctx context.Context, filter *model.DocumentSearchFilter) ([]*model.DocumentSearchHit, error) {
+	var hits []*model.DocumentSearchHit
+	query, err := r.searchQuery(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return documents, nil
+	fragmentos := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … "`, SearchMarkStart, SearchMarkStop)
+	completo := fmt.Sprintf(`StartSel="%s", StopSel="%s", HighlightAll=true`, SearchMarkStart, SearchMarkStop)
+
+	// ts_headline é cara; com ORDER BY e LIMIT o Postgres só a calcula para as
+	// linhas da página
+	err = query.
+		Model(&hits).
+		ColumnExpr("?TableColumns").
+		ColumnExpr("ts_rank_cd(?TableAlias.search_vector, search_query) AS rank").
+		ColumnExpr("ts_headline(?, coalesce(?TableAlias.discriminacao, ''), search_query, ?) AS destaque_discriminacao", searchConfig, fragmentos).
+		ColumnExpr("ts_headline(?, ?TableAlias.razao_social_emitente, search_query, ?) AS destaque_emitente", searchConfig, completo).
+		ColumnExpr("ts_headline(?, coalesce(?TableAlias.razao_social_destinatario, ''), search_query, ?) AS destaque_destinatario", searchConfig, completo).
+		Order("rank DESC", "data_emissao DESC", "id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(ctx)
+	return hits, err
 }
 
-// CountByCompetencia conta documentos por competência
-func (r *DocumentRepository) CountByCompetencia(ctx context.Context, competencia string) (int, error) {
-	count, err := r.db.NewSelect().
+// CountSearch conta os documentos encontrados pela busca
+func (r *DocumentRepository) CountSearch(ctx context.Context, filter *model.DocumentSearchFilter) (int, error) {
+	query, err := r.searchQuery(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return query.
 		Model((*model.Document)(nil)).
-		Where("competencia = ?", competencia).
 		Count(ctx)
+}
+
+func (r *DocumentRepository) searchQuery(ctx context.Context, filter *model.DocumentSearchFilter) (*bun.SelectQuery, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query.
+		TableExpr("websearch_to_tsquery(?, ?) AS search_query", searchConfig, filter.Query).
+		Where("?TableAlias.search_vector @@ search_query")
 
-	return count, err
+	applyDocumentFilter(query, &filter.DocumentFilter)
+
+	return query, nil
+}
+
+// List lista os documentos que atendem aos filtros na ordenação pedida, a partir
+// do cursor (paginação por chave: sem OFFSET, estável com inserções concorrentes)
+func (r *DocumentRepository) List(ctx context.Context, req *model.DocumentListRequest) ([]*model.Document, error) {
+	if !req.Sort.Valid() {
+		return nil, fmt.Errorf("ordenação inválida: %s", req.Sort.Column)
+	}
+
+	var documents []*model.Document
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applyDocumentFilter(query, &req.DocumentFilter)
+
+	column := bun.Ident(req.Sort.Column)
+	direction := "ASC"
+	comparison := ">"
+	if req.Sort.Desc {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	if req.Cursor != nil {
+		query.Where("(?TableAlias.?, ?TableAlias.id) "+comparison+" (?, ?)", column, req.Cursor.Value, req.Cursor.ID)
+	}
+
+	err = query.
+		Model(&documents).
+		OrderExpr("?TableAlias.? "+direction, column).
+		OrderExpr("?TableAlias.id " + direction).
+		Limit(req.Limit).
+		Scan(ctx)
+	return documents, err
+}
+
+// applyDocumentFilter aplica os filtros comuns às listagens e à busca
+func applyDocumentFilter(query *bun.SelectQuery, filter *model.DocumentFilter) {
+	if len(filter.DocumentTypes) > 0 {
+		query.Where("?TableAlias.document_type IN (?)", bun.In(filter.DocumentTypes))
+	}
+	if len(filter.Status) > 0 {
+		query.Where("?TableAlias.status IN (?)", bun.In(filter.Status))
+	}
+	if filter.CompetenciaInicio != "" {
+		query.Where("?TableAlias.competencia >= ?", filter.CompetenciaInicio)
+	}
+	if filter.CompetenciaFim != "" {
+		query.Where("?TableAlias.competencia <= ?", filter.CompetenciaFim)
+	}
+	if !filter.DataInicio.IsZero() {
+		query.Where("?TableAlias.data_emissao >= ?", filter.DataInicio)
+	}
+	if !filter.DataFim.IsZero() {
+		query.Where("?TableAlias.data_emissao < ?", filter.DataFim)
+	}
+	if filter.CNPJ != "" {
+		query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("?TableAlias.cnpj_emitente = ?", filter.CNPJ).
+				WhereOr("?TableAlias.cnpj_destinatario = ?", filter.CNPJ)
+		})
+	}
+	if filter.CNPJEmitente != "" {
+		query.Where("?TableAlias.cnpj_emitente = ?", filter.CNPJEmitente)
+	}
+	if filter.CNPJDestinatario != "" {
+		query.Where("?TableAlias.cnpj_destinatario = ?", filter.CNPJDestinatario)
+	}
+	if filter.EmpresaID != nil {
+		query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("?TableAlias.emitente_empresa_id = ?", *filter.EmpresaID).
+				WhereOr("?TableAlias.destinatario_empresa_id = ?", *filter.EmpresaID)
+		})
+	}
+	if filter.EmitenteEmpresaID != nil {
+		query.Where("?TableAlias.emitente_empresa_id = ?", *filter.EmitenteEmpresaID)
+	}
+	if filter.DestinatarioEmpresaID != nil {
+		query.Where("?TableAlias.destinatario_empresa_id = ?", *filter.DestinatarioEmpresaID)
+	}
+	if filter.ValorMin != nil {
+		query.Where("?TableAlias.valor_nota >= ?", *filter.ValorMin)
+	}
+	if filter.ValorMax != nil {
+		query.Where("?TableAlias.valor_nota <= ?", *filter.ValorMax)
+	}
+	if filter.ItemListaServico != "" {
+		query.Where("?TableAlias.item_lista_servico = ?", filter.ItemListaServico)
+	}
+	if filter.CodigoMunicipio != "" {
+		query.Where("?TableAlias.codigo_municipio = ?", filter.CodigoMunicipio)
+	}
 }
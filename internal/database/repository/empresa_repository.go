@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"time"
 	"zemdocs/internal/database/model"
 
 	"github.com/uptrace/bun"
@@ -15,16 +17,57 @@ func NewEmpresaRepository(db *bun.DB) *EmpresaRepository {
 	return &EmpresaRepository{db: db}
 }
 
-// Create cria uma nova empresa
+const empresaEscritorio = "?TableAlias.escritorio_id"
+
+// newSelect inicia uma consulta de empresas restrita ao escritório do contexto
+func (r *EmpresaRepository) newSelect(ctx context.Context) (*bun.SelectQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), empresaEscritorio)
+}
+
+// empresasVisiveis subconsulta com os IDs das empresas do escritório do
+// contexto, usada para restringir as tabelas filhas (atividades, membros etc.)
+func (r *EmpresaRepository) empresasVisiveis(ctx context.Context) (*bun.SelectQuery, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return query.Model((*model.Empresa)(nil)).Column("e.id"), nil
+}
+
+// checkEmpresa garante que a empresa pertence ao escritório do contexto antes
+// de gravar registros filhos; retorna sql.ErrNoRows se não pertencer
+func (r *EmpresaRepository) checkEmpresa(ctx context.Context, empresaID int) error {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return err
+	}
+	exists, err := query.Model((*model.Empresa)(nil)).Where("e.id = ?", empresaID).Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Create cria uma nova empresa no escritório do contexto
 func (r *EmpresaRepository) Create(ctx context.Context, empresa *model.Empresa) error {
-	_, err := r.db.NewInsert().Model(empresa).Exec(ctx)
+	if err := stampEscritorio(ctx, &empresa.EscritorioID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(empresa).Exec(ctx)
 	return err
 }
 
 // GetByCNPJ busca empresa por CNPJ
 func (r *EmpresaRepository) GetByCNPJ(ctx context.Context, cnpj string) (*model.Empresa, error) {
 	empresa := &model.Empresa{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(empresa).
 		Where("cnpj = ?", cnpj).
 		Scan(ctx)
@@ -37,7 +80,11 @@ func (r *EmpresaRepository) GetByCNPJ(ctx context.Context, cnpj string) (*model.
 // GetByID busca empresa por ID
 func (r *EmpresaRepository) GetByID(ctx context.Context, id int) (*model.Empresa, error) {
 	empresa := &model.Empresa{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(empresa).
 		Where("id = ?", id).
 		Scan(ctx)
@@ -50,7 +97,11 @@ func (r *EmpresaRepository) GetByID(ctx context.Context, id int) (*model.Empresa
 // GetAll lista todas as empresas com paginação
 func (r *EmpresaRepository) GetAll(ctx context.Context, limit, offset int) ([]*model.Empresa, error) {
 	var empresas []*model.Empresa
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(&empresas).
 		Order("razao_social ASC").
 		Limit(limit).
@@ -62,7 +113,11 @@ func (r *EmpresaRepository) GetAll(ctx context.Context, limit, offset int) ([]*m
 // Search busca empresas por termo (CNPJ, razão social ou nome fantasia)
 func (r *EmpresaRepository) Search(ctx context.Context, termo string, limit, offset int) ([]*model.Empresa, error) {
 	var empresas []*model.Empresa
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(&empresas).
 		Where("cnpj ILIKE ? OR razao_social ILIKE ? OR nome_fantasia ILIKE ?",
 			"%"+termo+"%", "%"+termo+"%", "%"+termo+"%").
@@ -75,26 +130,201 @@ func (r *EmpresaRepository) Search(ctx context.Context, termo string, limit, off
 
 // Update atualiza uma empresa
 func (r *EmpresaRepository) Update(ctx context.Context, empresa *model.Empresa) error {
-	_, err := r.db.NewUpdate().
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), empresaEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
 		Model(empresa).
+		ExcludeColumn("escritorio_id").
 		Where("id = ?", empresa.ID).
 		Exec(ctx)
 	return err
 }
 
-// Delete remove uma empresa
+// empresaChildren tabelas filhas, excluídas e restauradas junto com a empresa
+var empresaChildren = []interface{}{
+	(*model.AtividadeSecundaria)(nil),
+	(*model.EmpresaMembro)(nil),
+	(*model.EmpresaInscricaoEstadual)(nil),
+	(*model.EmpresaSuframa)(nil),
+	(*model.EmpresaTelefone)(nil),
+	(*model.EmpresaEmail)(nil),
+}
+
+// Delete exclui logicamente a empresa e seus registros filhos com o mesmo
+// deleted_at, para que a restauração traga de volta apenas o que saiu junto
 func (r *EmpresaRepository) Delete(ctx context.Context, id int) error {
-	_, err := r.db.NewDelete().
+	deletedAt := time.Now()
+	return conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query, err := whereEscritorio(ctx, tx.NewUpdate(), empresaEscritorio)
+		if err != nil {
+			return err
+		}
+		result, err := query.
+			Model((*model.Empresa)(nil)).
+			Set("deleted_at = ?", deletedAt).
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			return nil
+		}
+
+		for _, child := range empresaChildren {
+			_, err := tx.NewUpdate().
+				Model(child).
+				Set("deleted_at = ?", deletedAt).
+				Where("empresa_id = ?", id).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Restore restaura a empresa excluída logicamente e os registros filhos excluídos
+// com ela; retorna sql.ErrNoRows se não houver empresa excluída com o ID
+func (r *EmpresaRepository) Restore(ctx context.Context, id int) error {
+	return conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query, err := whereEscritorio(ctx, tx.NewSelect(), empresaEscritorio)
+		if err != nil {
+			return err
+		}
+		empresa := &model.Empresa{}
+		err = query.
+			Model(empresa).
+			WhereDeleted().
+			Where("id = ?", id).
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range empresaChildren {
+			_, err := tx.NewUpdate().
+				Model(child).
+				WhereDeleted().
+				Set("deleted_at = NULL").
+				Where("empresa_id = ?", id).
+				Where("deleted_at = ?", empresa.DeletedAt).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*model.Empresa)(nil)).
+			WhereDeleted().
+			Set("deleted_at = NULL").
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", id).
+			Exec(ctx)
+		return err
+	})
+}
+
+// Purge remove definitivamente a empresa já excluída logicamente; os registros
+// filhos saem em cascata e os documentos perdem o vínculo com ela
+func (r *EmpresaRepository) Purge(ctx context.Context, id int) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewDelete(), empresaEscritorio)
+	if err != nil {
+		return err
+	}
+	result, err := query.
 		Model((*model.Empresa)(nil)).
+		WhereDeleted().
+		ForceDelete().
 		Where("id = ?", id).
 		Exec(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDeletedByID busca uma empresa excluída logicamente
+func (r *EmpresaRepository) GetDeletedByID(ctx context.Context, id int) (*model.Empresa, error) {
+	empresa := &model.Empresa{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(empresa).
+		WhereDeleted().
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return empresa, nil
+}
+
+// GetDeletedByCNPJ busca uma empresa excluída logicamente pelo CNPJ
+func (r *EmpresaRepository) GetDeletedByCNPJ(ctx context.Context, cnpj string) (*model.Empresa, error) {
+	empresa := &model.Empresa{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(empresa).
+		WhereDeleted().
+		Where("cnpj = ?", cnpj).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return empresa, nil
+}
+
+// ListDeleted lista as empresas excluídas logicamente, mais recentes primeiro
+func (r *EmpresaRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*model.Empresa, error) {
+	var empresas []*model.Empresa
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&empresas).
+		WhereDeleted().
+		Order("deleted_at DESC", "id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+	return empresas, err
+}
+
+// CountDeleted conta as empresas excluídas logicamente
+func (r *EmpresaRepository) CountDeleted(ctx context.Context) (int, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return query.
+		Model((*model.Empresa)(nil)).
+		WhereDeleted().
+		Count(ctx)
 }
 
 // GetWithAtividades busca empresa com suas atividades secundárias
 func (r *EmpresaRepository) GetWithAtividades(ctx context.Context, id int) (*model.Empresa, error) {
 	empresa := &model.Empresa{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(empresa).
 		Where("e.id = ?", id).
 		Scan(ctx)
@@ -104,7 +334,7 @@ func (r *EmpresaRepository) GetWithAtividades(ctx context.Context, id int) (*mod
 
 	// Buscar atividades secundárias
 	var atividades []*model.AtividadeSecundaria
-	err = r.db.NewSelect().
+	err = conn(ctx, r.db).NewSelect().
 		Model(&atividades).
 		Where("empresa_id = ?", id).
 		Scan(ctx)
@@ -117,16 +347,24 @@ func (r *EmpresaRepository) GetWithAtividades(ctx context.Context, id int) (*mod
 
 // CreateAtividadeSecundaria cria uma atividade secundária
 func (r *EmpresaRepository) CreateAtividadeSecundaria(ctx context.Context, atividade *model.AtividadeSecundaria) error {
-	_, err := r.db.NewInsert().Model(atividade).Exec(ctx)
+	if err := r.checkEmpresa(ctx, atividade.EmpresaID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(atividade).Exec(ctx)
 	return err
 }
 
 // GetAtividadesByEmpresa busca atividades secundárias de uma empresa
 func (r *EmpresaRepository) GetAtividadesByEmpresa(ctx context.Context, empresaID int) ([]*model.AtividadeSecundaria, error) {
 	var atividades []*model.AtividadeSecundaria
-	err := r.db.NewSelect().
+	empresas, err := r.empresasVisiveis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = conn(ctx, r.db).NewSelect().
 		Model(&atividades).
 		Where("empresa_id = ?", empresaID).
+		Where("empresa_id IN (?)", empresas).
 		Order("codigo ASC").
 		Scan(ctx)
 	return atividades, err
@@ -134,16 +372,26 @@ func (r *EmpresaRepository) GetAtividadesByEmpresa(ctx context.Context, empresaI
 
 // DeleteAtividadesByEmpresa remove todas as atividades secundárias de uma empresa
 func (r *EmpresaRepository) DeleteAtividadesByEmpresa(ctx context.Context, empresaID int) error {
-	_, err := r.db.NewDelete().
+	empresas, err := r.empresasVisiveis(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).NewDelete().
 		Model((*model.AtividadeSecundaria)(nil)).
 		Where("empresa_id = ?", empresaID).
+		Where("empresa_id IN (?)", empresas).
+		ForceDelete().
 		Exec(ctx)
 	return err
 }
 
 // Count retorna o total de empresas
 func (r *EmpresaRepository) Count(ctx context.Context) (int, error) {
-	count, err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, err := query.
 		Model((*model.Empresa)(nil)).
 		Count(ctx)
 	return count, err
@@ -151,7 +399,11 @@ func (r *EmpresaRepository) Count(ctx context.Context) (int, error) {
 
 // CountBySearch retorna o total de empresas que correspondem ao termo de busca
 func (r *EmpresaRepository) CountBySearch(ctx context.Context, termo string) (int, error) {
-	count, err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, err := query.
 		Model((*model.Empresa)(nil)).
 		Where("cnpj ILIKE ? OR razao_social ILIKE ? OR nome_fantasia ILIKE ?",
 			"%"+termo+"%", "%"+termo+"%", "%"+termo+"%").
@@ -161,40 +413,60 @@ func (r *EmpresaRepository) CountBySearch(ctx context.Context, termo string) (in
 
 // CreateMembro cria um novo membro da empresa
 func (r *EmpresaRepository) CreateMembro(ctx context.Context, membro *model.EmpresaMembro) error {
-	_, err := r.db.NewInsert().Model(membro).Exec(ctx)
+	if err := r.checkEmpresa(ctx, membro.EmpresaID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(membro).Exec(ctx)
 	return err
 }
 
 // CreateInscricaoEstadual cria uma nova inscrição estadual
 func (r *EmpresaRepository) CreateInscricaoEstadual(ctx context.Context, inscricao *model.EmpresaInscricaoEstadual) error {
-	_, err := r.db.NewInsert().Model(inscricao).Exec(ctx)
+	if err := r.checkEmpresa(ctx, inscricao.EmpresaID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(inscricao).Exec(ctx)
 	return err
 }
 
 // CreateSuframa cria um novo registro SUFRAMA
 func (r *EmpresaRepository) CreateSuframa(ctx context.Context, suframa *model.EmpresaSuframa) error {
-	_, err := r.db.NewInsert().Model(suframa).Exec(ctx)
+	if err := r.checkEmpresa(ctx, suframa.EmpresaID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(suframa).Exec(ctx)
 	return err
 }
 
 // CreateTelefone cria um novo telefone da empresa
 func (r *EmpresaRepository) CreateTelefone(ctx context.Context, telefone *model.EmpresaTelefone) error {
-	_, err := r.db.NewInsert().Model(telefone).Exec(ctx)
+	if err := r.checkEmpresa(ctx, telefone.EmpresaID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(telefone).Exec(ctx)
 	return err
 }
 
 // CreateEmail cria um novo email da empresa
 func (r *EmpresaRepository) CreateEmail(ctx context.Context, email *model.EmpresaEmail) error {
-	_, err := r.db.NewInsert().Model(email).Exec(ctx)
+	if err := r.checkEmpresa(ctx, email.EmpresaID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(email).Exec(ctx)
 	return err
 }
 
 // GetInscricoesEstaduaisByEmpresa busca inscrições estaduais de uma empresa
 func (r *EmpresaRepository) GetInscricoesEstaduaisByEmpresa(ctx context.Context, empresaID int) ([]*model.EmpresaInscricaoEstadual, error) {
 	var inscricoes []*model.EmpresaInscricaoEstadual
-	err := r.db.NewSelect().
+	empresas, err := r.empresasVisiveis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = conn(ctx, r.db).NewSelect().
 		Model(&inscricoes).
 		Where("empresa_id = ?", empresaID).
+		Where("empresa_id IN (?)", empresas).
 		Order("estado ASC, numero ASC").
 		Scan(ctx)
 	return inscricoes, err
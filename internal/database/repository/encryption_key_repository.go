@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+// EncryptionKeyRepository guarda as chaves de dados cifradas usadas pelo armazenamento
+type EncryptionKeyRepository struct {
+	db *bun.DB
+}
+
+func NewEncryptionKeyRepository(db *bun.DB) *EncryptionKeyRepository {
+	return &EncryptionKeyRepository{db: db}
+}
+
+// GetCurrent retorna a versão mais recente da chave do tenant
+func (r *EncryptionKeyRepository) GetCurrent(ctx context.Context, tenant string) (*model.EncryptionKey, error) {
+	key := &model.EncryptionKey{}
+	err := r.db.NewSelect().
+		Model(key).
+		Where("tenant = ?", tenant).
+		Order("version DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetByVersion busca uma versão específica da chave do tenant
+func (r *EncryptionKeyRepository) GetByVersion(ctx context.Context, tenant string, version int) (*model.EncryptionKey, error) {
+	key := &model.EncryptionKey{}
+	err := r.db.NewSelect().
+		Model(key).
+		Where("tenant = ?", tenant).
+		Where("version = ?", version).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// CreateIfAbsent grava a chave se a versão ainda não existir. Retorna false se
+// outra réplica gravou a mesma versão antes; nesse caso a chave gravada prevalece.
+func (r *EncryptionKeyRepository) CreateIfAbsent(ctx context.Context, key *model.EncryptionKey) (bool, error) {
+	result, err := r.db.NewInsert().
+		Model(key).
+		On("CONFLICT (tenant, version) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// List lista todas as chaves
+func (r *EncryptionKeyRepository) List(ctx context.Context) ([]*model.EncryptionKey, error) {
+	var keys []*model.EncryptionKey
+	err := r.db.NewSelect().
+		Model(&keys).
+		Order("tenant ASC", "version ASC").
+		Scan(ctx)
+	return keys, err
+}
+
+// UpdateWrappedKey regrava a chave cifrada (rotação da chave mestra)
+func (r *EncryptionKeyRepository) UpdateWrappedKey(ctx context.Context, key *model.EncryptionKey) error {
+	_, err := r.db.NewUpdate().
+		Model(key).
+		Column("master_key_id", "wrapped_key", "updated_at").
+		WherePK().
+		Exec(ctx)
+	return err
+}
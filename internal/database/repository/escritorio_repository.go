@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type EscritorioRepository struct {
+	db *bun.DB
+}
+
+func NewEscritorioRepository(db *bun.DB) *EscritorioRepository {
+	return &EscritorioRepository{db: db}
+}
+
+// newSelect inicia uma consulta de escritórios; um escritório só enxerga a si mesmo
+func (r *EscritorioRepository) newSelect(ctx context.Context) (*bun.SelectQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), "?TableAlias.id")
+}
+
+// Create cadastra um escritório
+func (r *EscritorioRepository) Create(ctx context.Context, escritorio *model.Escritorio) error {
+	_, err := conn(ctx, r.db).NewInsert().Model(escritorio).Exec(ctx)
+	return err
+}
+
+// GetByID busca um escritório por ID
+func (r *EscritorioRepository) GetByID(ctx context.Context, id int64) (*model.Escritorio, error) {
+	escritorio := &model.Escritorio{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(escritorio).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return escritorio, nil
+}
+
+// GetPadrao busca o escritório mais antigo, criado pela migração de
+// multi-tenancy para receber os dados existentes
+func (r *EscritorioRepository) GetPadrao(ctx context.Context) (*model.Escritorio, error) {
+	escritorio := &model.Escritorio{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(escritorio).
+		Order("id ASC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return escritorio, nil
+}
+
+// ExistsByCNPJ verifica se já existe escritório com o CNPJ (em todos os escritórios)
+func (r *EscritorioRepository) ExistsByCNPJ(ctx context.Context, cnpj string, exceptID int64) (bool, error) {
+	return r.db.NewSelect().
+		Model((*model.Escritorio)(nil)).
+		Where("cnpj = ?", cnpj).
+		Where("id <> ?", exceptID).
+		Exists(ctx)
+}
+
+// List lista os escritórios
+func (r *EscritorioRepository) List(ctx context.Context) ([]*model.Escritorio, error) {
+	var escritorios []*model.Escritorio
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&escritorios).
+		Order("nome ASC").
+		Scan(ctx)
+	return escritorios, err
+}
+
+// Update atualiza um escritório
+func (r *EscritorioRepository) Update(ctx context.Context, escritorio *model.Escritorio) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), "?TableAlias.id")
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model(escritorio).
+		WherePK().
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type ExportRepository struct {
+	db *bun.DB
+}
+
+func NewExportRepository(db *bun.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+const exportEscritorio = "?TableAlias.escritorio_id"
+
+// Create registra uma nova exportação no escritório do contexto
+func (r *ExportRepository) Create(ctx context.Context, export *model.Export) error {
+	if err := stampEscritorio(ctx, &export.EscritorioID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(export).Exec(ctx)
+	return err
+}
+
+// GetByID busca uma exportação por ID
+func (r *ExportRepository) GetByID(ctx context.Context, id int64) (*model.Export, error) {
+	export := &model.Export{}
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), exportEscritorio)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(export).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// UpdateColumns atualiza apenas as colunas informadas (e updated_at)
+func (r *ExportRepository) UpdateColumns(ctx context.Context, export *model.Export, columns ...string) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), exportEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model(export).
+		Column(append(columns, "updated_at")...).
+		WherePK().
+		Exec(ctx)
+	return err
+}
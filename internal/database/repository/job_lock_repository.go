@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type JobLockRepository struct {
+	db *bun.DB
+}
+
+func NewJobLockRepository(db *bun.DB) *JobLockRepository {
+	return &JobLockRepository{db: db}
+}
+
+// Upsert registra o detentor atual de um lock
+func (r *JobLockRepository) Upsert(ctx context.Context, lock *model.JobLock) error {
+	_, err := r.db.NewInsert().
+		Model(lock).
+		On("CONFLICT (name) DO UPDATE").
+		Set("lock_key = EXCLUDED.lock_key").
+		Set("holder = EXCLUDED.holder").
+		Set("acquired_at = EXCLUDED.acquired_at").
+		Set("expires_at = EXCLUDED.expires_at").
+		Exec(ctx)
+	return err
+}
+
+// Delete remove o registro de um lock, desde que pertença ao detentor informado
+func (r *JobLockRepository) Delete(ctx context.Context, name, holder string) error {
+	_, err := r.db.NewDelete().
+		Model((*model.JobLock)(nil)).
+		Where("name = ?", name).
+		Where("holder = ?", holder).
+		Exec(ctx)
+	return err
+}
+
+// List lista os locks registrados, indicando se o advisory lock ainda está ativo
+func (r *JobLockRepository) List(ctx context.Context) ([]*model.JobLock, error) {
+	var locks []*model.JobLock
+	err := r.db.NewSelect().
+		Model(&locks).
+		ColumnExpr("jl.*").
+		ColumnExpr(`EXISTS (
+			SELECT 1 FROM pg_locks pl
+			WHERE pl.locktype = 'advisory' AND pl.granted AND pl.objsubid = 1
+			AND ((pl.classid::bigint << 32) | pl.objid::bigint) = jl.lock_key
+		) AS active`).
+		Order("jl.name ASC").
+		Scan(ctx)
+	return locks, err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type JobRunRepository struct {
+	db *bun.DB
+}
+
+func NewJobRunRepository(db *bun.DB) *JobRunRepository {
+	return &JobRunRepository{db: db}
+}
+
+// Create registra o início de uma execução
+func (r *JobRunRepository) Create(ctx context.Context, run *model.JobRun) error {
+	_, err := r.db.NewInsert().Model(run).Exec(ctx)
+	return err
+}
+
+// Update grava o estado atual de uma execução (status, contadores e logs). O
+// pedido de cancelamento é gravado apenas por RequestCancel.
+func (r *JobRunRepository) Update(ctx context.Context, run *model.JobRun) error {
+	_, err := r.db.NewUpdate().
+		Model(run).
+		ExcludeColumn("cancel_requested_at").
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+// RequestCancel registra o pedido de cancelamento de uma execução em andamento;
+// retorna false se a execução não existe ou já terminou
+func (r *JobRunRepository) RequestCancel(ctx context.Context, id string) (bool, error) {
+	result, err := r.db.NewUpdate().
+		Model((*model.JobRun)(nil)).
+		Set("cancel_requested_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("status = ?", model.JobRunStatusExecutando).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// CancelRequested informa se o cancelamento da execução foi pedido (por qualquer réplica)
+func (r *JobRunRepository) CancelRequested(ctx context.Context, id string) (bool, error) {
+	return r.db.NewSelect().
+		Model((*model.JobRun)(nil)).
+		Where("id = ?", id).
+		Where("cancel_requested_at IS NOT NULL").
+		Exists(ctx)
+}
+
+// GetByID busca uma execução por ID
+func (r *JobRunRepository) GetByID(ctx context.Context, id string) (*model.JobRun, error) {
+	run := &model.JobRun{}
+	err := r.db.NewSelect().
+		Model(run).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// ListByJob lista as execuções mais recentes de um job (sem os logs)
+func (r *JobRunRepository) ListByJob(ctx context.Context, jobName string, limit int) ([]*model.JobRun, error) {
+	var runs []*model.JobRun
+	err := r.db.NewSelect().
+		Model(&runs).
+		ExcludeColumn("logs").
+		Where("job_name = ?", jobName).
+		Order("started_at DESC").
+		Limit(limit).
+		Scan(ctx)
+	return runs, err
+}
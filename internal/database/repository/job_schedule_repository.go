@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type JobScheduleRepository struct {
+	db *bun.DB
+}
+
+func NewJobScheduleRepository(db *bun.DB) *JobScheduleRepository {
+	return &JobScheduleRepository{db: db}
+}
+
+const jobScheduleEscritorio = "?TableAlias.escritorio_id"
+
+// newSelect inicia uma consulta de agendamentos restrita ao escritório do contexto
+func (r *JobScheduleRepository) newSelect(ctx context.Context) (*bun.SelectQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), jobScheduleEscritorio)
+}
+
+// Create cria um novo agendamento no escritório do contexto
+func (r *JobScheduleRepository) Create(ctx context.Context, schedule *model.JobSchedule) error {
+	if err := stampEscritorio(ctx, &schedule.EscritorioID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(schedule).Exec(ctx)
+	return err
+}
+
+// GetByID busca um agendamento por ID
+func (r *JobScheduleRepository) GetByID(ctx context.Context, id int64) (*model.JobSchedule, error) {
+	schedule := &model.JobSchedule{}
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(schedule).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ExistsByName verifica se já existe agendamento com o nome informado. O nome
+// é único na tabela inteira, por isso a verificação não é restrita ao escritório.
+func (r *JobScheduleRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
+	return r.db.NewSelect().
+		Model((*model.JobSchedule)(nil)).
+		Where("name = ?", name).
+		Exists(ctx)
+}
+
+// ExistsByType verifica se já existe agendamento do tipo informado, em qualquer escritório
+func (r *JobScheduleRepository) ExistsByType(ctx context.Context, scheduleType string) (bool, error) {
+	return r.db.NewSelect().
+		Model((*model.JobSchedule)(nil)).
+		Where("type = ?", scheduleType).
+		Exists(ctx)
+}
+
+// List lista todos os agendamentos
+func (r *JobScheduleRepository) List(ctx context.Context) ([]*model.JobSchedule, error) {
+	var schedules []*model.JobSchedule
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Model(&schedules).
+		Order("id ASC").
+		Scan(ctx)
+	return schedules, err
+}
+
+// SetEnabled habilita ou desabilita (pausa) um agendamento. O updated_at é
+// alterado para que as demais réplicas percebam a mudança na próxima carga.
+func (r *JobScheduleRepository) SetEnabled(ctx context.Context, id int64, enabled bool) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), jobScheduleEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model((*model.JobSchedule)(nil)).
+		Set("enabled = ?", enabled).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// IsEnabled informa se o agendamento está habilitado
+func (r *JobScheduleRepository) IsEnabled(ctx context.Context, id int64) (bool, error) {
+	var enabled bool
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return false, err
+	}
+	err = query.
+		Model((*model.JobSchedule)(nil)).
+		Column("enabled").
+		Where("id = ?", id).
+		Scan(ctx, &enabled)
+	return enabled, err
+}
+
+// Count conta os agendamentos cadastrados
+func (r *JobScheduleRepository) Count(ctx context.Context) (int, error) {
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return query.Model((*model.JobSchedule)(nil)).Count(ctx)
+}
+
+// Update atualiza um agendamento
+func (r *JobScheduleRepository) Update(ctx context.Context, schedule *model.JobSchedule) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), jobScheduleEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model(schedule).
+		ExcludeColumn("escritorio_id").
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+// Delete remove um agendamento
+func (r *JobScheduleRepository) Delete(ctx context.Context, id int64) error {
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewDelete(), jobScheduleEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
+		Model((*model.JobSchedule)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/tenant"
+)
+
+// AuditLogRepository trilha de auditoria das alterações (somente inserção)
+type AuditLogRepository struct {
+	db *DB
+}
+
+func NewAuditLogRepository(db *DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// RunInTx executa fn e, se ela retornar erro, desfaz as alterações feitas por
+// todos os repositórios do mesmo DB, inclusive os registros de auditoria
+func (r *AuditLogRepository) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.runInTx(ctx, fn)
+}
+
+// Create grava o registro no escritório do contexto
+func (r *AuditLogRepository) Create(ctx context.Context, log *model.AuditLog) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	stampEscritorio(scope, &log.EscritorioID)
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	log.ID = r.db.nextID("audit_logs")
+	log.CreatedAt = time.Now()
+	r.db.auditLogs = append(r.db.auditLogs, *log)
+	return nil
+}
+
+// List lista os registros que atendem aos filtros, mais recentes primeiro, com ID
+// menor que beforeID (0 para a primeira página)
+func (r *AuditLogRepository) List(ctx context.Context, filter *model.AuditFilter, beforeID int64, limit int) ([]*model.AuditLog, error) {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Os registros são gravados em ordem de ID
+	var logs []*model.AuditLog
+	for i := len(r.db.auditLogs) - 1; i >= 0 && (limit <= 0 || len(logs) < limit); i-- {
+		log := r.db.auditLogs[i]
+		if visible(scope, log.EscritorioID) && (beforeID <= 0 || log.ID < beforeID) && matchAuditFilter(&log, filter) {
+			logs = append(logs, &log)
+		}
+	}
+	return logs, nil
+}
+
+// matchAuditFilter aplica os filtros da consulta à auditoria
+func matchAuditFilter(log *model.AuditLog, filter *model.AuditFilter) bool {
+	return (filter.Entity == "" || log.Entity == filter.Entity) &&
+		(filter.EntityID == nil || log.EntityID == *filter.EntityID) &&
+		(filter.Action == "" || log.Action == filter.Action) &&
+		(filter.Actor == "" || log.Actor == filter.Actor) &&
+		(filter.DataInicio.IsZero() || !log.CreatedAt.Before(filter.DataInicio)) &&
+		(filter.DataFim.IsZero() || log.CreatedAt.Before(filter.DataFim))
+}
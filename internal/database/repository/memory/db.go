@@ -0,0 +1,188 @@
+// Package memory implementa em memória os repositórios usados pelos serviços
+// (service.DocumentRepository, service.EmpresaRepository etc.), para testar
+// serviços e jobs sem Postgres. Segue as regras dos repositórios do banco: dados
+// restritos ao escritório do contexto, exclusão lógica e sql.ErrNoRows quando o
+// registro não existe. Restrições de unicidade do banco não são verificadas.
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/service"
+	"zemdocs/internal/tenant"
+)
+
+// DB banco em memória compartilhado pelos repositórios. Os registros são
+// guardados por valor e devolvidos como cópias, como se viessem do banco.
+type DB struct {
+	mu sync.Mutex
+
+	documents  map[int]model.Document
+	outbox     map[int64]model.StorageOutbox
+	orphans    map[string]model.StorageOrphan
+	downloads  []model.DocumentDownload
+	empresas   map[int]model.Empresa
+	atividades map[int]model.AtividadeSecundaria
+	membros    map[int]model.EmpresaMembro
+	inscricoes map[int]model.EmpresaInscricaoEstadual
+	suframa    map[int]model.EmpresaSuframa
+	telefones  map[int]model.EmpresaTelefone
+	emails     map[int]model.EmpresaEmail
+	users      map[int]model.User
+	auditLogs  []model.AuditLog
+
+	// Último ID gerado por tabela (equivalente às sequences)
+	seq map[string]int64
+}
+
+func NewDB() *DB {
+	return &DB{
+		documents:  make(map[int]model.Document),
+		outbox:     make(map[int64]model.StorageOutbox),
+		orphans:    make(map[string]model.StorageOrphan),
+		empresas:   make(map[int]model.Empresa),
+		atividades: make(map[int]model.AtividadeSecundaria),
+		membros:    make(map[int]model.EmpresaMembro),
+		inscricoes: make(map[int]model.EmpresaInscricaoEstadual),
+		suframa:    make(map[int]model.EmpresaSuframa),
+		telefones:  make(map[int]model.EmpresaTelefone),
+		emails:     make(map[int]model.EmpresaEmail),
+		users:      make(map[int]model.User),
+		seq:        make(map[string]int64),
+	}
+}
+
+// nextID gera o próximo ID da tabela; chamado com o lock adquirido
+func (db *DB) nextID(table string) int64 {
+	db.seq[table]++
+	return db.seq[table]
+}
+
+// snapshot copia o estado das tabelas para desfazer uma transação
+func (db *DB) snapshot() *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return &DB{
+		documents:  maps.Clone(db.documents),
+		outbox:     maps.Clone(db.outbox),
+		orphans:    maps.Clone(db.orphans),
+		downloads:  slices.Clone(db.downloads),
+		empresas:   maps.Clone(db.empresas),
+		atividades: maps.Clone(db.atividades),
+		membros:    maps.Clone(db.membros),
+		inscricoes: maps.Clone(db.inscricoes),
+		suframa:    maps.Clone(db.suframa),
+		telefones:  maps.Clone(db.telefones),
+		emails:     maps.Clone(db.emails),
+		users:      maps.Clone(db.users),
+		auditLogs:  slices.Clone(db.auditLogs),
+	}
+}
+
+// restore volta as tabelas ao estado do snapshot. As sequences não voltam,
+// como no Postgres.
+func (db *DB) restore(s *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.documents = s.documents
+	db.outbox = s.outbox
+	db.orphans = s.orphans
+	db.downloads = s.downloads
+	db.empresas = s.empresas
+	db.atividades = s.atividades
+	db.membros = s.membros
+	db.inscricoes = s.inscricoes
+	db.suframa = s.suframa
+	db.telefones = s.telefones
+	db.emails = s.emails
+	db.users = s.users
+	db.auditLogs = s.auditLogs
+}
+
+// runInTx executa fn e desfaz suas alterações se ela retornar erro. Não há
+// isolamento entre transações concorrentes: o banco em memória serve a testes
+// que executam as operações em sequência.
+func (db *DB) runInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	s := db.snapshot()
+	if err := fn(ctx); err != nil {
+		db.restore(s)
+		return err
+	}
+	return nil
+}
+
+// deletedFilter equivalente às cláusulas de exclusão lógica do bun
+type deletedFilter int
+
+const (
+	withoutDeleted deletedFilter = iota // Padrão: apenas os registros não excluídos
+	onlyDeleted                         // WhereDeleted
+	allWithDeleted                      // WhereAllWithDeleted
+)
+
+func (f deletedFilter) match(deletedAt time.Time) bool {
+	switch f {
+	case onlyDeleted:
+		return !deletedAt.IsZero()
+	case allWithDeleted:
+		return true
+	default:
+		return deletedAt.IsZero()
+	}
+}
+
+// visible informa se o registro pertence ao escopo: no acesso de sistema todos
+// os registros são visíveis
+func visible(scope tenant.Scope, escritorioID *int64) bool {
+	return scope.System || (escritorioID != nil && *escritorioID == scope.EscritorioID)
+}
+
+// stampEscritorio atribui ao registro o escritório do escopo. No acesso de
+// sistema mantém o escritório informado pelo chamador.
+func stampEscritorio(scope tenant.Scope, escritorioID **int64) {
+	if !scope.System {
+		id := scope.EscritorioID
+		*escritorioID = &id
+	}
+}
+
+// page aplica limit e offset à lista já ordenada; limit 0 não limita, como o
+// Limit do bun
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// sameEscritorio compara escritórios como IS NOT DISTINCT FROM
+func sameEscritorio(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// all filtro que aceita todos os registros
+func all[T any](*T) bool {
+	return true
+}
+
+// Os repositórios em memória substituem os do Postgres nos serviços
+var (
+	_ service.DocumentRepository         = (*DocumentRepository)(nil)
+	_ service.DocumentDownloadRepository = (*DocumentDownloadRepository)(nil)
+	_ service.EmpresaRepository          = (*EmpresaRepository)(nil)
+	_ service.UserRepository             = (*UserRepository)(nil)
+	_ service.AuditLogRepository         = (*AuditLogRepository)(nil)
+	_ service.StorageOutboxRepository    = (*StorageOutboxRepository)(nil)
+	_ service.StorageOrphanRepository    = (*StorageOrphanRepository)(nil)
+)
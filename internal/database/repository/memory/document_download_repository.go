@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+)
+
+// DocumentDownloadRepository auditoria dos acessos ao XML dos documentos
+type DocumentDownloadRepository struct {
+	db *DB
+}
+
+func NewDocumentDownloadRepository(db *DB) *DocumentDownloadRepository {
+	return &DocumentDownloadRepository{db: db}
+}
+
+// Create registra um acesso
+func (r *DocumentDownloadRepository) Create(ctx context.Context, download *model.DocumentDownload) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	download.ID = r.db.nextID("document_downloads")
+	download.CreatedAt = time.Now()
+	r.db.downloads = append(r.db.downloads, *download)
+	return nil
+}
@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+
+	"github.com/shopspring/decimal"
+)
+
+// List lista os documentos que atendem aos filtros na ordenação pedida, a partir
+// do cursor, com o ID como desempate
+func (r *DocumentRepository) List(ctx context.Context, req *model.DocumentListRequest) ([]*model.Document, error) {
+	if !req.Sort.Valid() {
+		return nil, fmt.Errorf("ordenação inválida: %s", req.Sort.Column)
+	}
+
+	column := req.Sort.Column
+	compare := func(value string, id int, d *model.Document) int {
+		c := cmp.Or(compareSortValue(column, value, d.SortValue(column)), cmp.Compare(id, d.ID))
+		if req.Sort.Desc {
+			return -c
+		}
+		return c
+	}
+
+	documents, err := r.selectDocuments(ctx, withoutDeleted, func(d *model.Document) bool {
+		if !matchDocumentFilter(d, &req.DocumentFilter) {
+			return false
+		}
+		return req.Cursor == nil || compare(req.Cursor.Value, req.Cursor.ID, d) < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(documents, func(a, b *model.Document) int {
+		return compare(a.SortValue(column), a.ID, b)
+	})
+	return page(documents, req.Limit, 0), nil
+}
+
+// compareSortValue compara dois valores de SortValue segundo o tipo da coluna
+func compareSortValue(column, a, b string) int {
+	switch column {
+	case "data_emissao", "created_at":
+		ta, errA := time.Parse(time.RFC3339Nano, a)
+		tb, errB := time.Parse(time.RFC3339Nano, b)
+		if errA == nil && errB == nil {
+			return ta.Compare(tb)
+		}
+	case "valor_nota", "valor_iss":
+		da, errA := decimal.NewFromString(a)
+		db, errB := decimal.NewFromString(b)
+		if errA == nil && errB == nil {
+			return da.Cmp(db)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// matchDocumentFilter aplica os filtros comuns às listagens e à busca
+func matchDocumentFilter(d *model.Document, filter *model.DocumentFilter) bool {
+	switch {
+	case len(filter.DocumentTypes) > 0 && !slices.Contains(filter.DocumentTypes, d.DocumentType):
+		return false
+	case len(filter.Status) > 0 && !slices.Contains(filter.Status, d.Status):
+		return false
+	case filter.CompetenciaInicio != "" && d.Competencia < filter.CompetenciaInicio:
+		return false
+	case filter.CompetenciaFim != "" && d.Competencia > filter.CompetenciaFim:
+		return false
+	case !filter.DataInicio.IsZero() && d.DataEmissao.Before(filter.DataInicio):
+		return false
+	case !filter.DataFim.IsZero() && !d.DataEmissao.Before(filter.DataFim):
+		return false
+	case filter.CNPJ != "" && d.CNPJEmitente != filter.CNPJ && d.CNPJDestinatario != filter.CNPJ:
+		return false
+	case filter.CNPJEmitente != "" && d.CNPJEmitente != filter.CNPJEmitente:
+		return false
+	case filter.CNPJDestinatario != "" && d.CNPJDestinatario != filter.CNPJDestinatario:
+		return false
+	case filter.EmpresaID != nil && !sameEmpresa(d.EmitenteEmpresaID, *filter.EmpresaID) && !sameEmpresa(d.DestinatarioEmpresaID, *filter.EmpresaID):
+		return false
+	case filter.EmitenteEmpresaID != nil && !sameEmpresa(d.EmitenteEmpresaID, *filter.EmitenteEmpresaID):
+		return false
+	case filter.DestinatarioEmpresaID != nil && !sameEmpresa(d.DestinatarioEmpresaID, *filter.DestinatarioEmpresaID):
+		return false
+	case filter.ValorMin != nil && d.ValorNota.LessThan(*filter.ValorMin):
+		return false
+	case filter.ValorMax != nil && d.ValorNota.GreaterThan(*filter.ValorMax):
+		return false
+	case filter.ItemListaServico != "" && d.ItemListaServico != filter.ItemListaServico:
+		return false
+	case filter.CodigoMunicipio != "" && d.CodigoMunicipio != filter.CodigoMunicipio:
+		return false
+	}
+	return true
+}
+
+func sameEmpresa(linked *int, id int) bool {
+	return linked != nil && *linked == id
+}
+
+// ListForExport lista, a partir de um cursor de ID, os documentos que atendem aos filtros da exportação
+func (r *DocumentRepository) ListForExport(ctx context.Context, filter *model.ExportFilter, afterID, limit int) ([]*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, withoutDeleted, func(d *model.Document) bool {
+		return d.ID > afterID && matchExportFilter(d, filter)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page(documents, limit, 0), nil
+}
+
+// CountForExport conta os documentos que atendem aos filtros da exportação
+func (r *DocumentRepository) CountForExport(ctx context.Context, filter *model.ExportFilter) (int, error) {
+	documents, err := r.selectDocuments(ctx, withoutDeleted, func(d *model.Document) bool { return matchExportFilter(d, filter) })
+	return len(documents), err
+}
+
+func matchExportFilter(d *model.Document, filter *model.ExportFilter) bool {
+	switch filter.Direcao {
+	case model.ExportDirecaoEmitidos:
+		if d.CNPJEmitente != filter.CNPJ {
+			return false
+		}
+	case model.ExportDirecaoRecebidos:
+		if d.CNPJDestinatario != filter.CNPJ {
+			return false
+		}
+	default:
+		if d.CNPJEmitente != filter.CNPJ && d.CNPJDestinatario != filter.CNPJ {
+			return false
+		}
+	}
+
+	switch {
+	case filter.CompetenciaInicio != "" && d.Competencia < filter.CompetenciaInicio:
+		return false
+	case filter.CompetenciaFim != "" && d.Competencia > filter.CompetenciaFim:
+		return false
+	case len(filter.DocumentTypes) > 0 && !slices.Contains(filter.DocumentTypes, d.DocumentType):
+		return false
+	}
+	return true
+}
+
+// Search busca documentos pelo termo, em ordem de relevância, com os trechos
+// destacados. Aproxima a busca textual do Postgres: cada palavra do termo deve
+// aparecer (sem diferenciar maiúsculas) e as precedidas de "-" não podem
+// aparecer; não há radicais, sinônimos nem remoção de acentos.
+func (r *DocumentRepository) Search(ctx context.Context, filter *model.DocumentSearchFilter) ([]*model.DocumentSearchHit, error) {
+	documents, err := r.searchDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	incluir, _ := searchTerms(filter.Query)
+	hits := make([]*model.DocumentSearchHit, 0, len(documents))
+	for _, document := range documents {
+		hits = append(hits, &model.DocumentSearchHit{
+			Document:              *document,
+			Rank:                  float64(countTerms(searchText(document), incluir)),
+			DestaqueDiscriminacao: highlight(document.Discriminacao, incluir),
+			DestaqueEmitente:      highlight(document.RazaoSocialEmitente, incluir),
+			DestaqueDestinatario:  highlight(document.RazaoSocialDestinatario, incluir),
+		})
+	}
+	slices.SortStableFunc(hits, func(a, b *model.DocumentSearchHit) int {
+		return cmp.Or(cmp.Compare(b.Rank, a.Rank), b.DataEmissao.Compare(a.DataEmissao), cmp.Compare(b.ID, a.ID))
+	})
+	return page(hits, filter.Limit, filter.Offset), nil
+}
+
+// CountSearch conta os documentos encontrados pela busca
+func (r *DocumentRepository) CountSearch(ctx context.Context, filter *model.DocumentSearchFilter) (int, error) {
+	documents, err := r.searchDocuments(ctx, filter)
+	return len(documents), err
+}
+
+func (r *DocumentRepository) searchDocuments(ctx context.Context, filter *model.DocumentSearchFilter) ([]*model.Document, error) {
+	incluir, excluir := searchTerms(filter.Query)
+	return r.selectDocuments(ctx, withoutDeleted, func(d *model.Document) bool {
+		if !matchDocumentFilter(d, &filter.DocumentFilter) {
+			return false
+		}
+		text := searchText(d)
+		for _, term := range incluir {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+		for _, term := range excluir {
+			if strings.Contains(text, term) {
+				return false
+			}
+		}
+		return len(incluir) > 0
+	})
+}
+
+// searchTerms separa as palavras do termo em obrigatórias e excluídas, em minúsculas
+func searchTerms(query string) (incluir, excluir []string) {
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(query, `"`, " "))) {
+		switch {
+		case word == "or":
+		case strings.HasPrefix(word, "-"):
+			if word = strings.TrimPrefix(word, "-"); word != "" {
+				excluir = append(excluir, word)
+			}
+		default:
+			incluir = append(incluir, word)
+		}
+	}
+	return incluir, excluir
+}
+
+// searchText campos indexados em search_vector, em minúsculas
+func searchText(d *model.Document) string {
+	return strings.ToLower(strings.Join([]string{
+		d.NumeroDocumento, d.CodigoVerificacao, d.CNPJEmitente, d.CNPJDestinatario,
+		d.RazaoSocialEmitente, d.RazaoSocialDestinatario, d.Discriminacao,
+	}, " "))
+}
+
+func countTerms(text string, terms []string) int {
+	total := 0
+	for _, term := range terms {
+		total += strings.Count(text, term)
+	}
+	return total
+}
+
+// highlight delimita as ocorrências dos termos como o ts_headline do repositório do banco
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Minúsculas com outro tamanho em bytes: as posições não correspondem
+		return text
+	}
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				marked[j] = true
+			}
+			start += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString(repository.SearchMarkStart)
+		}
+		b.WriteByte(text[i])
+		if marked[i] && (i == len(text)-1 || !marked[i+1]) {
+			b.WriteString(repository.SearchMarkStop)
+		}
+	}
+	return b.String()
+}
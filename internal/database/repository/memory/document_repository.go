@@ -0,0 +1,357 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/tenant"
+)
+
+type DocumentRepository struct {
+	db *DB
+}
+
+func NewDocumentRepository(db *DB) *DocumentRepository {
+	return &DocumentRepository{db: db}
+}
+
+// selectDocuments lista, em ordem de ID, cópias dos documentos do escritório do
+// contexto que atendem ao filtro
+func (r *DocumentRepository) selectDocuments(ctx context.Context, filter deletedFilter, where func(*model.Document) bool) ([]*model.Document, error) {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var documents []*model.Document
+	for _, document := range r.db.documents {
+		if visible(scope, document.EscritorioID) && filter.match(document.DeletedAt) && where(&document) {
+			documents = append(documents, &document)
+		}
+	}
+	slices.SortFunc(documents, func(a, b *model.Document) int { return cmp.Compare(a.ID, b.ID) })
+	return documents, nil
+}
+
+// updateDocuments aplica set aos documentos do escritório do contexto que atendem
+// ao filtro e retorna quantos foram alterados
+func (r *DocumentRepository) updateDocuments(ctx context.Context, filter deletedFilter, where func(*model.Document) bool, set func(*model.Document)) (int, error) {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	updated := 0
+	for id, document := range r.db.documents {
+		if visible(scope, document.EscritorioID) && filter.match(document.DeletedAt) && where(&document) {
+			set(&document)
+			r.db.documents[id] = document
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// getDocument retorna o primeiro documento que atende ao filtro ou sql.ErrNoRows
+func (r *DocumentRepository) getDocument(ctx context.Context, filter deletedFilter, where func(*model.Document) bool) (*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, filter, where)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return documents[0], nil
+}
+
+// GetByID busca documento por ID
+func (r *DocumentRepository) GetByID(ctx context.Context, id int) (*model.Document, error) {
+	return r.getDocument(ctx, withoutDeleted, func(d *model.Document) bool { return d.ID == id })
+}
+
+// GetByNumeroNfse busca documento por número
+func (r *DocumentRepository) GetByNumeroNfse(ctx context.Context, numeroNfse string) (*model.Document, error) {
+	return r.getDocument(ctx, withoutDeleted, func(d *model.Document) bool { return d.NumeroDocumento == numeroNfse })
+}
+
+// GetByNumeroDocumento busca documento por número
+func (r *DocumentRepository) GetByNumeroDocumento(ctx context.Context, numeroDocumento string) (*model.Document, error) {
+	return r.getDocument(ctx, withoutDeleted, func(d *model.Document) bool { return d.NumeroDocumento == numeroDocumento })
+}
+
+// ExistsByNumeroNfse verifica se um documento já existe pelo número, inclusive
+// entre os excluídos
+func (r *DocumentRepository) ExistsByNumeroNfse(ctx context.Context, numeroNfse string) (bool, error) {
+	documents, err := r.selectDocuments(ctx, allWithDeleted, func(d *model.Document) bool { return d.NumeroDocumento == numeroNfse })
+	return len(documents) > 0, err
+}
+
+// Create cria um novo documento no escritório do contexto
+func (r *DocumentRepository) Create(ctx context.Context, document *model.Document) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	stampEscritorio(scope, &document.EscritorioID)
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.insertDocument(document)
+	return nil
+}
+
+// CreateWithOutbox cria o documento e a entrada de upload no outbox juntos
+func (r *DocumentRepository) CreateWithOutbox(ctx context.Context, document *model.Document, entry *model.StorageOutbox) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	stampEscritorio(scope, &document.EscritorioID)
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.insertDocument(document)
+	entry.DocumentID = document.ID
+	r.db.insertOutbox(entry)
+	return nil
+}
+
+// insertDocument grava o documento com ID e datas de criação; chamado com o lock adquirido
+func (db *DB) insertDocument(document *model.Document) {
+	document.ID = int(db.nextID("documents"))
+	document.CreatedAt = time.Now()
+	document.UpdatedAt = document.CreatedAt
+	db.documents[document.ID] = *document
+}
+
+// UpdateXMLIntegrity registra o resultado da verificação de integridade do XML
+func (r *DocumentRepository) UpdateXMLIntegrity(ctx context.Context, id int, status string, verifiedAt time.Time) error {
+	_, err := r.updateDocuments(ctx, allWithDeleted,
+		func(d *model.Document) bool { return d.ID == id },
+		func(d *model.Document) {
+			d.XMLIntegrity = status
+			d.XMLVerifiedAt = verifiedAt
+		})
+	return err
+}
+
+// UpdateXMLStorage grava as colunas de localização do XML a partir do documento
+func (r *DocumentRepository) UpdateXMLStorage(ctx context.Context, document *model.Document) error {
+	document.UpdatedAt = time.Now()
+	_, err := r.updateDocuments(ctx, allWithDeleted,
+		func(d *model.Document) bool { return d.ID == document.ID },
+		func(d *model.Document) {
+			d.XMLBucket = document.XMLBucket
+			d.XMLKey = document.XMLKey
+			d.XMLSize = document.XMLSize
+			d.XMLSha256 = document.XMLSha256
+			d.XMLContentType = document.XMLContentType
+			d.XMLUploadedAt = document.XMLUploadedAt
+			d.XMLRetainUntil = document.XMLRetainUntil
+			d.XMLLegalHold = document.XMLLegalHold
+			d.UpdatedAt = document.UpdatedAt
+		})
+	return err
+}
+
+// empresaID retorna o vínculo do documento com a empresa conforme a direção:
+// emitidos (empresa como emitente) ou recebidos (empresa como tomadora)
+func empresaID(document *model.Document, direcao string) **int {
+	if direcao == model.ExportDirecaoRecebidos {
+		return &document.DestinatarioEmpresaID
+	}
+	return &document.EmitenteEmpresaID
+}
+
+// ListByEmpresa lista os documentos emitidos ou recebidos pela empresa
+func (r *DocumentRepository) ListByEmpresa(ctx context.Context, empresaID int, direcao string, limit, offset int) ([]*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, withoutDeleted, byEmpresa(empresaID, direcao))
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(documents, func(a, b *model.Document) int {
+		return cmp.Or(b.DataEmissao.Compare(a.DataEmissao), cmp.Compare(b.ID, a.ID))
+	})
+	return page(documents, limit, offset), nil
+}
+
+// CountByEmpresa conta os documentos emitidos ou recebidos pela empresa
+func (r *DocumentRepository) CountByEmpresa(ctx context.Context, empresaID int, direcao string) (int, error) {
+	documents, err := r.selectDocuments(ctx, withoutDeleted, byEmpresa(empresaID, direcao))
+	return len(documents), err
+}
+
+func byEmpresa(id int, direcao string) func(*model.Document) bool {
+	return func(d *model.Document) bool {
+		linked := *empresaID(d, direcao)
+		return linked != nil && *linked == id
+	}
+}
+
+// LinkEmpresa vincula à empresa os documentos do mesmo escritório ainda sem
+// vínculo em que o CNPJ dela aparece como emitente ou tomador. Retorna o total
+// de vínculos criados.
+func (r *DocumentRepository) LinkEmpresa(ctx context.Context, empresa *model.Empresa) (int, error) {
+	links := []struct {
+		cnpj    func(*model.Document) string
+		direcao string
+	}{
+		{func(d *model.Document) string { return d.CNPJEmitente }, model.ExportDirecaoEmitidos},
+		{func(d *model.Document) string { return d.CNPJDestinatario }, model.ExportDirecaoRecebidos},
+	}
+
+	total := 0
+	for _, link := range links {
+		linked, err := r.updateDocuments(ctx, withoutDeleted,
+			func(d *model.Document) bool {
+				return link.cnpj(d) == empresa.CNPJ &&
+					*empresaID(d, link.direcao) == nil &&
+					sameEscritorio(d.EscritorioID, empresa.EscritorioID)
+			},
+			func(d *model.Document) {
+				id := empresa.ID
+				*empresaID(d, link.direcao) = &id
+				d.UpdatedAt = time.Now()
+			})
+		if err != nil {
+			return total, err
+		}
+		total += linked
+	}
+	return total, nil
+}
+
+// Delete exclui logicamente o documento (deleted_at)
+func (r *DocumentRepository) Delete(ctx context.Context, id int) error {
+	_, err := r.updateDocuments(ctx, withoutDeleted,
+		func(d *model.Document) bool { return d.ID == id },
+		func(d *model.Document) { d.DeletedAt = time.Now() })
+	return err
+}
+
+// Restore restaura o documento excluído logicamente; retorna sql.ErrNoRows se
+// não houver documento excluído com o ID no escritório do contexto
+func (r *DocumentRepository) Restore(ctx context.Context, id int) error {
+	restored, err := r.updateDocuments(ctx, onlyDeleted,
+		func(d *model.Document) bool { return d.ID == id },
+		func(d *model.Document) {
+			d.DeletedAt = time.Time{}
+			d.UpdatedAt = time.Now()
+		})
+	if err != nil {
+		return err
+	}
+	if restored == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Purge remove definitivamente o documento já excluído logicamente e suas
+// entradas de outbox. A remoção não é gravada se outro documento, de qualquer
+// escritório, ainda usar a mesma chave.
+func (r *DocumentRepository) Purge(ctx context.Context, id int, removal *model.StorageOutbox) error {
+	if _, err := r.getDocument(ctx, onlyDeleted, func(d *model.Document) bool { return d.ID == id }); err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.documents, id)
+	for entryID, entry := range r.db.outbox {
+		if entry.DocumentID == id {
+			delete(r.db.outbox, entryID)
+		}
+	}
+	if removal == nil {
+		return nil
+	}
+	for _, document := range r.db.documents {
+		if document.XMLKey == removal.ObjectName {
+			return nil
+		}
+	}
+	removal.DocumentID = id
+	r.db.insertOutbox(removal)
+	return nil
+}
+
+// GetDeletedByID busca um documento excluído logicamente
+func (r *DocumentRepository) GetDeletedByID(ctx context.Context, id int) (*model.Document, error) {
+	return r.getDocument(ctx, onlyDeleted, func(d *model.Document) bool { return d.ID == id })
+}
+
+// ListDeleted lista os documentos excluídos logicamente, mais recentes primeiro
+func (r *DocumentRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, onlyDeleted, all[model.Document])
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(documents, func(a, b *model.Document) int {
+		return cmp.Or(b.DeletedAt.Compare(a.DeletedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return page(documents, limit, offset), nil
+}
+
+// CountDeleted conta os documentos excluídos logicamente
+func (r *DocumentRepository) CountDeleted(ctx context.Context) (int, error) {
+	documents, err := r.selectDocuments(ctx, onlyDeleted, all[model.Document])
+	return len(documents), err
+}
+
+// ListAfterID lista documentos em ordem de ID a partir de um cursor, inclusive
+// os excluídos
+func (r *DocumentRepository) ListAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, allWithDeleted, func(d *model.Document) bool { return d.ID > afterID })
+	if err != nil {
+		return nil, err
+	}
+	return page(documents, limit, 0), nil
+}
+
+// ListWithoutXMLKeyAfterID lista documentos sem chave de XML registrada a partir de
+// um cursor, inclusive os excluídos
+func (r *DocumentRepository) ListWithoutXMLKeyAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, allWithDeleted, func(d *model.Document) bool { return d.ID > afterID && d.XMLKey == "" })
+	if err != nil {
+		return nil, err
+	}
+	return page(documents, limit, 0), nil
+}
+
+// ListWithXMLKeyAfterID lista documentos com chave de XML registrada a partir de
+// um cursor, inclusive os excluídos
+func (r *DocumentRepository) ListWithXMLKeyAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error) {
+	documents, err := r.selectDocuments(ctx, allWithDeleted, func(d *model.Document) bool { return d.ID > afterID && d.XMLKey != "" })
+	if err != nil {
+		return nil, err
+	}
+	return page(documents, limit, 0), nil
+}
+
+// ExistingXMLKeys informa quais das chaves estão registradas em algum documento,
+// inclusive entre os excluídos
+func (r *DocumentRepository) ExistingXMLKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(keys) == 0 {
+		return existing, nil
+	}
+
+	documents, err := r.selectDocuments(ctx, allWithDeleted, func(d *model.Document) bool { return slices.Contains(keys, d.XMLKey) })
+	if err != nil {
+		return nil, err
+	}
+	for _, document := range documents {
+		existing[document.XMLKey] = true
+	}
+	return existing, nil
+}
@@ -0,0 +1,351 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/tenant"
+)
+
+type EmpresaRepository struct {
+	db *DB
+}
+
+func NewEmpresaRepository(db *DB) *EmpresaRepository {
+	return &EmpresaRepository{db: db}
+}
+
+// selectEmpresas lista, em ordem de ID, cópias das empresas do escritório do
+// contexto que atendem ao filtro
+func (r *EmpresaRepository) selectEmpresas(ctx context.Context, filter deletedFilter, where func(*model.Empresa) bool) ([]*model.Empresa, error) {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var empresas []*model.Empresa
+	for _, empresa := range r.db.empresas {
+		if visible(scope, empresa.EscritorioID) && filter.match(empresa.DeletedAt) && where(&empresa) {
+			empresas = append(empresas, &empresa)
+		}
+	}
+	slices.SortFunc(empresas, func(a, b *model.Empresa) int { return cmp.Compare(a.ID, b.ID) })
+	return empresas, nil
+}
+
+// getEmpresa retorna a primeira empresa que atende ao filtro ou sql.ErrNoRows
+func (r *EmpresaRepository) getEmpresa(ctx context.Context, filter deletedFilter, where func(*model.Empresa) bool) (*model.Empresa, error) {
+	empresas, err := r.selectEmpresas(ctx, filter, where)
+	if err != nil {
+		return nil, err
+	}
+	if len(empresas) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return empresas[0], nil
+}
+
+func empresaByID(id int) func(*model.Empresa) bool {
+	return func(e *model.Empresa) bool { return e.ID == id }
+}
+
+func empresaByCNPJ(cnpj string) func(*model.Empresa) bool {
+	return func(e *model.Empresa) bool { return e.CNPJ == cnpj }
+}
+
+// empresaByTermo equivalente ao ILIKE em CNPJ, razão social e nome fantasia
+func empresaByTermo(termo string) func(*model.Empresa) bool {
+	termo = strings.ToLower(termo)
+	return func(e *model.Empresa) bool {
+		return strings.Contains(strings.ToLower(e.CNPJ), termo) ||
+			strings.Contains(strings.ToLower(e.RazaoSocial), termo) ||
+			strings.Contains(strings.ToLower(e.NomeFantasia), termo)
+	}
+}
+
+// Create cria uma nova empresa no escritório do contexto
+func (r *EmpresaRepository) Create(ctx context.Context, empresa *model.Empresa) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	stampEscritorio(scope, &empresa.EscritorioID)
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	empresa.ID = int(r.db.nextID("empresas"))
+	empresa.CreatedAt = time.Now()
+	empresa.UpdatedAt = empresa.CreatedAt
+	r.db.empresas[empresa.ID] = *empresa
+	return nil
+}
+
+// GetByCNPJ busca empresa por CNPJ
+func (r *EmpresaRepository) GetByCNPJ(ctx context.Context, cnpj string) (*model.Empresa, error) {
+	return r.getEmpresa(ctx, withoutDeleted, empresaByCNPJ(cnpj))
+}
+
+// GetByID busca empresa por ID
+func (r *EmpresaRepository) GetByID(ctx context.Context, id int) (*model.Empresa, error) {
+	return r.getEmpresa(ctx, withoutDeleted, empresaByID(id))
+}
+
+// GetAll lista todas as empresas com paginação
+func (r *EmpresaRepository) GetAll(ctx context.Context, limit, offset int) ([]*model.Empresa, error) {
+	return r.listEmpresas(ctx, all[model.Empresa], limit, offset)
+}
+
+// Search busca empresas por termo (CNPJ, razão social ou nome fantasia)
+func (r *EmpresaRepository) Search(ctx context.Context, termo string, limit, offset int) ([]*model.Empresa, error) {
+	return r.listEmpresas(ctx, empresaByTermo(termo), limit, offset)
+}
+
+// listEmpresas lista as empresas em ordem de razão social
+func (r *EmpresaRepository) listEmpresas(ctx context.Context, where func(*model.Empresa) bool, limit, offset int) ([]*model.Empresa, error) {
+	empresas, err := r.selectEmpresas(ctx, withoutDeleted, where)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(empresas, func(a, b *model.Empresa) int { return strings.Compare(a.RazaoSocial, b.RazaoSocial) })
+	return page(empresas, limit, offset), nil
+}
+
+// Count retorna o total de empresas
+func (r *EmpresaRepository) Count(ctx context.Context) (int, error) {
+	empresas, err := r.selectEmpresas(ctx, withoutDeleted, all[model.Empresa])
+	return len(empresas), err
+}
+
+// CountBySearch retorna o total de empresas que correspondem ao termo de busca
+func (r *EmpresaRepository) CountBySearch(ctx context.Context, termo string) (int, error) {
+	empresas, err := r.selectEmpresas(ctx, withoutDeleted, empresaByTermo(termo))
+	return len(empresas), err
+}
+
+// Update atualiza uma empresa, mantendo o escritório
+func (r *EmpresaRepository) Update(ctx context.Context, empresa *model.Empresa) error {
+	stored, err := r.getEmpresa(ctx, withoutDeleted, empresaByID(empresa.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	empresa.UpdatedAt = time.Now()
+	updated := *empresa
+	updated.EscritorioID = stored.EscritorioID
+	updated.DeletedAt = stored.DeletedAt
+	r.db.empresas[empresa.ID] = updated
+	return nil
+}
+
+// empresaChildren percorre os registros filhos da empresa em todas as tabelas
+// filhas; fn recebe o deleted_at do registro para alterá-lo e retorna false
+// para removê-lo
+func (db *DB) empresaChildren(empresaID int, fn func(deletedAt *time.Time) bool) {
+	eachChild(db.atividades, empresaID, func(a *model.AtividadeSecundaria) (int, *time.Time) { return a.EmpresaID, &a.DeletedAt }, fn)
+	eachChild(db.membros, empresaID, func(m *model.EmpresaMembro) (int, *time.Time) { return m.EmpresaID, &m.DeletedAt }, fn)
+	eachChild(db.inscricoes, empresaID, func(i *model.EmpresaInscricaoEstadual) (int, *time.Time) { return i.EmpresaID, &i.DeletedAt }, fn)
+	eachChild(db.suframa, empresaID, func(s *model.EmpresaSuframa) (int, *time.Time) { return s.EmpresaID, &s.DeletedAt }, fn)
+	eachChild(db.telefones, empresaID, func(t *model.EmpresaTelefone) (int, *time.Time) { return t.EmpresaID, &t.DeletedAt }, fn)
+	eachChild(db.emails, empresaID, func(e *model.EmpresaEmail) (int, *time.Time) { return e.EmpresaID, &e.DeletedAt }, fn)
+}
+
+func eachChild[T any](rows map[int]T, empresaID int, fields func(*T) (int, *time.Time), fn func(deletedAt *time.Time) bool) {
+	for id, row := range rows {
+		rowEmpresa, deletedAt := fields(&row)
+		if rowEmpresa != empresaID {
+			continue
+		}
+		if fn(deletedAt) {
+			rows[id] = row
+		} else {
+			delete(rows, id)
+		}
+	}
+}
+
+// Delete exclui logicamente a empresa e seus registros filhos com o mesmo
+// deleted_at, para que a restauração traga de volta apenas o que saiu junto
+func (r *EmpresaRepository) Delete(ctx context.Context, id int) error {
+	empresa, err := r.getEmpresa(ctx, withoutDeleted, empresaByID(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	empresa.DeletedAt = time.Now()
+	r.db.empresas[id] = *empresa
+	r.db.empresaChildren(id, func(deletedAt *time.Time) bool {
+		if deletedAt.IsZero() {
+			*deletedAt = empresa.DeletedAt
+		}
+		return true
+	})
+	return nil
+}
+
+// Restore restaura a empresa excluída logicamente e os registros filhos excluídos
+// com ela; retorna sql.ErrNoRows se não houver empresa excluída com o ID
+func (r *EmpresaRepository) Restore(ctx context.Context, id int) error {
+	empresa, err := r.getEmpresa(ctx, onlyDeleted, empresaByID(id))
+	if err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.empresaChildren(id, func(deletedAt *time.Time) bool {
+		if deletedAt.Equal(empresa.DeletedAt) {
+			*deletedAt = time.Time{}
+		}
+		return true
+	})
+	empresa.DeletedAt = time.Time{}
+	empresa.UpdatedAt = time.Now()
+	r.db.empresas[id] = *empresa
+	return nil
+}
+
+// Purge remove definitivamente a empresa já excluída logicamente com seus
+// registros filhos; os documentos perdem o vínculo com ela
+func (r *EmpresaRepository) Purge(ctx context.Context, id int) error {
+	if _, err := r.getEmpresa(ctx, onlyDeleted, empresaByID(id)); err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.empresas, id)
+	r.db.empresaChildren(id, func(*time.Time) bool { return false })
+	for documentID, document := range r.db.documents {
+		if document.EmitenteEmpresaID != nil && *document.EmitenteEmpresaID == id {
+			document.EmitenteEmpresaID = nil
+		}
+		if document.DestinatarioEmpresaID != nil && *document.DestinatarioEmpresaID == id {
+			document.DestinatarioEmpresaID = nil
+		}
+		r.db.documents[documentID] = document
+	}
+	return nil
+}
+
+// GetDeletedByID busca uma empresa excluída logicamente
+func (r *EmpresaRepository) GetDeletedByID(ctx context.Context, id int) (*model.Empresa, error) {
+	return r.getEmpresa(ctx, onlyDeleted, empresaByID(id))
+}
+
+// GetDeletedByCNPJ busca uma empresa excluída logicamente pelo CNPJ
+func (r *EmpresaRepository) GetDeletedByCNPJ(ctx context.Context, cnpj string) (*model.Empresa, error) {
+	return r.getEmpresa(ctx, onlyDeleted, empresaByCNPJ(cnpj))
+}
+
+// ListDeleted lista as empresas excluídas logicamente, mais recentes primeiro
+func (r *EmpresaRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*model.Empresa, error) {
+	empresas, err := r.selectEmpresas(ctx, onlyDeleted, all[model.Empresa])
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(empresas, func(a, b *model.Empresa) int {
+		return cmp.Or(b.DeletedAt.Compare(a.DeletedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return page(empresas, limit, offset), nil
+}
+
+// CountDeleted conta as empresas excluídas logicamente
+func (r *EmpresaRepository) CountDeleted(ctx context.Context) (int, error) {
+	empresas, err := r.selectEmpresas(ctx, onlyDeleted, all[model.Empresa])
+	return len(empresas), err
+}
+
+// insertChild grava um registro filho se a empresa pertencer ao escritório do
+// contexto e não estiver excluída; caso contrário retorna sql.ErrNoRows
+func (r *EmpresaRepository) insertChild(ctx context.Context, empresaID int, insert func(db *DB, now time.Time)) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	empresa, ok := r.db.empresas[empresaID]
+	if !ok || !visible(scope, empresa.EscritorioID) || !empresa.DeletedAt.IsZero() {
+		return sql.ErrNoRows
+	}
+	insert(r.db, time.Now())
+	return nil
+}
+
+// CreateAtividadeSecundaria cria uma atividade secundária
+func (r *EmpresaRepository) CreateAtividadeSecundaria(ctx context.Context, atividade *model.AtividadeSecundaria) error {
+	return r.insertChild(ctx, atividade.EmpresaID, func(db *DB, now time.Time) {
+		atividade.ID = int(db.nextID("atividades_secundarias"))
+		atividade.CreatedAt = now
+		db.atividades[atividade.ID] = *atividade
+	})
+}
+
+// CreateMembro cria um novo membro da empresa
+func (r *EmpresaRepository) CreateMembro(ctx context.Context, membro *model.EmpresaMembro) error {
+	return r.insertChild(ctx, membro.EmpresaID, func(db *DB, now time.Time) {
+		membro.ID = int(db.nextID("empresa_membros"))
+		membro.CreatedAt = now
+		membro.UpdatedAt = now
+		db.membros[membro.ID] = *membro
+	})
+}
+
+// CreateInscricaoEstadual cria uma nova inscrição estadual
+func (r *EmpresaRepository) CreateInscricaoEstadual(ctx context.Context, inscricao *model.EmpresaInscricaoEstadual) error {
+	return r.insertChild(ctx, inscricao.EmpresaID, func(db *DB, now time.Time) {
+		inscricao.ID = int(db.nextID("empresa_inscricoes_estaduais"))
+		inscricao.CreatedAt = now
+		inscricao.UpdatedAt = now
+		db.inscricoes[inscricao.ID] = *inscricao
+	})
+}
+
+// CreateSuframa cria um novo registro SUFRAMA
+func (r *EmpresaRepository) CreateSuframa(ctx context.Context, suframa *model.EmpresaSuframa) error {
+	return r.insertChild(ctx, suframa.EmpresaID, func(db *DB, now time.Time) {
+		suframa.ID = int(db.nextID("empresa_suframa"))
+		suframa.CreatedAt = now
+		suframa.UpdatedAt = now
+		db.suframa[suframa.ID] = *suframa
+	})
+}
+
+// CreateTelefone cria um novo telefone da empresa
+func (r *EmpresaRepository) CreateTelefone(ctx context.Context, telefone *model.EmpresaTelefone) error {
+	return r.insertChild(ctx, telefone.EmpresaID, func(db *DB, now time.Time) {
+		telefone.ID = int(db.nextID("empresa_telefones"))
+		telefone.CreatedAt = now
+		telefone.UpdatedAt = now
+		db.telefones[telefone.ID] = *telefone
+	})
+}
+
+// CreateEmail cria um novo email da empresa
+func (r *EmpresaRepository) CreateEmail(ctx context.Context, email *model.EmpresaEmail) error {
+	return r.insertChild(ctx, email.EmpresaID, func(db *DB, now time.Time) {
+		email.ID = int(db.nextID("empresa_emails"))
+		email.CreatedAt = now
+		email.UpdatedAt = now
+		db.emails[email.ID] = *email
+	})
+}
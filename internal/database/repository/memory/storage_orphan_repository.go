@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+)
+
+type StorageOrphanRepository struct {
+	db *DB
+}
+
+func NewStorageOrphanRepository(db *DB) *StorageOrphanRepository {
+	return &StorageOrphanRepository{db: db}
+}
+
+// Record registra os objetos órfãos vistos em seenAt, mantendo a data em que cada
+// um foi encontrado pela primeira vez
+func (r *StorageOrphanRepository) Record(ctx context.Context, bucket string, keys []string, seenAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, key := range keys {
+		orphan, ok := r.db.orphans[key]
+		if !ok {
+			orphan = model.StorageOrphan{ObjectKey: key, FirstSeenAt: seenAt}
+		}
+		orphan.Bucket = bucket
+		orphan.LastSeenAt = seenAt
+		r.db.orphans[key] = orphan
+	}
+	return nil
+}
+
+// DeleteSeenBefore remove os órfãos não encontrados desde before e retorna quantos foram removidos
+func (r *StorageOrphanRepository) DeleteSeenBefore(ctx context.Context, before time.Time) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	removed := 0
+	for key, orphan := range r.db.orphans {
+		if orphan.LastSeenAt.Before(before) {
+			delete(r.db.orphans, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
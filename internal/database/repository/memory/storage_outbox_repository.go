@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+	"zemdocs/internal/database/model"
+)
+
+type StorageOutboxRepository struct {
+	db *DB
+}
+
+func NewStorageOutboxRepository(db *DB) *StorageOutboxRepository {
+	return &StorageOutboxRepository{db: db}
+}
+
+// insertOutbox grava a entrada com ID e os valores padrão da tabela; chamado com
+// o lock adquirido
+func (db *DB) insertOutbox(entry *model.StorageOutbox) {
+	entry.ID = db.nextID("storage_outbox")
+	entry.CreatedAt = time.Now()
+	entry.UpdatedAt = entry.CreatedAt
+	if entry.NextAttemptAt.IsZero() {
+		entry.NextAttemptAt = entry.CreatedAt
+	}
+	if entry.Status == "" {
+		entry.Status = model.OutboxStatusPendente
+	}
+	if entry.ContentType == "" {
+		entry.ContentType = "application/xml"
+	}
+	if entry.Operation == "" {
+		entry.Operation = model.OutboxOperationPut
+	}
+	db.outbox[entry.ID] = *entry
+}
+
+// GetPending busca entradas pendentes cujo horário de nova tentativa já passou
+func (r *StorageOutboxRepository) GetPending(ctx context.Context, limit int) ([]*model.StorageOutbox, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	var entries []*model.StorageOutbox
+	for _, entry := range r.db.outbox {
+		if entry.Status == model.OutboxStatusPendente && !entry.NextAttemptAt.After(now) {
+			entries = append(entries, &entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b *model.StorageOutbox) int { return cmp.Compare(a.ID, b.ID) })
+	return page(entries, limit, 0), nil
+}
+
+// GetByDocumentID busca a entrada mais recente do outbox para um documento
+func (r *StorageOutboxRepository) GetByDocumentID(ctx context.Context, documentID int) (*model.StorageOutbox, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var latest *model.StorageOutbox
+	for _, entry := range r.db.outbox {
+		if entry.DocumentID == documentID && (latest == nil || entry.ID > latest.ID) {
+			latest = &entry
+		}
+	}
+	if latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+// MarkDone marca a entrada como entregue, descarta o payload e registra no
+// documento o horário do upload
+func (r *StorageOutboxRepository) MarkDone(ctx context.Context, entry *model.StorageOutbox) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	if stored, ok := r.db.outbox[entry.ID]; ok {
+		stored.Status = model.OutboxStatusConcluido
+		stored.Attempts++
+		stored.LastError = ""
+		stored.Payload = []byte{}
+		stored.ProcessedAt = now
+		stored.UpdatedAt = now
+		r.db.outbox[entry.ID] = stored
+	}
+	if document, ok := r.db.documents[entry.DocumentID]; ok {
+		document.XMLUploadedAt = now
+		r.db.documents[entry.DocumentID] = document
+	}
+	return nil
+}
+
+// MarkFailed registra uma tentativa com erro e agenda a próxima
+func (r *StorageOutboxRepository) MarkFailed(ctx context.Context, id int64, status model.OutboxStatus, lastError string, nextAttemptAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if stored, ok := r.db.outbox[id]; ok {
+		stored.Status = status
+		stored.Attempts++
+		stored.LastError = lastError
+		stored.NextAttemptAt = nextAttemptAt
+		stored.UpdatedAt = time.Now()
+		r.db.outbox[id] = stored
+	}
+	return nil
+}
+
+// Requeue devolve uma entrada com falha para a fila, zerando as tentativas
+func (r *StorageOutboxRepository) Requeue(ctx context.Context, id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if stored, ok := r.db.outbox[id]; ok && stored.Status == model.OutboxStatusFalha {
+		stored.Status = model.OutboxStatusPendente
+		stored.Attempts = 0
+		stored.NextAttemptAt = time.Now()
+		stored.UpdatedAt = stored.NextAttemptAt
+		r.db.outbox[id] = stored
+	}
+	return nil
+}
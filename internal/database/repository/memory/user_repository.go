@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/tenant"
+)
+
+type UserRepository struct {
+	db *DB
+}
+
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// selectUsers lista, em ordem de ID, cópias dos usuários do escritório do
+// contexto que atendem ao filtro
+func (r *UserRepository) selectUsers(ctx context.Context, where func(*model.User) bool) ([]*model.User, error) {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var users []*model.User
+	for _, user := range r.db.users {
+		if visible(scope, user.EscritorioID) && where(&user) {
+			users = append(users, &user)
+		}
+	}
+	slices.SortFunc(users, func(a, b *model.User) int { return cmp.Compare(a.ID, b.ID) })
+	return users, nil
+}
+
+func (r *UserRepository) getUser(ctx context.Context, where func(*model.User) bool) (*model.User, error) {
+	users, err := r.selectUsers(ctx, where)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return users[0], nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	stampEscritorio(scope, &user.EscritorioID)
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	user.ID = int(r.db.nextID("users"))
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	r.db.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
+	return r.getUser(ctx, func(u *model.User) bool { return u.ID == id })
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.getUser(ctx, func(u *model.User) bool { return u.Email == email })
+}
+
+func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*model.User, error) {
+	users, err := r.selectUsers(ctx, all[model.User])
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(users, func(a, b *model.User) int { return strings.Compare(a.Name, b.Name) })
+	return page(users, limit, offset), nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	stored, err := r.getUser(ctx, func(u *model.User) bool { return u.ID == user.ID })
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	user.UpdatedAt = time.Now()
+	updated := *user
+	updated.EscritorioID = stored.EscritorioID
+	r.db.users[user.ID] = updated
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	_, err := r.getUser(ctx, func(u *model.User) bool { return u.ID == id })
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.users, id)
+	return nil
+}
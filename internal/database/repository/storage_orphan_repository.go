@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type StorageOrphanRepository struct {
+	db *bun.DB
+}
+
+func NewStorageOrphanRepository(db *bun.DB) *StorageOrphanRepository {
+	return &StorageOrphanRepository{db: db}
+}
+
+// Record registra os objetos órfãos vistos em seenAt, mantendo a data em que cada
+// um foi encontrado pela primeira vez
+func (r *StorageOrphanRepository) Record(ctx context.Context, bucket string, keys []string, seenAt time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	orphans := make([]*model.StorageOrphan, len(keys))
+	for i, key := range keys {
+		orphans[i] = &model.StorageOrphan{
+			ObjectKey:   key,
+			Bucket:      bucket,
+			FirstSeenAt: seenAt,
+			LastSeenAt:  seenAt,
+		}
+	}
+
+	_, err := r.db.NewInsert().
+		Model(&orphans).
+		On("CONFLICT (object_key) DO UPDATE").
+		Set("bucket = EXCLUDED.bucket").
+		Set("last_seen_at = EXCLUDED.last_seen_at").
+		Exec(ctx)
+	return err
+}
+
+// DeleteSeenBefore remove os órfãos não encontrados desde before e retorna quantos foram removidos
+func (r *StorageOrphanRepository) DeleteSeenBefore(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.NewDelete().
+		Model((*model.StorageOrphan)(nil)).
+		Where("last_seen_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// List lista os órfãos registrados, dos mais antigos aos mais recentes
+func (r *StorageOrphanRepository) List(ctx context.Context, limit, offset int) ([]*model.StorageOrphan, error) {
+	var orphans []*model.StorageOrphan
+	err := r.db.NewSelect().
+		Model(&orphans).
+		Order("first_seen_at ASC", "object_key ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+	return orphans, err
+}
+
+// Count conta os órfãos registrados
+func (r *StorageOrphanRepository) Count(ctx context.Context) (int, error) {
+	return r.db.NewSelect().
+		Model((*model.StorageOrphan)(nil)).
+		Count(ctx)
+}
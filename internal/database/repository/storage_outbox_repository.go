@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/uptrace/bun"
+)
+
+type StorageOutboxRepository struct {
+	db *bun.DB
+}
+
+func NewStorageOutboxRepository(db *bun.DB) *StorageOutboxRepository {
+	return &StorageOutboxRepository{db: db}
+}
+
+// Create cria uma nova entrada no outbox
+func (r *StorageOutboxRepository) Create(ctx context.Context, entry *model.StorageOutbox) error {
+	_, err := r.db.NewInsert().Model(entry).Exec(ctx)
+	return err
+}
+
+// GetPending busca entradas pendentes cujo horário de nova tentativa já passou
+func (r *StorageOutboxRepository) GetPending(ctx context.Context, limit int) ([]*model.StorageOutbox, error) {
+	var entries []*model.StorageOutbox
+	err := r.db.NewSelect().
+		Model(&entries).
+		Where("status = ?", model.OutboxStatusPendente).
+		Where("next_attempt_at <= ?", time.Now()).
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+	return entries, err
+}
+
+// GetByDocumentID busca a entrada mais recente do outbox para um documento
+func (r *StorageOutboxRepository) GetByDocumentID(ctx context.Context, documentID int) (*model.StorageOutbox, error) {
+	entry := &model.StorageOutbox{}
+	err := r.db.NewSelect().
+		Model(entry).
+		Where("document_id = ?", documentID).
+		Order("id DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// MarkDone marca a entrada como entregue, descarta o payload e registra no
+// documento o horário do upload, na mesma transação
+func (r *StorageOutboxRepository) MarkDone(ctx context.Context, entry *model.StorageOutbox) error {
+	now := time.Now()
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model((*model.StorageOutbox)(nil)).
+			Set("status = ?", model.OutboxStatusConcluido).
+			Set("attempts = attempts + 1").
+			Set("last_error = ''").
+			Set("payload = ''::bytea").
+			Set("processed_at = ?", now).
+			Set("updated_at = ?", now).
+			Where("id = ?", entry.ID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*model.Document)(nil)).
+			WhereAllWithDeleted().
+			Set("xml_uploaded_at = ?", now).
+			Where("id = ?", entry.DocumentID).
+			Exec(ctx)
+		return err
+	})
+}
+
+// MarkFailed registra uma tentativa com erro e agenda a próxima
+func (r *StorageOutboxRepository) MarkFailed(ctx context.Context, id int64, status model.OutboxStatus, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.NewUpdate().
+		Model((*model.StorageOutbox)(nil)).
+		Set("status = ?", status).
+		Set("attempts = attempts + 1").
+		Set("last_error = ?", lastError).
+		Set("next_attempt_at = ?", nextAttemptAt).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// Requeue devolve uma entrada com falha para a fila, zerando as tentativas
+func (r *StorageOutboxRepository) Requeue(ctx context.Context, id int64) error {
+	_, err := r.db.NewUpdate().
+		Model((*model.StorageOutbox)(nil)).
+		Set("status = ?", model.OutboxStatusPendente).
+		Set("attempts = 0").
+		Set("next_attempt_at = ?", time.Now()).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("status = ?", model.OutboxStatusFalha).
+		Exec(ctx)
+	return err
+}
+
+// CountByStatus conta entradas do outbox por status
+func (r *StorageOutboxRepository) CountByStatus(ctx context.Context, status model.OutboxStatus) (int, error) {
+	return r.db.NewSelect().
+		Model((*model.StorageOutbox)(nil)).
+		Where("status = ?", status).
+		Count(ctx)
+}
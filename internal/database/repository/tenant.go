@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"zemdocs/internal/tenant"
+
+	"github.com/uptrace/bun"
+)
+
+// conn retorna a transação da requisição (com app.escritorio_id definido para o
+// row-level security) ou, fora de uma requisição, o próprio banco
+func conn(ctx context.Context, db *bun.DB) bun.IDB {
+	return tenant.Conn(ctx, db)
+}
+
+// whereEscritorio restringe a consulta ao escritório do contexto. No acesso de
+// sistema a consulta não é alterada; sem escopo, retorna tenant.ErrNoScope.
+func whereEscritorio[Q interface {
+	Where(query string, args ...interface{}) Q
+}](ctx context.Context, query Q, column string) (Q, error) {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return query, err
+	}
+	if !scope.System {
+		query = query.Where(column+" = ?", scope.EscritorioID)
+	}
+	return query, nil
+}
+
+// stampEscritorio atribui ao registro o escritório do contexto. No acesso de
+// sistema mantém o escritório informado pelo chamador.
+func stampEscritorio(ctx context.Context, escritorioID **int64) error {
+	scope, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !scope.System {
+		id := scope.EscritorioID
+		*escritorioID = &id
+	}
+	return nil
+}
@@ -15,14 +15,27 @@ func NewUserRepository(db *bun.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+const userEscritorio = "?TableAlias.escritorio_id"
+
+func (r *UserRepository) newSelect(ctx context.Context) (*bun.SelectQuery, error) {
+	return whereEscritorio(ctx, conn(ctx, r.db).NewSelect(), userEscritorio)
+}
+
 func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
-	_, err := r.db.NewInsert().Model(user).Exec(ctx)
+	if err := stampEscritorio(ctx, &user.EscritorioID); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).NewInsert().Model(user).Exec(ctx)
 	return err
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
 	user := &model.User{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(user).
 		Where("id = ?", id).
 		Scan(ctx)
@@ -34,7 +47,11 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, erro
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	user := &model.User{}
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(user).
 		Where("email = ?", email).
 		Scan(ctx)
@@ -46,7 +63,11 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 
 func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*model.User, error) {
 	var users []*model.User
-	err := r.db.NewSelect().
+	query, err := r.newSelect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
 		Model(&users).
 		Order("name ASC").
 		Limit(limit).
@@ -56,15 +77,24 @@ func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*mode
 }
 
 func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
-	_, err := r.db.NewUpdate().
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewUpdate(), userEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
 		Model(user).
+		ExcludeColumn("escritorio_id").
 		Where("id = ?", user.ID).
 		Exec(ctx)
 	return err
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id int) error {
-	_, err := r.db.NewDelete().
+	query, err := whereEscritorio(ctx, conn(ctx, r.db).NewDelete(), userEscritorio)
+	if err != nil {
+		return err
+	}
+	_, err = query.
 		Model((*model.User)(nil)).
 		Where("id = ?", id).
 		Exec(ctx)
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"zemdocs/internal/logger"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
+)
+
+// DocumentPartitionJob job que cria as partições anuais de documents e arquiva as antigas
+type DocumentPartitionJob struct {
+	partitionService *service.DocumentPartitionService
+}
+
+// NewDocumentPartitionJob cria uma nova instância do job
+func NewDocumentPartitionJob(partitionService *service.DocumentPartitionService) *DocumentPartitionJob {
+	return &DocumentPartitionJob{partitionService: partitionService}
+}
+
+// Name retorna o nome do job
+func (j *DocumentPartitionJob) Name() string {
+	return "documents-partitions"
+}
+
+// Execute executa a manutenção das partições
+func (j *DocumentPartitionJob) Execute(ctx context.Context) error {
+	run := scheduler.RunFromContext(ctx)
+
+	report, err := j.partitionService.Manter(ctx, time.Now())
+	for _, name := range report.Created {
+		run.Infof("Partição criada: %s", name)
+	}
+	for _, name := range report.Archived {
+		run.Infof("Partição arquivada: %s", name)
+	}
+	run.Add("created", int64(len(report.Created)))
+	run.Add("archived", int64(len(report.Archived)))
+	if err != nil {
+		return err
+	}
+
+	if len(report.Created) > 0 || len(report.Archived) > 0 {
+		logger.Database().Info().
+			Strs("created", report.Created).
+			Strs("archived", report.Archived).
+			Msg("Partições de documentos atualizadas")
+	}
+
+	return nil
+}
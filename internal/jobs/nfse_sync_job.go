@@ -8,39 +8,87 @@ import (
 
 	"zemdocs/internal/clientes/documents"
 	"zemdocs/internal/database/model"
-	"zemdocs/internal/database/repository"
 	"zemdocs/internal/logger"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
 	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
 	"zemdocs/internal/utils"
+
+	"github.com/shopspring/decimal"
 )
 
 // NFSeSyncJob job para sincronizar NFS-e da prefeitura
 type NFSeSyncJob struct {
-	nfseClient  documents.Client
-	nfseRepo    *repository.DocumentRepository
-	minioClient *storage.MinIOClient
-	competencia string
-	maxRetries  int
-	pageSize    int
+	nfseClient    documents.Client
+	nfseRepo      service.DocumentRepository
+	store         storage.Store
+	outboxService *service.StorageOutboxService
+	onFailure     documents.FailureHandler
+	empresaLinker *service.EmpresaLinkService
+	dashboard     *service.DashboardService
+	audit         *service.AuditService
+	competencia   string
+	maxRetries    int
+	pageSize      int
+
+	// Filtros opcionais definidos pelo agendamento
+	documentTypes map[documents.DocumentType]bool
+	cnpjEmpresa   string
 }
 
 // NewNFSeSyncJob cria uma nova instância do job
 func NewNFSeSyncJob(
 	nfseClient documents.Client,
-	nfseRepo *repository.DocumentRepository,
-	minioClient *storage.MinIOClient,
+	nfseRepo service.DocumentRepository,
+	store storage.Store,
+	outboxService *service.StorageOutboxService,
 	competencia string,
 ) *NFSeSyncJob {
 	return &NFSeSyncJob{
-		nfseClient:  nfseClient,
-		nfseRepo:    nfseRepo,
-		minioClient: minioClient,
-		competencia: competencia,
-		maxRetries:  3,
-		pageSize:    100,
+		nfseClient:    nfseClient,
+		nfseRepo:      nfseRepo,
+		store:         store,
+		outboxService: outboxService,
+		competencia:   competencia,
+		maxRetries:    3,
+		pageSize:      100,
+	}
+}
+
+// SetFailureHandler define quem recebe as NFS-e que falharem ao ser salvas
+func (j *NFSeSyncJob) SetFailureHandler(handler documents.FailureHandler) {
+	j.onFailure = handler
+}
+
+// SetEmpresaLinker define o serviço que vincula os documentos às empresas cadastradas
+func (j *NFSeSyncJob) SetEmpresaLinker(linker *service.EmpresaLinkService) {
+	j.empresaLinker = linker
+}
+
+// SetDashboard define o dashboard cujo recálculo é agendado ao fim da sincronização
+func (j *NFSeSyncJob) SetDashboard(dashboard *service.DashboardService) {
+	j.dashboard = dashboard
+}
+
+// SetAudit define a auditoria em que os documentos sincronizados são registrados
+func (j *NFSeSyncJob) SetAudit(auditService *service.AuditService) {
+	j.audit = auditService
+}
+
+// SetDocumentTypes restringe a sincronização aos tipos de documento informados
+func (j *NFSeSyncJob) SetDocumentTypes(types []documents.DocumentType) {
+	j.documentTypes = make(map[documents.DocumentType]bool, len(types))
+	for _, documentType := range types {
+		j.documentTypes[documentType] = true
 	}
 }
 
+// SetEmpresa restringe a sincronização aos documentos emitidos ou recebidos pelo CNPJ
+func (j *NFSeSyncJob) SetEmpresa(cnpj string) {
+	j.cnpjEmpresa = cnpj
+}
+
 // Name retorna o nome do job
 func (j *NFSeSyncJob) Name() string {
 	return fmt.Sprintf("nfse-sync-%s", j.competencia)
@@ -52,6 +100,9 @@ func (j *NFSeSyncJob) Execute(ctx context.Context) error {
 		Str("competencia", j.competencia).
 		Msg("Iniciando sincronização de NFS-e")
 
+	run := scheduler.RunFromContext(ctx)
+	run.Infof("Sincronizando competência %s", j.competencia)
+
 	// Buscar NFS-e por competência com paginação
 	page := 1
 	totalProcessed := 0
@@ -72,6 +123,7 @@ func (j *NFSeSyncJob) Execute(ctx context.Context) error {
 				Int("page", page).
 				Str("competencia", j.competencia).
 				Msg("Erro ao buscar NFS-e")
+			run.Errorf("Erro ao buscar página %d: %v", page, err)
 
 			if page == 1 {
 				return fmt.Errorf("erro na primeira página: %w", err)
@@ -86,16 +138,26 @@ func (j *NFSeSyncJob) Execute(ctx context.Context) error {
 
 		// Processar cada NFS-e
 		for _, nfseResp := range nfseList {
+			if !j.accepts(&nfseResp) {
+				run.Add("skipped", 1)
+				continue
+			}
+
 			if err := j.processNFSe(ctx, &nfseResp); err != nil {
 				logger.Database().Error().
 					Err(err).
 					Str("numero_nfse", nfseResp.NumeroNfse).
 					Msg("Erro ao processar NFS-e")
+				j.reportFailure(ctx, &nfseResp, err)
+				run.Warnf("NFS-e %s: %v", nfseResp.NumeroNfse, err)
+				run.Add("errors", 1)
 				totalErrors++
 			} else {
+				run.Add("processed", 1)
 				totalProcessed++
 			}
 		}
+		run.Add("pages", 1)
 
 		logger.Database().Info().
 			Int("page", page).
@@ -117,10 +179,57 @@ func (j *NFSeSyncJob) Execute(ctx context.Context) error {
 		Int("total_processed", totalProcessed).
 		Int("total_errors", totalErrors).
 		Msg("Sincronização de NFS-e concluída")
+	run.Infof("Concluída: %d processadas, %d erros", totalProcessed, totalErrors)
+
+	if j.dashboard != nil && totalProcessed > 0 {
+		j.dashboard.SolicitarAtualizacao()
+	}
 
 	return nil
 }
 
+// accepts verifica se o documento passa pelos filtros do agendamento
+func (j *NFSeSyncJob) accepts(nfseResp *documents.Response) bool {
+	if len(j.documentTypes) > 0 {
+		documentType := nfseResp.DocumentType
+		if documentType == "" {
+			documentType = documents.DocumentTypeNFSe
+		}
+		if !j.documentTypes[documentType] {
+			return false
+		}
+	}
+
+	if j.cnpjEmpresa == "" {
+		return true
+	}
+
+	// Sem XML não é possível identificar as partes; o documento é processado
+	// para não ser perdido
+	metadata, _ := j.extractMetadata(nfseResp.XMLContent)
+	if metadata == nil {
+		return true
+	}
+	return metadata.CNPJPrestador == j.cnpjEmpresa || metadata.CNPJTomador == j.cnpjEmpresa
+}
+
+// reportFailure encaminha uma NFS-e que falhou ao handler configurado
+func (j *NFSeSyncJob) reportFailure(ctx context.Context, nfseResp *documents.Response, err error) {
+	if j.onFailure == nil {
+		return
+	}
+
+	j.onFailure(ctx, documents.Failure{
+		Source:          j.Name(),
+		Stage:           documents.FailureStagePersist,
+		NumeroDocumento: nfseResp.NumeroNfse,
+		Competencia:     nfseResp.Competencia,
+		Payload:         nfseResp.XMLContent,
+		PayloadFormat:   documents.PayloadFormatXML,
+		Err:             err,
+	})
+}
+
 // fetchNFSeByCompetencia busca NFS-e por competência
 func (j *NFSeSyncJob) fetchNFSeByCompetencia(ctx context.Context, page int) ([]documents.Response, error) {
 	req := documents.ConsultarXMLRequest{
@@ -178,8 +287,9 @@ func (j *NFSeSyncJob) processNFSe(ctx context.Context, nfseResp *documents.Respo
 		return fmt.Errorf("erro ao extrair metadados: %w", err)
 	}
 
-	// Criar modelo Document
+	// Criar modelo Document; o escritório define a chave que cifra o XML no outbox
 	nfse := &model.Document{
+		EscritorioID:      tenant.EscritorioID(ctx),
 		DocumentType:      model.DocumentTypeNFSe,
 		NumeroDocumento:   nfseResp.NumeroNfse,
 		NumeroRps:         nfseResp.NumeroRps,
@@ -189,7 +299,7 @@ func (j *NFSeSyncJob) processNFSe(ctx context.Context, nfseResp *documents.Respo
 		Status:            nfseResp.Status,
 		CodigoVerificacao: nfseResp.CodigoVerificacao,
 		ValorNota:         nfseResp.ValorServico, // Mapear ValorServico para ValorNota
-		AliquotaIss:       0,                     // Será extraído do XML se necessário
+		AliquotaIss:       decimal.Zero,          // Será extraído do XML se necessário
 		ValorIss:          nfseResp.ValorIss,
 		Competencia:       nfseResp.Competencia,
 		XMLContent:        "", // Não armazenar XML no banco
@@ -197,13 +307,14 @@ func (j *NFSeSyncJob) processNFSe(ctx context.Context, nfseResp *documents.Respo
 
 	// Aplicar metadados extraídos
 	if metadata != nil {
-		if metadata.ValorServico > 0 {
+		if metadata.ValorServico.IsPositive() {
 			nfse.ValorNota = metadata.ValorServico
 		}
-		if metadata.ValorIss > 0 {
+		if metadata.ValorIss.IsPositive() {
 			nfse.ValorIss = metadata.ValorIss
 		}
-		if metadata.Aliquota > 0 {
+		nfse.ValorIssRetido = metadata.ValorIssRetido
+		if metadata.Aliquota.IsPositive() {
 			nfse.AliquotaIss = metadata.Aliquota
 		}
 		if metadata.CNPJPrestador != "" {
@@ -238,39 +349,51 @@ func (j *NFSeSyncJob) processNFSe(ctx context.Context, nfseResp *documents.Respo
 		}
 	}
 
-	// Salvar no banco
-	if err := j.nfseRepo.Create(ctx, nfse); err != nil {
-		return fmt.Errorf("erro ao salvar no banco: %w", err)
+	// Vincular emitente e tomador às empresas cadastradas
+	if j.empresaLinker != nil {
+		j.empresaLinker.Vincular(ctx, nfse)
 	}
 
-	// Salvar XML no MinIO
-	if nfseResp.XMLContent != "" {
-		// Usar CNPJ do prestador se disponível nos metadados, senão usar padrão
-		var objectName string
-		if metadata != nil && len(nfse.CNPJEmitente) > 0 {
-			objectName = j.minioClient.GenerateObjectNameWithCNPJ(nfseResp.NumeroNfse, nfseResp.Competencia, nfse.CNPJEmitente)
-		} else {
-			objectName = j.minioClient.GenerateObjectName(nfseResp.NumeroNfse, nfseResp.Competencia)
+	// Sem XML não há o que enviar ao armazenamento
+	if nfseResp.XMLContent == "" {
+		if err := service.SalvarDocumento(ctx, j.nfseRepo, j.audit, nfse, nil); err != nil {
+			return fmt.Errorf("erro ao salvar no banco: %w", err)
 		}
+		return nil
+	}
 
-		if err := j.minioClient.UploadXML(ctx, objectName, []byte(nfseResp.XMLContent)); err != nil {
-			logger.Database().Error().
-				Err(err).
-				Str("numero_nfse", nfseResp.NumeroNfse).
-				Str("object_name", objectName).
-				Msg("Erro ao salvar XML no MinIO")
-			// Não retornar erro, pois o registro já foi salvo no banco
-		} else {
-			logger.Database().Info().
-				Str("numero_nfse", nfseResp.NumeroNfse).
-				Str("object_name", objectName).
-				Msg("XML salvo no MinIO com sucesso")
-		}
+	// Documentos já sincronizados foram descartados pelo número; reenvios do mesmo
+	// conteúdo para a mesma chave são dispensados pelo outbox (hash no objeto)
+	xmlContent := []byte(nfseResp.XMLContent)
+	hash := storage.ContentSHA256(xmlContent)
+
+	// Chave no escritório do documento, com o CNPJ do prestador se disponível
+	objectName := storage.GenerateEscritorioObjectName(nfse.EscritorioID, nfseResp.NumeroNfse, nfseResp.Competencia, nfse.CNPJEmitente)
+
+	// Salvar documento e upload pendente na mesma transação
+	nfse.SetXMLStorage(j.store.Bucket(), objectName, xmlContent, hash)
+	entry := j.outboxService.NewEntry(nfse, xmlContent)
+	if err := service.SalvarDocumento(ctx, j.nfseRepo, j.audit, nfse, entry); err != nil {
+		return fmt.Errorf("erro ao salvar no banco: %w", err)
+	}
+
+	// Tentar o upload imediatamente; em caso de falha o outbox tentará novamente
+	if err := j.outboxService.Deliver(ctx, entry); err != nil {
+		logger.Database().Warn().
+			Err(err).
+			Str("numero_nfse", nfseResp.NumeroNfse).
+			Str("object_name", objectName).
+			Msg("Upload do XML adiado para o outbox")
+	} else {
+		logger.Database().Info().
+			Str("numero_nfse", nfseResp.NumeroNfse).
+			Str("object_name", objectName).
+			Msg("XML salvo no armazenamento com sucesso")
 	}
 
 	logger.Database().Debug().
 		Str("numero_nfse", nfseResp.NumeroNfse).
-		Float64("valor_nota", nfse.ValorNota).
+		Str("valor_nota", nfse.ValorNota.StringFixed(2)).
 		Msg("NFS-e processada com sucesso")
 
 	return nil
@@ -278,9 +401,10 @@ func (j *NFSeSyncJob) processNFSe(ctx context.Context, nfseResp *documents.Respo
 
 // XMLMetadata metadados extraídos do XML
 type XMLMetadata struct {
-	ValorServico                float64
-	ValorIss                    float64
-	Aliquota                    float64
+	ValorServico                decimal.Decimal
+	ValorIss                    decimal.Decimal
+	ValorIssRetido              decimal.Decimal
+	Aliquota                    decimal.Decimal
 	Discriminacao               string
 	CodigoServico               string
 	ItemListaServico            string
@@ -312,6 +436,7 @@ func (j *NFSeSyncJob) extractMetadata(xmlContent string) (*XMLMetadata, error) {
 	metadata := &XMLMetadata{
 		ValorServico:                xmlData.ValorServico,
 		ValorIss:                    xmlData.ValorIss,
+		ValorIssRetido:              xmlData.ValorIssRetido,
 		Aliquota:                    xmlData.Aliquota,
 		Discriminacao:               xmlData.Discriminacao,
 		CodigoServico:               xmlData.CodigoServico,
@@ -0,0 +1,181 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository/memory"
+	"zemdocs/internal/jobs"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeClient cliente de documentos com uma única página de resultados
+type fakeClient struct {
+	responses []documents.Response
+	pages     []string
+}
+
+func (c *fakeClient) ConsultarDocuments(ctx context.Context, req documents.ConsultarRequest) (*documents.Response, error) {
+	return nil, errors.New("não implementado")
+}
+
+func (c *fakeClient) ConsultarXMLDocuments(ctx context.Context, req documents.ConsultarXMLRequest) ([]documents.Response, error) {
+	c.pages = append(c.pages, req.NrCompetencia+"/"+req.NrPage)
+	if req.NrPage != "1" {
+		return nil, nil
+	}
+	return c.responses, nil
+}
+
+func (c *fakeClient) UltimoRPSEnviado(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+// nfseResponse documento com XML mínimo no layout de Imperatriz
+func nfseResponse(numero string, documentType documents.DocumentType, cnpjPrestador, cnpjTomador string) documents.Response {
+	return documents.Response{
+		DocumentType:      documentType,
+		NumeroNfse:        numero,
+		DataEmissao:       time.Date(2024, time.August, 29, 11, 47, 0, 0, time.UTC),
+		Status:            "Emitida",
+		CodigoVerificacao: "ABC123",
+		ValorServico:      decimal.NewFromInt(100),
+		Competencia:       "202408",
+		XMLContent: fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?><consultarNotaResponse><ListaNfse><ComplNfse><Nfse><InfNfse>`+
+			`<Numero>%s</Numero><Servico><Valores><ValorServicos>250.00</ValorServicos><ValorIss>12.50</ValorIss></Valores></Servico>`+
+			`<PrestadorServico><IdentificacaoPrestador><Cnpj>%s</Cnpj></IdentificacaoPrestador></PrestadorServico>`+
+			`<TomadorServico><IdentificacaoTomador><CpfCnpj><Cnpj>%s</Cnpj></CpfCnpj></IdentificacaoTomador></TomadorServico>`+
+			`</InfNfse></Nfse></ComplNfse></ListaNfse></consultarNotaResponse>`, numero, cnpjPrestador, cnpjTomador),
+	}
+}
+
+// syncFixture job de sincronização sobre o banco em memória
+type syncFixture struct {
+	client     *fakeClient
+	documentos *memory.DocumentRepository
+	outbox     *memory.StorageOutboxRepository
+	audit      *memory.AuditLogRepository
+	job        *jobs.NFSeSyncJob
+}
+
+func newSyncFixture(t *testing.T, responses ...documents.Response) *syncFixture {
+	t.Helper()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost", "chave-de-teste")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	db := memory.NewDB()
+	f := &syncFixture{
+		client:     &fakeClient{responses: responses},
+		documentos: memory.NewDocumentRepository(db),
+		outbox:     memory.NewStorageOutboxRepository(db),
+		audit:      memory.NewAuditLogRepository(db),
+	}
+	outboxService := service.NewStorageOutboxService(f.outbox, store, nil)
+	f.job = jobs.NewNFSeSyncJob(f.client, f.documentos, store, outboxService, "202408")
+	f.job.SetAudit(service.NewAuditService(f.audit))
+	return f
+}
+
+// numeros números dos documentos visíveis no contexto
+func (f *syncFixture) numeros(t *testing.T, ctx context.Context) []string {
+	t.Helper()
+	docs, err := f.documentos.ListAfterID(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("ListAfterID: %v", err)
+	}
+	var numeros []string
+	for _, doc := range docs {
+		numeros = append(numeros, doc.NumeroDocumento)
+	}
+	return numeros
+}
+
+func TestNFSeSyncJobExecute(t *testing.T) {
+	f := newSyncFixture(t,
+		nfseResponse("2001", documents.DocumentTypeNFSe, "11111111000191", "22222222000191"),
+		nfseResponse("2002", documents.DocumentTypeNFSe, "33333333000191", "22222222000191"),
+	)
+	ctx := tenant.WithEscritorio(context.Background(), 3)
+
+	if err := f.job.Execute(ctx); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !slices.Equal(f.client.pages, []string{"202408/1"}) {
+		t.Errorf("páginas consultadas = %v, esperado apenas a primeira", f.client.pages)
+	}
+
+	document, err := f.documentos.GetByNumeroNfse(ctx, "2001")
+	if err != nil {
+		t.Fatalf("documento 2001 não gravado: %v", err)
+	}
+	if document.EscritorioID == nil || *document.EscritorioID != 3 {
+		t.Errorf("documento gravado no escritório %v, esperado 3", document.EscritorioID)
+	}
+	if !document.ValorNota.Equal(decimal.RequireFromString("250.00")) {
+		t.Errorf("valor da nota %s, esperado o do XML (250.00)", document.ValorNota)
+	}
+	if document.XMLKey == "" || document.XMLSha256 == "" {
+		t.Error("localização do XML não registrada no documento")
+	}
+
+	entry, err := f.outbox.GetByDocumentID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("GetByDocumentID: %v", err)
+	}
+	if entry.Status != model.OutboxStatusConcluido || entry.Tenant != "3" {
+		t.Errorf("outbox = status %s, tenant %q; esperado concluído no escritório 3", entry.Status, entry.Tenant)
+	}
+
+	logs, err := f.audit.List(ctx, &model.AuditFilter{Entity: model.AuditEntityDocument, Action: model.AuditActionCreate}, 0, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("%d registros de auditoria, esperado um por documento", len(logs))
+	}
+
+	// Uma nova execução não duplica os documentos
+	if err := f.job.Execute(ctx); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if numeros := f.numeros(t, ctx); !slices.Equal(numeros, []string{"2001", "2002"}) {
+		t.Errorf("documentos após duas execuções = %v", numeros)
+	}
+
+	// Outro escritório não enxerga os documentos sincronizados
+	if numeros := f.numeros(t, tenant.WithEscritorio(context.Background(), 4)); len(numeros) != 0 {
+		t.Errorf("documentos visíveis em outro escritório: %v", numeros)
+	}
+}
+
+func TestNFSeSyncJobFiltros(t *testing.T) {
+	f := newSyncFixture(t,
+		nfseResponse("3001", documents.DocumentTypeNFSe, "11111111000191", "22222222000191"),
+		nfseResponse("3002", documents.DocumentTypeNFSe, "33333333000191", "11111111000191"),
+		nfseResponse("3003", documents.DocumentTypeNFSe, "33333333000191", "22222222000191"),
+		nfseResponse("3004", documents.DocumentTypeNFe, "11111111000191", "22222222000191"),
+	)
+	ctx := tenant.WithEscritorio(context.Background(), 1)
+
+	// Emitidos ou recebidos pela empresa, apenas NFS-e
+	f.job.SetEmpresa("11111111000191")
+	f.job.SetDocumentTypes([]documents.DocumentType{documents.DocumentTypeNFSe})
+
+	if err := f.job.Execute(ctx); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if numeros := f.numeros(t, ctx); !slices.Equal(numeros, []string{"3001", "3002"}) {
+		t.Errorf("documentos sincronizados = %v, esperado [3001 3002]", numeros)
+	}
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+)
+
+// ScheduleFactory monta os jobs definidos na tabela de agendamentos
+type ScheduleFactory struct {
+	registry      *documents.Registry
+	nfseRepo      service.DocumentRepository
+	outboxRepo    service.StorageOutboxRepository
+	orphanRepo    service.StorageOrphanRepository
+	empresaRepo   service.EmpresaRepository
+	store         storage.Store
+	outboxService *service.StorageOutboxService
+	onFailure     documents.FailureHandler
+	empresaLinker *service.EmpresaLinkService
+	dashboard     *service.DashboardService
+	audit         *service.AuditService
+	partitions    *service.DocumentPartitionService
+}
+
+// NewScheduleFactory cria uma nova fábrica de jobs agendados
+func NewScheduleFactory(
+	registry *documents.Registry,
+	nfseRepo service.DocumentRepository,
+	outboxRepo service.StorageOutboxRepository,
+	orphanRepo service.StorageOrphanRepository,
+	empresaRepo service.EmpresaRepository,
+	store storage.Store,
+	outboxService *service.StorageOutboxService,
+) *ScheduleFactory {
+	return &ScheduleFactory{
+		registry:      registry,
+		nfseRepo:      nfseRepo,
+		outboxRepo:    outboxRepo,
+		orphanRepo:    orphanRepo,
+		empresaRepo:   empresaRepo,
+		store:         store,
+		outboxService: outboxService,
+	}
+}
+
+// SetFailureHandler define quem recebe os documentos que falharem nos jobs de sincronização
+func (f *ScheduleFactory) SetFailureHandler(handler documents.FailureHandler) {
+	f.onFailure = handler
+}
+
+// SetEmpresaLinker define o serviço que vincula os documentos sincronizados às empresas cadastradas
+func (f *ScheduleFactory) SetEmpresaLinker(linker *service.EmpresaLinkService) {
+	f.empresaLinker = linker
+}
+
+// SetDashboard define o dashboard cujo recálculo é agendado ao fim de cada sincronização
+func (f *ScheduleFactory) SetDashboard(dashboard *service.DashboardService) {
+	f.dashboard = dashboard
+}
+
+// SetAudit define a auditoria em que os documentos sincronizados são registrados
+func (f *ScheduleFactory) SetAudit(auditService *service.AuditService) {
+	f.audit = auditService
+}
+
+// SetPartitionService define o serviço usado pelos agendamentos de manutenção das partições
+func (f *ScheduleFactory) SetPartitionService(partitionService *service.DocumentPartitionService) {
+	f.partitions = partitionService
+}
+
+// Build implementa scheduler.JobFactory
+func (f *ScheduleFactory) Build(ctx context.Context, schedule *model.JobSchedule) (scheduler.Job, error) {
+	switch schedule.Type {
+	case model.JobScheduleTypeNFSeSync:
+		return f.buildNFSeSync(ctx, schedule)
+	case model.JobScheduleTypeStorageOutbox:
+		return NewStorageOutboxJob(f.outboxService), nil
+	case model.JobScheduleTypeStorageReconcile:
+		return NewStorageReconcileJob(f.nfseRepo, f.outboxRepo, f.orphanRepo, f.store), nil
+	case model.JobScheduleTypeStorageScrub:
+		return NewStorageScrubJob(f.nfseRepo, f.outboxRepo, f.store), nil
+	case model.JobScheduleTypeDocumentPartition:
+		if f.partitions == nil {
+			return nil, fmt.Errorf("manutenção de partições não configurada")
+		}
+		return NewDocumentPartitionJob(f.partitions), nil
+	default:
+		return nil, fmt.Errorf("tipo de job desconhecido: %s", schedule.Type)
+	}
+}
+
+// buildNFSeSync monta a sincronização de um município, opcionalmente restrita a uma empresa.
+// A competência é resolvida a cada execução, de modo que "atual" acompanha o calendário.
+func (f *ScheduleFactory) buildNFSeSync(ctx context.Context, schedule *model.JobSchedule) (scheduler.Job, error) {
+	client, exists := f.registry.GetClient(schedule.CodigoIBGE)
+	if !exists {
+		return nil, fmt.Errorf("município %s não possui cliente registrado", schedule.CodigoIBGE)
+	}
+
+	documentTypes, err := service.ParseDocumentTypes(schedule.Params[model.JobParamDocumentTypes])
+	if err != nil {
+		return nil, err
+	}
+
+	var cnpjEmpresa string
+	if schedule.EmpresaID != nil {
+		empresa, err := f.empresaRepo.GetByID(ctx, *schedule.EmpresaID)
+		if err != nil {
+			return nil, fmt.Errorf("empresa %d não encontrada: %w", *schedule.EmpresaID, err)
+		}
+		cnpjEmpresa = empresa.CNPJ
+	}
+
+	competenciaParam := schedule.Params[model.JobParamCompetencia]
+	escritorioID := schedule.EscritorioID
+
+	return scheduler.NewFuncJob(schedule.JobName(), func(ctx context.Context) error {
+		// Os documentos sincronizados pertencem ao escritório do agendamento
+		if escritorioID != nil {
+			ctx = tenant.WithEscritorio(ctx, *escritorioID)
+		}
+
+		competencia, err := service.ResolverCompetencia(competenciaParam, time.Now())
+		if err != nil {
+			return err
+		}
+
+		syncJob := NewNFSeSyncJob(client, f.nfseRepo, f.store, f.outboxService, competencia)
+		syncJob.SetFailureHandler(f.onFailure)
+		syncJob.SetEmpresaLinker(f.empresaLinker)
+		syncJob.SetDashboard(f.dashboard)
+		syncJob.SetAudit(f.audit)
+		syncJob.SetDocumentTypes(documentTypes)
+		syncJob.SetEmpresa(cnpjEmpresa)
+
+		return syncJob.Execute(ctx)
+	}), nil
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+
+	"zemdocs/internal/logger"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
+)
+
+// StorageOutboxJob job que reenvia ao armazenamento os uploads pendentes do outbox
+type StorageOutboxJob struct {
+	outboxService *service.StorageOutboxService
+	batchSize     int
+}
+
+// NewStorageOutboxJob cria uma nova instância do job
+func NewStorageOutboxJob(outboxService *service.StorageOutboxService) *StorageOutboxJob {
+	return &StorageOutboxJob{
+		outboxService: outboxService,
+		batchSize:     100,
+	}
+}
+
+// Name retorna o nome do job
+func (j *StorageOutboxJob) Name() string {
+	return "storage-outbox"
+}
+
+// Execute processa lotes do outbox até não restarem entradas prontas para envio
+func (j *StorageOutboxJob) Execute(ctx context.Context) error {
+	run := scheduler.RunFromContext(ctx)
+	totalDelivered := 0
+	totalFailed := 0
+
+	for {
+		delivered, failed, err := j.outboxService.ProcessPending(ctx, j.batchSize)
+		totalDelivered += delivered
+		totalFailed += failed
+		run.Add("delivered", int64(delivered))
+		run.Add("failed", int64(failed))
+		if err != nil {
+			return err
+		}
+
+		// Lote incompleto ou somente falhas (já reagendadas): nada mais a fazer agora
+		if delivered+failed < j.batchSize || delivered == 0 {
+			break
+		}
+	}
+
+	if totalDelivered > 0 || totalFailed > 0 {
+		logger.Database().Info().
+			Int("delivered", totalDelivered).
+			Int("failed", totalFailed).
+			Msg("Outbox de armazenamento processado")
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+)
+
+// ReconcileReport resultado de uma execução da reconciliação entre banco e armazenamento
+type ReconcileReport struct {
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	DocumentsChecked int       `json:"documents_checked"`
+	ObjectsChecked   int       `json:"objects_checked"`
+	PendingUpload    int       `json:"pending_upload"`   // Sem objeto, mas com upload pendente no outbox
+	Requeued         int       `json:"requeued"`         // Sem objeto, upload com falha devolvido à fila
+	Missing          int       `json:"missing"`          // Sem objeto e sem como reparar (marcados como XML ausente)
+	Orphans          int       `json:"orphans"`          // Objetos sem documento (registrados em storage_orphans)
+	OrphansResolved  int       `json:"orphans_resolved"` // Órfãos de execuções anteriores que deixaram de existir
+}
+
+// StorageReconcileJob job que compara os documentos do banco com os objetos do
+// armazenamento. Documentos sem objeto são reparados pelo outbox ou marcados como
+// XML ausente; objetos sem documento ficam registrados em storage_orphans. Objetos
+// no layout legado só têm documento depois de registrados pelo storagemigrate.
+type StorageReconcileJob struct {
+	nfseRepo   service.DocumentRepository
+	outboxRepo service.StorageOutboxRepository
+	orphanRepo service.StorageOrphanRepository
+	store      storage.Store
+	prefixes   []string
+	pageSize   int
+
+	mu         sync.RWMutex
+	lastReport *ReconcileReport
+}
+
+// NewStorageReconcileJob cria uma nova instância do job
+func NewStorageReconcileJob(
+	nfseRepo service.DocumentRepository,
+	outboxRepo service.StorageOutboxRepository,
+	orphanRepo service.StorageOrphanRepository,
+	store storage.Store,
+) *StorageReconcileJob {
+	return &StorageReconcileJob{
+		nfseRepo:   nfseRepo,
+		outboxRepo: outboxRepo,
+		orphanRepo: orphanRepo,
+		store:      store,
+		prefixes:   []string{"XML/", "nfse/"},
+		pageSize:   500,
+	}
+}
+
+// Name retorna o nome do job
+func (j *StorageReconcileJob) Name() string {
+	return "storage-reconcile"
+}
+
+// LastReport retorna o relatório da última execução concluída
+func (j *StorageReconcileJob) LastReport() *ReconcileReport {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.lastReport
+}
+
+// Execute executa a reconciliação
+func (j *StorageReconcileJob) Execute(ctx context.Context) error {
+	run := scheduler.RunFromContext(ctx)
+	report := &ReconcileReport{StartedAt: time.Now()}
+
+	if err := j.checkDocuments(ctx, report); err != nil {
+		return err
+	}
+	if err := j.checkObjects(ctx, report); err != nil {
+		return err
+	}
+
+	// Órfãos não vistos nesta varredura completa já foram resolvidos
+	resolved, err := j.orphanRepo.DeleteSeenBefore(ctx, report.StartedAt)
+	if err != nil {
+		return fmt.Errorf("erro ao remover órfãos resolvidos: %w", err)
+	}
+	report.OrphansResolved = resolved
+	report.FinishedAt = time.Now()
+
+	j.mu.Lock()
+	j.lastReport = report
+	j.mu.Unlock()
+
+	run.Add("documents", int64(report.DocumentsChecked))
+	run.Add("objects", int64(report.ObjectsChecked))
+	run.Add("pending_upload", int64(report.PendingUpload))
+	run.Add("requeued", int64(report.Requeued))
+	run.Add("missing", int64(report.Missing))
+	run.Add("orphans", int64(report.Orphans))
+	run.Add("orphans_resolved", int64(report.OrphansResolved))
+
+	logEvent := logger.Database().Info()
+	if report.Missing > 0 || report.Orphans > 0 {
+		logEvent = logger.Database().Warn()
+	}
+	logEvent.
+		Int("documents", report.DocumentsChecked).
+		Int("objects", report.ObjectsChecked).
+		Int("pending_upload", report.PendingUpload).
+		Int("requeued", report.Requeued).
+		Int("missing", report.Missing).
+		Int("orphans", report.Orphans).
+		Int("orphans_resolved", report.OrphansResolved).
+		Msg("Reconciliação de armazenamento concluída")
+
+	return nil
+}
+
+// checkDocuments confere, página a página, se o objeto de cada documento com XML existe
+func (j *StorageReconcileJob) checkDocuments(ctx context.Context, report *ReconcileReport) error {
+	afterID := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		docs, err := j.nfseRepo.ListWithXMLKeyAfterID(ctx, afterID, j.pageSize)
+		if err != nil {
+			return fmt.Errorf("erro ao listar documentos: %w", err)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		for _, doc := range docs {
+			afterID = doc.ID
+			report.DocumentsChecked++
+
+			_, err := j.store.Stat(ctx, doc.XMLKey)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, storage.ErrObjectNotFound) {
+				return fmt.Errorf("erro ao consultar %s: %w", doc.XMLKey, err)
+			}
+			if err := j.repairMissing(ctx, doc, report); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// repairMissing tenta reparar um documento sem objeto a partir do outbox; sem
+// como reparar, marca o XML do documento como ausente
+func (j *StorageReconcileJob) repairMissing(ctx context.Context, doc *model.Document, report *ReconcileReport) error {
+	entry, err := j.outboxRepo.GetByDocumentID(ctx, doc.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("erro ao consultar outbox do documento %d: %w", doc.ID, err)
+	}
+
+	switch {
+	case entry != nil && entry.Status == model.OutboxStatusPendente:
+		report.PendingUpload++
+		return nil
+	case entry != nil && entry.Status == model.OutboxStatusFalha:
+		if err := j.outboxRepo.Requeue(ctx, entry.ID); err != nil {
+			return fmt.Errorf("erro ao reenfileirar outbox %d: %w", entry.ID, err)
+		}
+		report.Requeued++
+		return nil
+	}
+
+	// Sem outbox ou entregue mas o objeto sumiu: o payload já foi descartado
+	report.Missing++
+	scheduler.RunFromContext(ctx).Errorf("Documento %d (%s): XML ausente em %s", doc.ID, doc.NumeroDocumento, doc.XMLKey)
+	if err := j.nfseRepo.UpdateXMLIntegrity(ctx, doc.ID, model.XMLIntegrityAusente, time.Now()); err != nil {
+		return fmt.Errorf("erro ao registrar integridade do documento %d: %w", doc.ID, err)
+	}
+	return nil
+}
+
+// checkObjects percorre a listagem do armazenamento em páginas e registra os
+// objetos que não são o XML de nenhum documento
+func (j *StorageReconcileJob) checkObjects(ctx context.Context, report *ReconcileReport) error {
+	for _, prefix := range j.prefixes {
+		startAfter := ""
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			keys, err := j.store.ListPage(ctx, prefix, startAfter, j.pageSize)
+			if err != nil {
+				return fmt.Errorf("erro ao listar objetos com prefixo %s: %w", prefix, err)
+			}
+			if len(keys) == 0 {
+				break
+			}
+			startAfter = keys[len(keys)-1]
+			report.ObjectsChecked += len(keys)
+
+			existing, err := j.nfseRepo.ExistingXMLKeys(ctx, keys)
+			if err != nil {
+				return fmt.Errorf("erro ao consultar chaves de XML: %w", err)
+			}
+
+			var orphans []string
+			for _, key := range keys {
+				if !existing[key] {
+					orphans = append(orphans, key)
+				}
+			}
+			if err := j.orphanRepo.Record(ctx, j.store.Bucket(), orphans, report.StartedAt); err != nil {
+				return fmt.Errorf("erro ao registrar objetos órfãos: %w", err)
+			}
+			report.Orphans += len(orphans)
+		}
+	}
+	return nil
+}
+
+// documentObjectKeys chaves em que o XML de um documento pode estar, da mais recente à legada
+func documentObjectKeys(doc *model.Document) []string {
+	if doc.XMLKey != "" {
+		return []string{doc.XMLKey}
+	}
+	if len(doc.Competencia) < 6 {
+		return nil
+	}
+
+	keys := []string{storage.GenerateEscritorioObjectName(doc.EscritorioID, doc.NumeroDocumento, doc.Competencia, doc.CNPJEmitente)}
+	if doc.CNPJEmitente != "" {
+		keys = append(keys, storage.GenerateObjectNameWithCNPJ(doc.NumeroDocumento, doc.Competencia, doc.CNPJEmitente))
+	}
+	return append(keys, storage.GenerateObjectName(doc.NumeroDocumento, doc.Competencia))
+}
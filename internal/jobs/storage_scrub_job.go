@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/scheduler"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+)
+
+// ScrubReport resultado de uma verificação de integridade dos XMLs
+type ScrubReport struct {
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	DocumentsChecked int       `json:"documents_checked"`
+	Verified         int       `json:"verified"`
+	Corrupted        []int     `json:"corrupted"` // IDs de documentos com hash divergente
+	Missing          []int     `json:"missing"`   // IDs de documentos sem objeto
+	PendingUpload    int       `json:"pending_upload"`
+}
+
+// StorageScrubJob job que baixa novamente os XMLs armazenados, recalcula o
+// SHA-256 e marca no documento os objetos corrompidos ou ausentes
+type StorageScrubJob struct {
+	nfseRepo   service.DocumentRepository
+	outboxRepo service.StorageOutboxRepository
+	store      storage.Store
+	pageSize   int
+
+	mu         sync.RWMutex
+	lastReport *ScrubReport
+}
+
+// NewStorageScrubJob cria uma nova instância do job
+func NewStorageScrubJob(nfseRepo service.DocumentRepository, outboxRepo service.StorageOutboxRepository, store storage.Store) *StorageScrubJob {
+	return &StorageScrubJob{
+		nfseRepo:   nfseRepo,
+		outboxRepo: outboxRepo,
+		store:      store,
+		pageSize:   200,
+	}
+}
+
+// Name retorna o nome do job
+func (j *StorageScrubJob) Name() string {
+	return "storage-scrub"
+}
+
+// LastReport retorna o relatório da última execução concluída
+func (j *StorageScrubJob) LastReport() *ScrubReport {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.lastReport
+}
+
+// Execute percorre os documentos com hash registrado e confere seus XMLs
+func (j *StorageScrubJob) Execute(ctx context.Context) error {
+	run := scheduler.RunFromContext(ctx)
+	report := &ScrubReport{StartedAt: time.Now()}
+
+	afterID := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		docs, err := j.nfseRepo.ListAfterID(ctx, afterID, j.pageSize)
+		if err != nil {
+			return fmt.Errorf("erro ao listar documentos: %w", err)
+		}
+		if len(docs) == 0 {
+			break
+		}
+
+		for _, doc := range docs {
+			afterID = doc.ID
+			if doc.XMLSha256 == "" {
+				continue
+			}
+			report.DocumentsChecked++
+
+			status, err := j.check(ctx, doc)
+			if err != nil {
+				return err
+			}
+
+			// Upload ainda na fila do outbox: não é perda de dados
+			if status == model.XMLIntegrityAusente && j.pendingUpload(ctx, doc.ID) {
+				report.PendingUpload++
+				continue
+			}
+
+			switch status {
+			case model.XMLIntegrityOK:
+				report.Verified++
+			case model.XMLIntegrityCorrompido:
+				report.Corrupted = append(report.Corrupted, doc.ID)
+				run.Errorf("Documento %d (%s): XML corrompido", doc.ID, doc.NumeroDocumento)
+			case model.XMLIntegrityAusente:
+				report.Missing = append(report.Missing, doc.ID)
+				run.Errorf("Documento %d (%s): XML ausente", doc.ID, doc.NumeroDocumento)
+			}
+			run.Add(status, 1)
+
+			if err := j.nfseRepo.UpdateXMLIntegrity(ctx, doc.ID, status, time.Now()); err != nil {
+				return fmt.Errorf("erro ao registrar integridade do documento %d: %w", doc.ID, err)
+			}
+		}
+	}
+	report.FinishedAt = time.Now()
+
+	j.mu.Lock()
+	j.lastReport = report
+	j.mu.Unlock()
+
+	logEvent := logger.Database().Info()
+	if len(report.Corrupted) > 0 || len(report.Missing) > 0 {
+		logEvent = logger.Database().Warn()
+	}
+	logEvent.
+		Int("documents", report.DocumentsChecked).
+		Int("verified", report.Verified).
+		Int("corrupted", len(report.Corrupted)).
+		Int("missing", len(report.Missing)).
+		Int("pending_upload", report.PendingUpload).
+		Ints("corrupted_ids", report.Corrupted).
+		Ints("missing_ids", report.Missing).
+		Msg("Verificação de integridade dos XMLs concluída")
+
+	return nil
+}
+
+// check baixa o XML do documento e compara o hash; erros de acesso ao armazenamento interrompem o job
+func (j *StorageScrubJob) check(ctx context.Context, doc *model.Document) (string, error) {
+	for _, key := range documentObjectKeys(doc) {
+		data, err := j.store.Get(ctx, key)
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("erro ao baixar %s: %w", key, err)
+		}
+
+		if err := storage.VerifySHA256(data, doc.XMLSha256); err != nil {
+			return model.XMLIntegrityCorrompido, nil
+		}
+		return model.XMLIntegrityOK, nil
+	}
+
+	return model.XMLIntegrityAusente, nil
+}
+
+// pendingUpload verifica se o XML do documento ainda aguarda envio pelo outbox
+func (j *StorageScrubJob) pendingUpload(ctx context.Context, documentID int) bool {
+	entry, err := j.outboxRepo.GetByDocumentID(ctx, documentID)
+	if err != nil {
+		return false
+	}
+	return entry.Status == model.OutboxStatusPendente
+}
@@ -0,0 +1,229 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"zemdocs/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// Colunas da tabela de produtos do DANFE
+var danfeColumns = []struct {
+	label string
+	width float64
+	align string
+}{
+	{"Código", 20, "L"},
+	{"Descrição do produto", 66, "L"},
+	{"NCM", 16, "C"},
+	{"CFOP", 12, "C"},
+	{"UN", 10, "C"},
+	{"Quant.", 20, "R"},
+	{"Valor unit.", 22, "R"},
+	{"Valor total", 24, "R"},
+}
+
+// DANFE gera o documento auxiliar da NF-e: emitente, chave de acesso com QR code,
+// destinatário, cálculo do imposto, produtos e dados adicionais
+func (r *Renderer) DANFE(data *utils.NFeXMLData, w io.Writer) error {
+	d := newDocument("NF-e "+data.Numero, data.DataEmissao)
+
+	y, err := r.danfeHeader(d, data, 1)
+	if err != nil {
+		return err
+	}
+	x := pageMargin
+
+	// Destinatário
+	y = d.section(y, "Destinatário / remetente")
+	documento := data.Destinatario.CNPJ
+	if documento == "" {
+		documento = data.Destinatario.CPF
+	}
+	d.field(x, y, 120, 9, "Nome / razão social", data.Destinatario.Nome, "L")
+	d.field(x+120, y, 40, 9, "CNPJ / CPF", formatDocumento(documento), "L")
+	d.field(x+160, y, 30, 9, "Data de emissão", data.DataEmissao.Format("02/01/2006"), "C")
+	y += 9
+	d.field(x, y, 100, 9, "Endereço", joinNonEmpty(", ", data.Destinatario.Logradouro, data.Destinatario.Numero), "L")
+	d.field(x+100, y, 60, 9, "Bairro", data.Destinatario.Bairro, "L")
+	d.field(x+160, y, 30, 9, "CEP", formatCEP(data.Destinatario.CEP), "L")
+	y += 9
+	d.field(x, y, 80, 9, "Município", data.Destinatario.Municipio, "L")
+	d.field(x+80, y, 15, 9, "UF", data.Destinatario.UF, "C")
+	d.field(x+95, y, 45, 9, "Telefone", data.Destinatario.Telefone, "L")
+	d.field(x+140, y, 50, 9, "Inscrição estadual", data.Destinatario.InscricaoEstadual, "L")
+	y += 11
+
+	// Cálculo do imposto
+	y = d.section(y, "Cálculo do imposto")
+	totals := []struct {
+		label string
+		value decimal.Decimal
+	}{
+		{"Base de cálculo do ICMS", data.BaseCalculoICMS},
+		{"Valor do ICMS", data.ValorICMS},
+		{"Valor total dos produtos", data.ValorProdutos},
+		{"Valor do frete", data.ValorFrete},
+		{"Valor do seguro", data.ValorSeguro},
+		{"Desconto", data.ValorDesconto},
+		{"Outras despesas", data.ValorOutros},
+		{"Valor do IPI", data.ValorIPI},
+		{"Valor total da nota", data.ValorNota},
+	}
+	cellWidth := pageWidth / 5
+	for i, total := range totals {
+		width := cellWidth
+		if i == len(totals)-1 {
+			width = cellWidth * 2
+		}
+		d.field(x+float64(i%5)*cellWidth, y+float64(i/5)*9, width, 9, total.label, formatMoney(total.value), "R")
+	}
+	y += 20
+
+	// Produtos, continuando em novas folhas quando necessário
+	y = d.section(y, "Dados dos produtos / serviços")
+	y = danfeTableHeader(d, y)
+	page := 1
+	for _, item := range data.Itens {
+		d.SetFont("Helvetica", "", 7)
+		lines := d.SplitLines([]byte(d.tr(item.Descricao)), danfeColumns[1].width-1)
+		height := float64(len(lines))*3.2 + 1.5
+		if y+height > pageBottom-30 {
+			page++
+			d.AddPage()
+			if y, err = r.danfeHeader(d, data, page); err != nil {
+				return err
+			}
+			y = danfeTableHeader(d, d.section(y, "Dados dos produtos / serviços (continuação)"))
+		}
+
+		values := []string{
+			item.Codigo,
+			"",
+			item.NCM,
+			item.CFOP,
+			item.Unidade,
+			formatDecimal(item.Quantidade, 4),
+			formatDecimal(item.ValorUnitario, 4),
+			formatMoney(item.ValorTotal),
+		}
+		colX := x
+		for i, column := range danfeColumns {
+			d.Rect(colX, y, column.width, height, "D")
+			if i == 1 {
+				for j, line := range lines {
+					d.SetXY(colX+0.5, y+0.7+float64(j)*3.2)
+					d.CellFormat(column.width-1, 3.2, string(line), "", 0, "L", false, 0, "")
+				}
+			} else {
+				d.SetXY(colX+0.5, y+0.7)
+				d.CellFormat(column.width-1, 3.2, d.tr(d.fit(values[i], column.width-1)), "", 0, column.align, false, 0, "")
+			}
+			colX += column.width
+		}
+		y += height
+	}
+	y += 2
+
+	// Dados adicionais
+	if y < pageBottom-20 {
+		y = d.section(y, "Dados adicionais")
+		d.text(y, pageBottom-y, "Informações complementares", data.InformacoesComplementares)
+	}
+
+	return d.output(w)
+}
+
+// danfeHeader quadro do emitente, identificação da nota e chave de acesso
+func (r *Renderer) danfeHeader(d *document, data *utils.NFeXMLData, page int) (float64, error) {
+	x, y := pageMargin, pageMargin
+
+	// Emitente
+	d.Rect(x, y, 80, 34, "D")
+	d.SetXY(x+2, y+3)
+	d.SetFont("Helvetica", "B", 9)
+	d.MultiCell(76, 4.5, d.tr(data.Emitente.Nome), "", "C", false)
+	d.SetX(x + 2)
+	d.SetFont("Helvetica", "", 7)
+	emitente := data.Emitente
+	d.MultiCell(76, 3.5, d.tr(joinNonEmpty(", ", emitente.Logradouro, emitente.Numero, emitente.Bairro)), "", "C", false)
+	d.SetX(x + 2)
+	d.MultiCell(76, 3.5, d.tr(joinNonEmpty(" - ", emitente.Municipio, emitente.UF, formatCEP(emitente.CEP))), "", "C", false)
+	if emitente.Telefone != "" {
+		d.SetX(x + 2)
+		d.CellFormat(76, 3.5, d.tr("Fone: "+emitente.Telefone), "", 0, "C", false, 0, "")
+	}
+
+	// Identificação
+	d.Rect(x+80, y, 40, 34, "D")
+	d.SetXY(x+80, y+2)
+	d.SetFont("Helvetica", "B", 12)
+	d.CellFormat(40, 6, "DANFE", "", 2, "C", false, 0, "")
+	d.SetX(x + 80)
+	d.SetFont("Helvetica", "", 6)
+	d.MultiCell(40, 2.8, d.tr("Documento Auxiliar da Nota Fiscal Eletrônica"), "", "C", false)
+	d.SetX(x + 80)
+	d.SetFont("Helvetica", "", 7)
+	d.CellFormat(28, 5, d.tr("0 - ENTRADA / 1 - SAÍDA"), "", 0, "C", false, 0, "")
+	d.SetFont("Helvetica", "B", 10)
+	d.CellFormat(8, 5, data.TipoOperacao, "1", 2, "C", false, 0, "")
+	d.SetX(x + 80)
+	d.SetFont("Helvetica", "B", 8)
+	d.CellFormat(40, 4.5, d.tr("Nº "+data.Numero), "", 2, "C", false, 0, "")
+	d.SetX(x + 80)
+	d.CellFormat(40, 4.5, d.tr(fmt.Sprintf("SÉRIE %s - FOLHA %d", data.Serie, page)), "", 0, "C", false, 0, "")
+
+	// Chave de acesso com QR code
+	if err := d.qrCode(fmt.Sprintf("qrcode-%d", page), data.ChaveAcesso, x+122, y+2, 22); err != nil {
+		return 0, err
+	}
+	d.SetXY(x+146, y+2)
+	d.SetFont("Helvetica", "", 5.5)
+	d.CellFormat(44, 2.5, d.tr("CHAVE DE ACESSO"), "", 2, "L", false, 0, "")
+	d.SetX(x + 146)
+	d.SetFont("Helvetica", "B", 7)
+	d.MultiCell(44, 3.3, formatChave(data.ChaveAcesso), "", "L", false)
+	d.SetXY(x+122, y+25)
+	d.SetFont("Helvetica", "", 6)
+	d.MultiCell(68, 2.8, d.tr("Consulte a autenticidade no portal nacional da NF-e (www.nfe.fazenda.gov.br) ou no site da SEFAZ autorizadora"), "", "L", false)
+	d.Rect(x+120, y, 70, 34, "D")
+	y += 34
+
+	d.field(x, y, 120, 9, "Natureza da operação", data.NaturezaOperacao, "L")
+	protocolo := joinNonEmpty(" - ", data.Protocolo, formatDateTime(data.DataAutorizacao))
+	d.field(x+120, y, 70, 9, "Protocolo de autorização de uso", protocolo, "C")
+	y += 9
+	documento := emitente.CNPJ
+	if documento == "" {
+		documento = emitente.CPF
+	}
+	d.field(x, y, 95, 9, "Inscrição estadual", emitente.InscricaoEstadual, "L")
+	d.field(x+95, y, 95, 9, "CNPJ / CPF", formatDocumento(documento), "L")
+
+	return y + 11, nil
+}
+
+// danfeTableHeader cabeçalho da tabela de produtos
+func danfeTableHeader(d *document, y float64) float64 {
+	d.SetFont("Helvetica", "B", 6)
+	x := pageMargin
+	for _, column := range danfeColumns {
+		d.SetXY(x, y)
+		d.CellFormat(column.width, 5, d.tr(strings.ToUpper(column.label)), "1", 0, "C", false, 0, "")
+		x += column.width
+	}
+	return y + 5
+}
+
+// formatChave separa a chave de acesso em grupos de quatro dígitos
+func formatChave(chave string) string {
+	var groups []string
+	for len(chave) > 4 {
+		groups = append(groups, chave[:4])
+		chave = chave[4:]
+	}
+	return strings.Join(append(groups, chave), " ")
+}
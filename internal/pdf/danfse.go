@@ -0,0 +1,136 @@
+package pdf
+
+import (
+	"io"
+	"strings"
+
+	"zemdocs/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// DANFSE gera o documento auxiliar da NFS-e: cabeçalho do município, prestador,
+// tomador, discriminação, valores e QR code com o código de verificação
+func (r *Renderer) DANFSE(data *utils.NFSeXMLData, w io.Writer) error {
+	d := newDocument("NFS-e "+data.NumeroNfse, data.DataEmissao)
+	x := pageMargin
+
+	// Cabeçalho: logo, município e identificação da nota
+	y := pageMargin
+	d.Rect(x, y, pageWidth, 26, "D")
+	titleX := x + 2
+	if r.logo != nil {
+		d.image("logo", r.logo, r.logoType, x+2, y+2, 22, 22)
+		titleX = x + 26
+	}
+	d.SetXY(titleX, y+3)
+	d.SetFont("Helvetica", "B", 10)
+	d.CellFormat(120, 5, d.tr(strings.ToUpper(r.municipio)), "", 2, "L", false, 0, "")
+	d.SetX(titleX)
+	d.SetFont("Helvetica", "B", 12)
+	d.CellFormat(120, 7, d.tr("NOTA FISCAL DE SERVIÇOS ELETRÔNICA - NFS-e"), "", 2, "L", false, 0, "")
+	d.SetX(titleX)
+	d.SetFont("Helvetica", "", 7)
+	d.CellFormat(120, 4, d.tr("Documento auxiliar gerado a partir do XML autorizado"), "", 0, "L", false, 0, "")
+
+	d.field(x+140, y, 50, 9, "Número da NFS-e", data.NumeroNfse, "C")
+	d.field(x+140, y+9, 50, 8.5, "Data e hora de emissão", formatDateTime(data.DataEmissao), "C")
+	d.field(x+140, y+17.5, 50, 8.5, "Código de verificação", data.CodigoVerificacao, "C")
+	y += 28
+
+	// Prestador
+	y = d.section(y, "Prestador de serviços")
+	d.field(x, y, 130, 9, "Razão social", data.RazaoSocialPrestador, "L")
+	d.field(x+130, y, 60, 9, "CNPJ", formatDocumento(data.CNPJPrestador), "L")
+	y += 9
+	d.field(x, y, 130, 9, "Nome fantasia", data.NomeFantasiaPrestador, "L")
+	d.field(x+130, y, 60, 9, "Inscrição municipal", data.InscricaoMunicipalPrestador, "L")
+	y += 9
+	d.field(x, y, 150, 9, "Endereço", joinNonEmpty(", ", data.EnderecoPrestador, data.NumeroPrestador, data.BairroPrestador), "L")
+	d.field(x+150, y, 40, 9, "CEP", formatCEP(data.CEPPrestador), "L")
+	y += 11
+
+	// Tomador
+	y = d.section(y, "Tomador de serviços")
+	d.field(x, y, 130, 9, "Razão social / Nome", data.RazaoSocialTomador, "L")
+	d.field(x+130, y, 60, 9, "CNPJ / CPF", formatDocumento(data.CNPJTomador), "L")
+	y += 9
+	d.field(x, y, 110, 9, "Endereço", joinNonEmpty(", ", data.EnderecoTomador, data.NumeroTomador, data.ComplementoTomador, data.BairroTomador), "L")
+	d.field(x+110, y, 30, 9, "Município (IBGE)", data.CidadeTomador, "L")
+	d.field(x+140, y, 15, 9, "UF", data.UFTomador, "L")
+	d.field(x+155, y, 35, 9, "CEP", formatCEP(data.CEPTomador), "L")
+	y += 11
+
+	// Discriminação
+	y = d.section(y, "Discriminação dos serviços")
+	y = d.text(y, 50, "Descrição", data.Discriminacao) + 2
+
+	// Serviço
+	y = d.section(y, "Serviço")
+	d.field(x, y, 60, 9, "Item da lista de serviços", data.ItemListaServico, "L")
+	d.field(x+60, y, 70, 9, "Código do serviço (CNAE)", data.CodigoServico, "L")
+	d.field(x+130, y, 60, 9, "Município da prestação (IBGE)", data.CodigoMunicipio, "L")
+	y += 11
+
+	// Valores
+	retencoes := decimal.Sum(data.ValorPis, data.ValorCofins, data.ValorInss, data.ValorIr, data.ValorCsll, data.OutrasRetencoes)
+	y = d.section(y, "Valores")
+	values := []struct {
+		label string
+		value string
+	}{
+		{"Valor dos serviços", formatMoney(data.ValorServico)},
+		{"Deduções", formatMoney(data.ValorDeducoes)},
+		{"Base de cálculo", formatMoney(data.BaseCalculo)},
+		{"Alíquota (%)", formatDecimal(data.Aliquota, 2)},
+		{"Valor do ISS", formatMoney(data.ValorIss)},
+		{"Valor líquido", formatMoney(data.ValorServico.Sub(retencoes))},
+		{"PIS", formatMoney(data.ValorPis)},
+		{"COFINS", formatMoney(data.ValorCofins)},
+		{"INSS", formatMoney(data.ValorInss)},
+		{"IR", formatMoney(data.ValorIr)},
+		{"CSLL", formatMoney(data.ValorCsll)},
+		{"Outras retenções", formatMoney(data.OutrasRetencoes)},
+	}
+	cellWidth := pageWidth / 6
+	for i, value := range values {
+		d.field(x+float64(i%6)*cellWidth, y+float64(i/6)*9, cellWidth, 9, value.label, value.value, "R")
+	}
+	y += 20
+
+	// Autenticidade: QR code e código de verificação
+	y = d.section(y, "Autenticidade")
+	d.Rect(x, y, pageWidth, 34, "D")
+	if err := d.qrCode("qrcode", r.nfseVerification(data), x+2, y+2, 30); err != nil {
+		return err
+	}
+	d.SetXY(x+36, y+4)
+	d.SetFont("Helvetica", "B", 9)
+	d.CellFormat(150, 5, d.tr("Código de verificação: "+data.CodigoVerificacao), "", 2, "L", false, 0, "")
+	d.SetX(x + 36)
+	d.SetFont("Helvetica", "", 8)
+	if r.verificationURL != "" {
+		d.MultiCell(150, 4, d.tr("Confira a autenticidade lendo o QR code ou em "+r.nfseVerification(data)), "", "L", false)
+	} else {
+		d.MultiCell(150, 4, d.tr("Confira a autenticidade no portal da NFS-e do município com o número e o código de verificação."), "", "L", false)
+	}
+	if rps := joinNonEmpty(" / ", data.NumeroRps, data.SerieRps); rps != "" {
+		d.SetX(x + 36)
+		d.CellFormat(150, 4, d.tr("RPS: "+rps), "", 0, "L", false, 0, "")
+	}
+
+	return d.output(w)
+}
+
+// nfseVerification conteúdo do QR code: o link de verificação configurado ou,
+// sem ele, os dados necessários para a consulta manual
+func (r *Renderer) nfseVerification(data *utils.NFSeXMLData) string {
+	if r.verificationURL == "" {
+		return "NFS-e " + data.NumeroNfse + " | Prestador " + data.CNPJPrestador + " | Código de verificação " + data.CodigoVerificacao
+	}
+	return strings.NewReplacer(
+		"{numero}", data.NumeroNfse,
+		"{codigo}", data.CodigoVerificacao,
+		"{cnpj}", data.CNPJPrestador,
+	).Replace(r.verificationURL)
+}
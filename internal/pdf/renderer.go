@@ -0,0 +1,279 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/utils"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/shopspring/decimal"
+	"github.com/skip2/go-qrcode"
+)
+
+var ErrUnsupportedType = errors.New("tipo de documento sem representação em PDF")
+
+// Version versão do layout; PDFs em cache gerados por outra versão são refeitos
+const Version = "1"
+
+// Medidas da página A4 retrato, em milímetros
+const (
+	pageMargin = 10.0
+	pageWidth  = 190.0
+	pageBottom = 280.0
+)
+
+// Renderer gera o DANFSE (NFS-e) e o DANFE (NF-e) a partir do XML, sem binários externos
+type Renderer struct {
+	logo            []byte
+	logoType        string
+	municipio       string
+	verificationURL string
+}
+
+// NewRenderer cria o gerador de PDFs. O logo do município é opcional (PNG ou JPEG).
+func NewRenderer(cfg config.PDFConfig) (*Renderer, error) {
+	renderer := &Renderer{
+		municipio:       cfg.Municipio,
+		verificationURL: cfg.NFSeVerificationURL,
+	}
+
+	if cfg.LogoPath != "" {
+		logo, err := os.ReadFile(cfg.LogoPath)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler logo do município: %w", err)
+		}
+		switch http.DetectContentType(logo) {
+		case "image/png":
+			renderer.logoType = "PNG"
+		case "image/jpeg":
+			renderer.logoType = "JPG"
+		default:
+			return nil, fmt.Errorf("logo do município deve ser PNG ou JPEG: %s", cfg.LogoPath)
+		}
+		renderer.logo = logo
+	}
+
+	return renderer, nil
+}
+
+// Render gera o PDF adequado ao tipo do documento a partir do seu XML
+func (r *Renderer) Render(docType model.DocumentType, xmlContent []byte, w io.Writer) error {
+	switch docType {
+	case model.DocumentTypeNFSe:
+		data, err := utils.ParseNFSeXML(string(xmlContent))
+		if err != nil {
+			return err
+		}
+		return r.DANFSE(data, w)
+	case model.DocumentTypeNFe:
+		data, err := utils.ParseNFeXML(string(xmlContent))
+		if err != nil {
+			return err
+		}
+		return r.DANFE(data, w)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, docType)
+	}
+}
+
+// document página A4 com as fontes padrão em cp1252 (acentuação do português)
+type document struct {
+	*fpdf.Fpdf
+	tr func(string) string
+}
+
+// newDocument cria o PDF. As datas fixas tornam a saída idêntica para o mesmo XML.
+func newDocument(title string, date time.Time) *document {
+	f := fpdf.New("P", "mm", "A4", "")
+	f.SetMargins(pageMargin, pageMargin, pageMargin)
+	f.SetAutoPageBreak(false, pageMargin)
+	f.SetCompression(true)
+	f.SetCatalogSort(true)
+	if date.IsZero() {
+		date = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	f.SetCreationDate(date)
+	f.SetModificationDate(date)
+	f.SetTitle(title, true)
+	f.SetCreator("zemdocs", false)
+	f.SetDrawColor(0, 0, 0)
+	f.SetLineWidth(0.2)
+	f.AddPage()
+
+	return &document{Fpdf: f, tr: f.UnicodeTranslatorFromDescriptor("")}
+}
+
+// field caixa com rótulo pequeno e valor, no padrão dos documentos auxiliares
+func (d *document) field(x, y, w, h float64, label, value, align string) {
+	d.Rect(x, y, w, h, "D")
+	d.SetFont("Helvetica", "", 5.5)
+	d.SetXY(x+0.8, y+0.6)
+	d.CellFormat(w-1.6, 2.5, d.tr(strings.ToUpper(label)), "", 0, "L", false, 0, "")
+	d.SetFont("Helvetica", "", 8)
+	d.SetXY(x+0.8, y+3.2)
+	d.CellFormat(w-1.6, h-3.8, d.tr(d.fit(value, w-1.6)), "", 0, align, false, 0, "")
+}
+
+// section barra de título de um bloco
+func (d *document) section(y float64, title string) float64 {
+	d.SetFont("Helvetica", "B", 7)
+	d.SetXY(pageMargin, y)
+	d.CellFormat(pageWidth, 4, d.tr(strings.ToUpper(title)), "", 0, "L", false, 0, "")
+	return y + 4
+}
+
+// text bloco de texto livre com quebra de linha, dentro de uma caixa de altura mínima
+func (d *document) text(y, minHeight float64, label, value string) float64 {
+	d.SetFont("Helvetica", "", 8)
+	lines := d.SplitLines([]byte(d.tr(value)), pageWidth-2)
+	height := float64(len(lines))*3.5 + 5
+	if height < minHeight {
+		height = minHeight
+	}
+	if y+height > pageBottom {
+		height = pageBottom - y
+	}
+
+	d.Rect(pageMargin, y, pageWidth, height, "D")
+	d.SetFont("Helvetica", "", 5.5)
+	d.SetXY(pageMargin+0.8, y+0.6)
+	d.CellFormat(pageWidth-1.6, 2.5, d.tr(strings.ToUpper(label)), "", 0, "L", false, 0, "")
+	d.SetFont("Helvetica", "", 8)
+	lineY := y + 4
+	for _, line := range lines {
+		if lineY+3.5 > y+height {
+			break
+		}
+		d.SetXY(pageMargin+1, lineY)
+		d.CellFormat(pageWidth-2, 3.5, string(line), "", 0, "L", false, 0, "")
+		lineY += 3.5
+	}
+	return y + height
+}
+
+// qrCode desenha o QR code com o conteúdo informado
+func (d *document) qrCode(name, content string, x, y, size float64) error {
+	png, err := qrcode.Encode(content, qrcode.Medium, 256)
+	if err != nil {
+		return fmt.Errorf("erro ao gerar QR code: %w", err)
+	}
+	d.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	d.ImageOptions(name, x, y, size, size, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	return nil
+}
+
+// image desenha uma imagem PNG ou JPEG
+func (d *document) image(name string, data []byte, imageType string, x, y, w, h float64) {
+	options := fpdf.ImageOptions{ImageType: imageType}
+	d.RegisterImageOptionsReader(name, options, bytes.NewReader(data))
+	d.ImageOptions(name, x, y, w, h, false, options, 0, "")
+}
+
+// fit corta o texto que não cabe na largura, com reticências
+func (d *document) fit(value string, width float64) string {
+	if d.GetStringWidth(d.tr(value)) <= width {
+		return value
+	}
+	runes := []rune(value)
+	for len(runes) > 0 && d.GetStringWidth(d.tr(string(runes)+"...")) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func (d *document) output(w io.Writer) error {
+	if err := d.Error(); err != nil {
+		return fmt.Errorf("erro ao gerar PDF: %w", err)
+	}
+	return d.Output(w)
+}
+
+// formatDocumento formata CNPJ (14 dígitos) ou CPF (11 dígitos)
+func formatDocumento(value string) string {
+	digits := onlyDigits(value)
+	switch len(digits) {
+	case 14:
+		return fmt.Sprintf("%s.%s.%s/%s-%s", digits[:2], digits[2:5], digits[5:8], digits[8:12], digits[12:])
+	case 11:
+		return fmt.Sprintf("%s.%s.%s-%s", digits[:3], digits[3:6], digits[6:9], digits[9:])
+	default:
+		return value
+	}
+}
+
+// formatCEP formata o CEP como 00000-000
+func formatCEP(value string) string {
+	digits := onlyDigits(value)
+	if len(digits) != 8 {
+		return value
+	}
+	return digits[:5] + "-" + digits[5:]
+}
+
+// formatMoney formata valores no padrão brasileiro (1.234,56)
+func formatMoney(value decimal.Decimal) string {
+	return formatDecimal(value, 2)
+}
+
+// formatDecimal formata com separador de milhar "." e decimal ","
+func formatDecimal(value decimal.Decimal, decimals int32) string {
+	formatted := value.StringFixed(decimals)
+	negative := strings.HasPrefix(formatted, "-")
+	formatted = strings.TrimPrefix(formatted, "-")
+
+	integer, fraction, _ := strings.Cut(formatted, ".")
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	result := grouped.String()
+	if fraction != "" {
+		result += "," + fraction
+	}
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
+
+// formatDateTime formata data e hora; datas zeradas ficam em branco
+func formatDateTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format("02/01/2006 15:04:05")
+}
+
+func onlyDigits(value string) string {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
+
+// joinNonEmpty junta as partes preenchidas com o separador
+func joinNonEmpty(separator string, parts ...string) string {
+	var filled []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			filled = append(filled, part)
+		}
+	}
+	return strings.Join(filled, separator)
+}
@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+
+	"github.com/uptrace/bun"
+)
+
+// Locker garante que um job seja executado por apenas uma réplica por vez
+type Locker interface {
+	TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, bool, error)
+}
+
+// Lock representa um lock adquirido
+type Lock interface {
+	Unlock(ctx context.Context) error
+}
+
+// PostgresLocker implementa Locker com advisory locks de sessão do Postgres.
+// Cada lock usa uma conexão dedicada: se o processo cair, a conexão é
+// encerrada e o Postgres libera o lock automaticamente. O TTL não libera o lock:
+// ele só é solto em Unlock, depois que o job retornar, para que as gravações de um
+// job atrasado nunca se sobreponham às de outra réplica.
+type PostgresLocker struct {
+	db       *bun.DB
+	lockRepo *repository.JobLockRepository
+	holder   string
+}
+
+// NewPostgresLocker cria um novo locker baseado no Postgres
+func NewPostgresLocker(db *bun.DB, lockRepo *repository.JobLockRepository, holder string) *PostgresLocker {
+	return &PostgresLocker{
+		db:       db,
+		lockRepo: lockRepo,
+		holder:   holder,
+	}
+}
+
+// TryLock tenta adquirir o lock sem bloquear; retorna false se outra réplica o detém
+func (l *PostgresLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, bool, error) {
+	key := lockKey(name)
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("erro ao obter conexão para lock: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.NewRaw("SELECT pg_try_advisory_lock(?)", key).Scan(ctx, &acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("erro ao adquirir lock %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	now := time.Now()
+	if err := l.lockRepo.Upsert(ctx, &model.JobLock{
+		Name:       name,
+		LockKey:    key,
+		Holder:     l.holder,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}); err != nil {
+		// O lock continua válido; apenas a visibilidade fica comprometida
+		logger.Database().Warn().Err(err).Str("lock", name).Msg("Erro ao registrar detentor do lock")
+	}
+
+	return &postgresLock{locker: l, conn: conn, name: name, key: key}, true, nil
+}
+
+// postgresLock lock mantido em uma conexão dedicada
+type postgresLock struct {
+	locker *PostgresLocker
+	conn   bun.Conn
+	name   string
+	key    int64
+
+	mu       sync.Mutex
+	released bool
+}
+
+// Unlock libera o advisory lock e devolve a conexão ao pool
+func (p *postgresLock) Unlock(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.released {
+		return nil
+	}
+	p.released = true
+
+	p.removeRecord(ctx)
+
+	var released bool
+	if err := p.conn.NewRaw("SELECT pg_advisory_unlock(?)", p.key).Scan(ctx, &released); err != nil {
+		// A conexão não volta ao pool ainda com o lock de sessão
+		p.discardConn()
+		return fmt.Errorf("erro ao liberar lock %s: %w", p.name, err)
+	}
+	return p.conn.Close()
+}
+
+// discardConn fecha a conexão dedicada sem devolvê-la ao pool: retornar
+// driver.ErrBadConn em Raw faz o database/sql descartar a conexão física
+func (p *postgresLock) discardConn() {
+	_ = p.conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = p.conn.Close()
+}
+
+func (p *postgresLock) removeRecord(ctx context.Context) {
+	if err := p.locker.lockRepo.Delete(ctx, p.name, p.locker.holder); err != nil {
+		logger.Database().Warn().Err(err).Str("lock", p.name).Msg("Erro ao remover registro do lock")
+	}
+}
+
+// lockKey converte o nome do job em uma chave de advisory lock.
+// A chave é mantida positiva e abaixo de 2^62 para que possa ser
+// reconstruída a partir de pg_locks sem overflow.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64() & 0x3fffffffffffffff)
+}
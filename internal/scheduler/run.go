@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"zemdocs/internal/database/model"
+
+	"github.com/google/uuid"
+)
+
+// maxRunLogs limita as linhas de log mantidas por execução
+const maxRunLogs = 500
+
+// Run acompanha uma execução em andamento: contadores, logs e cancelamento.
+// Os métodos aceitam receptor nil, de modo que jobs executados fora do
+// scheduler podem chamá-los sem verificação.
+type Run struct {
+	mu     sync.Mutex
+	data   model.JobRun
+	cancel context.CancelFunc
+}
+
+type runContextKey struct{}
+
+// RunFromContext retorna a execução associada ao contexto, ou nil
+func RunFromContext(ctx context.Context) *Run {
+	run, _ := ctx.Value(runContextKey{}).(*Run)
+	return run
+}
+
+// newRun cria uma nova execução para o job
+func newRun(jobName, trigger, holder string) *Run {
+	return &Run{
+		data: model.JobRun{
+			ID:        uuid.NewString(),
+			JobName:   jobName,
+			Trigger:   trigger,
+			Status:    model.JobRunStatusExecutando,
+			Holder:    holder,
+			Counters:  make(map[string]int64),
+			StartedAt: time.Now(),
+		},
+	}
+}
+
+// ID retorna o identificador da execução
+func (r *Run) ID() string {
+	if r == nil {
+		return ""
+	}
+	return r.data.ID
+}
+
+// Add incrementa um contador da execução
+func (r *Run) Add(counter string, n int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data.Counters[counter] += n
+}
+
+// Infof registra uma linha de log informativa
+func (r *Run) Infof(format string, args ...interface{}) {
+	r.log("info", format, args...)
+}
+
+// Warnf registra uma linha de log de alerta
+func (r *Run) Warnf(format string, args ...interface{}) {
+	r.log("warn", format, args...)
+}
+
+// Errorf registra uma linha de log de erro
+func (r *Run) Errorf(format string, args ...interface{}) {
+	r.log("error", format, args...)
+}
+
+func (r *Run) log(level, format string, args ...interface{}) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.data.Logs) >= maxRunLogs {
+		r.data.Logs = r.data.Logs[1:]
+	}
+	r.data.Logs = append(r.data.Logs, model.JobRunLog{
+		Time:    time.Now(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// finish encerra a execução com o status e erro informados
+func (r *Run) finish(status model.JobRunStatus, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data.Status = status
+	r.data.FinishedAt = time.Now()
+	if err != nil {
+		r.data.Error = err.Error()
+	}
+}
+
+// Snapshot retorna uma cópia do estado atual da execução
+func (r *Run) Snapshot() *model.JobRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := r.data
+	snapshot.Counters = make(map[string]int64, len(r.data.Counters))
+	for k, v := range r.data.Counters {
+		snapshot.Counters[k] = v
+	}
+	snapshot.Logs = append([]model.JobRunLog(nil), r.data.Logs...)
+	return &snapshot
+}
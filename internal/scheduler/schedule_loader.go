@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+	"zemdocs/internal/logger"
+)
+
+// JobFactory monta o job correspondente a um agendamento do banco
+type JobFactory func(ctx context.Context, schedule *model.JobSchedule) (Job, error)
+
+// namedJob dá ao job o nome do agendamento, único no scheduler, e o lock do
+// trabalho que executa; guarda o ID do agendamento, onde a pausa do job é gravada
+type namedJob struct {
+	name       string
+	lockName   string
+	job        Job
+	scheduleID int64
+}
+
+func (n *namedJob) Name() string {
+	return n.name
+}
+
+func (n *namedJob) LockName() string {
+	return n.lockName
+}
+
+func (n *namedJob) Execute(ctx context.Context) error {
+	return n.job.Execute(ctx)
+}
+
+// loadedSchedule versão de um agendamento registrada no scheduler
+type loadedSchedule struct {
+	jobName   string
+	updatedAt time.Time
+	active    bool // false quando o job não pôde ser montado ou agendado
+}
+
+// ScheduleLoader mantém o scheduler sincronizado com os agendamentos do banco.
+// Alterações feitas por esta réplica são aplicadas imediatamente via Reload;
+// as feitas por outras réplicas são percebidas na verificação periódica.
+// Agendamentos desabilitados são registrados como jobs pausados.
+type ScheduleLoader struct {
+	scheduler    *Scheduler
+	scheduleRepo *repository.JobScheduleRepository
+	factory      JobFactory
+	interval     time.Duration
+
+	mu     sync.Mutex
+	loaded map[int64]loadedSchedule
+	reload chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduleLoader cria um novo carregador de agendamentos
+func NewScheduleLoader(scheduler *Scheduler, scheduleRepo *repository.JobScheduleRepository, factory JobFactory, interval time.Duration) *ScheduleLoader {
+	return &ScheduleLoader{
+		scheduler:    scheduler,
+		scheduleRepo: scheduleRepo,
+		factory:      factory,
+		interval:     interval,
+		loaded:       make(map[int64]loadedSchedule),
+		reload:       make(chan struct{}, 1),
+	}
+}
+
+// Start carrega os agendamentos e passa a acompanhar alterações
+func (l *ScheduleLoader) Start(ctx context.Context) error {
+	if err := l.Sync(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-l.reload:
+			}
+
+			if err := l.Sync(ctx); err != nil {
+				logger.Database().Error().Err(err).Msg("Erro ao recarregar agendamentos")
+			}
+		}
+	}()
+
+	logger.Database().Info().
+		Dur("interval", l.interval).
+		Msg("Carregador de agendamentos iniciado")
+	return nil
+}
+
+// Stop encerra o acompanhamento de alterações
+func (l *ScheduleLoader) Stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.wg.Wait()
+}
+
+// Reload solicita uma nova sincronização sem aguardar o próximo ciclo
+func (l *ScheduleLoader) Reload() {
+	select {
+	case l.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Sync aplica ao scheduler os agendamentos do banco
+func (l *ScheduleLoader) Sync(ctx context.Context) error {
+	schedules, err := l.scheduleRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen := make(map[int64]bool, len(schedules))
+	for _, schedule := range schedules {
+		seen[schedule.ID] = true
+
+		current, exists := l.loaded[schedule.ID]
+		if exists && current.updatedAt.Equal(schedule.UpdatedAt) {
+			continue
+		}
+		if exists && current.active {
+			l.scheduler.RemoveJob(current.jobName)
+		}
+
+		l.loaded[schedule.ID] = loadedSchedule{
+			jobName:   schedule.JobName(),
+			updatedAt: schedule.UpdatedAt,
+			active:    l.add(ctx, schedule),
+		}
+	}
+
+	// Agendamentos removidos
+	for id, current := range l.loaded {
+		if seen[id] {
+			continue
+		}
+		if current.active {
+			l.scheduler.RemoveJob(current.jobName)
+		}
+		delete(l.loaded, id)
+	}
+
+	return nil
+}
+
+// add monta e registra o job de um agendamento; retorna false em caso de erro
+func (l *ScheduleLoader) add(ctx context.Context, schedule *model.JobSchedule) bool {
+	job, err := l.factory(ctx, schedule)
+	if err == nil {
+		err = l.scheduler.addJob(schedule.Cron, &namedJob{name: schedule.JobName(), lockName: schedule.LockName(), job: job, scheduleID: schedule.ID}, !schedule.Enabled)
+	}
+	if err != nil {
+		logger.Database().Error().
+			Err(err).
+			Int64("schedule_id", schedule.ID).
+			Str("type", schedule.Type).
+			Str("cron", schedule.Cron).
+			Msg("Erro ao agendar job do banco")
+		return false
+	}
+	return true
+}
@@ -2,55 +2,199 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/robfig/cron/v3"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
 	"zemdocs/internal/logger"
+	"zemdocs/internal/tenant"
+
+	"github.com/robfig/cron/v3"
 )
 
+var (
+	ErrJobNotFound      = errors.New("job não encontrado")
+	ErrJobAlreadyExists = errors.New("job já registrado")
+	ErrRunNotFound      = errors.New("execução não encontrada")
+	ErrRunNotActive     = errors.New("execução não está em andamento")
+	ErrJobNotPersistent = errors.New("job sem agendamento no banco; a pausa não seria vista pelas demais réplicas")
+	errJobLocked        = errors.New("job em execução em outra réplica")
+)
+
+// maxRecentRuns limita o histórico de execuções mantido em memória
+const maxRecentRuns = 100
+
+// cancelPollInterval intervalo em que uma execução confere no banco se outra
+// réplica pediu seu cancelamento
+const cancelPollInterval = 10 * time.Second
+
 // Job representa um job agendado
 type Job interface {
 	Execute(ctx context.Context) error
 	Name() string
 }
 
+// LockNamer é implementado pelos jobs cujo lock distribuído não é o próprio
+// nome, como os agendamentos do banco (ver model.JobSchedule.LockName)
+type LockNamer interface {
+	LockName() string
+}
+
+// lockName nome do lock distribuído do job
+func lockName(job Job) string {
+	if namer, ok := job.(LockNamer); ok {
+		return namer.LockName()
+	}
+	return job.Name()
+}
+
+// FuncJob adapta uma função para a interface Job
+type FuncJob struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewFuncJob cria um job a partir de uma função
+func NewFuncJob(name string, fn func(ctx context.Context) error) *FuncJob {
+	return &FuncJob{name: name, fn: fn}
+}
+
+// Name retorna o nome do job
+func (f *FuncJob) Name() string {
+	return f.name
+}
+
+// Execute executa a função do job
+func (f *FuncJob) Execute(ctx context.Context) error {
+	return f.fn(ctx)
+}
+
+// JobInfo resumo de um job agendado
+type JobInfo struct {
+	Name         string    `json:"name"`
+	Schedule     string    `json:"schedule"`
+	Paused       bool      `json:"paused"`
+	NextRun      time.Time `json:"next_run"`
+	PrevRun      time.Time `json:"prev_run"`
+	RunningRunID string    `json:"running_run_id,omitempty"`
+}
+
+// jobEntry job registrado no cron
+type jobEntry struct {
+	job      Job
+	schedule string
+	entryID  cron.EntryID
+	paused   bool
+}
+
 // Scheduler gerencia jobs agendados
 type Scheduler struct {
 	cron   *cron.Cron
-	jobs   map[string]Job
+	jobs   map[string]*jobEntry
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 	mu     sync.RWMutex
+
+	// Lock distribuído opcional para execução em múltiplas réplicas
+	locker  Locker
+	lockTTL time.Duration
+
+	// Pausa dos jobs, gravada no agendamento (job_schedules.enabled)
+	scheduleRepo *repository.JobScheduleRepository
+
+	// Execuções em andamento e histórico recente
+	runRepo    *repository.JobRunRepository
+	instanceID string
+	active     map[string]*Run
+	recent     []*model.JobRun
 }
 
 // NewScheduler cria uma nova instância do scheduler
 func NewScheduler() *Scheduler {
-	ctx, cancel := context.WithCancel(context.Background())
-	
+	// Jobs percorrem os dados de todos os escritórios; os que atendem a um
+	// escritório restringem o próprio contexto
+	ctx, cancel := context.WithCancel(tenant.WithSystem(context.Background()))
+
 	return &Scheduler{
 		cron:   cron.New(cron.WithSeconds()),
-		jobs:   make(map[string]Job),
+		jobs:   make(map[string]*jobEntry),
 		ctx:    ctx,
 		cancel: cancel,
+		active: make(map[string]*Run),
 	}
 }
 
+// SetLocker habilita o lock distribuído: cada execução só ocorre se a réplica
+// adquirir o lock do job, e é cancelada ao atingir o timeout informado. O lock é
+// mantido até o job retornar, mesmo que ele ultrapasse o timeout.
+func (s *Scheduler) SetLocker(locker Locker, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locker = locker
+	s.lockTTL = timeout
+}
+
+// SetRunRepository habilita a persistência do histórico de execuções
+func (s *Scheduler) SetRunRepository(runRepo *repository.JobRunRepository, instanceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runRepo = runRepo
+	s.instanceID = instanceID
+}
+
+// SetScheduleRepository habilita a pausa compartilhada entre réplicas: pausar ou
+// retomar um job altera o enabled do seu agendamento, conferido a cada execução
+func (s *Scheduler) SetScheduleRepository(scheduleRepo *repository.JobScheduleRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scheduleRepo = scheduleRepo
+}
+
 // AddJob adiciona um job ao scheduler
 func (s *Scheduler) AddJob(schedule string, job Job) error {
+	return s.addJob(schedule, job, false)
+}
+
+// addJob adiciona um job, opcionalmente já pausado
+func (s *Scheduler) addJob(schedule string, job Job, paused bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.cron.AddFunc(schedule, func() {
-		s.executeJob(job)
+	if _, exists := s.jobs[job.Name()]; exists {
+		return ErrJobAlreadyExists
+	}
+
+	entry := &jobEntry{job: job, schedule: schedule, paused: paused}
+	entryID, err := s.cron.AddFunc(schedule, func() {
+		s.mu.RLock()
+		paused := entry.paused
+		s.mu.RUnlock()
+
+		if paused {
+			logger.Database().Debug().
+				Str("job", job.Name()).
+				Msg("Job pausado, pulando execução agendada")
+			return
+		}
+
+		s.wg.Add(1)
+		s.executeJob(job, nil)
 	})
-	
+
 	if err != nil {
 		return err
 	}
 
-	s.jobs[job.Name()] = job
+	entry.entryID = entryID
+	s.jobs[job.Name()] = entry
 	logger.Database().Info().
 		Str("job", job.Name()).
 		Str("schedule", schedule).
@@ -59,30 +203,142 @@ func (s *Scheduler) AddJob(schedule string, job Job) error {
 	return nil
 }
 
-// executeJob executa um job com tratamento de erro
-func (s *Scheduler) executeJob(job Job) {
-	s.wg.Add(1)
+// executeJob executa um job com tratamento de erro. Quando run é nil a
+// execução é agendada e só é registrada se o lock for adquirido.
+// O chamador deve ter incrementado s.wg.
+func (s *Scheduler) executeJob(job Job, run *Run) {
 	defer s.wg.Done()
 
+	ctx := s.ctx
+	s.mu.RLock()
+	locker, lockTTL := s.locker, s.lockTTL
+	s.mu.RUnlock()
+
+	if locker != nil {
+		lock, acquired, err := locker.TryLock(ctx, lockName(job), lockTTL)
+		if err != nil {
+			logger.Database().Error().
+				Err(err).
+				Str("job", job.Name()).
+				Msg("Erro ao adquirir lock do job")
+			if run != nil {
+				s.finishRun(run, model.JobRunStatusErro, err)
+			}
+			return
+		}
+		if !acquired {
+			logger.Database().Info().
+				Str("job", job.Name()).
+				Msg("Job em execução em outra réplica, pulando")
+			if run != nil {
+				s.finishRun(run, model.JobRunStatusIgnorado, errJobLocked)
+			}
+			return
+		}
+		defer func() {
+			// Liberar só depois que o job retornou, mesmo que ele tenha ultrapassado o
+			// timeout, e ainda que o contexto do scheduler já tenha sido cancelado
+			if err := lock.Unlock(context.Background()); err != nil {
+				logger.Database().Error().
+					Err(err).
+					Str("job", job.Name()).
+					Msg("Erro ao liberar lock do job")
+			}
+		}()
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lockTTL)
+		defer cancel()
+	}
+
+	if run == nil {
+		// A pausa pode ter sido feita em outra réplica depois da última carga dos agendamentos
+		if s.pausedInDatabase(ctx, job) {
+			logger.Database().Info().
+				Str("job", job.Name()).
+				Msg("Job pausado, pulando execução agendada")
+			return
+		}
+		run = s.startRun(job.Name(), model.JobRunTriggerAgendado)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run.mu.Lock()
+	run.cancel = cancel
+	run.mu.Unlock()
+	ctx = context.WithValue(ctx, runContextKey{}, run)
+	s.watchCancel(ctx, run, cancel)
+
 	start := time.Now()
 	logger.Database().Info().
 		Str("job", job.Name()).
+		Str("run_id", run.ID()).
 		Msg("Iniciando execução do job")
 
-	err := job.Execute(s.ctx)
+	err := job.Execute(ctx)
 	duration := time.Since(start)
 
-	if err != nil {
+	switch {
+	case err == nil:
+		s.finishRun(run, model.JobRunStatusSucesso, nil)
+		logger.Database().Info().
+			Str("job", job.Name()).
+			Str("run_id", run.ID()).
+			Dur("duration", duration).
+			Msg("Job executado com sucesso")
+	case errors.Is(err, context.Canceled):
+		s.finishRun(run, model.JobRunStatusCancelado, err)
+		logger.Database().Warn().
+			Str("job", job.Name()).
+			Str("run_id", run.ID()).
+			Dur("duration", duration).
+			Msg("Job cancelado")
+	default:
+		s.finishRun(run, model.JobRunStatusErro, err)
 		logger.Database().Error().
 			Err(err).
 			Str("job", job.Name()).
+			Str("run_id", run.ID()).
 			Dur("duration", duration).
 			Msg("Job executado com erro")
-	} else {
-		logger.Database().Info().
-			Str("job", job.Name()).
-			Dur("duration", duration).
-			Msg("Job executado com sucesso")
+	}
+}
+
+// startRun registra uma nova execução como ativa
+func (s *Scheduler) startRun(jobName, trigger string) *Run {
+	s.mu.Lock()
+	run := newRun(jobName, trigger, s.instanceID)
+	s.active[run.ID()] = run
+	runRepo := s.runRepo
+	s.mu.Unlock()
+
+	if runRepo != nil {
+		if err := runRepo.Create(context.Background(), run.Snapshot()); err != nil {
+			logger.Database().Warn().Err(err).Str("run_id", run.ID()).Msg("Erro ao registrar execução")
+		}
+	}
+	return run
+}
+
+// finishRun encerra uma execução e a move para o histórico
+func (s *Scheduler) finishRun(run *Run, status model.JobRunStatus, err error) {
+	run.finish(status, err)
+	snapshot := run.Snapshot()
+
+	s.mu.Lock()
+	delete(s.active, run.ID())
+	s.recent = append(s.recent, snapshot)
+	if len(s.recent) > maxRecentRuns {
+		s.recent = s.recent[len(s.recent)-maxRecentRuns:]
+	}
+	runRepo := s.runRepo
+	s.mu.Unlock()
+
+	if runRepo != nil {
+		if err := runRepo.Update(context.Background(), snapshot); err != nil {
+			logger.Database().Warn().Err(err).Str("run_id", run.ID()).Msg("Erro ao gravar execução")
+		}
 	}
 }
 
@@ -112,13 +368,271 @@ func (s *Scheduler) GetJobs() []string {
 	return jobNames
 }
 
+// ListJobs retorna os jobs agendados com próxima e última execução
+func (s *Scheduler) ListJobs() []JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	running := make(map[string]string)
+	for id, run := range s.active {
+		running[run.data.JobName] = id
+	}
+
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for name, entry := range s.jobs {
+		cronEntry := s.cron.Entry(entry.entryID)
+		infos = append(infos, JobInfo{
+			Name:         name,
+			Schedule:     entry.schedule,
+			Paused:       entry.paused,
+			NextRun:      cronEntry.Next,
+			PrevRun:      cronEntry.Prev,
+			RunningRunID: running[name],
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
 // RemoveJob remove um job do scheduler
 func (s *Scheduler) RemoveJob(jobName string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	entry, exists := s.jobs[jobName]
+	if !exists {
+		return
+	}
+
+	s.cron.Remove(entry.entryID)
 	delete(s.jobs, jobName)
 	logger.Database().Info().
 		Str("job", jobName).
 		Msg("Job removido do scheduler")
 }
+
+// PauseJob suspende as execuções agendadas de um job em todas as réplicas
+func (s *Scheduler) PauseJob(ctx context.Context, jobName string) error {
+	return s.setPaused(ctx, jobName, true)
+}
+
+// ResumeJob retoma as execuções agendadas de um job em todas as réplicas
+func (s *Scheduler) ResumeJob(ctx context.Context, jobName string) error {
+	return s.setPaused(ctx, jobName, false)
+}
+
+// setPaused grava a pausa no agendamento do job; as demais réplicas a aplicam na
+// próxima carga dos agendamentos e, até lá, a conferem antes de cada execução
+func (s *Scheduler) setPaused(ctx context.Context, jobName string, paused bool) error {
+	s.mu.RLock()
+	entry, exists := s.jobs[jobName]
+	scheduleRepo := s.scheduleRepo
+	s.mu.RUnlock()
+
+	if !exists {
+		return ErrJobNotFound
+	}
+	scheduled, ok := entry.job.(*namedJob)
+	if !ok || scheduleRepo == nil {
+		return ErrJobNotPersistent
+	}
+	if err := scheduleRepo.SetEnabled(ctx, scheduled.scheduleID, !paused); err != nil {
+		return fmt.Errorf("erro ao gravar pausa do job: %w", err)
+	}
+
+	s.mu.Lock()
+	entry.paused = paused
+	s.mu.Unlock()
+
+	logger.Database().Info().
+		Str("job", jobName).
+		Bool("paused", paused).
+		Msg("Estado do job alterado")
+	return nil
+}
+
+// TriggerJob dispara uma execução imediata de um job registrado e retorna o ID da execução
+func (s *Scheduler) TriggerJob(jobName string) (string, error) {
+	s.mu.RLock()
+	entry, exists := s.jobs[jobName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return "", ErrJobNotFound
+	}
+	return s.RunOnce(entry.job), nil
+}
+
+// RunOnce executa um job avulso em segundo plano e retorna o ID da execução
+func (s *Scheduler) RunOnce(job Job) string {
+	run := s.startRun(job.Name(), model.JobRunTriggerManual)
+
+	s.wg.Add(1)
+	go s.executeJob(job, run)
+
+	return run.ID()
+}
+
+// CancelRun cancela uma execução em andamento. Execuções de outras réplicas
+// recebem o pedido pelo banco e são canceladas em até cancelPollInterval.
+func (s *Scheduler) CancelRun(ctx context.Context, runID string) error {
+	s.mu.RLock()
+	run, exists := s.active[runID]
+	runRepo := s.runRepo
+	s.mu.RUnlock()
+
+	if !exists {
+		if runRepo == nil {
+			return ErrRunNotActive
+		}
+		requested, err := runRepo.RequestCancel(ctx, runID)
+		if err != nil {
+			return fmt.Errorf("erro ao registrar cancelamento: %w", err)
+		}
+		if !requested {
+			return ErrRunNotActive
+		}
+		return nil
+	}
+
+	run.mu.Lock()
+	cancel := run.cancel
+	run.mu.Unlock()
+
+	// Execução ainda aguardando o lock: não há contexto para cancelar
+	if cancel == nil {
+		return ErrRunNotActive
+	}
+
+	cancel()
+	run.Warnf("Cancelamento solicitado")
+	return nil
+}
+
+// pausedInDatabase confere no agendamento se o job foi pausado; na dúvida (erro
+// ao consultar) a execução agendada não é feita
+func (s *Scheduler) pausedInDatabase(ctx context.Context, job Job) bool {
+	s.mu.RLock()
+	scheduleRepo := s.scheduleRepo
+	s.mu.RUnlock()
+
+	scheduled, ok := job.(*namedJob)
+	if !ok || scheduleRepo == nil {
+		return false
+	}
+
+	enabled, err := scheduleRepo.IsEnabled(ctx, scheduled.scheduleID)
+	if err != nil {
+		logger.Database().Error().
+			Err(err).
+			Str("job", job.Name()).
+			Msg("Erro ao consultar pausa do job")
+		return true
+	}
+	return !enabled
+}
+
+// watchCancel cancela a execução quando outra réplica registrar o pedido no banco
+func (s *Scheduler) watchCancel(ctx context.Context, run *Run, cancel context.CancelFunc) {
+	s.mu.RLock()
+	runRepo := s.runRepo
+	s.mu.RUnlock()
+
+	if runRepo == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			requested, err := runRepo.CancelRequested(ctx, run.ID())
+			if err != nil {
+				continue
+			}
+			if requested {
+				run.Warnf("Cancelamento solicitado")
+				cancel()
+				return
+			}
+		}
+	}()
+}
+
+// GetRun retorna uma execução (em andamento ou concluída)
+func (s *Scheduler) GetRun(ctx context.Context, runID string) (*model.JobRun, error) {
+	s.mu.RLock()
+	run, active := s.active[runID]
+	runRepo := s.runRepo
+	var recent *model.JobRun
+	for _, r := range s.recent {
+		if r.ID == runID {
+			recent = r
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if active {
+		return run.Snapshot(), nil
+	}
+	if recent != nil {
+		return recent, nil
+	}
+	if runRepo != nil {
+		if stored, err := runRepo.GetByID(ctx, runID); err == nil {
+			return stored, nil
+		}
+	}
+	return nil, ErrRunNotFound
+}
+
+// ListRuns lista as execuções mais recentes de um job
+func (s *Scheduler) ListRuns(ctx context.Context, jobName string, limit int) ([]*model.JobRun, error) {
+	s.mu.RLock()
+	runRepo := s.runRepo
+	activeRuns := make(map[string]*Run)
+	for id, run := range s.active {
+		activeRuns[id] = run
+	}
+	var recent []*model.JobRun
+	for i := len(s.recent) - 1; i >= 0 && len(recent) < limit; i-- {
+		if s.recent[i].JobName == jobName {
+			recent = append(recent, s.recent[i])
+		}
+	}
+	s.mu.RUnlock()
+
+	if runRepo == nil {
+		runs := make([]*model.JobRun, 0, len(activeRuns)+len(recent))
+		for _, run := range activeRuns {
+			if snapshot := run.Snapshot(); snapshot.JobName == jobName {
+				runs = append(runs, snapshot)
+			}
+		}
+		return append(runs, recent...), nil
+	}
+
+	runs, err := runRepo.ListByJob(ctx, jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execuções ativas desta réplica têm contadores mais atuais que o banco
+	for i, stored := range runs {
+		if run, ok := activeRuns[stored.ID]; ok {
+			snapshot := run.Snapshot()
+			snapshot.Logs = nil
+			runs[i] = snapshot
+		}
+	}
+	return runs, nil
+}
@@ -0,0 +1,96 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/scheduler"
+)
+
+// memoryLocker locker em memória: um lock por nome, como o advisory lock
+type memoryLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func newMemoryLocker() *memoryLocker {
+	return &memoryLocker{held: make(map[string]bool)}
+}
+
+func (l *memoryLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (scheduler.Lock, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	return &memoryLock{locker: l, name: name}, true, nil
+}
+
+func (l *memoryLocker) isHeld(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held[name]
+}
+
+type memoryLock struct {
+	locker *memoryLocker
+	name   string
+}
+
+func (m *memoryLock) Unlock(ctx context.Context) error {
+	m.locker.mu.Lock()
+	defer m.locker.mu.Unlock()
+	delete(m.locker.held, m.name)
+	return nil
+}
+
+func TestSchedulerMantemLockAteOJobRetornar(t *testing.T) {
+	const ttl = 20 * time.Millisecond
+	locker := newMemoryLocker()
+	s := scheduler.NewScheduler()
+	s.SetLocker(locker, ttl)
+	defer s.Stop()
+
+	cancelled := make(chan struct{})
+	release := make(chan struct{})
+	// O job ignora o fim do prazo e continua gravando depois do TTL
+	job := scheduler.NewFuncJob("lento", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		<-release
+		return ctx.Err()
+	})
+	runID := s.RunOnce(job)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("job não foi cancelado ao fim do TTL")
+	}
+
+	// Passado o TTL, outra réplica ainda não pode adquirir o lock
+	time.Sleep(2 * ttl)
+	if _, acquired, _ := locker.TryLock(context.Background(), "lento", ttl); acquired {
+		t.Fatal("lock liberado com o job ainda em execução")
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for locker.isHeld("lento") {
+		if time.Now().After(deadline) {
+			t.Fatal("lock não liberado depois que o job retornou")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	run, err := s.GetRun(context.Background(), runID)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if run.Status == model.JobRunStatusSucesso {
+		t.Errorf("execução além do TTL registrada como %s", run.Status)
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+	"zemdocs/internal/audit"
+	"zemdocs/internal/database/model"
+)
+
+// Limites da consulta e da exportação da auditoria
+const (
+	auditDefaultLimit = 50
+	auditMaxLimit     = 500
+	auditExportBatch  = 1000
+)
+
+// AuditEntities entidades aceitas no filtro da auditoria
+var AuditEntities = []string{model.AuditEntityDocument, model.AuditEntityEmpresa, model.AuditEntityUser}
+
+// AuditActions ações aceitas no filtro da auditoria
+var AuditActions = []string{
+	model.AuditActionCreate, model.AuditActionUpdate, model.AuditActionDelete,
+	model.AuditActionRestore, model.AuditActionPurge,
+}
+
+// AuditService trilha de auditoria das alterações de documentos, empresas e
+// usuários. Os serviços executam a alteração e o registro em Transacao, para que
+// nenhum dos dois seja gravado sem o outro.
+type AuditService struct {
+	auditRepo AuditLogRepository
+}
+
+// NewAuditService cria uma nova instância do serviço de auditoria
+func NewAuditService(auditRepo AuditLogRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Transacao executa fn em uma transação; os repositórios usados com o contexto
+// recebido gravam nela
+func (s *AuditService) Transacao(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.auditRepo.RunInTx(ctx, fn)
+}
+
+// Registrar grava a alteração descrita em log (entidade, ID, escritório e ação) com o
+// ator do contexto. before e after são o registro antes e depois (nil na criação e na
+// exclusão, respectivamente). Em uma requisição de escritório, o escritório é o do contexto.
+func (s *AuditService) Registrar(ctx context.Context, log *model.AuditLog, before, after interface{}) error {
+	actor := audit.ActorFromContext(ctx)
+	log.Actor = actor.Name
+	log.IP = actor.IP
+	log.UserAgent = actor.UserAgent
+
+	var err error
+	if log.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if log.After, err = snapshot(after); err != nil {
+		return err
+	}
+
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		return fmt.Errorf("erro ao registrar auditoria: %w", err)
+	}
+	return nil
+}
+
+// DocumentAudit registro de auditoria da alteração do documento
+func DocumentAudit(document *model.Document, action string) *model.AuditLog {
+	return &model.AuditLog{
+		EscritorioID: document.EscritorioID,
+		Entity:       model.AuditEntityDocument,
+		EntityID:     int64(document.ID),
+		Action:       action,
+	}
+}
+
+// SalvarDocumento grava o documento sincronizado, com o upload pendente do XML se
+// houver (entry), e o registro de auditoria da criação na mesma transação. Sem
+// auditoria configurada (nil), grava apenas o documento.
+func SalvarDocumento(ctx context.Context, documentRepo DocumentRepository, auditService *AuditService, document *model.Document, entry *model.StorageOutbox) error {
+	create := func(ctx context.Context) error {
+		if entry == nil {
+			return documentRepo.Create(ctx, document)
+		}
+		return documentRepo.CreateWithOutbox(ctx, document, entry)
+	}
+	if auditService == nil {
+		return create(ctx)
+	}
+
+	return auditService.Transacao(ctx, func(ctx context.Context) error {
+		if err := create(ctx); err != nil {
+			return err
+		}
+		return auditService.Registrar(ctx, DocumentAudit(document, model.AuditActionCreate), nil, document)
+	})
+}
+
+// Listar retorna uma página da auditoria, mais recentes primeiro. O cursor é o
+// next_cursor da página anterior.
+func (s *AuditService) Listar(ctx context.Context, filter *model.AuditFilter, cursor string, limit int) (*model.AuditListResponse, error) {
+	if err := prepararFiltroAuditoria(filter); err != nil {
+		return nil, err
+	}
+
+	var beforeID int64
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: cursor inválido", ErrInvalidData)
+		}
+		beforeID = id
+	}
+
+	if limit <= 0 {
+		limit = auditDefaultLimit
+	}
+	if limit > auditMaxLimit {
+		limit = auditMaxLimit
+	}
+
+	// Um registro a mais indica que existe a próxima página
+	logs, err := s.auditRepo.List(ctx, filter, beforeID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar auditoria: %w", err)
+	}
+
+	response := &model.AuditListResponse{Logs: logs, Limit: limit}
+	if len(logs) > limit {
+		response.Logs = logs[:limit]
+		response.NextCursor = strconv.FormatInt(response.Logs[limit-1].ID, 10)
+	}
+	if response.Logs == nil {
+		response.Logs = []*model.AuditLog{}
+	}
+	return response, nil
+}
+
+// Exportar grava em w, em CSV (separado por ponto e vírgula), todos os registros que
+// atendem aos filtros, mais recentes primeiro. Retorna a quantidade exportada.
+func (s *AuditService) Exportar(ctx context.Context, filter *model.AuditFilter, w io.Writer) (int, error) {
+	if err := prepararFiltroAuditoria(filter); err != nil {
+		return 0, err
+	}
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = ';'
+	csvWriter.Write([]string{
+		"id", "data", "escritorio_id", "ator", "ip", "user_agent",
+		"entidade", "entidade_id", "acao", "antes", "depois",
+	})
+
+	total := 0
+	var beforeID int64
+	for {
+		logs, err := s.auditRepo.List(ctx, filter, beforeID, auditExportBatch)
+		if err != nil {
+			return total, fmt.Errorf("erro ao consultar auditoria: %w", err)
+		}
+
+		for _, log := range logs {
+			escritorioID := ""
+			if log.EscritorioID != nil {
+				escritorioID = strconv.FormatInt(*log.EscritorioID, 10)
+			}
+			csvWriter.Write([]string{
+				strconv.FormatInt(log.ID, 10),
+				log.CreatedAt.Format(time.RFC3339),
+				escritorioID,
+				log.Actor,
+				log.IP,
+				log.UserAgent,
+				log.Entity,
+				strconv.FormatInt(log.EntityID, 10),
+				log.Action,
+				string(log.Before),
+				string(log.After),
+			})
+		}
+		total += len(logs)
+
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return total, fmt.Errorf("erro ao gravar exportação: %w", err)
+		}
+		if len(logs) < auditExportBatch {
+			return total, nil
+		}
+		beforeID = logs[len(logs)-1].ID
+	}
+}
+
+// prepararFiltroAuditoria valida a entidade, a ação e o período
+func prepararFiltroAuditoria(filter *model.AuditFilter) error {
+	if filter.Entity != "" && !slices.Contains(AuditEntities, filter.Entity) {
+		return fmt.Errorf("%w: entidade inválida: %s", ErrInvalidData, filter.Entity)
+	}
+	if filter.Action != "" && !slices.Contains(AuditActions, filter.Action) {
+		return fmt.Errorf("%w: ação inválida: %s", ErrInvalidData, filter.Action)
+	}
+	if !filter.DataInicio.IsZero() && !filter.DataFim.IsZero() && filter.DataFim.Before(filter.DataInicio) {
+		return fmt.Errorf("%w: data final anterior à inicial", ErrInvalidData)
+	}
+	return nil
+}
+
+// snapshot serializa o estado da entidade; nil (inclusive ponteiro nulo) não gera registro
+func snapshot(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar registro de auditoria: %w", err)
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/storage"
+)
+
+// maxCertificadoSize tamanho máximo aceito para o arquivo do certificado A1
+const maxCertificadoSize = 1 << 20
+
+// certificadoPrefix prefixo das chaves dos certificados no armazenamento
+const certificadoPrefix = "certificados/"
+
+// CertificadoService guarda o certificado digital A1 (PFX) das empresas. O
+// armazenamento é sempre o cifrado: o tipo do parâmetro impede que um
+// certificado seja gravado em texto puro.
+type CertificadoService struct {
+	empresaRepo EmpresaRepository
+	store       *storage.EncryptedStore
+	audit       *AuditService
+}
+
+// NewCertificadoService cria uma nova instância do serviço de certificados
+func NewCertificadoService(empresaRepo EmpresaRepository, store *storage.EncryptedStore, auditService *AuditService) *CertificadoService {
+	return &CertificadoService{
+		empresaRepo: empresaRepo,
+		store:       store,
+		audit:       auditService,
+	}
+}
+
+// CertificadoObjectName chave do certificado da empresa: certificados/{escritório}/{empresa}.pfx
+func CertificadoObjectName(empresa *model.Empresa) string {
+	return fmt.Sprintf("%s%s/%d.pfx", certificadoPrefix, storage.EscritorioTenant(empresa.EscritorioID), empresa.ID)
+}
+
+// Enviar grava, cifrado com a chave do escritório, o certificado da empresa,
+// substituindo o anterior, e retorna o horário registrado do envio
+func (s *CertificadoService) Enviar(ctx context.Context, empresaID int, content []byte) (time.Time, error) {
+	if len(content) == 0 || len(content) > maxCertificadoSize {
+		return time.Time{}, fmt.Errorf("%w: certificado vazio ou maior que %d bytes", ErrInvalidData, maxCertificadoSize)
+	}
+
+	empresa, err := s.buscar(ctx, empresaID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	key := CertificadoObjectName(empresa)
+	err = s.store.Put(ctx, key, content, storage.PutOptions{
+		ContentType: "application/x-pkcs12",
+		Metadata:    map[string]string{storage.MetadataSHA256: storage.ContentSHA256(content)},
+		Tenant:      storage.EscritorioTenant(empresa.EscritorioID),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("erro ao gravar certificado: %w", err)
+	}
+
+	antes := *empresa
+	empresa.CertificadoKey = key
+	empresa.CertificadoEnviadoEm = time.Now()
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Update(ctx, empresa); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionUpdate), &antes, empresa)
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("erro ao registrar certificado: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Certificado da empresa %s atualizado", empresa.CNPJ))
+
+	return empresa.CertificadoEnviadoEm, nil
+}
+
+// Certificado retorna o conteúdo decifrado do certificado da empresa
+func (s *CertificadoService) Certificado(ctx context.Context, empresaID int) ([]byte, error) {
+	empresa, err := s.buscar(ctx, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	if empresa.CertificadoKey == "" {
+		return nil, ErrCertificadoNotFound
+	}
+
+	content, err := s.store.Get(ctx, empresa.CertificadoKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, ErrCertificadoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler certificado: %w", err)
+	}
+	return content, nil
+}
+
+// Remover desvincula o certificado da empresa e, confirmada a alteração, apaga o objeto
+func (s *CertificadoService) Remover(ctx context.Context, empresaID int) error {
+	empresa, err := s.buscar(ctx, empresaID)
+	if err != nil {
+		return err
+	}
+	if empresa.CertificadoKey == "" {
+		return ErrCertificadoNotFound
+	}
+
+	antes := *empresa
+	empresa.CertificadoKey = ""
+	empresa.CertificadoEnviadoEm = time.Time{}
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Update(ctx, empresa); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionUpdate), &antes, empresa)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao remover certificado: %w", err)
+	}
+
+	s.RemoverObjeto(ctx, &antes)
+	return nil
+}
+
+// RemoverObjeto apaga do armazenamento o certificado de uma empresa removida ou
+// cujo certificado foi desvinculado. Falhas são apenas registradas: o objeto
+// continua cifrado e sem referência no banco.
+func (s *CertificadoService) RemoverObjeto(ctx context.Context, empresa *model.Empresa) {
+	if empresa.CertificadoKey == "" {
+		return
+	}
+	err := s.store.Delete(ctx, empresa.CertificadoKey)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		logger.Database().Warn().Err(err).
+			Int("empresa_id", empresa.ID).
+			Str("object", empresa.CertificadoKey).
+			Msg("Erro ao remover certificado do armazenamento")
+	}
+}
+
+func (s *CertificadoService) buscar(ctx context.Context, empresaID int) (*model.Empresa, error) {
+	empresa, err := s.empresaRepo.GetByID(ctx, empresaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEmpresaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar empresa: %w", err)
+	}
+	return empresa, nil
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+
+	"github.com/shopspring/decimal"
+)
+
+// Limites do dashboard
+const (
+	dashboardDefaultMeses   = 12  // Período padrão: últimos 12 meses
+	dashboardMaxMeses       = 120 // Maior período aceito
+	dashboardDefaultTomador = 10
+	dashboardMaxTomador     = 100
+)
+
+// mesesAbreviados rótulos dos meses no gráfico
+var mesesAbreviados = [...]string{"Jan", "Fev", "Mar", "Abr", "Mai", "Jun", "Jul", "Ago", "Set", "Out", "Nov", "Dez"}
+
+var cem = decimal.NewFromInt(100)
+
+// DashboardService indicadores do dashboard (faturamento, documentos, crescimento e
+// ISS), calculados a partir das views materializadas. O recálculo cobre todos os
+// escritórios, por isso as alterações pedem uma atualização adiada, que agrupa as
+// feitas em refreshDelay.
+type DashboardService struct {
+	dashboardRepo DashboardRepository
+	refreshDelay  time.Duration
+
+	// Atualizações pedidas durante outra em andamento são agrupadas em uma só
+	mu         sync.Mutex
+	refreshing bool
+	pending    bool
+	scheduled  *time.Timer // Atualização adiada ainda não iniciada
+}
+
+// NewDashboardService cria uma nova instância do serviço de dashboard
+func NewDashboardService(dashboardRepo DashboardRepository, refreshDelay time.Duration) *DashboardService {
+	return &DashboardService{dashboardRepo: dashboardRepo, refreshDelay: refreshDelay}
+}
+
+// SolicitarAtualizacao agenda o recálculo dos agregados para depois de refreshDelay.
+// Pedidos feitos até lá são atendidos pela mesma atualização.
+func (s *DashboardService) SolicitarAtualizacao() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scheduled != nil {
+		return
+	}
+	s.scheduled = time.AfterFunc(s.refreshDelay, s.atualizarAgendada)
+}
+
+// atualizarAgendada executa a atualização adiada
+func (s *DashboardService) atualizarAgendada() {
+	s.mu.Lock()
+	s.scheduled = nil
+	s.mu.Unlock()
+
+	if err := s.Atualizar(context.Background()); err != nil {
+		logger.Error(err, "Erro ao atualizar agregados do dashboard")
+	}
+}
+
+// Encerrar executa agora a atualização adiada, se houver, para que ela não se
+// perca no desligamento
+func (s *DashboardService) Encerrar(ctx context.Context) error {
+	s.mu.Lock()
+	timer := s.scheduled
+	s.scheduled = nil
+	s.mu.Unlock()
+
+	// Timer já disparado: a atualização está em andamento
+	if timer == nil || !timer.Stop() {
+		return nil
+	}
+	return s.Atualizar(ctx)
+}
+
+// Atualizar recalcula os agregados. Se já houver uma atualização em andamento,
+// ela é repetida ao terminar, para incluir os documentos gravados nesse meio tempo.
+func (s *DashboardService) Atualizar(ctx context.Context) error {
+	s.mu.Lock()
+	if s.refreshing {
+		s.pending = true
+		s.mu.Unlock()
+		return nil
+	}
+	s.refreshing = true
+	s.mu.Unlock()
+
+	for {
+		err := s.dashboardRepo.Refresh(ctx)
+
+		s.mu.Lock()
+		if err != nil || !s.pending {
+			s.refreshing = false
+			s.pending = false
+			s.mu.Unlock()
+			if err != nil {
+				return fmt.Errorf("erro ao atualizar dashboard: %w", err)
+			}
+			return nil
+		}
+		s.pending = false
+		s.mu.Unlock()
+	}
+}
+
+// Grafico retorna a quantidade de documentos, o faturamento e o ISS de cada mês do período
+func (s *DashboardService) Grafico(ctx context.Context, filter *model.DashboardFilter) ([]*model.DashboardChartPoint, error) {
+	meses, err := s.resumoMensal(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*model.DashboardChartPoint, 0, len(meses)-1)
+	for _, mes := range meses[1:] {
+		points = append(points, &model.DashboardChartPoint{
+			Month:       rotuloCompetencia(mes.Competencia),
+			Competencia: mes.Competencia,
+			Documents:   mes.Documentos,
+			Revenue:     mes.ValorNota,
+			ISS:         mes.ValorIss,
+			ISSRetido:   mes.ValorIssRetido,
+		})
+	}
+	return points, nil
+}
+
+// Estatisticas retorna a quantidade de documentos emitidos no período e no mês final
+func (s *DashboardService) Estatisticas(ctx context.Context, filter *model.DashboardFilter) (*model.DashboardDocumentStats, error) {
+	meses, err := s.resumoMensal(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	atual, anterior := ultimosMeses(meses)
+	stats := &model.DashboardDocumentStats{
+		ThisMonth:   atual.Documentos,
+		Growth:      variacao(decimal.NewFromInt(int64(atual.Documentos)), decimal.NewFromInt(int64(anterior.Documentos))),
+		Competencia: atual.Competencia,
+	}
+	for _, mes := range meses[1:] {
+		stats.Total += mes.Documentos
+	}
+	return stats, nil
+}
+
+// Receita retorna o faturamento do período e do mês final
+func (s *DashboardService) Receita(ctx context.Context, filter *model.DashboardFilter) (*model.DashboardRevenue, error) {
+	meses, err := s.resumoMensal(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	atual, anterior := ultimosMeses(meses)
+	revenue := &model.DashboardRevenue{
+		ThisMonth:   atual.ValorNota,
+		Growth:      variacao(atual.ValorNota, anterior.ValorNota),
+		Competencia: atual.Competencia,
+	}
+	for _, mes := range meses[1:] {
+		revenue.Total = revenue.Total.Add(mes.ValorNota)
+	}
+	return revenue, nil
+}
+
+// Crescimento retorna a variação do faturamento do mês final sobre o mês anterior
+func (s *DashboardService) Crescimento(ctx context.Context, filter *model.DashboardFilter) (*model.DashboardGrowth, error) {
+	meses, err := s.resumoMensal(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	atual, anterior := ultimosMeses(meses)
+	percentage := variacao(atual.ValorNota, anterior.ValorNota)
+	return &model.DashboardGrowth{
+		Percentage:  percentage,
+		IsPositive:  percentage >= 0,
+		Comparison:  "vs " + rotuloCompetencia(anterior.Competencia),
+		Competencia: atual.Competencia,
+		Atual:       atual.ValorNota,
+		Anterior:    anterior.ValorNota,
+	}, nil
+}
+
+// MetricasISS retorna o ISS devido, retido e a recolher no período e no mês final.
+// A meta (opcional) é o ISS devido esperado para o período.
+func (s *DashboardService) MetricasISS(ctx context.Context, filter *model.DashboardFilter, meta *decimal.Decimal) (*model.DashboardISS, error) {
+	if meta != nil && !meta.IsPositive() {
+		return nil, fmt.Errorf("%w: a meta de ISS deve ser positiva", ErrInvalidData)
+	}
+
+	meses, err := s.resumoMensal(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	atual, _ := ultimosMeses(meses)
+	metrics := &model.DashboardISS{
+		MonthlyISS:        atual.ValorIss,
+		MonthlyWithheld:   atual.ValorIssRetido,
+		MonthlyPayableISS: atual.ValorIss.Sub(atual.ValorIssRetido),
+		Competencia:       atual.Competencia,
+	}
+	faturamento := decimal.Zero
+	for _, mes := range meses[1:] {
+		metrics.TotalISS = metrics.TotalISS.Add(mes.ValorIss)
+		metrics.WithheldISS = metrics.WithheldISS.Add(mes.ValorIssRetido)
+		faturamento = faturamento.Add(mes.ValorNota)
+	}
+	metrics.PayableISS = metrics.TotalISS.Sub(metrics.WithheldISS)
+
+	if faturamento.IsPositive() {
+		metrics.AverageRate = metrics.TotalISS.Div(faturamento).Mul(cem).Round(2).InexactFloat64()
+	}
+	if meta != nil {
+		metrics.ProgressToTarget = metrics.TotalISS.Div(*meta).Mul(cem).Round(2).InexactFloat64()
+	}
+	return metrics, nil
+}
+
+// TopTomadores retorna os tomadores com maior faturamento no período
+func (s *DashboardService) TopTomadores(ctx context.Context, filter *model.DashboardFilter, limit int) ([]*model.DocumentResumoTomador, error) {
+	if err := prepararFiltroDashboard(filter, time.Now()); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = dashboardDefaultTomador
+	}
+	if limit > dashboardMaxTomador {
+		limit = dashboardMaxTomador
+	}
+
+	tomadores, err := s.dashboardRepo.TopTomadores(ctx, filter, limit)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar maiores tomadores: %w", err)
+	}
+	return tomadores, nil
+}
+
+// resumoMensal totais de cada competência do período, inclusive das sem documentos,
+// e da competência anterior ao início, base de comparação do primeiro mês
+func (s *DashboardService) resumoMensal(ctx context.Context, filter *model.DashboardFilter) ([]*model.DocumentResumoMensal, error) {
+	if err := prepararFiltroDashboard(filter, time.Now()); err != nil {
+		return nil, err
+	}
+
+	inicio, _ := time.Parse("200601", filter.CompetenciaInicio)
+	fim, _ := time.Parse("200601", filter.CompetenciaFim)
+	consulta := *filter
+	consulta.CompetenciaInicio = inicio.AddDate(0, -1, 0).Format("200601")
+
+	rows, err := s.dashboardRepo.ResumoMensal(ctx, &consulta)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar resumo mensal: %w", err)
+	}
+	porCompetencia := make(map[string]*model.DocumentResumoMensal, len(rows))
+	for _, row := range rows {
+		porCompetencia[row.Competencia] = row
+	}
+
+	var meses []*model.DocumentResumoMensal
+	for mes := inicio.AddDate(0, -1, 0); !mes.After(fim); mes = mes.AddDate(0, 1, 0) {
+		competencia := mes.Format("200601")
+		row, ok := porCompetencia[competencia]
+		if !ok {
+			row = &model.DocumentResumoMensal{Competencia: competencia}
+		}
+		meses = append(meses, row)
+	}
+	return meses, nil
+}
+
+// prepararFiltroDashboard valida o período, que por padrão são os 12 meses até a
+// competência atual. Sem início, o período termina no fim informado.
+func prepararFiltroDashboard(filter *model.DashboardFilter, now time.Time) error {
+	var err error
+	if filter.CompetenciaInicio, err = normalizarCompetencia(filter.CompetenciaInicio); err != nil {
+		return err
+	}
+	if filter.CompetenciaFim, err = normalizarCompetencia(filter.CompetenciaFim); err != nil {
+		return err
+	}
+
+	if filter.CompetenciaFim == "" {
+		filter.CompetenciaFim = now.Format("200601")
+	}
+	fim, _ := time.Parse("200601", filter.CompetenciaFim)
+	if filter.CompetenciaInicio == "" {
+		filter.CompetenciaInicio = fim.AddDate(0, 1-dashboardDefaultMeses, 0).Format("200601")
+	}
+	inicio, _ := time.Parse("200601", filter.CompetenciaInicio)
+
+	if fim.Before(inicio) {
+		return fmt.Errorf("%w: competência final anterior à inicial", ErrInvalidData)
+	}
+	if inicio.AddDate(0, dashboardMaxMeses, 0).Before(fim) {
+		return fmt.Errorf("%w: período maior que %d meses", ErrInvalidData, dashboardMaxMeses)
+	}
+	return nil
+}
+
+// ultimosMeses retorna o mês final do período e o anterior
+func ultimosMeses(meses []*model.DocumentResumoMensal) (atual, anterior *model.DocumentResumoMensal) {
+	return meses[len(meses)-1], meses[len(meses)-2]
+}
+
+// variacao percentual de atual sobre anterior; sem base de comparação, 100% se
+// houve movimento e 0 caso contrário
+func variacao(atual, anterior decimal.Decimal) float64 {
+	if anterior.IsZero() {
+		if atual.IsZero() {
+			return 0
+		}
+		return 100
+	}
+	return atual.Sub(anterior).Div(anterior).Mul(cem).Round(2).InexactFloat64()
+}
+
+// rotuloCompetencia converte AAAAMM no rótulo do gráfico, ex.: "Jan/2026"
+func rotuloCompetencia(competencia string) string {
+	mes, err := time.Parse("200601", competencia)
+	if err != nil {
+		return competencia
+	}
+	return fmt.Sprintf("%s/%d", mesesAbreviados[mes.Month()-1], mes.Year())
+}
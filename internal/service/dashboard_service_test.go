@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/service"
+)
+
+// countingDashboardRepository conta os recálculos das views
+type countingDashboardRepository struct {
+	refreshes atomic.Int32
+}
+
+func (r *countingDashboardRepository) ResumoMensal(ctx context.Context, filter *model.DashboardFilter) ([]*model.DocumentResumoMensal, error) {
+	return nil, nil
+}
+
+func (r *countingDashboardRepository) TopTomadores(ctx context.Context, filter *model.DashboardFilter, limit int) ([]*model.DocumentResumoTomador, error) {
+	return nil, nil
+}
+
+func (r *countingDashboardRepository) Refresh(ctx context.Context) error {
+	r.refreshes.Add(1)
+	return nil
+}
+
+func TestDashboardServiceAgrupaAtualizacoes(t *testing.T) {
+	repo := &countingDashboardRepository{}
+	dashboard := service.NewDashboardService(repo, 20*time.Millisecond)
+
+	for range 5 {
+		dashboard.SolicitarAtualizacao()
+	}
+	if got := repo.refreshes.Load(); got != 0 {
+		t.Fatalf("%d atualizações antes do intervalo, esperado 0", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for repo.refreshes.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := repo.refreshes.Load(); got != 1 {
+		t.Errorf("%d atualizações para 5 pedidos no intervalo, esperado 1", got)
+	}
+}
+
+func TestDashboardServiceEncerrarExecutaAtualizacaoPendente(t *testing.T) {
+	repo := &countingDashboardRepository{}
+	dashboard := service.NewDashboardService(repo, time.Hour)
+
+	dashboard.SolicitarAtualizacao()
+	if err := dashboard.Encerrar(context.Background()); err != nil {
+		t.Fatalf("Encerrar: %v", err)
+	}
+	if got := repo.refreshes.Load(); got != 1 {
+		t.Errorf("%d atualizações no encerramento, esperado 1", got)
+	}
+
+	// Sem pedido pendente, encerrar não recalcula
+	if err := dashboard.Encerrar(context.Background()); err != nil {
+		t.Fatalf("Encerrar: %v", err)
+	}
+	if got := repo.refreshes.Load(); got != 1 {
+		t.Errorf("%d atualizações, esperado 1", got)
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/tenant"
+	"zemdocs/internal/utils"
+)
+
+var (
+	ErrDeadLetterResolved = errors.New("registro já foi reprocessado ou descartado")
+)
+
+// DeadLetterService gerencia documentos que falharam na conversão ou persistência
+type DeadLetterService struct {
+	deadLetterRepo DeadLetterRepository
+	nfseService    *NFSeService
+}
+
+// NewDeadLetterService cria uma nova instância do serviço de falhas
+func NewDeadLetterService(deadLetterRepo DeadLetterRepository, nfseService *NFSeService) *DeadLetterService {
+	return &DeadLetterService{
+		deadLetterRepo: deadLetterRepo,
+		nfseService:    nfseService,
+	}
+}
+
+// Record grava uma falha na fila; implementa documents.FailureHandler
+func (s *DeadLetterService) Record(ctx context.Context, failure documents.Failure) {
+	errMsg := "erro desconhecido"
+	if failure.Err != nil {
+		errMsg = failure.Err.Error()
+	}
+
+	deadLetter := &model.DeadLetter{
+		Source:          failure.Source,
+		CodigoIBGE:      failure.CodigoIBGE,
+		Stage:           failure.Stage,
+		NumeroDocumento: failure.NumeroDocumento,
+		Competencia:     failure.Competencia,
+		Payload:         failure.Payload,
+		PayloadFormat:   failure.PayloadFormat,
+		Error:           errMsg,
+		Status:          model.DeadLetterStatusPendente,
+		EscritorioID:    tenant.EscritorioID(ctx),
+	}
+
+	if err := s.deadLetterRepo.Create(ctx, deadLetter); err != nil {
+		// Último recurso: o documento só fica registrado no log
+		logger.Error(err, fmt.Sprintf("Erro ao gravar falha do documento %s (etapa %s)", failure.NumeroDocumento, failure.Stage))
+		return
+	}
+
+	logger.Database().Warn().
+		Int64("dead_letter_id", deadLetter.ID).
+		Str("source", failure.Source).
+		Str("stage", failure.Stage).
+		Str("numero_documento", failure.NumeroDocumento).
+		Str("competencia", failure.Competencia).
+		Str("error", errMsg).
+		Msg("Documento enviado para a fila de falhas")
+}
+
+// Listar lista registros da fila de falhas
+func (s *DeadLetterService) Listar(ctx context.Context, filter model.DeadLetterFilter) ([]*model.DeadLetter, int, error) {
+	deadLetters, err := s.deadLetterRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao listar falhas: %w", err)
+	}
+
+	total, err := s.deadLetterRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao contar falhas: %w", err)
+	}
+
+	return deadLetters, total, nil
+}
+
+// Consultar retorna um registro com o payload completo
+func (s *DeadLetterService) Consultar(ctx context.Context, id int64) (*model.DeadLetter, error) {
+	deadLetter, err := s.deadLetterRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("registro não encontrado: %w", err)
+	}
+	return deadLetter, nil
+}
+
+// Reprocessar tenta novamente converter e salvar o documento a partir do payload preservado
+func (s *DeadLetterService) Reprocessar(ctx context.Context, id int64) (*model.DeadLetter, error) {
+	deadLetter, err := s.Consultar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetter.Status != model.DeadLetterStatusPendente {
+		return nil, ErrDeadLetterResolved
+	}
+
+	// O documento volta para o escritório cuja sincronização falhou
+	documentCtx := ctx
+	if deadLetter.EscritorioID != nil {
+		documentCtx = tenant.WithEscritorio(ctx, *deadLetter.EscritorioID)
+	}
+
+	stage, err := s.reprocessar(documentCtx, deadLetter)
+	if err != nil {
+		if recErr := s.deadLetterRepo.RecordAttempt(ctx, id, stage, err.Error()); recErr != nil {
+			logger.Error(recErr, fmt.Sprintf("Erro ao registrar tentativa da falha %d", id))
+		}
+		return nil, fmt.Errorf("reprocessamento falhou na etapa %s: %w", stage, err)
+	}
+
+	if err := s.deadLetterRepo.Resolve(ctx, id, model.DeadLetterStatusReprocessado); err != nil {
+		return nil, fmt.Errorf("erro ao atualizar registro: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Documento %s reprocessado a partir da fila de falhas (id %d)", deadLetter.NumeroDocumento, id))
+
+	return s.Consultar(ctx, id)
+}
+
+// Descartar marca um registro como descartado sem reprocessá-lo
+func (s *DeadLetterService) Descartar(ctx context.Context, id int64) error {
+	deadLetter, err := s.Consultar(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deadLetter.Status != model.DeadLetterStatusPendente {
+		return ErrDeadLetterResolved
+	}
+
+	if err := s.deadLetterRepo.Resolve(ctx, id, model.DeadLetterStatusDescartado); err != nil {
+		return fmt.Errorf("erro ao descartar registro: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Documento %s descartado da fila de falhas (id %d)", deadLetter.NumeroDocumento, id))
+	return nil
+}
+
+// reprocessar executa as etapas de conversão e persistência, retornando a etapa em que parou
+func (s *DeadLetterService) reprocessar(ctx context.Context, deadLetter *model.DeadLetter) (string, error) {
+	xmlContent := deadLetter.Payload
+	if deadLetter.PayloadFormat == documents.PayloadFormatBase64Zip {
+		decompressed, err := utils.DecompressXML(deadLetter.Payload)
+		if err != nil {
+			return documents.FailureStageDecompress, err
+		}
+		xmlContent = decompressed
+	}
+
+	xmlData, err := utils.ParseNFSeXML(xmlContent)
+	if err != nil {
+		return documents.FailureStageParse, err
+	}
+
+	if xmlData.DataEmissao.IsZero() {
+		return documents.FailureStageDate, fmt.Errorf("data de emissão ausente ou inválida no XML")
+	}
+
+	numero := deadLetter.NumeroDocumento
+	if numero == "" {
+		numero = xmlData.NumeroNfse
+	}
+
+	resp := &documents.Response{
+		DocumentType:      documents.DocumentTypeNFSe,
+		NumeroDocumento:   numero,
+		NumeroNfse:        numero,
+		NumeroRps:         xmlData.NumeroRps,
+		SerieRps:          xmlData.SerieRps,
+		DataEmissao:       xmlData.DataEmissao,
+		Status:            "Emitida",
+		CodigoVerificacao: xmlData.CodigoVerificacao,
+		ValorServico:      xmlData.ValorServico,
+		ValorIss:          xmlData.ValorIss,
+		Competencia:       deadLetter.Competencia,
+		XMLContent:        xmlContent,
+	}
+
+	if err := s.nfseService.ProcessarDocumento(ctx, resp); err != nil {
+		return documents.FailureStagePersist, err
+	}
+
+	return "", nil
+}
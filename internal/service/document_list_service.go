@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+
+	"github.com/shopspring/decimal"
+)
+
+// Limites da página da listagem de documentos
+const (
+	listDefaultLimit = 50
+	listMaxLimit     = 200
+)
+
+// DefaultDocumentSort ordenação padrão: emissões mais recentes primeiro
+var DefaultDocumentSort = model.DocumentSort{Column: "data_emissao", Desc: true}
+
+// DocumentListService listagem de documentos com filtros, ordenação e paginação por cursor
+type DocumentListService struct {
+	documentRepo DocumentRepository
+}
+
+// NewDocumentListService cria uma nova instância do serviço de listagem
+func NewDocumentListService(documentRepo DocumentRepository) *DocumentListService {
+	return &DocumentListService{documentRepo: documentRepo}
+}
+
+// Listar retorna uma página de documentos. O cursor é o next_cursor da página
+// anterior e só vale para a mesma ordenação.
+func (s *DocumentListService) Listar(ctx context.Context, req *model.DocumentListRequest, cursor string) (*model.DocumentListResponse, error) {
+	if err := prepararFiltroDocumentos(&req.DocumentFilter); err != nil {
+		return nil, err
+	}
+
+	if req.Sort.Column == "" {
+		req.Sort = DefaultDocumentSort
+	}
+	if !req.Sort.Valid() {
+		return nil, fmt.Errorf("%w: ordenação inválida: %s (use %s)", ErrInvalidData, req.Sort.Column, strings.Join(model.DocumentSortColumns, ", "))
+	}
+
+	req.Cursor = nil
+	if cursor != "" {
+		decoded, err := decodeDocumentCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.Sort != req.Sort {
+			return nil, fmt.Errorf("%w: cursor de outra ordenação", ErrInvalidData)
+		}
+		req.Cursor = decoded
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = listDefaultLimit
+	}
+	if req.Limit > listMaxLimit {
+		req.Limit = listMaxLimit
+	}
+	limit := req.Limit
+
+	// Um documento a mais indica que existe a próxima página
+	req.Limit = limit + 1
+	documents, err := s.documentRepo.List(ctx, req)
+	req.Limit = limit
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar documentos: %w", err)
+	}
+
+	response := &model.DocumentListResponse{
+		Documents: make([]*model.DocumentResponse, 0, len(documents)),
+		Limit:     limit,
+	}
+	if len(documents) > limit {
+		documents = documents[:limit]
+		last := documents[len(documents)-1]
+		response.NextCursor = encodeDocumentCursor(&model.DocumentCursor{
+			Sort:  req.Sort,
+			Value: last.SortValue(req.Sort.Column),
+			ID:    last.ID,
+		})
+	}
+	for _, document := range documents {
+		response.Documents = append(response.Documents, toDocumentResponse(document))
+	}
+
+	return response, nil
+}
+
+// prepararFiltroDocumentos valida e normaliza os filtros comuns às listagens e à busca
+func prepararFiltroDocumentos(filter *model.DocumentFilter) error {
+	var err error
+	if filter.CompetenciaInicio, err = normalizarCompetencia(filter.CompetenciaInicio); err != nil {
+		return err
+	}
+	if filter.CompetenciaFim, err = normalizarCompetencia(filter.CompetenciaFim); err != nil {
+		return err
+	}
+	if filter.CompetenciaInicio != "" && filter.CompetenciaFim != "" && filter.CompetenciaFim < filter.CompetenciaInicio {
+		return fmt.Errorf("%w: competência final anterior à inicial", ErrInvalidData)
+	}
+	if !filter.DataInicio.IsZero() && !filter.DataFim.IsZero() && filter.DataFim.Before(filter.DataInicio) {
+		return fmt.Errorf("%w: data final anterior à inicial", ErrInvalidData)
+	}
+	if filter.ValorMin != nil && filter.ValorMax != nil && filter.ValorMax.LessThan(*filter.ValorMin) {
+		return fmt.Errorf("%w: valor máximo menor que o mínimo", ErrInvalidData)
+	}
+
+	filter.CNPJ = limparCNPJ(filter.CNPJ)
+	filter.CNPJEmitente = limparCNPJ(filter.CNPJEmitente)
+	filter.CNPJDestinatario = limparCNPJ(filter.CNPJDestinatario)
+	filter.ItemListaServico = strings.TrimSpace(filter.ItemListaServico)
+	filter.CodigoMunicipio = strings.TrimSpace(filter.CodigoMunicipio)
+	return nil
+}
+
+// encodeDocumentCursor serializa o cursor em um token opaco para a URL
+func encodeDocumentCursor(cursor *model.DocumentCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeDocumentCursor(token string) (*model.DocumentCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor inválido", ErrInvalidData)
+	}
+	cursor := &model.DocumentCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil || !cursor.Sort.Valid() || !cursorValueValido(cursor) {
+		return nil, fmt.Errorf("%w: cursor inválido", ErrInvalidData)
+	}
+	return cursor, nil
+}
+
+// cursorValueValido confere o valor do cursor com o tipo da coluna de ordenação,
+// já que o token vem do cliente
+func cursorValueValido(cursor *model.DocumentCursor) bool {
+	switch cursor.Sort.Column {
+	case "data_emissao", "created_at":
+		_, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		return err == nil
+	case "valor_nota", "valor_iss":
+		_, err := decimal.NewFromString(cursor.Value)
+		return err == nil
+	default:
+		return cursor.Value != ""
+	}
+}
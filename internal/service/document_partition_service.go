@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"zemdocs/internal/config"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+)
+
+// DocumentPartitionService manutenção das partições anuais de documents: cria as
+// partições dos anos que chegam e arquiva as antigas em um tablespace mais barato.
+// As partições arquivadas continuam anexadas, então a leitura não muda.
+type DocumentPartitionService struct {
+	partitionRepo DocumentPartitionRepository
+	cfg           config.PartitionConfig
+}
+
+// NewDocumentPartitionService cria uma nova instância do serviço de partições
+func NewDocumentPartitionService(partitionRepo DocumentPartitionRepository, cfg config.PartitionConfig) *DocumentPartitionService {
+	return &DocumentPartitionService{partitionRepo: partitionRepo, cfg: cfg}
+}
+
+// Listar retorna as partições com tamanho e tablespace
+func (s *DocumentPartitionService) Listar(ctx context.Context) ([]*model.DocumentPartition, error) {
+	partitions, err := s.partitionRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar partições: %w", err)
+	}
+	return partitions, nil
+}
+
+// Manter cria as partições do ano atual, dos próximos e dos anos que caíram na
+// partição padrão, e arquiva as partições fora do período mantido no tablespace padrão
+func (s *DocumentPartitionService) Manter(ctx context.Context, now time.Time) (*model.DocumentPartitionReport, error) {
+	report := &model.DocumentPartitionReport{}
+
+	anos, err := s.partitionRepo.DefaultYears(ctx)
+	if err != nil {
+		return report, fmt.Errorf("erro ao consultar a partição padrão: %w", err)
+	}
+	for ano := now.Year(); ano <= now.Year()+s.cfg.AheadYears; ano++ {
+		anos = append(anos, ano)
+	}
+
+	for _, ano := range anos {
+		created, err := s.partitionRepo.Create(ctx, ano)
+		if err != nil {
+			return report, fmt.Errorf("erro ao criar partição de %d: %w", ano, err)
+		}
+		if created {
+			report.Created = append(report.Created, fmt.Sprintf("documents_%d", ano))
+		}
+	}
+
+	if s.cfg.ArchiveTablespace == "" {
+		return report, nil
+	}
+
+	partitions, err := s.Listar(ctx)
+	if err != nil {
+		return report, err
+	}
+	limite := now.Year() - s.cfg.ArchiveAfterYears
+	for _, partition := range partitions {
+		if partition.Ano == 0 || partition.Ano >= limite || partition.Tablespace == s.cfg.ArchiveTablespace {
+			continue
+		}
+
+		logger.Database().Info().
+			Str("partition", partition.Name).
+			Str("tablespace", s.cfg.ArchiveTablespace).
+			Int64("size", partition.Size).
+			Msg("Arquivando partição de documentos")
+		if err := s.partitionRepo.Move(ctx, partition.Name, s.cfg.ArchiveTablespace); err != nil {
+			return report, fmt.Errorf("erro ao arquivar %s: %w", partition.Name, err)
+		}
+		report.Archived = append(report.Archived, partition.Name)
+	}
+
+	return report, nil
+}
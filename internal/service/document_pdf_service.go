@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/pdf"
+	"zemdocs/internal/storage"
+)
+
+var ErrPDFNotSupported = errors.New("documento sem representação em PDF")
+
+const pdfPrefix = "PDF/"
+
+// Metadados dos PDFs em cache: o PDF é refeito se o XML ou o layout mudar
+const (
+	metadataSourceSHA256 = "source-sha256"
+	metadataPDFVersion   = "pdf-version"
+)
+
+// DocumentPDF DANFSE/DANFE de um documento. Content só é preenchido por BaixarPDF.
+type DocumentPDF struct {
+	xml      *DocumentXML
+	Content  []byte
+	FileName string
+	ETag     string
+}
+
+// DocumentPDFService gera o DANFSE/DANFE a partir do XML e mantém o PDF em cache no armazenamento
+type DocumentPDFService struct {
+	xmlService *DocumentXMLService
+	store      storage.Store
+	renderer   *pdf.Renderer
+}
+
+// NewDocumentPDFService cria uma nova instância do serviço de PDFs
+func NewDocumentPDFService(xmlService *DocumentXMLService, store storage.Store, renderer *pdf.Renderer) *DocumentPDFService {
+	return &DocumentPDFService{
+		xmlService: xmlService,
+		store:      store,
+		renderer:   renderer,
+	}
+}
+
+// ConsultarPDF retorna os metadados do PDF do documento, sem gerá-lo
+func (s *DocumentPDFService) ConsultarPDF(ctx context.Context, id int) (*DocumentPDF, error) {
+	xml, err := s.xmlService.ConsultarXML(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !SupportsPDF(xml.Document.DocumentType) {
+		return nil, fmt.Errorf("%w: %s", ErrPDFNotSupported, xml.Document.DocumentType)
+	}
+
+	documentPDF := &DocumentPDF{
+		xml:      xml,
+		FileName: xml.Document.NumeroDocumento + ".pdf",
+	}
+	// Sem o hash do XML o PDF não tem versão identificável
+	if xml.ETag != "" {
+		documentPDF.ETag = xml.ETag + "-v" + pdf.Version
+	}
+	return documentPDF, nil
+}
+
+// BaixarPDF obtém o PDF (do cache ou gerando-o) e audita o acesso
+func (s *DocumentPDFService) BaixarPDF(ctx context.Context, documentPDF *DocumentPDF, access DownloadAccess) error {
+	content, err := s.pdf(ctx, documentPDF.xml)
+	if err != nil {
+		return err
+	}
+
+	if err := s.xmlService.registrar(ctx, documentPDF.xml.Document, model.DocumentDownloadPDF, PDFObjectName(documentPDF.xml.Document), access, time.Time{}); err != nil {
+		return err
+	}
+
+	documentPDF.Content = content
+	return nil
+}
+
+// PDF obtém o PDF de um documento já carregado, sem auditoria (usado na exportação)
+func (s *DocumentPDFService) PDF(ctx context.Context, document *model.Document) ([]byte, error) {
+	xml, err := newDocumentXML(document)
+	if err != nil {
+		return nil, err
+	}
+	return s.pdf(ctx, xml)
+}
+
+// pdf devolve o PDF em cache se ele foi gerado a partir do mesmo XML e layout;
+// caso contrário gera e grava no armazenamento
+func (s *DocumentPDFService) pdf(ctx context.Context, xml *DocumentXML) ([]byte, error) {
+	document := xml.Document
+	key := PDFObjectName(document)
+
+	if info, err := s.store.Stat(ctx, key); err == nil && xml.ETag != "" &&
+		info.MetadataValue(metadataSourceSHA256) == xml.ETag &&
+		info.MetadataValue(metadataPDFVersion) == pdf.Version {
+		if content, err := s.store.Get(ctx, key); err == nil {
+			return content, nil
+		}
+	}
+
+	content, err := s.xmlService.conteudo(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := s.renderer.Render(document.DocumentType, content, &buf); err != nil {
+		if errors.Is(err, pdf.ErrUnsupportedType) {
+			return nil, fmt.Errorf("%w: %s", ErrPDFNotSupported, document.DocumentType)
+		}
+		return nil, fmt.Errorf("erro ao gerar PDF do documento %d: %w", document.ID, err)
+	}
+
+	// Falha no cache não impede a entrega; o PDF é gerado de novo na próxima vez
+	err = s.store.Put(ctx, key, buf.Bytes(), storage.PutOptions{
+		ContentType: "application/pdf",
+		Metadata: map[string]string{
+			storage.MetadataSHA256: storage.ContentSHA256(buf.Bytes()),
+			metadataSourceSHA256:   xml.ETag,
+			metadataPDFVersion:     pdf.Version,
+		},
+		Tenant: storage.EscritorioTenant(document.EscritorioID),
+	})
+	if err != nil {
+		logger.Database().Warn().Err(err).Int("document_id", document.ID).Msg("Erro ao gravar PDF em cache")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SupportsPDF indica se o tipo de documento tem DANFSE/DANFE
+func SupportsPDF(docType model.DocumentType) bool {
+	return docType == model.DocumentTypeNFSe || docType == model.DocumentTypeNFe
+}
+
+// PDFObjectName chave do PDF em cache, espelhando a chave do XML:
+// PDF/{escritório}/NFS/{ano}/{MMAAAA}/{cnpj}/{número}.pdf
+func PDFObjectName(document *model.Document) string {
+	if document.XMLKey == "" {
+		return fmt.Sprintf("%sdocuments/%d.pdf", pdfPrefix, document.ID)
+	}
+	key := strings.TrimPrefix(document.XMLKey, "XML/")
+	return pdfPrefix + strings.TrimSuffix(key, ".xml") + ".pdf"
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+)
+
+// Limites da paginação da busca
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+)
+
+// destaqueReplacer troca os delimitadores do banco pelas tags de destaque
+var destaqueReplacer = strings.NewReplacer(
+	repository.SearchMarkStart, "<mark>",
+	repository.SearchMarkStop, "</mark>",
+)
+
+// DocumentSearchService busca textual nos documentos (português, sem acentos)
+type DocumentSearchService struct {
+	documentRepo DocumentRepository
+}
+
+// NewDocumentSearchService cria uma nova instância do serviço de busca
+func NewDocumentSearchService(documentRepo DocumentRepository) *DocumentSearchService {
+	return &DocumentSearchService{documentRepo: documentRepo}
+}
+
+// Buscar retorna a página de documentos encontrados, em ordem de relevância, e o total.
+// O termo aceita a sintaxe de buscadores: "frase exata", OR e -exclusão.
+func (s *DocumentSearchService) Buscar(ctx context.Context, filter *model.DocumentSearchFilter) ([]*model.DocumentSearchResult, int, error) {
+	if err := prepararBusca(filter); err != nil {
+		return nil, 0, err
+	}
+
+	hits, err := s.documentRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao buscar documentos: %w", err)
+	}
+
+	total, err := s.documentRepo.CountSearch(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao contar documentos: %w", err)
+	}
+
+	results := make([]*model.DocumentSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, &model.DocumentSearchResult{
+			DocumentResponse: toDocumentResponse(&hit.Document),
+			Rank:             hit.Rank,
+			Destaques:        destaques(hit),
+		})
+	}
+
+	return results, total, nil
+}
+
+// prepararBusca valida e normaliza o termo, os filtros e a paginação
+func prepararBusca(filter *model.DocumentSearchFilter) error {
+	filter.Query = normalizarTermo(filter.Query)
+	if filter.Query == "" {
+		return fmt.Errorf("%w: informe o termo da busca", ErrInvalidData)
+	}
+
+	if err := prepararFiltroDocumentos(&filter.DocumentFilter); err != nil {
+		return err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = searchDefaultLimit
+	}
+	if filter.Limit > searchMaxLimit {
+		filter.Limit = searchMaxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return nil
+}
+
+// normalizarTermo remove a pontuação de CNPJ, CPF e números ("12.345.678/0001-90"
+// vira "12345678000190"), que estão gravados sem ela
+func normalizarTermo(termo string) string {
+	palavras := strings.Fields(termo)
+	for i, palavra := range palavras {
+		// "-" inicial é a exclusão da sintaxe de busca
+		exclusao := strings.HasPrefix(palavra, "-")
+		if !ehIdentificador(strings.TrimPrefix(palavra, "-")) {
+			continue
+		}
+		palavras[i] = strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, palavra)
+		if exclusao {
+			palavras[i] = "-" + palavras[i]
+		}
+	}
+	return strings.Join(palavras, " ")
+}
+
+// ehIdentificador verifica se a palavra só tem dígitos e pontuação de documentos
+func ehIdentificador(palavra string) bool {
+	digitos := 0
+	for _, r := range palavra {
+		switch {
+		case unicode.IsDigit(r):
+			digitos++
+		case r == '.' || r == '/' || r == '-':
+		default:
+			return false
+		}
+	}
+	return digitos > 0
+}
+
+// destaques monta os trechos destacados que contêm algum termo encontrado
+func destaques(hit *model.DocumentSearchHit) map[string]string {
+	campos := map[string]string{
+		"discriminacao":             hit.DestaqueDiscriminacao,
+		"razao_social_emitente":     hit.DestaqueEmitente,
+		"razao_social_destinatario": hit.DestaqueDestinatario,
+	}
+
+	result := make(map[string]string)
+	for campo, trecho := range campos {
+		if !strings.Contains(trecho, repository.SearchMarkStart) {
+			continue
+		}
+		result[campo] = destaqueReplacer.Replace(html.EscapeString(trecho))
+	}
+	return result
+}
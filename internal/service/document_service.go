@@ -2,32 +2,62 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 	"zemdocs/internal/clientes/documents"
 	"zemdocs/internal/database/model"
-	"zemdocs/internal/database/repository"
 	"zemdocs/internal/logger"
 	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
 	"zemdocs/internal/utils"
+
+	"github.com/shopspring/decimal"
 )
 
 type NFSeService struct {
-	nfseRegistry *documents.Registry
-	nfseRepo     *repository.DocumentRepository
-	minioClient  *storage.MinIOClient
-	useLocalData bool // Flag para usar dados locais ou API externa
+	nfseRegistry  *documents.Registry
+	nfseRepo      DocumentRepository
+	store         storage.Store
+	outboxService *StorageOutboxService
+	onFailure     documents.FailureHandler
+	empresaLinker *EmpresaLinkService
+	dashboard     *DashboardService
+	audit         *AuditService
+	useLocalData  bool // Flag para usar dados locais ou API externa
 }
 
-func NewNFSeService(nfseRegistry *documents.Registry, nfseRepo *repository.DocumentRepository, minioClient *storage.MinIOClient) *NFSeService {
+func NewNFSeService(nfseRegistry *documents.Registry, nfseRepo DocumentRepository, store storage.Store, outboxService *StorageOutboxService) *NFSeService {
 	return &NFSeService{
-		nfseRegistry: nfseRegistry,
-		nfseRepo:     nfseRepo,
-		minioClient:  minioClient,
-		useLocalData: true, // Por padrão, usar dados locais
+		nfseRegistry:  nfseRegistry,
+		nfseRepo:      nfseRepo,
+		store:         store,
+		outboxService: outboxService,
+		useLocalData:  true, // Por padrão, usar dados locais
 	}
 }
 
+// SetFailureHandler define quem recebe os documentos que falharem na sincronização
+func (s *NFSeService) SetFailureHandler(handler documents.FailureHandler) {
+	s.onFailure = handler
+}
+
+// SetEmpresaLinker define o serviço que vincula os documentos às empresas cadastradas
+func (s *NFSeService) SetEmpresaLinker(linker *EmpresaLinkService) {
+	s.empresaLinker = linker
+}
+
+// SetDashboard define o dashboard cujo recálculo é agendado após a sincronização
+func (s *NFSeService) SetDashboard(dashboard *DashboardService) {
+	s.dashboard = dashboard
+}
+
+// SetAudit define a auditoria em que os documentos sincronizados são registrados
+func (s *NFSeService) SetAudit(auditService *AuditService) {
+	s.audit = auditService
+}
+
 // ConsultarPorNumero consulta documento por número
 func (s *NFSeService) ConsultarPorNumero(ctx context.Context, numeroNfse string) (*model.DocumentResponse, error) {
 	if s.useLocalData {
@@ -185,7 +215,7 @@ func (s *NFSeService) toModelResponse(nfseResp *documents.Response) *model.Docum
 		Status:            nfseResp.Status,
 		CodigoVerificacao: nfseResp.CodigoVerificacao,
 		ValorNota:         nfseResp.ValorServico, // Mapear ValorServico para ValorNota
-		AliquotaIss:       0,                     // Será extraído do XML se necessário
+		AliquotaIss:       decimal.Zero,          // Será extraído do XML se necessário
 		ValorIss:          nfseResp.ValorIss,
 		Competencia:       nfseResp.Competencia,
 		XMLContent:        nfseResp.XMLContent,
@@ -194,7 +224,12 @@ func (s *NFSeService) toModelResponse(nfseResp *documents.Response) *model.Docum
 
 // toModelResponseFromDB converte model.Document para model.DocumentResponse
 func (s *NFSeService) toModelResponseFromDB(document *model.Document) *model.DocumentResponse {
-	return &model.DocumentResponse{
+	return toDocumentResponse(document)
+}
+
+// toDocumentResponse converte model.Document para model.DocumentResponse, sem o XML
+func toDocumentResponse(document *model.Document) *model.DocumentResponse {
+	response := &model.DocumentResponse{
 		ID:                         document.ID,
 		DocumentType:               document.DocumentType,
 		NumeroDocumento:            document.NumeroDocumento,
@@ -206,40 +241,60 @@ func (s *NFSeService) toModelResponseFromDB(document *model.Document) *model.Doc
 		ValorNota:                  document.ValorNota,
 		AliquotaIss:                document.AliquotaIss,
 		ValorIss:                   document.ValorIss,
+		ValorIssRetido:             document.ValorIssRetido,
 		Competencia:                document.Competencia,
 		CNPJEmitente:               document.CNPJEmitente,
 		RazaoSocialEmitente:        document.RazaoSocialEmitente,
 		InscricaoMunicipalEmitente: document.InscricaoMunicipalEmitente,
 		CNPJDestinatario:           document.CNPJDestinatario,
 		RazaoSocialDestinatario:    document.RazaoSocialDestinatario,
+		EmitenteEmpresaID:          document.EmitenteEmpresaID,
+		DestinatarioEmpresaID:      document.DestinatarioEmpresaID,
 		Discriminacao:              document.Discriminacao,
 		CodigoServico:              document.CodigoServico,
 		ItemListaServico:           document.ItemListaServico,
 		CodigoMunicipio:            document.CodigoMunicipio,
 		CodigoIBGE:                 document.CodigoIBGE,
-		XMLContent:                 "", // XML será buscado do MinIO se necessário
+		XMLContent:                 "", // XML será buscado no armazenamento se necessário
+	}
+	if !document.DeletedAt.IsZero() {
+		response.DeletedAt = &document.DeletedAt
 	}
+	return response
 }
 
-// GetXMLContent busca o conteúdo XML do MinIO
-func (s *NFSeService) GetXMLContent(ctx context.Context, numeroNfse, competencia string) (string, error) {
-	// Primeiro tentar buscar pela estrutura nova (com CNPJ)
-	// Para isso, precisamos buscar o CNPJ do prestador no banco
+// GetXMLContent busca o XML do documento pela chave gravada nele, conferindo o hash
+// registrado. Documentos sem chave (layouts legados ainda não registrados pelo
+// cmd/storagemigrate) retornam ErrXMLNotAvailable.
+func (s *NFSeService) GetXMLContent(ctx context.Context, numeroNfse string) (string, error) {
 	nfse, err := s.nfseRepo.GetByNumeroNfse(ctx, numeroNfse)
-	if err == nil && nfse.CNPJEmitente != "" {
-		objectName := s.minioClient.GenerateObjectNameWithCNPJ(numeroNfse, competencia, nfse.CNPJEmitente)
-		xmlData, err := s.minioClient.DownloadXML(ctx, objectName)
-		if err == nil {
-			return string(xmlData), nil
-		}
+	if err != nil {
+		return "", fmt.Errorf("erro ao buscar documento: %w", err)
+	}
+	if nfse.XMLKey == "" {
+		return "", fmt.Errorf("%w: documento %s sem chave de XML registrada", ErrXMLNotAvailable, numeroNfse)
 	}
 
-	// Fallback para estrutura antiga
-	objectName := s.minioClient.GenerateObjectName(numeroNfse, competencia)
-	xmlData, err := s.minioClient.DownloadXML(ctx, objectName)
+	xmlData, err := s.store.Get(ctx, nfse.XMLKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return "", fmt.Errorf("%w: objeto %s ausente no armazenamento", ErrXMLNotAvailable, nfse.XMLKey)
+	}
 	if err != nil {
 		return "", fmt.Errorf("erro ao buscar XML: %w", err)
 	}
+
+	return s.verifyXML(ctx, nfse, xmlData)
+}
+
+// verifyXML confere o XML baixado com o hash do documento antes de entregá-lo
+func (s *NFSeService) verifyXML(ctx context.Context, nfse *model.Document, xmlData []byte) (string, error) {
+	if err := storage.VerifySHA256(xmlData, nfse.XMLSha256); err != nil {
+		if markErr := s.nfseRepo.UpdateXMLIntegrity(ctx, nfse.ID, model.XMLIntegrityCorrompido, time.Now()); markErr != nil {
+			logger.Error(markErr, fmt.Sprintf("Erro ao marcar XML do documento %d como corrompido", nfse.ID))
+		}
+		return "", fmt.Errorf("XML do documento %s: %w", nfse.NumeroDocumento, err)
+	}
+
 	return string(xmlData), nil
 }
 
@@ -273,6 +328,7 @@ func (s *NFSeService) SincronizarNFSe(ctx context.Context, competencia string) e
 	for _, nfseResp := range nfseList {
 		if err := s.processarNFSe(ctx, &nfseResp); err != nil {
 			logger.Error(err, fmt.Sprintf("Erro ao processar NFS-e %s", nfseResp.NumeroNfse))
+			s.reportFailure(ctx, &nfseResp, err)
 			totalErrors++
 		} else {
 			totalProcessed++
@@ -282,9 +338,35 @@ func (s *NFSeService) SincronizarNFSe(ctx context.Context, competencia string) e
 	logger.Info(fmt.Sprintf("Sincronização concluída - competencia: %s, processadas: %d, erros: %d",
 		competencia, totalProcessed, totalErrors))
 
+	if s.dashboard != nil && totalProcessed > 0 {
+		s.dashboard.SolicitarAtualizacao()
+	}
+
 	return nil
 }
 
+// ProcessarDocumento salva um documento já convertido (usado no reprocessamento de falhas)
+func (s *NFSeService) ProcessarDocumento(ctx context.Context, nfseResp *documents.Response) error {
+	return s.processarNFSe(ctx, nfseResp)
+}
+
+// reportFailure encaminha um documento que falhou ao handler configurado
+func (s *NFSeService) reportFailure(ctx context.Context, nfseResp *documents.Response, err error) {
+	if s.onFailure == nil {
+		return
+	}
+
+	s.onFailure(ctx, documents.Failure{
+		Source:          "nfse-service",
+		Stage:           documents.FailureStagePersist,
+		NumeroDocumento: nfseResp.NumeroNfse,
+		Competencia:     nfseResp.Competencia,
+		Payload:         nfseResp.XMLContent,
+		PayloadFormat:   documents.PayloadFormatXML,
+		Err:             err,
+	})
+}
+
 // processarNFSe processa um documento individual
 func (s *NFSeService) processarNFSe(ctx context.Context, nfseResp *documents.Response) error {
 	// Verificar se já existe no banco
@@ -306,8 +388,10 @@ func (s *NFSeService) processarNFSe(ctx context.Context, nfseResp *documents.Res
 		}
 	}
 
-	// Criar modelo Document com valores da resposta da API
+	// Criar modelo Document com valores da resposta da API. O escritório é
+	// definido já aqui porque a chave do XML no outbox depende dele.
 	nfse := &model.Document{
+		EscritorioID:        tenant.EscritorioID(ctx),
 		DocumentType:        model.DocumentTypeNFSe,
 		NumeroDocumento:     nfseResp.NumeroNfse,
 		NumeroRps:           nfseResp.NumeroRps,
@@ -317,16 +401,17 @@ func (s *NFSeService) processarNFSe(ctx context.Context, nfseResp *documents.Res
 		Status:              nfseResp.Status,
 		CodigoVerificacao:   nfseResp.CodigoVerificacao,
 		ValorNota:           nfseResp.ValorServico, // Mapear ValorServico para ValorNota
-		AliquotaIss:         0,                     // Será extraído do XML se necessário
+		AliquotaIss:         decimal.Zero,          // Será extraído do XML se necessário
 		ValorIss:            nfseResp.ValorIss,
 		Competencia:         nfseResp.Competencia,
 		CNPJEmitente:        "",
 		RazaoSocialEmitente: "",
-		XMLContent:          nfseResp.XMLContent, // Salvar XML no banco também
+		XMLContent:          "", // XML fica apenas no armazenamento
 	}
 
 	// Aplicar metadados extraídos do XML
 	if metadata != nil {
+		nfse.ValorIssRetido = metadata.ValorIssRetido
 		if metadata.CNPJPrestador != "" {
 			nfse.CNPJEmitente = metadata.CNPJPrestador
 		}
@@ -359,37 +444,54 @@ func (s *NFSeService) processarNFSe(ctx context.Context, nfseResp *documents.Res
 		}
 	}
 
-	// Salvar no banco
-	if err := s.nfseRepo.Create(ctx, nfse); err != nil {
-		return fmt.Errorf("erro ao salvar no banco: %w", err)
+	// Vincular emitente e tomador às empresas cadastradas
+	if s.empresaLinker != nil {
+		s.empresaLinker.Vincular(ctx, nfse)
 	}
 
-	// Salvar XML no MinIO
-	if nfseResp.XMLContent != "" {
-		// Usar CNPJ do prestador se disponível, senão usar estrutura padrão
-		var objectName string
-		if metadata != nil && metadata.CNPJPrestador != "" {
-			objectName = s.minioClient.GenerateObjectNameWithCNPJ(nfseResp.NumeroNfse, nfseResp.Competencia, metadata.CNPJPrestador)
-		} else {
-			objectName = s.minioClient.GenerateObjectName(nfseResp.NumeroNfse, nfseResp.Competencia)
+	// Sem XML não há o que enviar ao armazenamento
+	if nfseResp.XMLContent == "" {
+		if err := SalvarDocumento(ctx, s.nfseRepo, s.audit, nfse, nil); err != nil {
+			return fmt.Errorf("erro ao salvar no banco: %w", err)
 		}
+		return nil
+	}
 
-		if err := s.minioClient.UploadXML(ctx, objectName, []byte(nfseResp.XMLContent)); err != nil {
-			logger.Error(err, fmt.Sprintf("Erro ao salvar XML no MinIO para NFS-e %s", nfseResp.NumeroNfse))
-			// Não retornar erro, pois o registro já foi salvo no banco
-		} else {
-			logger.Info(fmt.Sprintf("XML da NFS-e %s salvo no MinIO: %s", nfseResp.NumeroNfse, objectName))
-		}
+	// Documentos já sincronizados foram descartados pelo número; reenvios do mesmo
+	// conteúdo para a mesma chave são dispensados pelo outbox (hash no objeto)
+	xmlContent := []byte(nfseResp.XMLContent)
+	hash := storage.ContentSHA256(xmlContent)
+
+	// Chave no escritório do documento, com o CNPJ do prestador se disponível
+	cnpjPrestador := ""
+	if metadata != nil {
+		cnpjPrestador = metadata.CNPJPrestador
+	}
+	objectName := storage.GenerateEscritorioObjectName(nfse.EscritorioID, nfseResp.NumeroNfse, nfseResp.Competencia, cnpjPrestador)
+
+	// Salvar documento e upload pendente na mesma transação
+	nfse.SetXMLStorage(s.store.Bucket(), objectName, xmlContent, hash)
+	entry := s.outboxService.NewEntry(nfse, xmlContent)
+	if err := SalvarDocumento(ctx, s.nfseRepo, s.audit, nfse, entry); err != nil {
+		return fmt.Errorf("erro ao salvar no banco: %w", err)
+	}
+
+	// Tentar o upload imediatamente; em caso de falha o outbox tentará novamente
+	if err := s.outboxService.Deliver(ctx, entry); err != nil {
+		logger.Error(err, fmt.Sprintf("Upload do XML da NFS-e %s adiado para o outbox", nfseResp.NumeroNfse))
+	} else {
+		logger.Info(fmt.Sprintf("XML da NFS-e %s salvo no armazenamento: %s", nfseResp.NumeroNfse, objectName))
 	}
 
-	logger.Debug(fmt.Sprintf("Documento %s processado com sucesso - valor: R$ %.2f",
-		nfse.NumeroDocumento, nfse.ValorNota))
+	logger.Debug(fmt.Sprintf("Documento %s processado com sucesso - valor: R$ %s",
+		nfse.NumeroDocumento, nfse.ValorNota.StringFixed(2)))
 
 	return nil
 }
 
 // XMLMetadata metadados extraídos do XML para o serviço
 type XMLMetadata struct {
+	ValorIssRetido              decimal.Decimal
 	CNPJPrestador               string
 	RazaoSocialPrestador        string
 	InscricaoMunicipalPrestador string
@@ -415,6 +517,7 @@ func (s *NFSeService) extractMetadataFromXML(xmlContent string) (*XMLMetadata, e
 	}
 
 	metadata := &XMLMetadata{
+		ValorIssRetido:              xmlData.ValorIssRetido,
 		CNPJPrestador:               xmlData.CNPJPrestador,
 		RazaoSocialPrestador:        xmlData.RazaoSocialPrestador,
 		InscricaoMunicipalPrestador: xmlData.InscricaoMunicipalPrestador,
@@ -0,0 +1,419 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/config"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository/memory"
+	"zemdocs/internal/service"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeClient cliente de documentos que devolve sempre a mesma lista
+type fakeClient struct {
+	responses []documents.Response
+}
+
+func (c *fakeClient) ConsultarDocuments(ctx context.Context, req documents.ConsultarRequest) (*documents.Response, error) {
+	return nil, errors.New("não implementado")
+}
+
+func (c *fakeClient) ConsultarXMLDocuments(ctx context.Context, req documents.ConsultarXMLRequest) ([]documents.Response, error) {
+	return c.responses, nil
+}
+
+func (c *fakeClient) UltimoRPSEnviado(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+// failingStore armazenamento indisponível para gravação
+type failingStore struct {
+	storage.Store
+}
+
+func (s failingStore) Put(ctx context.Context, key string, data []byte, opts storage.PutOptions) error {
+	return errors.New("armazenamento indisponível")
+}
+
+// nfseXML XML mínimo no layout de Imperatriz
+func nfseXML(numero, cnpjPrestador, cnpjTomador string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?><consultarNotaResponse><ListaNfse><ComplNfse><Nfse><InfNfse>`+
+		`<Numero>%s</Numero><CodigoVerificacao>ABC123</CodigoVerificacao>`+
+		`<Servico><Valores><ValorServicos>100.00</ValorServicos><ValorIss>5.00</ValorIss></Valores></Servico>`+
+		`<PrestadorServico><IdentificacaoPrestador><Cnpj>%s</Cnpj></IdentificacaoPrestador><RazaoSocial>Prestador LTDA</RazaoSocial></PrestadorServico>`+
+		`<TomadorServico><IdentificacaoTomador><CpfCnpj><Cnpj>%s</Cnpj></CpfCnpj></IdentificacaoTomador><RazaoSocial>Tomador LTDA</RazaoSocial></TomadorServico>`+
+		`</InfNfse></Nfse></ComplNfse></ListaNfse></consultarNotaResponse>`, numero, cnpjPrestador, cnpjTomador)
+}
+
+func nfseResponse(numero, cnpjPrestador, cnpjTomador string) documents.Response {
+	return documents.Response{
+		DocumentType:      documents.DocumentTypeNFSe,
+		NumeroNfse:        numero,
+		DataEmissao:       time.Date(2024, time.August, 29, 11, 47, 0, 0, time.UTC),
+		Status:            "Emitida",
+		CodigoVerificacao: "ABC123",
+		ValorServico:      decimal.NewFromInt(100),
+		ValorIss:          decimal.NewFromInt(5),
+		Competencia:       "202408",
+		XMLContent:        nfseXML(numero, cnpjPrestador, cnpjTomador),
+	}
+}
+
+func newLocalStore(t *testing.T) storage.Store {
+	t.Helper()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost", "chave-de-teste")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return store
+}
+
+// nfseFixture serviço de NFS-e sobre o banco em memória
+type nfseFixture struct {
+	db            *memory.DB
+	store         storage.Store
+	documentos    *memory.DocumentRepository
+	outbox        *memory.StorageOutboxRepository
+	outboxService *service.StorageOutboxService
+	audit         *service.AuditService
+	nfseService   *service.NFSeService
+}
+
+func newNFSeFixture(t *testing.T, store storage.Store, responses ...documents.Response) *nfseFixture {
+	t.Helper()
+	db := memory.NewDB()
+	f := &nfseFixture{
+		db:         db,
+		store:      store,
+		documentos: memory.NewDocumentRepository(db),
+		outbox:     memory.NewStorageOutboxRepository(db),
+		audit:      service.NewAuditService(memory.NewAuditLogRepository(db)),
+	}
+
+	registry := documents.NewRegistry()
+	registry.Register("2105302", &fakeClient{responses: responses})
+
+	f.outboxService = service.NewStorageOutboxService(f.outbox, store, nil)
+	f.nfseService = service.NewNFSeService(registry, f.documentos, store, f.outboxService)
+	f.nfseService.SetAudit(f.audit)
+	f.nfseService.SetEmpresaLinker(service.NewEmpresaLinkService(memory.NewEmpresaRepository(db), f.documentos, nil, false))
+	return f
+}
+
+func TestNFSeServiceSincronizarNFSe(t *testing.T) {
+	store := newLocalStore(t)
+	f := newNFSeFixture(t, store,
+		nfseResponse("1001", "11111111000191", "22222222000191"),
+		nfseResponse("1002", "33333333000191", "22222222000191"),
+	)
+	ctx := tenant.WithEscritorio(context.Background(), 7)
+
+	empresa := &model.Empresa{CNPJ: "11111111000191", RazaoSocial: "Prestador LTDA"}
+	if err := memory.NewEmpresaRepository(f.db).Create(ctx, empresa); err != nil {
+		t.Fatalf("Create empresa: %v", err)
+	}
+
+	if err := f.nfseService.SincronizarNFSe(ctx, "202408"); err != nil {
+		t.Fatalf("SincronizarNFSe: %v", err)
+	}
+
+	document, err := f.documentos.GetByNumeroNfse(ctx, "1001")
+	if err != nil {
+		t.Fatalf("documento 1001 não gravado: %v", err)
+	}
+	if document.EscritorioID == nil || *document.EscritorioID != 7 {
+		t.Errorf("documento gravado no escritório %v, esperado 7", document.EscritorioID)
+	}
+	if document.CNPJEmitente != "11111111000191" || document.CNPJDestinatario != "22222222000191" {
+		t.Errorf("partes do XML não extraídas: emitente %q, tomador %q", document.CNPJEmitente, document.CNPJDestinatario)
+	}
+	if document.EmitenteEmpresaID == nil || *document.EmitenteEmpresaID != empresa.ID {
+		t.Errorf("emitente não vinculado à empresa %d", empresa.ID)
+	}
+	if document.DestinatarioEmpresaID != nil {
+		t.Error("tomador sem cadastro foi vinculado")
+	}
+
+	// O XML é enviado na hora, com o escritório como tenant da chave de cifra
+	entry, err := f.outbox.GetByDocumentID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("GetByDocumentID: %v", err)
+	}
+	if entry.Status != model.OutboxStatusConcluido {
+		t.Errorf("outbox com status %s, esperado %s", entry.Status, model.OutboxStatusConcluido)
+	}
+	if entry.Tenant != "7" {
+		t.Errorf("outbox com tenant %q, esperado o escritório 7", entry.Tenant)
+	}
+
+	content, err := f.nfseService.GetXMLContent(ctx, "1001")
+	if err != nil {
+		t.Fatalf("GetXMLContent: %v", err)
+	}
+	if content != nfseXML("1001", "11111111000191", "22222222000191") {
+		t.Error("XML lido difere do sincronizado")
+	}
+}
+
+func TestNFSeServiceSincronizarNFSeIgnoraExistentes(t *testing.T) {
+	f := newNFSeFixture(t, newLocalStore(t), nfseResponse("1001", "11111111000191", "22222222000191"))
+	ctxA := tenant.WithEscritorio(context.Background(), 1)
+	ctxB := tenant.WithEscritorio(context.Background(), 2)
+
+	for range 2 {
+		if err := f.nfseService.SincronizarNFSe(ctxA, "202408"); err != nil {
+			t.Fatalf("SincronizarNFSe: %v", err)
+		}
+	}
+	docs, err := f.documentos.ListAfterID(ctxA, 0, 0)
+	if err != nil {
+		t.Fatalf("ListAfterID: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Errorf("%d documentos após sincronizar duas vezes, esperado 1", len(docs))
+	}
+
+	// O mesmo número sincronizado por outro escritório é outro documento
+	if err := f.nfseService.SincronizarNFSe(ctxB, "202408"); err != nil {
+		t.Fatalf("SincronizarNFSe: %v", err)
+	}
+	if _, err := f.documentos.GetByNumeroNfse(ctxB, "1001"); err != nil {
+		t.Errorf("documento não gravado no segundo escritório: %v", err)
+	}
+}
+
+func TestNFSeServiceUploadFalhoFicaNoOutbox(t *testing.T) {
+	f := newNFSeFixture(t, failingStore{newLocalStore(t)}, nfseResponse("1001", "11111111000191", "22222222000191"))
+	ctx := tenant.WithEscritorio(context.Background(), 1)
+
+	if err := f.nfseService.SincronizarNFSe(ctx, "202408"); err != nil {
+		t.Fatalf("SincronizarNFSe: %v", err)
+	}
+
+	document, err := f.documentos.GetByNumeroNfse(ctx, "1001")
+	if err != nil {
+		t.Fatalf("documento não gravado com o armazenamento indisponível: %v", err)
+	}
+	entry, err := f.outbox.GetByDocumentID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("GetByDocumentID: %v", err)
+	}
+	if entry.Status != model.OutboxStatusPendente || entry.Attempts != 1 || len(entry.Payload) == 0 {
+		t.Errorf("outbox = status %s, %d tentativas; esperado pendente com o XML para nova tentativa", entry.Status, entry.Attempts)
+	}
+}
+
+func TestDocumentXMLServiceMarcaXMLCorrompido(t *testing.T) {
+	store := newLocalStore(t)
+	f := newNFSeFixture(t, store, nfseResponse("1001", "11111111000191", "22222222000191"))
+	ctx := tenant.WithEscritorio(context.Background(), 1)
+
+	if err := f.nfseService.SincronizarNFSe(ctx, "202408"); err != nil {
+		t.Fatalf("SincronizarNFSe: %v", err)
+	}
+	document, err := f.documentos.GetByNumeroNfse(ctx, "1001")
+	if err != nil {
+		t.Fatalf("GetByNumeroNfse: %v", err)
+	}
+	if err := store.Put(ctx, document.XMLKey, []byte("<adulterado/>"), storage.PutOptions{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	xmlService := service.NewDocumentXMLService(f.documentos, memory.NewDocumentDownloadRepository(f.db), store, time.Minute)
+	xml, err := xmlService.ConsultarXML(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("ConsultarXML: %v", err)
+	}
+	if err := xmlService.BaixarXML(ctx, xml, service.DownloadAccess{}); !errors.Is(err, service.ErrXMLCorrupted) {
+		t.Fatalf("BaixarXML = %v, esperado ErrXMLCorrupted", err)
+	}
+
+	document, err = f.documentos.GetByID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if document.XMLIntegrity != model.XMLIntegrityCorrompido {
+		t.Errorf("integridade do XML = %q, esperado %q", document.XMLIntegrity, model.XMLIntegrityCorrompido)
+	}
+}
+
+func TestDocumentListServicePaginaPorCursor(t *testing.T) {
+	db := memory.NewDB()
+	documentos := memory.NewDocumentRepository(db)
+	ctx := tenant.WithEscritorio(context.Background(), 1)
+
+	for i, valor := range []int64{300, 100, 200, 100} {
+		document := &model.Document{
+			DocumentType:    model.DocumentTypeNFSe,
+			NumeroDocumento: fmt.Sprintf("%d", 1001+i),
+			Competencia:     "202408",
+			ValorNota:       decimal.NewFromInt(valor),
+		}
+		if err := documentos.Create(ctx, document); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	// Documento de outro escritório não aparece na listagem
+	if err := documentos.Create(tenant.WithEscritorio(context.Background(), 2), &model.Document{NumeroDocumento: "9999"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	listService := service.NewDocumentListService(documentos)
+	sort := model.DocumentSort{Column: "valor_nota"}
+	var numeros []string
+	cursor := ""
+	for {
+		response, err := listService.Listar(ctx, &model.DocumentListRequest{Sort: sort, Limit: 3}, cursor)
+		if err != nil {
+			t.Fatalf("Listar: %v", err)
+		}
+		for _, document := range response.Documents {
+			numeros = append(numeros, document.NumeroDocumento)
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		cursor = response.NextCursor
+	}
+
+	// Valor crescente com o ID como desempate
+	expected := []string{"1002", "1004", "1003", "1001"}
+	if fmt.Sprint(numeros) != fmt.Sprint(expected) {
+		t.Errorf("listagem = %v, esperado %v", numeros, expected)
+	}
+}
+
+// nfseResponseExpirada NFS-e emitida antes do prazo mínimo de retenção, que já pode ser purgada
+func nfseResponseExpirada(numero, cnpjPrestador, cnpjTomador string) documents.Response {
+	response := nfseResponse(numero, cnpjPrestador, cnpjTomador)
+	response.DataEmissao = time.Date(2015, time.August, 3, 10, 0, 0, 0, time.UTC)
+	response.Competencia = "201508"
+	return response
+}
+
+// purgar exclui e purga o documento e entrega as remoções do outbox
+func (f *nfseFixture) purgar(t *testing.T, ctx context.Context, id int) {
+	t.Helper()
+	retention := service.NewRetentionService(f.documentos, f.store, service.NewRetentionPolicy(config.RetentionConfig{}), f.outboxService, f.audit)
+	if err := retention.ExcluirDocumento(ctx, id); err != nil {
+		t.Fatalf("ExcluirDocumento: %v", err)
+	}
+	if err := retention.PurgarDocumento(ctx, id); err != nil {
+		t.Fatalf("PurgarDocumento: %v", err)
+	}
+	if _, failed, err := f.outboxService.ProcessPending(tenant.WithSystem(context.Background()), 10); err != nil || failed > 0 {
+		t.Fatalf("ProcessPending: %d falhas, %v", failed, err)
+	}
+}
+
+func TestRetentionServicePurgaMantemXMLDeOutroEscritorio(t *testing.T) {
+	f := newNFSeFixture(t, newLocalStore(t), nfseResponseExpirada("1001", "11111111000191", "22222222000191"))
+	ctxA := tenant.WithEscritorio(context.Background(), 1)
+	ctxB := tenant.WithEscritorio(context.Background(), 2)
+
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		if err := f.nfseService.SincronizarNFSe(ctx, "201508"); err != nil {
+			t.Fatalf("SincronizarNFSe: %v", err)
+		}
+	}
+	documentA, err := f.documentos.GetByNumeroNfse(ctxA, "1001")
+	if err != nil {
+		t.Fatalf("GetByNumeroNfse: %v", err)
+	}
+	documentB, err := f.documentos.GetByNumeroNfse(ctxB, "1001")
+	if err != nil {
+		t.Fatalf("GetByNumeroNfse: %v", err)
+	}
+	if documentA.XMLKey == documentB.XMLKey {
+		t.Fatalf("os dois escritórios gravaram o XML na mesma chave %s", documentA.XMLKey)
+	}
+
+	f.purgar(t, ctxA, documentA.ID)
+
+	if _, err := f.store.Stat(ctxA, documentA.XMLKey); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("XML do documento purgado continua armazenado: %v", err)
+	}
+	content, err := f.nfseService.GetXMLContent(ctxB, "1001")
+	if err != nil {
+		t.Fatalf("XML do outro escritório perdido na purga: %v", err)
+	}
+	if content != nfseXML("1001", "11111111000191", "22222222000191") {
+		t.Error("XML do outro escritório alterado pela purga")
+	}
+}
+
+func TestRetentionServicePurgaNaoRemoveChaveCompartilhada(t *testing.T) {
+	f := newNFSeFixture(t, newLocalStore(t), nfseResponseExpirada("1001", "11111111000191", "22222222000191"))
+	ctxA := tenant.WithEscritorio(context.Background(), 1)
+	ctxB := tenant.WithEscritorio(context.Background(), 2)
+	system := tenant.WithSystem(context.Background())
+
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		if err := f.nfseService.SincronizarNFSe(ctx, "201508"); err != nil {
+			t.Fatalf("SincronizarNFSe: %v", err)
+		}
+	}
+
+	// Documentos gravados antes das chaves por escritório compartilham o objeto
+	content := []byte(nfseXML("1001", "11111111000191", "22222222000191"))
+	sharedKey := storage.GenerateObjectNameWithCNPJ("1001", "201508", "11111111000191")
+	if err := f.store.Put(system, sharedKey, content, storage.PutOptions{Tenant: "1"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	documents, err := f.documentos.ListWithXMLKeyAfterID(system, 0, 10)
+	if err != nil || len(documents) != 2 {
+		t.Fatalf("ListWithXMLKeyAfterID = %d documentos, %v", len(documents), err)
+	}
+	for _, document := range documents {
+		document.XMLKey = sharedKey
+		if err := f.documentos.UpdateXMLStorage(system, document); err != nil {
+			t.Fatalf("UpdateXMLStorage: %v", err)
+		}
+	}
+	documentA, documentB := documents[0], documents[1]
+	if *documentA.EscritorioID != 1 {
+		documentA, documentB = documentB, documentA
+	}
+
+	f.purgar(t, ctxA, documentA.ID)
+
+	if _, err := f.outbox.GetByDocumentID(system, documentA.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("remoção da chave compartilhada registrada no outbox: %v", err)
+	}
+	if _, err := f.nfseService.GetXMLContent(ctxB, "1001"); err != nil {
+		t.Fatalf("XML compartilhado removido na purga do outro escritório: %v", err)
+	}
+
+	// A migração de layout dá ao documento restante a chave do seu escritório e
+	// remove o objeto compartilhado, que ninguém mais usa
+	layout := service.NewStorageLayoutService(f.documentos, f.store, service.NewRetentionPolicy(config.RetentionConfig{}))
+	report, err := layout.Migrate(system, service.LayoutMigrationOptions{DeleteLegacy: true})
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if report.Relocated != 1 || len(report.Failed) > 0 {
+		t.Errorf("migração moveu %d XMLs com falhas %v, esperado 1", report.Relocated, report.Failed)
+	}
+	documentB, err = f.documentos.GetByID(ctxB, documentB.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if want := storage.GenerateEscritorioObjectName(documentB.EscritorioID, "1001", "201508", "11111111000191"); documentB.XMLKey != want {
+		t.Errorf("chave após a migração = %s, esperado %s", documentB.XMLKey, want)
+	}
+	if _, err := f.store.Stat(system, sharedKey); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("objeto compartilhado sem uso não removido: %v", err)
+	}
+	if got, err := f.nfseService.GetXMLContent(ctxB, "1001"); err != nil || got != string(content) {
+		t.Errorf("XML ilegível após a migração: %v", err)
+	}
+}
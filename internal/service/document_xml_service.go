@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/storage"
+)
+
+var (
+	ErrXMLNotAvailable = errors.New("XML do documento não disponível")
+	ErrXMLCorrupted    = errors.New("XML do documento não confere com o hash registrado")
+)
+
+// DownloadAccess quem está acessando o XML, registrado na auditoria
+type DownloadAccess struct {
+	Principal string
+	IP        string
+	UserAgent string
+}
+
+// DocumentXML XML de um documento. Content só é preenchido por BaixarXML, para que
+// requisições condicionais (ETag) sejam respondidas sem ler o armazenamento.
+type DocumentXML struct {
+	Document     *model.Document
+	Content      []byte
+	ContentType  string
+	FileName     string
+	ETag         string
+	LastModified time.Time
+}
+
+// DocumentXMLURL link pré-assinado para o XML de um documento
+type DocumentXMLURL struct {
+	DocumentID int       `json:"document_id"`
+	URL        string    `json:"url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	FileName   string    `json:"file_name"`
+	SHA256     string    `json:"sha256,omitempty"`
+}
+
+// DocumentXMLService entrega o XML dos documentos e audita cada acesso
+type DocumentXMLService struct {
+	nfseRepo     DocumentRepository
+	downloadRepo DocumentDownloadRepository
+	store        storage.Store
+	urlExpiry    time.Duration
+}
+
+// NewDocumentXMLService cria uma nova instância do serviço de XML dos documentos
+func NewDocumentXMLService(nfseRepo DocumentRepository, downloadRepo DocumentDownloadRepository, store storage.Store, urlExpiry time.Duration) *DocumentXMLService {
+	return &DocumentXMLService{
+		nfseRepo:     nfseRepo,
+		downloadRepo: downloadRepo,
+		store:        store,
+		urlExpiry:    urlExpiry,
+	}
+}
+
+// ConsultarXML retorna os metadados do XML do documento, sem o conteúdo
+func (s *DocumentXMLService) ConsultarXML(ctx context.Context, id int) (*DocumentXML, error) {
+	document, err := s.nfseRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar documento: %w", err)
+	}
+
+	return newDocumentXML(document)
+}
+
+// newDocumentXML monta os metadados do XML de um documento já carregado
+func newDocumentXML(document *model.Document) (*DocumentXML, error) {
+	xml := &DocumentXML{
+		Document:     document,
+		ContentType:  document.XMLContentType,
+		FileName:     document.NumeroDocumento + ".xml",
+		ETag:         document.XMLSha256,
+		LastModified: document.XMLUploadedAt,
+	}
+	if xml.ContentType == "" {
+		xml.ContentType = "application/xml"
+	}
+
+	switch {
+	case document.XMLKey != "":
+	case document.XMLContent != "":
+		// Documento legado com o XML ainda no banco
+		if xml.ETag == "" {
+			xml.ETag = storage.ContentSHA256([]byte(document.XMLContent))
+		}
+		xml.LastModified = document.UpdatedAt
+	default:
+		return nil, ErrXMLNotAvailable
+	}
+
+	return xml, nil
+}
+
+// BaixarXML lê o XML, confere o hash registrado e audita o acesso
+func (s *DocumentXMLService) BaixarXML(ctx context.Context, xml *DocumentXML, access DownloadAccess) error {
+	content, err := s.conteudo(ctx, xml.Document)
+	if err != nil {
+		return err
+	}
+
+	if err := s.registrar(ctx, xml.Document, model.DocumentDownloadXML, xml.Document.XMLKey, access, time.Time{}); err != nil {
+		return err
+	}
+
+	xml.Content = content
+	return nil
+}
+
+// conteudo lê o XML do armazenamento (ou do banco, nos documentos legados) e confere o
+// hash; um XML divergente é marcado como corrompido para a verificação de integridade
+func (s *DocumentXMLService) conteudo(ctx context.Context, document *model.Document) ([]byte, error) {
+	var content []byte
+	if document.XMLKey != "" {
+		data, err := s.store.Get(ctx, document.XMLKey)
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, fmt.Errorf("%w: objeto %s ausente no armazenamento", ErrXMLNotAvailable, document.XMLKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler XML: %w", err)
+		}
+		content = data
+	} else {
+		content = []byte(document.XMLContent)
+	}
+
+	if err := storage.VerifySHA256(content, document.XMLSha256); err != nil {
+		if markErr := s.nfseRepo.UpdateXMLIntegrity(ctx, document.ID, model.XMLIntegrityCorrompido, time.Now()); markErr != nil {
+			logger.Error(markErr, fmt.Sprintf("Erro ao marcar XML do documento %d como corrompido", document.ID))
+		}
+		return nil, fmt.Errorf("%w: %v", ErrXMLCorrupted, err)
+	}
+	return content, nil
+}
+
+// GerarURL emite um link pré-assinado de curta duração para o XML e audita a emissão
+func (s *DocumentXMLService) GerarURL(ctx context.Context, id int, access DownloadAccess) (*DocumentXMLURL, error) {
+	xml, err := s.ConsultarXML(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	document := xml.Document
+	if document.XMLKey == "" {
+		return nil, fmt.Errorf("%w: XML legado sem objeto no armazenamento, use /xml", ErrXMLNotAvailable)
+	}
+
+	if _, err := s.store.Stat(ctx, document.XMLKey); err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, fmt.Errorf("%w: objeto %s ausente no armazenamento", ErrXMLNotAvailable, document.XMLKey)
+		}
+		return nil, fmt.Errorf("erro ao consultar XML: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.urlExpiry)
+	url, err := s.store.PresignGet(ctx, document.XMLKey, s.urlExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar link do XML: %w", err)
+	}
+
+	if err := s.registrar(ctx, document, model.DocumentDownloadURL, document.XMLKey, access, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &DocumentXMLURL{
+		DocumentID: document.ID,
+		URL:        url,
+		ExpiresAt:  expiresAt,
+		FileName:   xml.FileName,
+		SHA256:     document.XMLSha256,
+	}, nil
+}
+
+// registrar grava a auditoria; sem o registro o XML não é entregue
+func (s *DocumentXMLService) registrar(ctx context.Context, document *model.Document, kind, objectKey string, access DownloadAccess, expiresAt time.Time) error {
+	download := &model.DocumentDownload{
+		DocumentID: document.ID,
+		Kind:       kind,
+		Principal:  access.Principal,
+		ObjectKey:  objectKey,
+		IP:         access.IP,
+		UserAgent:  access.UserAgent,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.downloadRepo.Create(ctx, download); err != nil {
+		return fmt.Errorf("erro ao registrar auditoria do download: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+)
+
+// falhaCadastroTTL tempo em que um CNPJ que falhou no cadastro automático não é
+// consultado de novo na API CNPJA
+const falhaCadastroTTL = time.Hour
+
+// EmpresaLinkService vincula os documentos às empresas cadastradas, como
+// emitente e como tomadora, a partir dos CNPJs informados no documento
+type EmpresaLinkService struct {
+	empresaRepo    EmpresaRepository
+	documentRepo   DocumentRepository
+	empresaService *EmpresaService
+	autoRegister   bool
+
+	mu     sync.Mutex
+	falhas map[string]time.Time
+}
+
+// NewEmpresaLinkService cria o serviço de vínculo. Com autoRegister, CNPJs sem
+// empresa cadastrada são cadastrados pela API CNPJA durante a sincronização.
+func NewEmpresaLinkService(empresaRepo EmpresaRepository, documentRepo DocumentRepository, empresaService *EmpresaService, autoRegister bool) *EmpresaLinkService {
+	return &EmpresaLinkService{
+		empresaRepo:    empresaRepo,
+		documentRepo:   documentRepo,
+		empresaService: empresaService,
+		autoRegister:   autoRegister,
+		falhas:         make(map[string]time.Time),
+	}
+}
+
+// Vincular preenche as empresas emitente e tomadora do documento antes de ele
+// ser salvo. Falhas não impedem a gravação do documento: o vínculo é refeito
+// quando a empresa for cadastrada.
+func (s *EmpresaLinkService) Vincular(ctx context.Context, document *model.Document) {
+	document.EmitenteEmpresaID = s.resolver(ctx, document.CNPJEmitente)
+	document.DestinatarioEmpresaID = s.resolver(ctx, document.CNPJDestinatario)
+}
+
+// VincularDocumentos vincula à empresa recém-cadastrada os documentos já
+// sincronizados em que o CNPJ dela aparece
+func (s *EmpresaLinkService) VincularDocumentos(ctx context.Context, empresa *model.Empresa) {
+	total, err := s.documentRepo.LinkEmpresa(ctx, empresa)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Erro ao vincular documentos à empresa %s", empresa.CNPJ))
+		return
+	}
+	if total > 0 {
+		logger.Info(fmt.Sprintf("%d vínculos de documentos criados para a empresa %s", total, empresa.CNPJ))
+	}
+}
+
+// resolver retorna o ID da empresa do escritório do contexto com o CNPJ
+// informado, cadastrando-a se o cadastro automático estiver habilitado
+func (s *EmpresaLinkService) resolver(ctx context.Context, cnpj string) *int {
+	cnpj = limparCNPJ(cnpj)
+	if len(cnpj) != 14 {
+		// CPF ou documento sem tomador identificado
+		return nil
+	}
+
+	empresa, err := s.empresaRepo.GetByCNPJ(ctx, cnpj)
+	if err == nil {
+		return &empresa.ID
+	}
+
+	if !s.autoRegister || s.falhouRecentemente(cnpj) {
+		return nil
+	}
+
+	criada, err := s.empresaService.CriarEmpresaPorCNPJ(ctx, cnpj)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Erro ao cadastrar automaticamente a empresa %s", cnpj))
+		s.registrarFalha(cnpj)
+		return nil
+	}
+	return &criada.ID
+}
+
+func (s *EmpresaLinkService) falhouRecentemente(cnpj string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	falha, exists := s.falhas[cnpj]
+	if !exists {
+		return false
+	}
+	if time.Since(falha) > falhaCadastroTTL {
+		delete(s.falhas, cnpj)
+		return false
+	}
+	return true
+}
+
+func (s *EmpresaLinkService) registrarFalha(cnpj string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.falhas[cnpj] = time.Now()
+}
+
+// limparCNPJ remove a pontuação do CNPJ
+func limparCNPJ(cnpj string) string {
+	cnpj = strings.ReplaceAll(cnpj, ".", "")
+	cnpj = strings.ReplaceAll(cnpj, "/", "")
+	cnpj = strings.ReplaceAll(cnpj, "-", "")
+	return strings.TrimSpace(cnpj)
+}
@@ -2,28 +2,45 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 	"zemdocs/internal/database/model"
-	"zemdocs/internal/database/repository"
 	"zemdocs/internal/logger"
 )
 
 // EmpresaService serviço para gerenciamento de empresas
 type EmpresaService struct {
-	empresaRepo  *repository.EmpresaRepository
+	empresaRepo  EmpresaRepository
+	documentRepo DocumentRepository
 	cnpjaService *CNPJAService
+	audit        *AuditService
+	onCreated    func(ctx context.Context, empresa *model.Empresa)
+	onPurged     func(ctx context.Context, empresa *model.Empresa)
 }
 
 // NewEmpresaService cria uma nova instância do serviço de empresas
-func NewEmpresaService(empresaRepo *repository.EmpresaRepository, cnpjaService *CNPJAService) *EmpresaService {
+func NewEmpresaService(empresaRepo EmpresaRepository, documentRepo DocumentRepository, cnpjaService *CNPJAService, auditService *AuditService) *EmpresaService {
 	return &EmpresaService{
 		empresaRepo:  empresaRepo,
+		documentRepo: documentRepo,
 		cnpjaService: cnpjaService,
+		audit:        auditService,
 	}
 }
 
+// SetCreatedHandler define quem é avisado quando uma empresa é cadastrada
+func (s *EmpresaService) SetCreatedHandler(handler func(ctx context.Context, empresa *model.Empresa)) {
+	s.onCreated = handler
+}
+
+// SetPurgedHandler define quem é avisado quando uma empresa é removida definitivamente
+func (s *EmpresaService) SetPurgedHandler(handler func(ctx context.Context, empresa *model.Empresa)) {
+	s.onPurged = handler
+}
+
 // ConsultarCNPJAPI consulta dados de CNPJ na API sem salvar e retorna dados estruturados para o frontend
 func (s *EmpresaService) ConsultarCNPJAPI(ctx context.Context, cnpj string) (*model.CNPJAFormResponse, error) {
 	// Validar CNPJ
@@ -114,6 +131,30 @@ func (s *EmpresaService) ContarEmpresasPorBusca(ctx context.Context, termo strin
 	return total, nil
 }
 
+// ListarDocumentos lista os documentos emitidos ou recebidos pela empresa, conforme a direção
+func (s *EmpresaService) ListarDocumentos(ctx context.Context, id int, direcao string, limit, offset int) ([]*model.DocumentResponse, int, error) {
+	if _, err := s.empresaRepo.GetByID(ctx, id); err != nil {
+		return nil, 0, fmt.Errorf("empresa não encontrada: %w", err)
+	}
+
+	documents, err := s.documentRepo.ListByEmpresa(ctx, id, direcao, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao listar documentos da empresa: %w", err)
+	}
+
+	total, err := s.documentRepo.CountByEmpresa(ctx, id, direcao)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao contar documentos da empresa: %w", err)
+	}
+
+	responses := make([]*model.DocumentResponse, 0, len(documents))
+	for _, document := range documents {
+		responses = append(responses, toDocumentResponse(document))
+	}
+
+	return responses, total, nil
+}
+
 // AtualizarEmpresa atualiza uma empresa
 func (s *EmpresaService) AtualizarEmpresa(ctx context.Context, id int, req *model.EmpresaUpdateRequest) (*model.EmpresaResponse, error) {
 	// Buscar empresa existente
@@ -123,14 +164,21 @@ func (s *EmpresaService) AtualizarEmpresa(ctx context.Context, id int, req *mode
 	}
 
 	// Atualizar campos
+	antes := *empresa
 	empresa.RazaoSocial = req.RazaoSocial
 	empresa.NomeFantasia = req.NomeFantasia
 	empresa.Email = req.Email
 	empresa.Telefone = req.Telefone
 	empresa.Ativa = req.Ativa
 
-	// Salvar no banco
-	if err := s.empresaRepo.Update(ctx, empresa); err != nil {
+	// Salvar no banco junto com o registro de auditoria
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Update(ctx, empresa); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionUpdate), &antes, empresa)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("erro ao atualizar empresa: %w", err)
 	}
 
@@ -139,7 +187,8 @@ func (s *EmpresaService) AtualizarEmpresa(ctx context.Context, id int, req *mode
 	return s.toEmpresaResponse(empresa), nil
 }
 
-// ExcluirEmpresa exclui uma empresa
+// ExcluirEmpresa exclui logicamente uma empresa e seus dados relacionados; pode ser
+// restaurada por RestaurarEmpresa
 func (s *EmpresaService) ExcluirEmpresa(ctx context.Context, id int) error {
 	// Verificar se empresa existe
 	empresa, err := s.empresaRepo.GetByID(ctx, id)
@@ -147,8 +196,14 @@ func (s *EmpresaService) ExcluirEmpresa(ctx context.Context, id int) error {
 		return fmt.Errorf("empresa não encontrada: %w", err)
 	}
 
-	// Excluir empresa
-	if err := s.empresaRepo.Delete(ctx, id); err != nil {
+	// Excluir empresa junto com o registro de auditoria
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionDelete), empresa, nil)
+	})
+	if err != nil {
 		return fmt.Errorf("erro ao excluir empresa: %w", err)
 	}
 
@@ -157,6 +212,109 @@ func (s *EmpresaService) ExcluirEmpresa(ctx context.Context, id int) error {
 	return nil
 }
 
+// RestaurarEmpresa restaura uma empresa excluída, com os dados relacionados
+// excluídos junto com ela
+func (s *EmpresaService) RestaurarEmpresa(ctx context.Context, id int) (*model.EmpresaResponse, error) {
+	empresa, err := s.buscarExcluida(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	restaurada := *empresa
+	restaurada.DeletedAt = time.Time{}
+
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Restore(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionRestore), empresa, &restaurada)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao restaurar empresa: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Empresa restaurada: %s - %s", empresa.CNPJ, empresa.RazaoSocial))
+
+	// Documentos sincronizados enquanto a empresa estava excluída ficaram sem vínculo
+	s.notifyCreated(ctx, &restaurada)
+
+	return s.toEmpresaResponse(&restaurada), nil
+}
+
+// PurgarEmpresa remove definitivamente uma empresa já excluída e seus dados
+// relacionados. Os documentos são mantidos, sem o vínculo com ela.
+func (s *EmpresaService) PurgarEmpresa(ctx context.Context, id int) error {
+	empresa, err := s.buscarExcluida(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Purge(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionPurge), empresa, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao purgar empresa: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Empresa removida definitivamente: %s - %s", empresa.CNPJ, empresa.RazaoSocial))
+
+	if s.onPurged != nil {
+		s.onPurged(ctx, empresa)
+	}
+
+	return nil
+}
+
+// ListarExcluidas lista as empresas excluídas, mais recentes primeiro, e o total
+func (s *EmpresaService) ListarExcluidas(ctx context.Context, limit, offset int) ([]*model.EmpresaResponse, int, error) {
+	empresas, err := s.empresaRepo.ListDeleted(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao listar empresas excluídas: %w", err)
+	}
+
+	total, err := s.empresaRepo.CountDeleted(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao contar empresas excluídas: %w", err)
+	}
+
+	responses := make([]*model.EmpresaResponse, 0, len(empresas))
+	for _, empresa := range empresas {
+		responses = append(responses, s.toEmpresaResponse(empresa))
+	}
+
+	return responses, total, nil
+}
+
+// buscarExcluida busca a empresa excluída; uma empresa ativa precisa ser excluída antes
+func (s *EmpresaService) buscarExcluida(ctx context.Context, id int) (*model.Empresa, error) {
+	empresa, err := s.empresaRepo.GetDeletedByID(ctx, id)
+	if err == nil {
+		return empresa, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("erro ao buscar empresa: %w", err)
+	}
+
+	if _, err := s.empresaRepo.GetByID(ctx, id); err == nil {
+		return nil, fmt.Errorf("%w: a empresa não está excluída", ErrInvalidData)
+	}
+	return nil, ErrEmpresaNotFound
+}
+
+// verificarExcluida recusa o cadastro de um CNPJ cuja empresa foi excluída: o
+// cadastro anterior deve ser restaurado
+func (s *EmpresaService) verificarExcluida(ctx context.Context, cnpj string) error {
+	empresa, err := s.empresaRepo.GetDeletedByCNPJ(ctx, cnpj)
+	if err != nil {
+		return nil
+	}
+	return fmt.Errorf("%w: a empresa com CNPJ %s foi excluída; restaure-a (ID %d)",
+		ErrEmpresaDeleted, s.cnpjaService.FormatarCNPJ(cnpj), empresa.ID)
+}
+
 // CriarEmpresaPorCNPJ cria uma empresa consultando dados na API CNPJA
 func (s *EmpresaService) CriarEmpresaPorCNPJ(ctx context.Context, cnpj string) (*model.EmpresaResponse, error) {
 	// Validar CNPJ
@@ -174,6 +332,9 @@ func (s *EmpresaService) CriarEmpresaPorCNPJ(ctx context.Context, cnpj string) (
 	if err == nil && empresaExistente != nil {
 		return nil, fmt.Errorf("empresa com CNPJ %s já existe", s.cnpjaService.FormatarCNPJ(cnpjLimpo))
 	}
+	if err := s.verificarExcluida(ctx, cnpjLimpo); err != nil {
+		return nil, err
+	}
 
 	// Consultar dados na API CNPJA
 	cnpjaResp, err := s.cnpjaService.ConsultarCNPJ(ctx, cnpj)
@@ -185,7 +346,7 @@ func (s *EmpresaService) CriarEmpresaPorCNPJ(ctx context.Context, cnpj string) (
 	empresa := s.cnpjaService.ConvertToEmpresa(cnpjaResp)
 
 	// Salvar empresa no banco
-	if err := s.empresaRepo.Create(ctx, empresa); err != nil {
+	if err := s.criar(ctx, empresa); err != nil {
 		return nil, fmt.Errorf("erro ao salvar empresa: %w", err)
 	}
 
@@ -196,6 +357,7 @@ func (s *EmpresaService) CriarEmpresaPorCNPJ(ctx context.Context, cnpj string) (
 	}
 
 	logger.Info(fmt.Sprintf("Empresa criada com sucesso: %s - %s", empresa.CNPJ, empresa.RazaoSocial))
+	s.notifyCreated(ctx, empresa)
 
 	return s.toEmpresaResponse(empresa), nil
 }
@@ -212,12 +374,15 @@ func (s *EmpresaService) CriarEmpresaCompleta(ctx context.Context, formData *mod
 	if err == nil && empresaExistente != nil {
 		return nil, fmt.Errorf("empresa já cadastrada: uma empresa com este CNPJ já está registrada no sistema")
 	}
+	if err := s.verificarExcluida(ctx, cnpjLimpo); err != nil {
+		return nil, err
+	}
 
 	// Converter dados do formulário para modelo Empresa
 	empresa := s.convertFormToEmpresa(formData)
 
 	// Salvar empresa no banco
-	if err := s.empresaRepo.Create(ctx, empresa); err != nil {
+	if err := s.criar(ctx, empresa); err != nil {
 		return nil, fmt.Errorf("erro ao salvar empresa: %w", err)
 	}
 
@@ -228,10 +393,37 @@ func (s *EmpresaService) CriarEmpresaCompleta(ctx context.Context, formData *mod
 	}
 
 	logger.Info(fmt.Sprintf("Empresa criada com sucesso: %s - %s", empresa.CNPJ, empresa.RazaoSocial))
+	s.notifyCreated(ctx, empresa)
 
 	return s.toEmpresaResponse(empresa), nil
 }
 
+// criar grava a empresa junto com o registro de auditoria
+func (s *EmpresaService) criar(ctx context.Context, empresa *model.Empresa) error {
+	return s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.empresaRepo.Create(ctx, empresa); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, empresaAudit(empresa, model.AuditActionCreate), nil, empresa)
+	})
+}
+
+// empresaAudit registro de auditoria da alteração da empresa
+func empresaAudit(empresa *model.Empresa, action string) *model.AuditLog {
+	return &model.AuditLog{
+		EscritorioID: empresa.EscritorioID,
+		Entity:       model.AuditEntityEmpresa,
+		EntityID:     int64(empresa.ID),
+		Action:       action,
+	}
+}
+
+func (s *EmpresaService) notifyCreated(ctx context.Context, empresa *model.Empresa) {
+	if s.onCreated != nil {
+		s.onCreated(ctx, empresa)
+	}
+}
+
 // toEmpresaResponse converte Empresa para EmpresaResponse
 func (s *EmpresaService) toEmpresaResponse(empresa *model.Empresa) *model.EmpresaResponse {
 	response := &model.EmpresaResponse{
@@ -262,6 +454,12 @@ func (s *EmpresaService) toEmpresaResponse(empresa *model.Empresa) *model.Empres
 			UF:          empresa.UF,
 		},
 	}
+	if !empresa.DeletedAt.IsZero() {
+		response.DeletedAt = &empresa.DeletedAt
+	}
+	if empresa.CertificadoKey != "" {
+		response.CertificadoEnviadoEm = &empresa.CertificadoEnviadoEm
+	}
 
 	return response
 }
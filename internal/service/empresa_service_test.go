@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository/memory"
+	"zemdocs/internal/service"
+	"zemdocs/internal/tenant"
+)
+
+// newEmpresaService monta o serviço de empresas sobre o banco em memória
+func newEmpresaService(db *memory.DB) *service.EmpresaService {
+	return service.NewEmpresaService(
+		memory.NewEmpresaRepository(db),
+		memory.NewDocumentRepository(db),
+		service.NewCNPJAService(),
+		service.NewAuditService(memory.NewAuditLogRepository(db)),
+	)
+}
+
+func empresaForm(cnpj string) *model.CNPJAFormResponse {
+	return &model.CNPJAFormResponse{
+		CNPJ:        cnpj,
+		RazaoSocial: "Empresa Teste LTDA",
+		Municipio:   "Imperatriz",
+		UF:          "MA",
+		Ativa:       true,
+	}
+}
+
+func TestEmpresaServiceCriarEmpresaCompleta(t *testing.T) {
+	db := memory.NewDB()
+	empresas := newEmpresaService(db)
+	ctx := tenant.WithEscritorio(context.Background(), 1)
+
+	var criada *model.Empresa
+	empresas.SetCreatedHandler(func(ctx context.Context, empresa *model.Empresa) { criada = empresa })
+
+	response, err := empresas.CriarEmpresaCompleta(ctx, empresaForm("12.345.678/0001-95"))
+	if err != nil {
+		t.Fatalf("CriarEmpresaCompleta: %v", err)
+	}
+	if response.CNPJ != "12.345.678/0001-95" {
+		t.Errorf("CNPJ = %q, esperado formatado", response.CNPJ)
+	}
+	if criada == nil || criada.EscritorioID == nil || *criada.EscritorioID != 1 {
+		t.Fatalf("empresa criada fora do escritório do contexto: %+v", criada)
+	}
+
+	logs, err := memory.NewAuditLogRepository(db).List(ctx, &model.AuditFilter{Entity: model.AuditEntityEmpresa}, 0, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Action != model.AuditActionCreate {
+		t.Errorf("auditoria = %+v, esperado um registro de criação", logs)
+	}
+
+	if _, err := empresas.CriarEmpresaCompleta(ctx, empresaForm("12345678000195")); err == nil {
+		t.Error("CNPJ duplicado foi aceito")
+	}
+}
+
+func TestEmpresaServiceIsolamentoEntreEscritorios(t *testing.T) {
+	db := memory.NewDB()
+	empresas := newEmpresaService(db)
+	ctxA := tenant.WithEscritorio(context.Background(), 1)
+	ctxB := tenant.WithEscritorio(context.Background(), 2)
+
+	response, err := empresas.CriarEmpresaCompleta(ctxA, empresaForm("12345678000195"))
+	if err != nil {
+		t.Fatalf("CriarEmpresaCompleta: %v", err)
+	}
+
+	if _, err := empresas.ConsultarPorID(ctxB, response.ID); err == nil {
+		t.Error("empresa de outro escritório ficou visível")
+	}
+	if err := empresas.ExcluirEmpresa(ctxB, response.ID); err == nil {
+		t.Error("empresa de outro escritório foi excluída")
+	}
+
+	// O mesmo CNPJ pode ser cadastrado por outro escritório
+	if _, err := empresas.CriarEmpresaCompleta(ctxB, empresaForm("12345678000195")); err != nil {
+		t.Errorf("cadastro do mesmo CNPJ em outro escritório: %v", err)
+	}
+
+	total, err := empresas.ContarEmpresas(ctxA)
+	if err != nil {
+		t.Fatalf("ContarEmpresas: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("ContarEmpresas = %d, esperado 1", total)
+	}
+}
+
+func TestEmpresaServiceExcluirRestaurarPurgar(t *testing.T) {
+	db := memory.NewDB()
+	empresas := newEmpresaService(db)
+	ctx := tenant.WithEscritorio(context.Background(), 1)
+
+	response, err := empresas.CriarEmpresaCompleta(ctx, empresaForm("12345678000195"))
+	if err != nil {
+		t.Fatalf("CriarEmpresaCompleta: %v", err)
+	}
+	id := response.ID
+
+	if err := empresas.PurgarEmpresa(ctx, id); !errors.Is(err, service.ErrInvalidData) {
+		t.Errorf("PurgarEmpresa de empresa ativa = %v, esperado ErrInvalidData", err)
+	}
+
+	if err := empresas.ExcluirEmpresa(ctx, id); err != nil {
+		t.Fatalf("ExcluirEmpresa: %v", err)
+	}
+	if _, err := empresas.ConsultarPorID(ctx, id); err == nil {
+		t.Error("empresa excluída continua visível")
+	}
+	if _, err := empresas.CriarEmpresaCompleta(ctx, empresaForm("12345678000195")); !errors.Is(err, service.ErrEmpresaDeleted) {
+		t.Errorf("novo cadastro do CNPJ excluído = %v, esperado ErrEmpresaDeleted", err)
+	}
+
+	excluidas, total, err := empresas.ListarExcluidas(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListarExcluidas: %v", err)
+	}
+	if total != 1 || len(excluidas) != 1 || excluidas[0].DeletedAt == nil {
+		t.Errorf("ListarExcluidas = %d empresas (total %d), esperado 1", len(excluidas), total)
+	}
+
+	restaurada, err := empresas.RestaurarEmpresa(ctx, id)
+	if err != nil {
+		t.Fatalf("RestaurarEmpresa: %v", err)
+	}
+	if restaurada.DeletedAt != nil {
+		t.Error("empresa restaurada continua com data de exclusão")
+	}
+
+	var purgada *model.Empresa
+	empresas.SetPurgedHandler(func(ctx context.Context, empresa *model.Empresa) { purgada = empresa })
+
+	if err := empresas.ExcluirEmpresa(ctx, id); err != nil {
+		t.Fatalf("ExcluirEmpresa: %v", err)
+	}
+	if err := empresas.PurgarEmpresa(ctx, id); err != nil {
+		t.Fatalf("PurgarEmpresa: %v", err)
+	}
+	if purgada == nil || purgada.ID != id {
+		t.Errorf("handler de purga não recebeu a empresa %d", id)
+	}
+	if _, err := empresas.RestaurarEmpresa(ctx, id); !errors.Is(err, service.ErrEmpresaNotFound) {
+		t.Errorf("RestaurarEmpresa após purga = %v, esperado ErrEmpresaNotFound", err)
+	}
+
+	actions := []string{}
+	logs, err := memory.NewAuditLogRepository(db).List(ctx, &model.AuditFilter{Entity: model.AuditEntityEmpresa}, 0, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, log := range logs {
+		actions = append(actions, log.Action)
+	}
+	expected := []string{
+		model.AuditActionPurge, model.AuditActionDelete, model.AuditActionRestore,
+		model.AuditActionDelete, model.AuditActionCreate,
+	}
+	if !slices.Equal(actions, expected) {
+		t.Errorf("auditoria = %v, esperado %v", actions, expected)
+	}
+}
@@ -6,4 +6,11 @@ var (
 	ErrUserNotFound = errors.New("usuário não encontrado")
 	ErrEmailExists  = errors.New("email já existe")
 	ErrInvalidData  = errors.New("dados inválidos")
+
+	ErrDocumentNotFound = errors.New("documento não encontrado")
+
+	ErrEmpresaNotFound = errors.New("empresa não encontrada")
+	ErrEmpresaDeleted  = errors.New("empresa excluída")
+
+	ErrCertificadoNotFound = errors.New("certificado não encontrado")
 )
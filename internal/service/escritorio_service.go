@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/tenant"
+)
+
+var (
+	ErrEscritorioNotFound = errors.New("escritório não encontrado")
+	ErrEscritorioExists   = errors.New("já existe escritório com este CNPJ")
+	ErrAPITokenNotFound   = errors.New("credencial não encontrada")
+	ErrAPITokenInvalid    = errors.New("credencial inválida ou revogada")
+)
+
+// EscritorioService gerencia os escritórios de contabilidade (tenants) e suas credenciais de API
+type EscritorioService struct {
+	escritorioRepo EscritorioRepository
+	tokenRepo      APITokenRepository
+}
+
+// NewEscritorioService cria uma nova instância do serviço de escritórios
+func NewEscritorioService(escritorioRepo EscritorioRepository, tokenRepo APITokenRepository) *EscritorioService {
+	return &EscritorioService{
+		escritorioRepo: escritorioRepo,
+		tokenRepo:      tokenRepo,
+	}
+}
+
+// ListarEscritorios lista os escritórios visíveis no contexto
+func (s *EscritorioService) ListarEscritorios(ctx context.Context) ([]*model.Escritorio, error) {
+	escritorios, err := s.escritorioRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar escritórios: %w", err)
+	}
+	return escritorios, nil
+}
+
+// ConsultarEscritorio busca um escritório por ID
+func (s *EscritorioService) ConsultarEscritorio(ctx context.Context, id int64) (*model.Escritorio, error) {
+	escritorio, err := s.escritorioRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrEscritorioNotFound
+	}
+	return escritorio, nil
+}
+
+// ConsultarPadrao busca o escritório que recebeu os dados anteriores à multi-tenancy
+func (s *EscritorioService) ConsultarPadrao(ctx context.Context) (*model.Escritorio, error) {
+	escritorio, err := s.escritorioRepo.GetPadrao(ctx)
+	if err != nil {
+		return nil, ErrEscritorioNotFound
+	}
+	return escritorio, nil
+}
+
+// CriarEscritorio cadastra um escritório, ativo por padrão
+func (s *EscritorioService) CriarEscritorio(ctx context.Context, req *model.EscritorioRequest) (*model.Escritorio, error) {
+	escritorio := &model.Escritorio{Ativo: true}
+	if err := s.aplicar(ctx, escritorio, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.escritorioRepo.Create(ctx, escritorio); err != nil {
+		return nil, fmt.Errorf("erro ao criar escritório: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Escritório criado: %d - %s", escritorio.ID, escritorio.Nome))
+	return escritorio, nil
+}
+
+// AtualizarEscritorio altera nome, CNPJ ou situação do escritório. Um escritório
+// inativo deixa de ser aceito na autenticação por credencial.
+func (s *EscritorioService) AtualizarEscritorio(ctx context.Context, id int64, req *model.EscritorioRequest) (*model.Escritorio, error) {
+	escritorio, err := s.ConsultarEscritorio(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.aplicar(ctx, escritorio, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.escritorioRepo.Update(ctx, escritorio); err != nil {
+		return nil, fmt.Errorf("erro ao atualizar escritório: %w", err)
+	}
+	return escritorio, nil
+}
+
+// ListarTokens lista as credenciais do escritório (sem o token)
+func (s *EscritorioService) ListarTokens(ctx context.Context, escritorioID int64) ([]*model.APIToken, error) {
+	if _, err := s.ConsultarEscritorio(ctx, escritorioID); err != nil {
+		return nil, err
+	}
+	tokens, err := s.tokenRepo.ListByEscritorio(ctx, escritorioID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar credenciais: %w", err)
+	}
+	return tokens, nil
+}
+
+// CriarToken gera uma credencial para o escritório. O token é devolvido uma
+// única vez; no banco fica apenas o hash.
+func (s *EscritorioService) CriarToken(ctx context.Context, escritorioID int64, req *model.APITokenRequest) (*model.APITokenCreated, error) {
+	if _, err := s.ConsultarEscritorio(ctx, escritorioID); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("erro ao gerar credencial: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	apiToken := &model.APIToken{
+		EscritorioID: escritorioID,
+		Nome:         req.Nome,
+		TokenHash:    hashAPIToken(token),
+		Permissions:  req.Permissions,
+	}
+	if apiToken.Permissions == nil {
+		apiToken.Permissions = []string{}
+	}
+
+	// A credencial pertence ao escritório da rota, mesmo quando criada pelo sistema
+	if err := s.tokenRepo.Create(tenant.WithEscritorio(ctx, escritorioID), apiToken); err != nil {
+		return nil, fmt.Errorf("erro ao criar credencial: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Credencial %s criada para o escritório %d", apiToken.Nome, escritorioID))
+	return &model.APITokenCreated{APIToken: apiToken, Token: token}, nil
+}
+
+// RevogarToken revoga uma credencial do escritório
+func (s *EscritorioService) RevogarToken(ctx context.Context, escritorioID, id int64) error {
+	revoked, err := s.tokenRepo.Revoke(ctx, escritorioID, id)
+	if err != nil {
+		return fmt.Errorf("erro ao revogar credencial: %w", err)
+	}
+	if !revoked {
+		return ErrAPITokenNotFound
+	}
+
+	logger.Info(fmt.Sprintf("Credencial %d do escritório %d revogada", id, escritorioID))
+	return nil
+}
+
+// AutenticarToken retorna a credencial ativa correspondente ao token
+func (s *EscritorioService) AutenticarToken(ctx context.Context, token string) (*model.APIToken, error) {
+	apiToken, err := s.tokenRepo.GetActiveByHash(ctx, hashAPIToken(token))
+	if err != nil {
+		return nil, ErrAPITokenInvalid
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(ctx, apiToken.ID); err != nil {
+		logger.Error(err, fmt.Sprintf("Erro ao registrar uso da credencial %d", apiToken.ID))
+	}
+	return apiToken, nil
+}
+
+// aplicar copia os campos da requisição, verificando se o CNPJ já pertence a outro escritório
+func (s *EscritorioService) aplicar(ctx context.Context, escritorio *model.Escritorio, req *model.EscritorioRequest) error {
+	cnpj := strings.NewReplacer(".", "", "/", "", "-", "").Replace(strings.TrimSpace(req.CNPJ))
+	if cnpj != "" {
+		exists, err := s.escritorioRepo.ExistsByCNPJ(ctx, cnpj, escritorio.ID)
+		if err != nil {
+			return fmt.Errorf("erro ao verificar escritório: %w", err)
+		}
+		if exists {
+			return ErrEscritorioExists
+		}
+	}
+
+	escritorio.Nome = strings.TrimSpace(req.Nome)
+	escritorio.CNPJ = cnpj
+	if req.Ativo != nil {
+		escritorio.Ativo = *req.Ativo
+	}
+	if escritorio.Nome == "" {
+		return fmt.Errorf("%w: nome do escritório é obrigatório", ErrInvalidData)
+	}
+	return nil
+}
+
+// hashAPIToken hash SHA-256 (hex) com que a credencial é gravada e procurada
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
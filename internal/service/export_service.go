@@ -0,0 +1,469 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrExportNotFound = errors.New("exportação não encontrada")
+
+const (
+	exportPrefix    = "exports/"
+	exportBatchSize = 200
+)
+
+// Situação de cada documento no manifesto da exportação
+const (
+	ExportItemOK         = "ok"
+	ExportItemSemXML     = "sem_xml"
+	ExportItemAusente    = "ausente"
+	ExportItemCorrompido = "corrompido"
+)
+
+// ExportManifestItem linha do manifesto: um documento e o arquivo correspondente no ZIP
+type ExportManifestItem struct {
+	DocumentID              int                `json:"document_id"`
+	NumeroDocumento         string             `json:"numero_documento"`
+	DocumentType            model.DocumentType `json:"document_type"`
+	Competencia             string             `json:"competencia"`
+	DataEmissao             time.Time          `json:"data_emissao"`
+	Direcao                 string             `json:"direcao"`
+	CNPJEmitente            string             `json:"cnpj_emitente"`
+	RazaoSocialEmitente     string             `json:"razao_social_emitente"`
+	CNPJDestinatario        string             `json:"cnpj_destinatario"`
+	RazaoSocialDestinatario string             `json:"razao_social_destinatario"`
+	ValorNota               decimal.Decimal    `json:"valor_nota"`
+	ValorIss                decimal.Decimal    `json:"valor_iss"`
+	Arquivo                 string             `json:"arquivo,omitempty"`
+	ArquivoPDF              string             `json:"arquivo_pdf,omitempty"`
+	SHA256                  string             `json:"sha256,omitempty"`
+	Status                  string             `json:"status"`
+}
+
+// ExportManifest manifesto incluído no ZIP (manifest.json e manifest.csv)
+type ExportManifest struct {
+	Filter      model.ExportFilter   `json:"filter"`
+	GeneratedAt time.Time            `json:"generated_at"`
+	Documents   int                  `json:"documents"`
+	Files       int                  `json:"files"`
+	Failed      int                  `json:"failed"`
+	ValorTotal  decimal.Decimal      `json:"valor_total"`
+	IssTotal    decimal.Decimal      `json:"iss_total"`
+	Items       []ExportManifestItem `json:"items"`
+}
+
+// ExportService exporta em ZIP os XMLs de uma empresa, com manifesto
+type ExportService struct {
+	nfseRepo    DocumentRepository
+	empresaRepo EmpresaRepository
+	exportRepo  ExportRepository
+	pdfService  *DocumentPDFService
+	store       storage.Store
+	syncLimit   int
+	urlExpiry   time.Duration
+}
+
+// NewExportService cria uma nova instância do serviço de exportação. Exportações
+// com mais de syncLimit documentos são executadas em segundo plano.
+func NewExportService(nfseRepo DocumentRepository, empresaRepo EmpresaRepository, exportRepo ExportRepository, pdfService *DocumentPDFService, store storage.Store, syncLimit int, urlExpiry time.Duration) *ExportService {
+	return &ExportService{
+		nfseRepo:    nfseRepo,
+		empresaRepo: empresaRepo,
+		exportRepo:  exportRepo,
+		pdfService:  pdfService,
+		store:       store,
+		syncLimit:   syncLimit,
+		urlExpiry:   urlExpiry,
+	}
+}
+
+// PrepararFiltro valida os filtros, resolve o CNPJ da empresa e normaliza as competências
+func (s *ExportService) PrepararFiltro(ctx context.Context, filter *model.ExportFilter) error {
+	if filter.EmpresaID != nil {
+		empresa, err := s.empresaRepo.GetByID(ctx, *filter.EmpresaID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: empresa %d não encontrada", ErrInvalidData, *filter.EmpresaID)
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao buscar empresa: %w", err)
+		}
+		filter.CNPJ = empresa.CNPJ
+	}
+
+	filter.CNPJ = strings.NewReplacer(".", "", "/", "", "-", "").Replace(strings.TrimSpace(filter.CNPJ))
+	if filter.CNPJ == "" {
+		return fmt.Errorf("%w: informe a empresa ou o CNPJ", ErrInvalidData)
+	}
+
+	var err error
+	if filter.CompetenciaInicio, err = normalizarCompetencia(filter.CompetenciaInicio); err != nil {
+		return err
+	}
+	if filter.CompetenciaFim, err = normalizarCompetencia(filter.CompetenciaFim); err != nil {
+		return err
+	}
+	if filter.CompetenciaInicio == "" {
+		return fmt.Errorf("%w: informe a competência inicial", ErrInvalidData)
+	}
+	if filter.CompetenciaFim == "" {
+		filter.CompetenciaFim = filter.CompetenciaInicio
+	}
+	if filter.CompetenciaFim < filter.CompetenciaInicio {
+		return fmt.Errorf("%w: competência final anterior à inicial", ErrInvalidData)
+	}
+
+	switch filter.Direcao {
+	case "":
+		filter.Direcao = model.ExportDirecaoAmbos
+	case model.ExportDirecaoEmitidos, model.ExportDirecaoRecebidos, model.ExportDirecaoAmbos:
+	default:
+		return fmt.Errorf("%w: direção inválida: %s (use emitidos, recebidos ou ambos)", ErrInvalidData, filter.Direcao)
+	}
+
+	return nil
+}
+
+// EmSegundoPlano indica se a exportação é grande demais para ser enviada na própria requisição
+func (s *ExportService) EmSegundoPlano(ctx context.Context, filter *model.ExportFilter) (bool, int, error) {
+	count, err := s.nfseRepo.CountForExport(ctx, filter)
+	if err != nil {
+		return false, 0, fmt.Errorf("erro ao contar documentos: %w", err)
+	}
+	return count > s.syncLimit, count, nil
+}
+
+// GerarZIP escreve no writer o ZIP com os XMLs e o manifesto. XMLs ausentes ou
+// corrompidos não interrompem a exportação; ficam registrados no manifesto.
+func (s *ExportService) GerarZIP(ctx context.Context, filter *model.ExportFilter, w io.Writer) (*ExportManifest, error) {
+	manifest := &ExportManifest{
+		Filter:      *filter,
+		GeneratedAt: time.Now(),
+		Items:       []ExportManifestItem{},
+	}
+
+	zipWriter := zip.NewWriter(w)
+
+	afterID := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return manifest, ctx.Err()
+		default:
+		}
+
+		documents, err := s.nfseRepo.ListForExport(ctx, filter, afterID, exportBatchSize)
+		if err != nil {
+			return manifest, fmt.Errorf("erro ao listar documentos: %w", err)
+		}
+		if len(documents) == 0 {
+			break
+		}
+
+		for _, document := range documents {
+			afterID = document.ID
+			item, err := s.adicionarDocumento(ctx, zipWriter, filter, document)
+			if err != nil {
+				return manifest, err
+			}
+
+			manifest.Documents++
+			if item.Status == ExportItemOK {
+				manifest.Files++
+			} else {
+				manifest.Failed++
+			}
+			manifest.ValorTotal = manifest.ValorTotal.Add(item.ValorNota)
+			manifest.IssTotal = manifest.IssTotal.Add(item.ValorIss)
+			manifest.Items = append(manifest.Items, item)
+		}
+	}
+
+	if err := writeManifest(zipWriter, manifest); err != nil {
+		return manifest, err
+	}
+	if err := zipWriter.Close(); err != nil {
+		return manifest, fmt.Errorf("erro ao finalizar ZIP: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Agendar registra uma exportação a ser executada em segundo plano
+func (s *ExportService) Agendar(ctx context.Context, filter *model.ExportFilter) (*model.Export, error) {
+	export := &model.Export{
+		Status: model.ExportStatusPendente,
+		Filter: *filter,
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, fmt.Errorf("erro ao registrar exportação: %w", err)
+	}
+	return export, nil
+}
+
+// RegistrarExecucao associa a exportação à execução do scheduler que a processa
+func (s *ExportService) RegistrarExecucao(ctx context.Context, export *model.Export, runID string) error {
+	export.RunID = runID
+	if err := s.exportRepo.UpdateColumns(ctx, export, "run_id"); err != nil {
+		return fmt.Errorf("erro ao registrar execução da exportação: %w", err)
+	}
+	return nil
+}
+
+// Executar gera o ZIP de uma exportação agendada e o grava no armazenamento
+func (s *ExportService) Executar(ctx context.Context, id int64) error {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return ErrExportNotFound
+	}
+
+	// O job roda com acesso de sistema; os documentos são os do escritório que pediu a exportação
+	if export.EscritorioID != nil {
+		ctx = tenant.WithEscritorio(ctx, *export.EscritorioID)
+	}
+
+	export.Status = model.ExportStatusProcessando
+	if err := s.exportRepo.UpdateColumns(ctx, export, "status"); err != nil {
+		return fmt.Errorf("erro ao atualizar exportação: %w", err)
+	}
+
+	// Store.Put recebe o conteúdo inteiro; o ZIP é montado em memória
+	var buf bytes.Buffer
+	manifest, err := s.GerarZIP(ctx, &export.Filter, &buf)
+	if err == nil {
+		export.ObjectKey = fmt.Sprintf("%s%d/%s", exportPrefix, export.ID, ExportFileName(&export.Filter))
+		err = s.store.Put(ctx, export.ObjectKey, buf.Bytes(), storage.PutOptions{
+			ContentType: "application/zip",
+			Metadata:    map[string]string{storage.MetadataSHA256: storage.ContentSHA256(buf.Bytes())},
+			Tenant:      storage.EscritorioTenant(export.EscritorioID),
+		})
+	}
+
+	export.FinishedAt = time.Now()
+	if err != nil {
+		export.Status = model.ExportStatusFalha
+		export.Error = err.Error()
+		export.ObjectKey = ""
+	} else {
+		export.Status = model.ExportStatusConcluido
+		export.Documents = manifest.Documents
+		export.Size = int64(buf.Len())
+	}
+
+	if updateErr := s.exportRepo.UpdateColumns(ctx, export, "status", "object_key", "documents", "size", "error", "finished_at"); updateErr != nil {
+		logger.Error(updateErr, fmt.Sprintf("Erro ao registrar resultado da exportação %d", export.ID))
+	}
+
+	if err != nil {
+		return fmt.Errorf("erro na exportação %d: %w", export.ID, err)
+	}
+
+	logger.Info(fmt.Sprintf("Exportação %d concluída: %d documentos, %d bytes", export.ID, export.Documents, export.Size))
+	return nil
+}
+
+// Consultar retorna a exportação e, se concluída, um link pré-assinado para download
+func (s *ExportService) Consultar(ctx context.Context, id int64) (*model.ExportResponse, error) {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar exportação: %w", err)
+	}
+
+	response := &model.ExportResponse{Export: export}
+	if export.Status == model.ExportStatusConcluido && export.ObjectKey != "" {
+		url, err := s.store.PresignGet(ctx, export.ObjectKey, s.urlExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao gerar link de download: %w", err)
+		}
+		response.DownloadURL = url
+		response.ExpiresAt = time.Now().Add(s.urlExpiry)
+	}
+
+	return response, nil
+}
+
+// adicionarDocumento inclui o XML do documento no ZIP e monta sua linha do manifesto
+func (s *ExportService) adicionarDocumento(ctx context.Context, zipWriter *zip.Writer, filter *model.ExportFilter, document *model.Document) (ExportManifestItem, error) {
+	item := ExportManifestItem{
+		DocumentID:              document.ID,
+		NumeroDocumento:         document.NumeroDocumento,
+		DocumentType:            document.DocumentType,
+		Competencia:             document.Competencia,
+		DataEmissao:             document.DataEmissao,
+		Direcao:                 model.ExportDirecaoRecebidos,
+		CNPJEmitente:            document.CNPJEmitente,
+		RazaoSocialEmitente:     document.RazaoSocialEmitente,
+		CNPJDestinatario:        document.CNPJDestinatario,
+		RazaoSocialDestinatario: document.RazaoSocialDestinatario,
+		ValorNota:               document.ValorNota,
+		ValorIss:                document.ValorIss,
+		SHA256:                  document.XMLSha256,
+	}
+	if document.CNPJEmitente == filter.CNPJ {
+		item.Direcao = model.ExportDirecaoEmitidos
+	}
+
+	if document.XMLKey == "" {
+		item.Status = ExportItemSemXML
+		return item, nil
+	}
+
+	data, err := s.store.Get(ctx, document.XMLKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		item.Status = ExportItemAusente
+		return item, nil
+	}
+	if err != nil {
+		return item, fmt.Errorf("erro ao baixar XML do documento %d: %w", document.ID, err)
+	}
+	if err := storage.VerifySHA256(data, document.XMLSha256); err != nil {
+		item.Status = ExportItemCorrompido
+		return item, nil
+	}
+
+	// Arquivo organizado como o contador costuma pedir: competência, tipo e direção
+	item.Arquivo = fmt.Sprintf("%s/%s/%s/%s.xml", document.Competencia, document.DocumentType, item.Direcao, document.NumeroDocumento)
+	item.SHA256 = storage.ContentSHA256(data)
+
+	header := &zip.FileHeader{
+		Name:     item.Arquivo,
+		Method:   zip.Deflate,
+		Modified: document.DataEmissao,
+	}
+	entry, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return item, fmt.Errorf("erro ao adicionar %s ao ZIP: %w", item.Arquivo, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return item, fmt.Errorf("erro ao adicionar %s ao ZIP: %w", item.Arquivo, err)
+	}
+
+	if filter.IncluirPDF && s.pdfService != nil && SupportsPDF(document.DocumentType) {
+		if err := s.adicionarPDF(ctx, zipWriter, document, &item); err != nil {
+			return item, err
+		}
+	}
+
+	item.Status = ExportItemOK
+	return item, nil
+}
+
+// adicionarPDF inclui o DANFSE/DANFE ao lado do XML. Um PDF que não pôde ser
+// gerado não invalida o XML; fica sem arquivo_pdf no manifesto.
+func (s *ExportService) adicionarPDF(ctx context.Context, zipWriter *zip.Writer, document *model.Document, item *ExportManifestItem) error {
+	data, err := s.pdfService.PDF(ctx, document)
+	if err != nil {
+		logger.Database().Warn().Err(err).Int("document_id", document.ID).Msg("PDF não incluído na exportação")
+		return nil
+	}
+
+	name := strings.TrimSuffix(item.Arquivo, ".xml") + ".pdf"
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: document.DataEmissao,
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao adicionar %s ao ZIP: %w", name, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("erro ao adicionar %s ao ZIP: %w", name, err)
+	}
+
+	item.ArquivoPDF = name
+	return nil
+}
+
+// writeManifest grava manifest.json e manifest.csv no ZIP
+func writeManifest(zipWriter *zip.Writer, manifest *ExportManifest) error {
+	jsonEntry, err := zipWriter.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("erro ao criar manifesto: %w", err)
+	}
+	encoder := json.NewEncoder(jsonEntry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("erro ao gravar manifesto: %w", err)
+	}
+
+	csvEntry, err := zipWriter.Create("manifest.csv")
+	if err != nil {
+		return fmt.Errorf("erro ao criar manifesto: %w", err)
+	}
+	// Separador ";" para abrir direto no Excel em pt-BR
+	csvWriter := csv.NewWriter(csvEntry)
+	csvWriter.Comma = ';'
+	csvWriter.Write([]string{
+		"documento_id", "numero_documento", "tipo", "competencia", "data_emissao", "direcao",
+		"cnpj_emitente", "razao_social_emitente", "cnpj_destinatario", "razao_social_destinatario",
+		"valor_nota", "valor_iss", "arquivo", "sha256", "status", "arquivo_pdf",
+	})
+	for _, item := range manifest.Items {
+		csvWriter.Write([]string{
+			strconv.Itoa(item.DocumentID),
+			item.NumeroDocumento,
+			string(item.DocumentType),
+			item.Competencia,
+			item.DataEmissao.Format("2006-01-02"),
+			item.Direcao,
+			item.CNPJEmitente,
+			item.RazaoSocialEmitente,
+			item.CNPJDestinatario,
+			item.RazaoSocialDestinatario,
+			item.ValorNota.StringFixed(2),
+			item.ValorIss.StringFixed(2),
+			item.Arquivo,
+			item.SHA256,
+			item.Status,
+			item.ArquivoPDF,
+		})
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("erro ao gravar manifesto: %w", err)
+	}
+
+	return nil
+}
+
+// ExportFileName nome sugerido para o ZIP: xmls_{cnpj}_{inicio}[-{fim}].zip
+func ExportFileName(filter *model.ExportFilter) string {
+	if filter.CompetenciaFim == "" || filter.CompetenciaFim == filter.CompetenciaInicio {
+		return fmt.Sprintf("xmls_%s_%s.zip", filter.CNPJ, filter.CompetenciaInicio)
+	}
+	return fmt.Sprintf("xmls_%s_%s-%s.zip", filter.CNPJ, filter.CompetenciaInicio, filter.CompetenciaFim)
+}
+
+// normalizarCompetencia aceita AAAAMM ou MM/AAAA e retorna AAAAMM
+func normalizarCompetencia(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	layouts := []string{"200601", "01/2006", "2006-01"}
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("200601"), nil
+		}
+	}
+	return "", fmt.Errorf("%w: competência inválida: %s (use AAAAMM ou MM/AAAA)", ErrInvalidData, value)
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"zemdocs/internal/clientes/documents"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/tenant"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrJobScheduleNotFound = errors.New("agendamento não encontrado")
+	ErrJobScheduleExists   = errors.New("já existe agendamento com este nome")
+)
+
+// cronParser mesmo formato aceito pelo scheduler (com segundos)
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// JobScheduleService gerencia os agendamentos de jobs armazenados no banco
+type JobScheduleService struct {
+	scheduleRepo JobScheduleRepository
+	empresaRepo  EmpresaRepository
+	registry     *documents.Registry
+	onChange     func()
+}
+
+// NewJobScheduleService cria uma nova instância do serviço de agendamentos
+func NewJobScheduleService(scheduleRepo JobScheduleRepository, empresaRepo EmpresaRepository, registry *documents.Registry) *JobScheduleService {
+	return &JobScheduleService{
+		scheduleRepo: scheduleRepo,
+		empresaRepo:  empresaRepo,
+		registry:     registry,
+	}
+}
+
+// SetChangeHandler define quem é avisado quando um agendamento é alterado
+func (s *JobScheduleService) SetChangeHandler(handler func()) {
+	s.onChange = handler
+}
+
+// Listar lista todos os agendamentos
+func (s *JobScheduleService) Listar(ctx context.Context) ([]*model.JobSchedule, error) {
+	schedules, err := s.scheduleRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar agendamentos: %w", err)
+	}
+	return schedules, nil
+}
+
+// Consultar busca um agendamento por ID
+func (s *JobScheduleService) Consultar(ctx context.Context, id int64) (*model.JobSchedule, error) {
+	schedule, err := s.scheduleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrJobScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// Criar cadastra um novo agendamento
+func (s *JobScheduleService) Criar(ctx context.Context, req *model.JobScheduleRequest) (*model.JobSchedule, error) {
+	exists, err := s.scheduleRepo.ExistsByName(ctx, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao verificar agendamento: %w", err)
+	}
+	if exists {
+		return nil, ErrJobScheduleExists
+	}
+
+	schedule := &model.JobSchedule{Enabled: true, EscritorioID: req.EscritorioID}
+	applyJobScheduleRequest(schedule, req)
+
+	if err := s.validar(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("erro ao criar agendamento: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Agendamento %s criado (%s, %s)", schedule.Name, schedule.Type, schedule.Cron))
+	s.notifyChange()
+	return schedule, nil
+}
+
+// Atualizar altera um agendamento existente
+func (s *JobScheduleService) Atualizar(ctx context.Context, id int64, req *model.JobScheduleRequest) (*model.JobSchedule, error) {
+	schedule, err := s.Consultar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != schedule.Name {
+		exists, err := s.scheduleRepo.ExistsByName(ctx, req.Name)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao verificar agendamento: %w", err)
+		}
+		if exists {
+			return nil, ErrJobScheduleExists
+		}
+	}
+
+	applyJobScheduleRequest(schedule, req)
+
+	if err := s.validar(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("erro ao atualizar agendamento: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Agendamento %s atualizado", schedule.Name))
+	s.notifyChange()
+	return schedule, nil
+}
+
+// Excluir remove um agendamento
+func (s *JobScheduleService) Excluir(ctx context.Context, id int64) error {
+	schedule, err := s.Consultar(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.scheduleRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("erro ao excluir agendamento: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Agendamento %s excluído", schedule.Name))
+	s.notifyChange()
+	return nil
+}
+
+// CriarPadrao cadastra o agendamento informado somente se a tabela estiver vazia.
+// Usado na primeira inicialização para migrar o job antes definido por variáveis de ambiente.
+func (s *JobScheduleService) CriarPadrao(ctx context.Context, req *model.JobScheduleRequest) error {
+	total, err := s.scheduleRepo.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao contar agendamentos: %w", err)
+	}
+	if total > 0 {
+		return nil
+	}
+
+	_, err = s.Criar(ctx, req)
+	return err
+}
+
+// GarantirSistema cadastra o agendamento de um job de sistema se ainda não houver
+// nenhum do mesmo tipo. Todos os jobs recorrentes ficam na tabela de agendamentos,
+// onde podem ser pausados e têm um único lock por tipo.
+func (s *JobScheduleService) GarantirSistema(ctx context.Context, req *model.JobScheduleRequest) error {
+	exists, err := s.scheduleRepo.ExistsByType(ctx, req.Type)
+	if err != nil {
+		return fmt.Errorf("erro ao verificar agendamento: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = s.Criar(ctx, req)
+	return err
+}
+
+// ResolverCompetencia converte o parâmetro de competência em AAAAMM.
+// Aceita "atual" (ou vazio), "anterior" ou uma competência fixa.
+func ResolverCompetencia(param string, now time.Time) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(param)) {
+	case "", "atual":
+		return now.Format("200601"), nil
+	case "anterior":
+		return now.AddDate(0, -1, 0).Format("200601"), nil
+	}
+
+	if _, err := time.Parse("200601", param); err != nil {
+		return "", fmt.Errorf("competência inválida: %s (use AAAAMM, atual ou anterior)", param)
+	}
+	return param, nil
+}
+
+// ParseDocumentTypes converte a lista separada por vírgulas em tipos de documento
+func ParseDocumentTypes(param string) ([]documents.DocumentType, error) {
+	known := map[documents.DocumentType]bool{
+		documents.DocumentTypeNFSe: true,
+		documents.DocumentTypeNFe:  true,
+		documents.DocumentTypeNFCe: true,
+		documents.DocumentTypeCTe:  true,
+		documents.DocumentTypeMDFe: true,
+	}
+
+	var types []documents.DocumentType
+	for _, item := range strings.Split(param, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		documentType := documents.DocumentType(item)
+		if !known[documentType] {
+			return nil, fmt.Errorf("tipo de documento desconhecido: %s", item)
+		}
+		types = append(types, documentType)
+	}
+	return types, nil
+}
+
+// validar verifica cron, tipo, alvo e parâmetros do agendamento
+func (s *JobScheduleService) validar(ctx context.Context, schedule *model.JobSchedule) error {
+	if _, err := cronParser.Parse(schedule.Cron); err != nil {
+		return fmt.Errorf("%w: expressão cron inválida: %v", ErrInvalidData, err)
+	}
+
+	// Escritórios só agendam a sincronização dos próprios documentos; os jobs de
+	// armazenamento percorrem todos os escritórios e são do sistema
+	escritorioID := tenant.EscritorioID(ctx)
+	if escritorioID != nil && schedule.Type != model.JobScheduleTypeNFSeSync {
+		return fmt.Errorf("%w: escritórios só podem agendar jobs do tipo %s", ErrInvalidData, model.JobScheduleTypeNFSeSync)
+	}
+
+	switch schedule.Type {
+	case model.JobScheduleTypeStorageOutbox, model.JobScheduleTypeStorageReconcile, model.JobScheduleTypeStorageScrub,
+		model.JobScheduleTypeDocumentPartition:
+		schedule.EscritorioID = nil
+		return nil
+	case model.JobScheduleTypeNFSeSync:
+	default:
+		return fmt.Errorf("%w: tipo de job desconhecido: %s", ErrInvalidData, schedule.Type)
+	}
+
+	if escritorioID == nil {
+		escritorioID = schedule.EscritorioID
+	}
+	if escritorioID == nil {
+		return fmt.Errorf("%w: informe o escritório dono dos documentos sincronizados", ErrInvalidData)
+	}
+
+	if schedule.CodigoIBGE == "" {
+		return fmt.Errorf("%w: código IBGE do município é obrigatório", ErrInvalidData)
+	}
+	if _, exists := s.registry.GetClient(schedule.CodigoIBGE); !exists {
+		return fmt.Errorf("%w: município %s não possui cliente registrado", ErrInvalidData, schedule.CodigoIBGE)
+	}
+
+	if schedule.EmpresaID != nil {
+		empresa, err := s.empresaRepo.GetByID(ctx, *schedule.EmpresaID)
+		if err != nil || empresa.EscritorioID == nil || *empresa.EscritorioID != *escritorioID {
+			return fmt.Errorf("%w: empresa %d não encontrada", ErrInvalidData, *schedule.EmpresaID)
+		}
+	}
+
+	if _, err := ResolverCompetencia(schedule.Params[model.JobParamCompetencia], time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	if _, err := ParseDocumentTypes(schedule.Params[model.JobParamDocumentTypes]); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	return nil
+}
+
+func (s *JobScheduleService) notifyChange() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// applyJobScheduleRequest copia os campos da requisição para o agendamento
+func applyJobScheduleRequest(schedule *model.JobSchedule, req *model.JobScheduleRequest) {
+	schedule.Name = req.Name
+	schedule.Type = req.Type
+	schedule.EmpresaID = req.EmpresaID
+	schedule.CodigoIBGE = req.CodigoIBGE
+	schedule.Cron = req.Cron
+	schedule.Description = req.Description
+
+	schedule.Params = req.Params
+	if schedule.Params == nil {
+		schedule.Params = map[string]string{}
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/database/repository"
+)
+
+// Repositórios usados pelos serviços e jobs. São implementados pelo pacote
+// repository (Postgres) e, os de documentos, empresas, usuários, auditoria e
+// armazenamento, também pelo pacote repository/memory, para testes sem banco.
+// Todos restringem os dados ao escritório do contexto.
+
+// DocumentRepository persistência dos documentos fiscais
+type DocumentRepository interface {
+	GetByID(ctx context.Context, id int) (*model.Document, error)
+	GetByNumeroNfse(ctx context.Context, numeroNfse string) (*model.Document, error)
+	GetByNumeroDocumento(ctx context.Context, numeroDocumento string) (*model.Document, error)
+	ExistsByNumeroNfse(ctx context.Context, numeroNfse string) (bool, error)
+	Create(ctx context.Context, document *model.Document) error
+	CreateWithOutbox(ctx context.Context, document *model.Document, entry *model.StorageOutbox) error
+	UpdateXMLIntegrity(ctx context.Context, id int, status string, verifiedAt time.Time) error
+	UpdateXMLStorage(ctx context.Context, document *model.Document) error
+	ListByEmpresa(ctx context.Context, empresaID int, direcao string, limit, offset int) ([]*model.Document, error)
+	CountByEmpresa(ctx context.Context, empresaID int, direcao string) (int, error)
+	LinkEmpresa(ctx context.Context, empresa *model.Empresa) (int, error)
+
+	// Listagem, busca textual e exportação
+	List(ctx context.Context, req *model.DocumentListRequest) ([]*model.Document, error)
+	Search(ctx context.Context, filter *model.DocumentSearchFilter) ([]*model.DocumentSearchHit, error)
+	CountSearch(ctx context.Context, filter *model.DocumentSearchFilter) (int, error)
+	ListForExport(ctx context.Context, filter *model.ExportFilter, afterID, limit int) ([]*model.Document, error)
+	CountForExport(ctx context.Context, filter *model.ExportFilter) (int, error)
+
+	// Exclusão lógica, restauração e remoção definitiva
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int, removal *model.StorageOutbox) error
+	GetDeletedByID(ctx context.Context, id int) (*model.Document, error)
+	ListDeleted(ctx context.Context, limit, offset int) ([]*model.Document, error)
+	CountDeleted(ctx context.Context) (int, error)
+
+	// Varreduras do armazenamento, em ordem de ID e inclusive entre os excluídos
+	ListAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error)
+	ListWithXMLKeyAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error)
+	ListWithoutXMLKeyAfterID(ctx context.Context, afterID, limit int) ([]*model.Document, error)
+	ExistingXMLKeys(ctx context.Context, keys []string) (map[string]bool, error)
+}
+
+// DocumentDownloadRepository registro dos acessos ao XML dos documentos
+type DocumentDownloadRepository interface {
+	Create(ctx context.Context, download *model.DocumentDownload) error
+}
+
+// EmpresaRepository persistência das empresas e de seus dados relacionados
+type EmpresaRepository interface {
+	Create(ctx context.Context, empresa *model.Empresa) error
+	GetByID(ctx context.Context, id int) (*model.Empresa, error)
+	GetByCNPJ(ctx context.Context, cnpj string) (*model.Empresa, error)
+	GetAll(ctx context.Context, limit, offset int) ([]*model.Empresa, error)
+	Search(ctx context.Context, termo string, limit, offset int) ([]*model.Empresa, error)
+	Count(ctx context.Context) (int, error)
+	CountBySearch(ctx context.Context, termo string) (int, error)
+	Update(ctx context.Context, empresa *model.Empresa) error
+
+	// Exclusão lógica, restauração e remoção definitiva
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+	GetDeletedByID(ctx context.Context, id int) (*model.Empresa, error)
+	GetDeletedByCNPJ(ctx context.Context, cnpj string) (*model.Empresa, error)
+	ListDeleted(ctx context.Context, limit, offset int) ([]*model.Empresa, error)
+	CountDeleted(ctx context.Context) (int, error)
+
+	// Dados relacionados
+	CreateAtividadeSecundaria(ctx context.Context, atividade *model.AtividadeSecundaria) error
+	CreateMembro(ctx context.Context, membro *model.EmpresaMembro) error
+	CreateInscricaoEstadual(ctx context.Context, inscricao *model.EmpresaInscricaoEstadual) error
+	CreateSuframa(ctx context.Context, suframa *model.EmpresaSuframa) error
+	CreateTelefone(ctx context.Context, telefone *model.EmpresaTelefone) error
+	CreateEmail(ctx context.Context, email *model.EmpresaEmail) error
+}
+
+// UserRepository persistência dos usuários
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, id int) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetAll(ctx context.Context, limit, offset int) ([]*model.User, error)
+	Update(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, id int) error
+}
+
+// AuditLogRepository trilha de auditoria. RunInTx executa fn em uma transação usada
+// pelos demais repositórios que recebem o contexto de fn.
+type AuditLogRepository interface {
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
+	Create(ctx context.Context, log *model.AuditLog) error
+	List(ctx context.Context, filter *model.AuditFilter, beforeID int64, limit int) ([]*model.AuditLog, error)
+}
+
+// StorageOutboxRepository fila de operações pendentes do armazenamento
+type StorageOutboxRepository interface {
+	GetPending(ctx context.Context, limit int) ([]*model.StorageOutbox, error)
+	GetByDocumentID(ctx context.Context, documentID int) (*model.StorageOutbox, error)
+	MarkDone(ctx context.Context, entry *model.StorageOutbox) error
+	MarkFailed(ctx context.Context, id int64, status model.OutboxStatus, lastError string, nextAttemptAt time.Time) error
+	Requeue(ctx context.Context, id int64) error
+}
+
+// StorageOrphanRepository objetos do armazenamento sem documento, encontrados pela reconciliação
+type StorageOrphanRepository interface {
+	Record(ctx context.Context, bucket string, keys []string, seenAt time.Time) error
+	DeleteSeenBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// DashboardRepository agregados do dashboard de documentos
+type DashboardRepository interface {
+	ResumoMensal(ctx context.Context, filter *model.DashboardFilter) ([]*model.DocumentResumoMensal, error)
+	TopTomadores(ctx context.Context, filter *model.DashboardFilter, limit int) ([]*model.DocumentResumoTomador, error)
+	Refresh(ctx context.Context) error
+}
+
+// DeadLetterRepository documentos que falharam no processamento
+type DeadLetterRepository interface {
+	Create(ctx context.Context, deadLetter *model.DeadLetter) error
+	GetByID(ctx context.Context, id int64) (*model.DeadLetter, error)
+	List(ctx context.Context, filter model.DeadLetterFilter) ([]*model.DeadLetter, error)
+	Count(ctx context.Context, filter model.DeadLetterFilter) (int, error)
+	RecordAttempt(ctx context.Context, id int64, stage, lastError string) error
+	Resolve(ctx context.Context, id int64, status model.DeadLetterStatus) error
+}
+
+// EscritorioRepository escritórios de contabilidade
+type EscritorioRepository interface {
+	Create(ctx context.Context, escritorio *model.Escritorio) error
+	GetByID(ctx context.Context, id int64) (*model.Escritorio, error)
+	GetPadrao(ctx context.Context) (*model.Escritorio, error)
+	ExistsByCNPJ(ctx context.Context, cnpj string, exceptID int64) (bool, error)
+	List(ctx context.Context) ([]*model.Escritorio, error)
+	Update(ctx context.Context, escritorio *model.Escritorio) error
+}
+
+// APITokenRepository tokens de acesso dos escritórios
+type APITokenRepository interface {
+	Create(ctx context.Context, token *model.APIToken) error
+	ListByEscritorio(ctx context.Context, escritorioID int64) ([]*model.APIToken, error)
+	Revoke(ctx context.Context, escritorioID, id int64) (bool, error)
+	GetActiveByHash(ctx context.Context, hash string) (*model.APIToken, error)
+	TouchLastUsed(ctx context.Context, id int64) error
+}
+
+// JobScheduleRepository agendamentos de jobs definidos no banco
+type JobScheduleRepository interface {
+	Create(ctx context.Context, schedule *model.JobSchedule) error
+	GetByID(ctx context.Context, id int64) (*model.JobSchedule, error)
+	ExistsByName(ctx context.Context, name string) (bool, error)
+	ExistsByType(ctx context.Context, scheduleType string) (bool, error)
+	List(ctx context.Context) ([]*model.JobSchedule, error)
+	Count(ctx context.Context) (int, error)
+	Update(ctx context.Context, schedule *model.JobSchedule) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// DocumentPartitionRepository partições anuais da tabela de documentos
+type DocumentPartitionRepository interface {
+	List(ctx context.Context) ([]*model.DocumentPartition, error)
+	DefaultYears(ctx context.Context) ([]int, error)
+	Create(ctx context.Context, ano int) (bool, error)
+	Move(ctx context.Context, name, tablespace string) error
+}
+
+// ExportRepository exportações de XMLs em lote
+type ExportRepository interface {
+	Create(ctx context.Context, export *model.Export) error
+	GetByID(ctx context.Context, id int64) (*model.Export, error)
+	UpdateColumns(ctx context.Context, export *model.Export, columns ...string) error
+}
+
+// Implementações do Postgres
+var (
+	_ DocumentRepository          = (*repository.DocumentRepository)(nil)
+	_ DocumentDownloadRepository  = (*repository.DocumentDownloadRepository)(nil)
+	_ EmpresaRepository           = (*repository.EmpresaRepository)(nil)
+	_ UserRepository              = (*repository.UserRepository)(nil)
+	_ AuditLogRepository          = (*repository.AuditLogRepository)(nil)
+	_ StorageOutboxRepository     = (*repository.StorageOutboxRepository)(nil)
+	_ StorageOrphanRepository     = (*repository.StorageOrphanRepository)(nil)
+	_ DashboardRepository         = (*repository.DashboardRepository)(nil)
+	_ DeadLetterRepository        = (*repository.DeadLetterRepository)(nil)
+	_ EscritorioRepository        = (*repository.EscritorioRepository)(nil)
+	_ APITokenRepository          = (*repository.APITokenRepository)(nil)
+	_ JobScheduleRepository       = (*repository.JobScheduleRepository)(nil)
+	_ DocumentPartitionRepository = (*repository.DocumentPartitionRepository)(nil)
+	_ ExportRepository            = (*repository.ExportRepository)(nil)
+)
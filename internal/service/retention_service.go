@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"zemdocs/internal/config"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/tenant"
+)
+
+var ErrDocumentRetained = errors.New("documento sob retenção fiscal")
+
+// Situação da retenção de um documento
+const (
+	RetentionStatusRetido        = "retido"
+	RetentionStatusBloqueioLegal = "bloqueio_legal"
+	RetentionStatusExpirado      = "expirado"
+)
+
+// RetentionPolicy prazos de guarda dos XMLs por tipo de documento
+type RetentionPolicy struct {
+	defaultYears int
+	years        map[model.DocumentType]int
+	legalHold    bool
+}
+
+// NewRetentionPolicy cria a política a partir da configuração (já validada)
+func NewRetentionPolicy(cfg config.RetentionConfig) *RetentionPolicy {
+	policy := &RetentionPolicy{
+		defaultYears: cfg.DefaultYears,
+		years:        make(map[model.DocumentType]int, len(cfg.Years)),
+		legalHold:    cfg.LegalHold,
+	}
+	if policy.defaultYears < config.MinRetentionYears {
+		policy.defaultYears = config.MinRetentionYears
+	}
+	for docType, years := range cfg.Years {
+		policy.years[model.DocumentType(docType)] = years
+	}
+	return policy
+}
+
+// Years prazo de guarda, em anos, do tipo de documento
+func (p *RetentionPolicy) Years(docType model.DocumentType) int {
+	if years, ok := p.years[docType]; ok && years >= config.MinRetentionYears {
+		return years
+	}
+	return p.defaultYears
+}
+
+// RetainUntil fim da retenção do documento. O prazo é contado a partir do
+// primeiro dia do exercício seguinte ao da emissão (CTN, art. 173, I).
+func (p *RetentionPolicy) RetainUntil(document *model.Document) time.Time {
+	issued := document.DataEmissao
+	if issued.IsZero() {
+		issued = time.Now()
+	}
+	return time.Date(issued.Year()+1+p.Years(document.DocumentType), time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Apply registra no documento a retenção que será aplicada ao seu XML
+func (p *RetentionPolicy) Apply(document *model.Document) {
+	document.XMLRetainUntil = p.RetainUntil(document)
+	document.XMLLegalHold = p.legalHold
+}
+
+// RetentionStatus situação da retenção de um documento e do seu XML
+type RetentionStatus struct {
+	DocumentID     int                `json:"document_id"`
+	DocumentType   model.DocumentType `json:"document_type"`
+	RetentionYears int                `json:"retention_years"`
+	RetainUntil    time.Time          `json:"retain_until"`
+	LegalHold      bool               `json:"legal_hold"`
+	Status         string             `json:"status"`
+	Deletable      bool               `json:"deletable"`
+
+	// Retenção efetivamente registrada no objeto armazenado
+	XMLKey            string     `json:"xml_key,omitempty"`
+	ObjectRetainUntil *time.Time `json:"object_retain_until,omitempty"`
+	ObjectLocked      bool       `json:"object_locked"`
+	ObjectMissing     bool       `json:"object_missing,omitempty"`
+}
+
+// RetentionService consulta a retenção dos documentos, exclui e restaura documentos e
+// impede a remoção definitiva antes do prazo
+type RetentionService struct {
+	nfseRepo      DocumentRepository
+	store         storage.Store
+	policy        *RetentionPolicy
+	outboxService *StorageOutboxService
+	audit         *AuditService
+	dashboard     *DashboardService
+}
+
+// NewRetentionService cria uma nova instância do serviço de retenção
+func NewRetentionService(nfseRepo DocumentRepository, store storage.Store, policy *RetentionPolicy, outboxService *StorageOutboxService, auditService *AuditService) *RetentionService {
+	return &RetentionService{
+		nfseRepo:      nfseRepo,
+		store:         store,
+		policy:        policy,
+		outboxService: outboxService,
+		audit:         auditService,
+	}
+}
+
+// SetDashboard define o dashboard cujo recálculo é agendado após exclusão,
+// restauração e purga de documentos
+func (s *RetentionService) SetDashboard(dashboard *DashboardService) {
+	s.dashboard = dashboard
+}
+
+// ConsultarRetencao retorna a situação da retenção de um documento
+func (s *RetentionService) ConsultarRetencao(ctx context.Context, id int) (*RetentionStatus, error) {
+	document, err := s.buscar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.status(ctx, document)
+}
+
+// ExcluirDocumento exclui logicamente o documento. O XML é mantido, de modo que a
+// exclusão não depende da retenção e pode ser desfeita por RestaurarDocumento.
+func (s *RetentionService) ExcluirDocumento(ctx context.Context, id int) error {
+	document, err := s.buscar(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.nfseRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, DocumentAudit(document, model.AuditActionDelete), document, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao excluir documento: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Documento %d excluído", id))
+	s.atualizarDashboard(ctx)
+
+	return nil
+}
+
+// RestaurarDocumento restaura um documento excluído
+func (s *RetentionService) RestaurarDocumento(ctx context.Context, id int) (*model.DocumentResponse, error) {
+	document, err := s.buscarExcluido(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	restaurado := *document
+	restaurado.DeletedAt = time.Time{}
+
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.nfseRepo.Restore(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, DocumentAudit(document, model.AuditActionRestore), document, &restaurado)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao restaurar documento: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Documento %d restaurado", id))
+	s.atualizarDashboard(ctx)
+
+	return toDocumentResponse(&restaurado), nil
+}
+
+// PurgarDocumento remove definitivamente o documento já excluído e seu XML, desde
+// que a retenção tenha expirado. O XML é removido pelo outbox depois que a purga
+// for confirmada no banco, de modo que uma falha na transação não apague o
+// objeto de um documento que continua existindo.
+func (s *RetentionService) PurgarDocumento(ctx context.Context, id int) error {
+	document, err := s.buscarExcluido(ctx, id)
+	if err != nil {
+		return err
+	}
+	status, err := s.status(ctx, document)
+	if err != nil {
+		return err
+	}
+	if !status.Deletable {
+		if status.LegalHold {
+			return fmt.Errorf("%w: bloqueio legal ativo", ErrDocumentRetained)
+		}
+		return fmt.Errorf("%w até %s", ErrDocumentRetained, status.RetainUntil.Format("02/01/2006"))
+	}
+
+	var removal *model.StorageOutbox
+	if document.XMLKey != "" {
+		removal = s.outboxService.NewRemoval(document)
+	}
+
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.nfseRepo.Purge(ctx, id, removal); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, DocumentAudit(document, model.AuditActionPurge), document, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao purgar documento: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Documento %d removido definitivamente após o fim da retenção (%s)", id, status.RetainUntil.Format("02/01/2006")))
+	s.atualizarDashboard(ctx)
+
+	return nil
+}
+
+// ListarExcluidos lista os documentos excluídos, mais recentes primeiro, e o total
+func (s *RetentionService) ListarExcluidos(ctx context.Context, limit, offset int) ([]*model.DocumentResponse, int, error) {
+	documents, err := s.nfseRepo.ListDeleted(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao listar documentos excluídos: %w", err)
+	}
+
+	total, err := s.nfseRepo.CountDeleted(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao contar documentos excluídos: %w", err)
+	}
+
+	responses := make([]*model.DocumentResponse, 0, len(documents))
+	for _, document := range documents {
+		responses = append(responses, toDocumentResponse(document))
+	}
+
+	return responses, total, nil
+}
+
+// atualizarDashboard pede o recálculo dos agregados do dashboard depois que a
+// alteração for confirmada: a atualização usa outra conexão e não enxergaria a
+// transação da requisição
+func (s *RetentionService) atualizarDashboard(ctx context.Context) {
+	if s.dashboard == nil {
+		return
+	}
+	tenant.AfterCommit(ctx, func(ctx context.Context) {
+		s.dashboard.SolicitarAtualizacao()
+	})
+}
+
+// buscar busca o documento no escritório do contexto
+func (s *RetentionService) buscar(ctx context.Context, id int) (*model.Document, error) {
+	document, err := s.nfseRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar documento: %w", err)
+	}
+	return document, nil
+}
+
+// buscarExcluido busca o documento excluído; um documento ativo precisa ser excluído antes
+func (s *RetentionService) buscarExcluido(ctx context.Context, id int) (*model.Document, error) {
+	document, err := s.nfseRepo.GetDeletedByID(ctx, id)
+	if err == nil {
+		return document, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("erro ao buscar documento: %w", err)
+	}
+
+	if _, err := s.buscar(ctx, id); err == nil {
+		return nil, fmt.Errorf("%w: o documento não está excluído", ErrInvalidData)
+	}
+	return nil, ErrDocumentNotFound
+}
+
+// status combina a política vigente, o registrado no documento e o aplicado ao objeto;
+// prevalece sempre o prazo mais longo
+func (s *RetentionService) status(ctx context.Context, document *model.Document) (*RetentionStatus, error) {
+	status := &RetentionStatus{
+		DocumentID:     document.ID,
+		DocumentType:   document.DocumentType,
+		RetentionYears: s.policy.Years(document.DocumentType),
+		RetainUntil:    s.policy.RetainUntil(document),
+		LegalHold:      document.XMLLegalHold,
+		XMLKey:         document.XMLKey,
+	}
+	if document.XMLRetainUntil.After(status.RetainUntil) {
+		status.RetainUntil = document.XMLRetainUntil
+	}
+
+	if document.XMLKey != "" {
+		info, err := s.store.Stat(ctx, document.XMLKey)
+		switch {
+		case errors.Is(err, storage.ErrObjectNotFound):
+			status.ObjectMissing = true
+		case err != nil:
+			return nil, fmt.Errorf("erro ao consultar XML: %w", err)
+		default:
+			if !info.RetainUntil.IsZero() {
+				status.ObjectRetainUntil = &info.RetainUntil
+				if info.RetainUntil.After(status.RetainUntil) {
+					status.RetainUntil = info.RetainUntil
+				}
+			}
+			status.ObjectLocked = info.Locked
+			status.LegalHold = status.LegalHold || info.LegalHold
+		}
+	}
+
+	switch {
+	case status.LegalHold:
+		status.Status = RetentionStatusBloqueioLegal
+	case time.Now().Before(status.RetainUntil):
+		status.Status = RetentionStatusRetido
+	default:
+		status.Status = RetentionStatusExpirado
+		status.Deletable = true
+	}
+
+	return status, nil
+}
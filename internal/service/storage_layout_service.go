@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/storage"
+	"zemdocs/internal/utils"
+)
+
+// legacyPrefix prefixo do layout antigo nfse/{ano}/{mês}/{número}.xml
+const legacyPrefix = "nfse/"
+
+// LayoutMigrationOptions opções da migração de layout
+type LayoutMigrationOptions struct {
+	DryRun         bool   // Apenas relata o que seria feito
+	CheckpointFile string // Arquivo com a última chave legada processada
+	Resume         bool   // Continua a partir do checkpoint
+	DeleteLegacy   bool   // Remove o objeto legado após copiá-lo e conferi-lo
+}
+
+// LayoutMigrationReport resultado da migração de layout
+type LayoutMigrationReport struct {
+	LegacyObjects   int      `json:"legacy_objects"`
+	Copied          int      `json:"copied"`
+	AlreadyPresent  int      `json:"already_present"`
+	KeysRecorded    int      `json:"keys_recorded"`
+	Relocated       int      `json:"relocated"` // XMLs copiados para a chave do escritório
+	LegacyDeleted   int      `json:"legacy_deleted"`
+	SkippedResume   int      `json:"skipped_resume"`
+	WithoutDocument []string `json:"without_document"` // Objetos legados sem documento no banco
+	Failed          []string `json:"failed"`
+}
+
+// StorageLayoutService migra os XMLs do layout legado e do layout por CNPJ sem
+// escritório para a chave do escritório dono do documento e registra no documento
+// a chave definitiva, eliminando a busca por tentativa
+type StorageLayoutService struct {
+	nfseRepo DocumentRepository
+	store    storage.Store
+	policy   *RetentionPolicy
+}
+
+// NewStorageLayoutService cria uma nova instância do serviço de migração de layout
+func NewStorageLayoutService(nfseRepo DocumentRepository, store storage.Store, policy *RetentionPolicy) *StorageLayoutService {
+	return &StorageLayoutService{
+		nfseRepo: nfseRepo,
+		store:    store,
+		policy:   policy,
+	}
+}
+
+// Migrate copia cada objeto legado para a chave canônica, registra a chave dos
+// documentos que ainda não a possuem e, por fim, copia para a chave do escritório
+// os XMLs registrados em chaves sem escritório
+func (s *StorageLayoutService) Migrate(ctx context.Context, opts LayoutMigrationOptions) (*LayoutMigrationReport, error) {
+	report := &LayoutMigrationReport{}
+
+	keys, err := s.store.List(ctx, legacyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar objetos legados: %w", err)
+	}
+	sort.Strings(keys)
+	report.LegacyObjects = len(keys)
+
+	checkpoint := ""
+	if opts.Resume && opts.CheckpointFile != "" {
+		checkpoint, err = readCheckpoint(opts.CheckpointFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		default:
+		}
+
+		if checkpoint != "" && key <= checkpoint {
+			report.SkippedResume++
+			continue
+		}
+
+		if err := s.migrateObject(ctx, key, opts, report); err != nil {
+			logger.Error(err, fmt.Sprintf("Erro ao migrar %s", key))
+			report.Failed = append(report.Failed, key)
+		}
+
+		if !opts.DryRun && opts.CheckpointFile != "" {
+			if err := os.WriteFile(opts.CheckpointFile, []byte(key), 0o644); err != nil {
+				return report, fmt.Errorf("erro ao gravar checkpoint: %w", err)
+			}
+		}
+	}
+
+	if err := s.recordMissingKeys(ctx, opts, report); err != nil {
+		return report, err
+	}
+
+	if err := s.relocateToEscritorio(ctx, opts, report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// migrateObject copia um objeto legado para a chave canônica do seu documento
+func (s *StorageLayoutService) migrateObject(ctx context.Context, legacyKey string, opts LayoutMigrationOptions, report *LayoutMigrationReport) error {
+	numero, competencia, ok := parseLegacyKey(legacyKey)
+	if !ok {
+		report.WithoutDocument = append(report.WithoutDocument, legacyKey)
+		return nil
+	}
+
+	doc, err := s.nfseRepo.GetByNumeroDocumento(ctx, numero)
+	if errors.Is(err, sql.ErrNoRows) {
+		report.WithoutDocument = append(report.WithoutDocument, legacyKey)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao buscar documento %s: %w", numero, err)
+	}
+	if doc.Competencia != "" {
+		competencia = doc.Competencia
+	}
+
+	data, err := s.store.Get(ctx, legacyKey)
+	if err != nil {
+		return err
+	}
+	if err := storage.VerifySHA256(data, doc.XMLSha256); err != nil {
+		return err
+	}
+	hash := storage.ContentSHA256(data)
+
+	// CNPJ do prestador: do documento ou, na falta, do próprio XML
+	cnpj := doc.CNPJEmitente
+	if cnpj == "" {
+		xmlData, err := utils.ParseNFSeXML(string(data))
+		if err != nil || xmlData.CNPJPrestador == "" {
+			return fmt.Errorf("CNPJ do prestador não encontrado para %s", legacyKey)
+		}
+		cnpj = xmlData.CNPJPrestador
+	}
+
+	targetKey := storage.GenerateEscritorioObjectName(doc.EscritorioID, numero, competencia, cnpj)
+
+	present, err := s.hasContent(ctx, targetKey, hash)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		if present {
+			report.AlreadyPresent++
+		} else {
+			report.Copied++
+		}
+		report.KeysRecorded++
+		logger.Info(fmt.Sprintf("[dry-run] %s -> %s (documento %d)", legacyKey, targetKey, doc.ID))
+		return nil
+	}
+
+	s.policy.Apply(doc)
+	if present {
+		report.AlreadyPresent++
+	} else {
+		if err := s.store.Put(ctx, targetKey, data, storage.PutOptions{
+			ContentType: "application/xml",
+			Metadata:    map[string]string{storage.MetadataSHA256: hash},
+			RetainUntil: doc.XMLRetainUntil,
+			LegalHold:   doc.XMLLegalHold,
+			Tenant:      storage.EscritorioTenant(doc.EscritorioID),
+		}); err != nil {
+			return err
+		}
+		report.Copied++
+	}
+
+	doc.SetXMLStorage(s.store.Bucket(), targetKey, data, hash)
+	doc.XMLUploadedAt = time.Now()
+	if err := s.nfseRepo.UpdateXMLStorage(ctx, doc); err != nil {
+		return fmt.Errorf("erro ao registrar chave do documento %d: %w", doc.ID, err)
+	}
+	report.KeysRecorded++
+
+	if opts.DeleteLegacy {
+		// Conferir a cópia antes de apagar o original
+		copied, err := s.store.Get(ctx, targetKey)
+		if err != nil {
+			return err
+		}
+		if err := storage.VerifySHA256(copied, hash); err != nil {
+			return err
+		}
+		if err := s.store.Delete(ctx, legacyKey); err != nil {
+			return err
+		}
+		report.LegacyDeleted++
+	}
+
+	return nil
+}
+
+// recordMissingKeys registra a chave dos documentos cujo XML já está na chave do
+// escritório ou no layout por CNPJ sem escritório
+func (s *StorageLayoutService) recordMissingKeys(ctx context.Context, opts LayoutMigrationOptions, report *LayoutMigrationReport) error {
+	afterID := 0
+	for {
+		docs, err := s.nfseRepo.ListWithoutXMLKeyAfterID(ctx, afterID, 500)
+		if err != nil {
+			return fmt.Errorf("erro ao listar documentos sem chave: %w", err)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		for _, doc := range docs {
+			afterID = doc.ID
+			if doc.CNPJEmitente == "" || len(doc.Competencia) < 6 {
+				continue
+			}
+
+			key, info, err := s.findObject(ctx,
+				storage.GenerateEscritorioObjectName(doc.EscritorioID, doc.NumeroDocumento, doc.Competencia, doc.CNPJEmitente),
+				storage.GenerateObjectNameWithCNPJ(doc.NumeroDocumento, doc.Competencia, doc.CNPJEmitente))
+			if err != nil {
+				return err
+			}
+			if info == nil {
+				continue
+			}
+
+			report.KeysRecorded++
+			if opts.DryRun {
+				continue
+			}
+			// Localização a partir dos metadados do objeto, sem baixá-lo
+			doc.XMLBucket = s.store.Bucket()
+			doc.XMLKey = key
+			doc.XMLSize = info.Size
+			doc.XMLContentType = info.ContentType
+			doc.XMLUploadedAt = info.LastModified
+			doc.XMLRetainUntil = info.RetainUntil
+			doc.XMLLegalHold = info.LegalHold
+			if hash := info.MetadataValue(storage.MetadataSHA256); hash != "" {
+				doc.XMLSha256 = hash
+			}
+			if err := s.nfseRepo.UpdateXMLStorage(ctx, doc); err != nil {
+				return fmt.Errorf("erro ao registrar chave do documento %d: %w", doc.ID, err)
+			}
+		}
+	}
+}
+
+// findObject devolve a primeira das chaves que existe no armazenamento, ou info nil
+func (s *StorageLayoutService) findObject(ctx context.Context, keys ...string) (string, *storage.ObjectInfo, error) {
+	for _, key := range keys {
+		info, err := s.store.Stat(ctx, key)
+		if err == nil {
+			return key, info, nil
+		}
+		if !errors.Is(err, storage.ErrObjectNotFound) {
+			return "", nil, err
+		}
+	}
+	return "", nil, nil
+}
+
+// relocateToEscritorio copia para a chave do escritório os XMLs registrados em
+// chaves sem escritório. Nessas chaves a mesma NFS-e sincronizada por dois
+// escritórios compartilhava um objeto, cifrado com a chave de apenas um deles.
+func (s *StorageLayoutService) relocateToEscritorio(ctx context.Context, opts LayoutMigrationOptions, report *LayoutMigrationReport) error {
+	afterID := 0
+	for {
+		docs, err := s.nfseRepo.ListWithXMLKeyAfterID(ctx, afterID, 500)
+		if err != nil {
+			return fmt.Errorf("erro ao listar documentos com chave: %w", err)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		for _, doc := range docs {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			afterID = doc.ID
+			if len(doc.Competencia) < 6 || strings.HasPrefix(doc.XMLKey, "XML/"+storage.EscritorioTenant(doc.EscritorioID)+"/") {
+				continue
+			}
+			if err := s.relocateObject(ctx, doc, opts, report); err != nil {
+				logger.Error(err, fmt.Sprintf("Erro ao mover %s do documento %d", doc.XMLKey, doc.ID))
+				report.Failed = append(report.Failed, doc.XMLKey)
+			}
+		}
+	}
+}
+
+// relocateObject copia o XML do documento para a chave do escritório, cifrado com a
+// chave do escritório, e registra a nova chave. O objeto anterior (e o PDF em cache)
+// só é removido quando nenhum outro documento o usa mais.
+func (s *StorageLayoutService) relocateObject(ctx context.Context, doc *model.Document, opts LayoutMigrationOptions, report *LayoutMigrationReport) error {
+	oldKey := doc.XMLKey
+	oldPDFKey := PDFObjectName(doc)
+	targetKey := storage.GenerateEscritorioObjectName(doc.EscritorioID, doc.NumeroDocumento, doc.Competencia, doc.CNPJEmitente)
+
+	data, err := s.store.Get(ctx, oldKey)
+	if err != nil {
+		return err
+	}
+	if err := storage.VerifySHA256(data, doc.XMLSha256); err != nil {
+		return err
+	}
+	hash := storage.ContentSHA256(data)
+
+	if opts.DryRun {
+		report.Relocated++
+		logger.Info(fmt.Sprintf("[dry-run] %s -> %s (documento %d)", oldKey, targetKey, doc.ID))
+		return nil
+	}
+
+	present, err := s.hasContent(ctx, targetKey, hash)
+	if err != nil {
+		return err
+	}
+	if !present {
+		if err := s.store.Put(ctx, targetKey, data, storage.PutOptions{
+			ContentType: "application/xml",
+			Metadata:    map[string]string{storage.MetadataSHA256: hash},
+			RetainUntil: doc.XMLRetainUntil,
+			LegalHold:   doc.XMLLegalHold,
+			Tenant:      storage.EscritorioTenant(doc.EscritorioID),
+		}); err != nil {
+			return err
+		}
+	}
+
+	doc.SetXMLStorage(s.store.Bucket(), targetKey, data, hash)
+	doc.XMLUploadedAt = time.Now()
+	if err := s.nfseRepo.UpdateXMLStorage(ctx, doc); err != nil {
+		return fmt.Errorf("erro ao registrar chave do documento %d: %w", doc.ID, err)
+	}
+	report.Relocated++
+
+	if !opts.DeleteLegacy {
+		return nil
+	}
+	inUse, err := s.nfseRepo.ExistingXMLKeys(ctx, []string{oldKey})
+	if err != nil {
+		return err
+	}
+	if inUse[oldKey] {
+		return nil
+	}
+	for _, key := range []string{oldKey, oldPDFKey} {
+		if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			return err
+		}
+	}
+	report.LegacyDeleted++
+	return nil
+}
+
+// hasContent verifica se a chave já contém o conteúdo com o hash informado
+func (s *StorageLayoutService) hasContent(ctx context.Context, key, hash string) (bool, error) {
+	data, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return storage.ContentSHA256(data) == hash, nil
+}
+
+// parseLegacyKey extrai número e competência de nfse/{ano}/{mês}/{número}.xml
+func parseLegacyKey(key string) (numero, competencia string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(key, legacyPrefix), "/")
+	if len(parts) != 3 || len(parts[0]) != 4 || len(parts[1]) != 2 || !strings.HasSuffix(parts[2], ".xml") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(parts[2], ".xml"), parts[0] + parts[1], true
+}
+
+func readCheckpoint(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler checkpoint: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+	"zemdocs/internal/storage"
+)
+
+// StorageOutboxService entrega ao armazenamento os uploads registrados no outbox
+type StorageOutboxService struct {
+	outboxRepo  StorageOutboxRepository
+	store       storage.Store
+	policy      *RetentionPolicy
+	maxAttempts int
+	maxBackoff  time.Duration
+}
+
+// NewStorageOutboxService cria uma nova instância do serviço de outbox
+func NewStorageOutboxService(outboxRepo StorageOutboxRepository, store storage.Store, policy *RetentionPolicy) *StorageOutboxService {
+	return &StorageOutboxService{
+		outboxRepo:  outboxRepo,
+		store:       store,
+		policy:      policy,
+		maxAttempts: 10,
+		maxBackoff:  6 * time.Hour,
+	}
+}
+
+// NewEntry monta a entrada de outbox para o XML de um documento, já com a chave
+// definida, e registra no documento a retenção que será aplicada ao objeto
+func (s *StorageOutboxService) NewEntry(document *model.Document, xmlContent []byte) *model.StorageOutbox {
+	if s.policy != nil {
+		s.policy.Apply(document)
+	}
+
+	return &model.StorageOutbox{
+		ObjectName:  document.XMLKey,
+		ContentType: "application/xml",
+		Payload:     xmlContent,
+		ContentHash: storage.ContentSHA256(xmlContent),
+		RetainUntil: document.XMLRetainUntil,
+		LegalHold:   document.XMLLegalHold,
+		Tenant:      storage.EscritorioTenant(document.EscritorioID),
+		Status:      model.OutboxStatusPendente,
+	}
+}
+
+// NewRemoval monta a entrada de outbox que remove o XML de um documento purgado.
+// Gravada na transação da purga, a remoção só ocorre depois que ela for confirmada
+// e é repetida pelo outbox até o objeto sair do armazenamento. O repositório não
+// grava a remoção se outro documento ainda usar a mesma chave.
+func (s *StorageOutboxService) NewRemoval(document *model.Document) *model.StorageOutbox {
+	return &model.StorageOutbox{
+		DocumentID:  document.ID,
+		Operation:   model.OutboxOperationDelete,
+		ObjectName:  document.XMLKey,
+		ContentType: "application/xml",
+		Payload:     []byte{},
+		Status:      model.OutboxStatusPendente,
+	}
+}
+
+// Deliver tenta executar uma entrada no armazenamento e registra o resultado
+func (s *StorageOutboxService) Deliver(ctx context.Context, entry *model.StorageOutbox) error {
+	var uploadErr error
+	if entry.Operation == model.OutboxOperationDelete {
+		uploadErr = s.remove(ctx, entry)
+	} else {
+		uploadErr = s.upload(ctx, entry)
+	}
+	if uploadErr == nil {
+		if err := s.outboxRepo.MarkDone(ctx, entry); err != nil {
+			return fmt.Errorf("erro ao marcar outbox como concluído: %w", err)
+		}
+		return nil
+	}
+
+	attempts := entry.Attempts + 1
+	status := model.OutboxStatusPendente
+	if attempts >= s.maxAttempts {
+		status = model.OutboxStatusFalha
+	}
+
+	if err := s.outboxRepo.MarkFailed(ctx, entry.ID, status, uploadErr.Error(), time.Now().Add(s.backoff(attempts))); err != nil {
+		logger.Error(err, fmt.Sprintf("Erro ao registrar falha do outbox %d", entry.ID))
+	}
+
+	logger.Database().Warn().
+		Err(uploadErr).
+		Int64("outbox_id", entry.ID).
+		Int("document_id", entry.DocumentID).
+		Str("object_name", entry.ObjectName).
+		Int("attempts", attempts).
+		Str("status", string(status)).
+		Msg("Falha ao entregar XML do outbox")
+
+	return uploadErr
+}
+
+// ProcessPending entrega um lote de entradas pendentes e retorna quantas foram entregues e quantas falharam
+func (s *StorageOutboxService) ProcessPending(ctx context.Context, batchSize int) (int, int, error) {
+	entries, err := s.outboxRepo.GetPending(ctx, batchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("erro ao buscar outbox pendente: %w", err)
+	}
+
+	delivered := 0
+	failed := 0
+	for _, entry := range entries {
+		select {
+		case <-ctx.Done():
+			return delivered, failed, ctx.Err()
+		default:
+		}
+
+		if err := s.Deliver(ctx, entry); err != nil {
+			failed++
+		} else {
+			delivered++
+		}
+	}
+
+	return delivered, failed, nil
+}
+
+// upload grava o payload da entrada; se o objeto já existe com o mesmo hash, o
+// upload é dispensado
+func (s *StorageOutboxService) upload(ctx context.Context, entry *model.StorageOutbox) error {
+	if s.alreadyStored(ctx, entry) {
+		logger.Database().Debug().
+			Int64("outbox_id", entry.ID).
+			Str("object_name", entry.ObjectName).
+			Msg("Objeto já armazenado com o mesmo conteúdo, upload dispensado")
+		return nil
+	}
+
+	return s.store.Put(ctx, entry.ObjectName, entry.Payload, storage.PutOptions{
+		ContentType: entry.ContentType,
+		Metadata:    map[string]string{storage.MetadataSHA256: entry.ContentHash},
+		RetainUntil: entry.RetainUntil,
+		LegalHold:   entry.LegalHold,
+		Tenant:      entry.Tenant,
+	})
+}
+
+// remove apaga o objeto da entrada; objeto já ausente conta como removido
+func (s *StorageOutboxService) remove(ctx context.Context, entry *model.StorageOutbox) error {
+	err := s.store.Delete(ctx, entry.ObjectName)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil
+	}
+	return err
+}
+
+// alreadyStored verifica se o objeto de destino já tem o conteúdo da entrada. As
+// chaves são separadas por escritório, então o objeto encontrado é do mesmo tenant.
+func (s *StorageOutboxService) alreadyStored(ctx context.Context, entry *model.StorageOutbox) bool {
+	if entry.ContentHash == "" {
+		return false
+	}
+
+	info, err := s.store.Stat(ctx, entry.ObjectName)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(info.MetadataValue(storage.MetadataSHA256), entry.ContentHash)
+}
+
+// backoff calcula o intervalo até a próxima tentativa (quadrático, limitado)
+func (s *StorageOutboxService) backoff(attempts int) time.Duration {
+	backoff := time.Duration(attempts*attempts) * time.Minute
+	if backoff > s.maxBackoff {
+		return s.maxBackoff
+	}
+	return backoff
+}
@@ -3,16 +3,17 @@ package service
 import (
 	"context"
 	"zemdocs/internal/database/model"
-	"zemdocs/internal/database/repository"
 )
 
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo UserRepository
+	audit    *AuditService
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+func NewUserService(userRepo UserRepository, auditService *AuditService) *UserService {
 	return &UserService{
 		userRepo: userRepo,
+		audit:    auditService,
 	}
 }
 
@@ -32,7 +33,12 @@ func (s *UserService) CreateUser(ctx context.Context, name, email, password stri
 		Password: hashedPassword,
 	}
 
-	err := s.userRepo.Create(ctx, user)
+	err := s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, userAudit(user, model.AuditActionCreate), nil, user)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -58,10 +64,16 @@ func (s *UserService) UpdateUser(ctx context.Context, id int, name, email string
 		return nil, err
 	}
 
+	before := *user
 	user.Name = name
 	user.Email = email
 
-	err = s.userRepo.Update(ctx, user)
+	err = s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, userAudit(user, model.AuditActionUpdate), &before, user)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +82,27 @@ func (s *UserService) UpdateUser(ctx context.Context, id int, name, email string
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, id int) error {
-	return s.userRepo.Delete(ctx, id)
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.audit.Transacao(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Registrar(ctx, userAudit(user, model.AuditActionDelete), user, nil)
+	})
+}
+
+// userAudit registro de auditoria da alteração do usuário; a senha não é serializada
+func userAudit(user *model.User, action string) *model.AuditLog {
+	return &model.AuditLog{
+		EscritorioID: user.EscritorioID,
+		Entity:       model.AuditEntityUser,
+		EntityID:     int64(user.ID),
+		Action:       action,
+	}
 }
 
 // hashPassword simula hash de senha (usar bcrypt em produção)
@@ -0,0 +1,602 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/database/model"
+	"zemdocs/internal/logger"
+)
+
+var (
+	ErrDecryption        = errors.New("não foi possível decifrar o objeto")
+	ErrMasterKeyNotFound = errors.New("chave mestra não encontrada")
+	ErrPlaintextObject   = errors.New("objeto gravado sem criptografia")
+)
+
+// Metadados gravados nos objetos cifrados
+const (
+	MetadataEncryption = "encryption" // Algoritmo usado ("aes-256-gcm")
+	MetadataPlainSize  = "plain-size" // Tamanho do conteúdo decifrado
+)
+
+// DefaultTenant tenant usado quando a gravação não informa um
+const DefaultTenant = "default"
+
+// EscritorioTenant tenant das chaves de dados de um objeto: o escritório dono do
+// registro, ou DefaultTenant para registros sem escritório
+func EscritorioTenant(escritorioID *int64) string {
+	if escritorioID == nil {
+		return DefaultTenant
+	}
+	return strconv.FormatInt(*escritorioID, 10)
+}
+
+const (
+	encryptionAlgorithm = "aes-256-gcm"
+	keySize             = 32
+	currentKeyTTL       = 5 * time.Minute // Rotação feita em outra réplica vale após este intervalo
+)
+
+// envelopeMagic identifica objetos cifrados; objetos sem ele só são lidos com SetAllowPlaintext
+var envelopeMagic = []byte("ZDX1")
+
+// KeyStore persistência das chaves de dados cifradas (implementado pelo repositório)
+type KeyStore interface {
+	GetCurrent(ctx context.Context, tenant string) (*model.EncryptionKey, error)
+	GetByVersion(ctx context.Context, tenant string, version int) (*model.EncryptionKey, error)
+	CreateIfAbsent(ctx context.Context, key *model.EncryptionKey) (bool, error)
+	List(ctx context.Context) ([]*model.EncryptionKey, error)
+	UpdateWrappedKey(ctx context.Context, key *model.EncryptionKey) error
+}
+
+// MasterKeys chaves mestras por ID. A atual cifra as novas chaves de dados; as
+// demais só são mantidas para decifrar chaves ainda não recifradas após uma rotação.
+type MasterKeys struct {
+	keys    map[string][]byte
+	current string
+}
+
+// LoadMasterKeys carrega as chaves mestras de STORAGE_MASTER_KEY_FILE (uma por
+// linha, "id:base64") e/ou de STORAGE_MASTER_KEY (base64, com o ID de
+// STORAGE_MASTER_KEY_ID). A atual é STORAGE_MASTER_KEY_ID ou a última do arquivo.
+func LoadMasterKeys(cfg config.StorageConfig) (*MasterKeys, error) {
+	masterKeys := &MasterKeys{keys: make(map[string][]byte)}
+
+	if cfg.MasterKeyFile != "" {
+		file, err := os.Open(cfg.MasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo de chaves mestras: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			id, encoded, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("linha inválida no arquivo de chaves mestras (use id:base64)")
+			}
+			if err := masterKeys.add(strings.TrimSpace(id), strings.TrimSpace(encoded)); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("erro ao ler arquivo de chaves mestras: %w", err)
+		}
+	}
+
+	if cfg.MasterKey != "" {
+		id := cfg.MasterKeyID
+		if id == "" {
+			id = "1"
+		}
+		if err := masterKeys.add(id, cfg.MasterKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.MasterKeyID != "" {
+		masterKeys.current = cfg.MasterKeyID
+	}
+	if masterKeys.current == "" {
+		return nil, fmt.Errorf("%w: configure STORAGE_MASTER_KEY ou STORAGE_MASTER_KEY_FILE", ErrMasterKeyNotFound)
+	}
+	if _, ok := masterKeys.keys[masterKeys.current]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMasterKeyNotFound, masterKeys.current)
+	}
+
+	return masterKeys, nil
+}
+
+func (m *MasterKeys) add(id, encoded string) error {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return fmt.Errorf("chave mestra %s inválida: use %d bytes em base64", id, keySize)
+	}
+	m.keys[id] = key
+	m.current = id
+	return nil
+}
+
+// Current ID da chave mestra atual
+func (m *MasterKeys) Current() string {
+	return m.current
+}
+
+// wrap cifra a chave de dados com a chave mestra atual
+func (m *MasterKeys) wrap(dataKey []byte, tenant string, version int) (string, []byte, error) {
+	wrapped, err := seal(m.keys[m.current], dataKey, wrapAAD(tenant, version))
+	if err != nil {
+		return "", nil, err
+	}
+	return m.current, wrapped, nil
+}
+
+// unwrap decifra a chave de dados com a chave mestra que a cifrou
+func (m *MasterKeys) unwrap(key *model.EncryptionKey) ([]byte, error) {
+	masterKey, ok := m.keys[key.MasterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s (chave de %s v%d)", ErrMasterKeyNotFound, key.MasterKeyID, key.Tenant, key.Version)
+	}
+	dataKey, err := open(masterKey, key.WrappedKey, wrapAAD(key.Tenant, key.Version))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao decifrar chave de %s v%d: %w", key.Tenant, key.Version, err)
+	}
+	return dataKey, nil
+}
+
+func wrapAAD(tenant string, version int) []byte {
+	return []byte(tenant + ":" + strconv.Itoa(version))
+}
+
+// currentKey versão atual da chave de um tenant, em cache
+type currentKey struct {
+	version  int
+	loadedAt time.Time
+}
+
+// EncryptedStore cifra os objetos de qualquer Store com envelope encryption:
+// cada tenant tem uma chave de dados (AES-256-GCM) guardada no banco cifrada
+// pela chave mestra. Get decifra de forma transparente e recusa objetos em texto
+// puro, salvo durante a migração (SetAllowPlaintext); os links assinados passam
+// pela API, que entrega o conteúdo decifrado.
+type EncryptedStore struct {
+	inner          Store
+	masterKeys     *MasterKeys
+	keyStore       KeyStore
+	signer         *urlSigner
+	allowPlaintext bool
+
+	mu       sync.Mutex
+	dataKeys map[string][]byte // tenant:versão -> chave decifrada
+	current  map[string]currentKey
+}
+
+// NewEncryptedStore envolve o armazenamento com criptografia
+func NewEncryptedStore(inner Store, masterKeys *MasterKeys, keyStore KeyStore, signer *urlSigner) *EncryptedStore {
+	return &EncryptedStore{
+		inner:      inner,
+		masterKeys: masterKeys,
+		keyStore:   keyStore,
+		signer:     signer,
+		dataKeys:   make(map[string][]byte),
+		current:    make(map[string]currentKey),
+	}
+}
+
+// SetAllowPlaintext permite ler objetos legados gravados antes da criptografia.
+// Deve ficar ativo apenas até "storagekeys reencrypt" cifrar o acervo; sem ele um
+// objeto em texto puro é tratado como adulterado.
+func (s *EncryptedStore) SetAllowPlaintext(allow bool) {
+	s.allowPlaintext = allow
+}
+
+// Put cifra o conteúdo com a chave atual do tenant e grava no armazenamento interno
+func (s *EncryptedStore) Put(ctx context.Context, key string, data []byte, opts PutOptions) error {
+	tenant := opts.Tenant
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+
+	version, dataKey, err := s.currentDataKey(ctx, tenant)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := sealObject(dataKey, key, tenant, version, data)
+	if err != nil {
+		return fmt.Errorf("erro ao cifrar objeto: %w", err)
+	}
+
+	metadata := make(map[string]string, len(opts.Metadata)+2)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataEncryption] = encryptionAlgorithm
+	metadata[MetadataPlainSize] = strconv.Itoa(len(data))
+	opts.Metadata = metadata
+
+	return s.inner.Put(ctx, key, sealed, opts)
+}
+
+// Get lê e decifra um objeto. Objetos gravados antes da criptografia só são
+// devolvidos como estão se SetAllowPlaintext estiver ativo.
+func (s *EncryptedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	raw, err := s.inner.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(raw, envelopeMagic) {
+		if !s.allowPlaintext {
+			return nil, fmt.Errorf("%w: %s", ErrPlaintextObject, key)
+		}
+		return raw, nil
+	}
+
+	tenant, version, _, err := parseEnvelope(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrDecryption, key, err)
+	}
+	dataKey, err := s.dataKey(ctx, tenant, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := openObject(dataKey, key, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrDecryption, key, err)
+	}
+	return data, nil
+}
+
+// Delete remove o objeto
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	return s.inner.Delete(ctx, key)
+}
+
+// List lista as chaves com o prefixo informado
+func (s *EncryptedStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.inner.List(ctx, prefix)
+}
+
+// ListPage lista uma página das chaves com o prefixo informado
+func (s *EncryptedStore) ListPage(ctx context.Context, prefix, startAfter string, limit int) ([]string, error) {
+	return s.inner.ListPage(ctx, prefix, startAfter, limit)
+}
+
+// Stat retorna os metadados do objeto, com o tamanho do conteúdo decifrado
+func (s *EncryptedStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.inner.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if plainSize, err := strconv.ParseInt(info.MetadataValue(MetadataPlainSize), 10, 64); err == nil {
+		info.Size = plainSize
+	}
+	return info, nil
+}
+
+// PresignGet gera um link para a API, que decifra o objeto antes de entregá-lo;
+// um link direto para o bucket entregaria o conteúdo cifrado
+func (s *EncryptedStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return s.signer.presign(key, expiry), nil
+}
+
+// VerifyPresigned valida um link gerado por PresignGet
+func (s *EncryptedStore) VerifyPresigned(key, expires, signature string) error {
+	return s.signer.verify(key, expires, signature)
+}
+
+// Bucket retorna o bucket do armazenamento interno
+func (s *EncryptedStore) Bucket() string {
+	return s.inner.Bucket()
+}
+
+// RotateDataKey cria uma nova versão da chave de dados do tenant. Objetos novos
+// passam a usá-la; os existentes continuam legíveis (use Reencrypt para migrá-los).
+func (s *EncryptedStore) RotateDataKey(ctx context.Context, tenant string) (int, error) {
+	next := 1
+	current, err := s.keyStore.GetCurrent(ctx, tenant)
+	if err == nil {
+		next = current.Version + 1
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("erro ao buscar chave de %s: %w", tenant, err)
+	}
+
+	if _, err := s.createDataKey(ctx, tenant, next); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	delete(s.current, tenant)
+	s.mu.Unlock()
+
+	logger.Database().Info().Str("tenant", tenant).Int("version", next).Msg("Chave de dados rotacionada")
+	return next, nil
+}
+
+// RotateMasterKey recifra com a chave mestra atual as chaves de dados cifradas
+// por chaves anteriores. Depois disso as chaves mestras antigas podem ser removidas.
+func (s *EncryptedStore) RotateMasterKey(ctx context.Context) (int, error) {
+	keys, err := s.keyStore.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao listar chaves de dados: %w", err)
+	}
+
+	rewrapped := 0
+	for _, key := range keys {
+		if key.MasterKeyID == s.masterKeys.Current() {
+			continue
+		}
+
+		dataKey, err := s.masterKeys.unwrap(key)
+		if err != nil {
+			return rewrapped, err
+		}
+		key.MasterKeyID, key.WrappedKey, err = s.masterKeys.wrap(dataKey, key.Tenant, key.Version)
+		if err != nil {
+			return rewrapped, fmt.Errorf("erro ao cifrar chave de %s v%d: %w", key.Tenant, key.Version, err)
+		}
+		if err := s.keyStore.UpdateWrappedKey(ctx, key); err != nil {
+			return rewrapped, fmt.Errorf("erro ao gravar chave de %s v%d: %w", key.Tenant, key.Version, err)
+		}
+		rewrapped++
+	}
+
+	return rewrapped, nil
+}
+
+// Reencrypt regrava o objeto com a chave atual do seu tenant. Objetos em texto
+// puro são cifrados com o tenant informado. Retorna false se já estava atualizado.
+func (s *EncryptedStore) Reencrypt(ctx context.Context, key, plaintextTenant string) (bool, error) {
+	raw, err := s.inner.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	tenant := plaintextTenant
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	data := raw
+	if bytes.HasPrefix(raw, envelopeMagic) {
+		var version int
+		tenant, version, _, err = parseEnvelope(raw)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s: %v", ErrDecryption, key, err)
+		}
+		current, _, err := s.currentDataKey(ctx, tenant)
+		if err != nil {
+			return false, err
+		}
+		if version == current {
+			return false, nil
+		}
+		if data, err = s.Get(ctx, key); err != nil {
+			return false, err
+		}
+	}
+
+	info, err := s.inner.Stat(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	// Preservar metadados e retenção; o hash continua sendo o do conteúdo decifrado
+	metadata := make(map[string]string)
+	for k, v := range info.Metadata {
+		switch strings.ToLower(k) {
+		case MetadataEncryption, MetadataPlainSize, MetadataRetainUntil, MetadataLegalHold, "upload-time":
+			continue
+		}
+		metadata[strings.ToLower(k)] = v
+	}
+	if metadata[MetadataSHA256] == "" {
+		metadata[MetadataSHA256] = ContentSHA256(data)
+	}
+
+	err = s.Put(ctx, key, data, PutOptions{
+		ContentType: info.ContentType,
+		Metadata:    metadata,
+		RetainUntil: info.RetainUntil,
+		LegalHold:   info.LegalHold,
+		Tenant:      tenant,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// currentDataKey retorna a versão atual da chave do tenant, criando a primeira se necessário
+func (s *EncryptedStore) currentDataKey(ctx context.Context, tenant string) (int, []byte, error) {
+	s.mu.Lock()
+	cached, ok := s.current[tenant]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < currentKeyTTL {
+		dataKey, err := s.dataKey(ctx, tenant, cached.version)
+		return cached.version, dataKey, err
+	}
+
+	key, err := s.keyStore.GetCurrent(ctx, tenant)
+	if errors.Is(err, sql.ErrNoRows) {
+		key, err = s.createDataKey(ctx, tenant, 1)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("erro ao obter chave de %s: %w", tenant, err)
+	}
+
+	dataKey, err := s.cacheDataKey(key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.mu.Lock()
+	s.current[tenant] = currentKey{version: key.Version, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return key.Version, dataKey, nil
+}
+
+// dataKey retorna uma versão específica da chave do tenant
+func (s *EncryptedStore) dataKey(ctx context.Context, tenant string, version int) ([]byte, error) {
+	s.mu.Lock()
+	dataKey, ok := s.dataKeys[dataKeyID(tenant, version)]
+	s.mu.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	key, err := s.keyStore.GetByVersion(ctx, tenant, version)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter chave de %s v%d: %w", tenant, version, err)
+	}
+	return s.cacheDataKey(key)
+}
+
+// createDataKey gera e grava uma chave de dados. Se outra réplica gravou a mesma
+// versão antes, a chave dela é usada, para que nenhum objeto fique com chave perdida.
+func (s *EncryptedStore) createDataKey(ctx context.Context, tenant string, version int) (*model.EncryptionKey, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("erro ao gerar chave de dados: %w", err)
+	}
+
+	masterKeyID, wrapped, err := s.masterKeys.wrap(dataKey, tenant, version)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao cifrar chave de dados: %w", err)
+	}
+
+	key := &model.EncryptionKey{
+		Tenant:      tenant,
+		Version:     version,
+		MasterKeyID: masterKeyID,
+		WrappedKey:  wrapped,
+	}
+	created, err := s.keyStore.CreateIfAbsent(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gravar chave de dados: %w", err)
+	}
+	if !created {
+		return s.keyStore.GetByVersion(ctx, tenant, version)
+	}
+	return key, nil
+}
+
+func (s *EncryptedStore) cacheDataKey(key *model.EncryptionKey) ([]byte, error) {
+	dataKey, err := s.masterKeys.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.dataKeys[dataKeyID(key.Tenant, key.Version)] = dataKey
+	s.mu.Unlock()
+
+	return dataKey, nil
+}
+
+func dataKeyID(tenant string, version int) string {
+	return tenant + ":" + strconv.Itoa(version)
+}
+
+// sealObject monta o envelope: magic | tamanho do tenant | tenant | versão | nonce | cifrado.
+// O cabeçalho e a chave do objeto entram como dados autenticados, impedindo a troca de objetos.
+func sealObject(dataKey []byte, objectKey, tenant string, version int, data []byte) ([]byte, error) {
+	if len(tenant) > 255 {
+		return nil, fmt.Errorf("tenant muito longo: %s", tenant)
+	}
+
+	header := make([]byte, 0, len(envelopeMagic)+1+len(tenant)+4)
+	header = append(header, envelopeMagic...)
+	header = append(header, byte(len(tenant)))
+	header = append(header, tenant...)
+	header = binary.BigEndian.AppendUint32(header, uint32(version))
+
+	sealed, err := seal(dataKey, data, append(append([]byte{}, header...), objectKey...))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, sealed...), nil
+}
+
+// openObject decifra um envelope gerado por sealObject
+func openObject(dataKey []byte, objectKey string, raw []byte) ([]byte, error) {
+	_, _, headerLen, err := parseEnvelope(raw)
+	if err != nil {
+		return nil, err
+	}
+	header := raw[:headerLen]
+	return open(dataKey, raw[headerLen:], append(append([]byte{}, header...), objectKey...))
+}
+
+// parseEnvelope lê tenant e versão do cabeçalho do envelope
+func parseEnvelope(raw []byte) (string, int, int, error) {
+	offset := len(envelopeMagic)
+	if len(raw) < offset+1 {
+		return "", 0, 0, errors.New("cabeçalho truncado")
+	}
+	tenantLen := int(raw[offset])
+	offset++
+	if len(raw) < offset+tenantLen+4 {
+		return "", 0, 0, errors.New("cabeçalho truncado")
+	}
+	tenant := string(raw[offset : offset+tenantLen])
+	offset += tenantLen
+	version := int(binary.BigEndian.Uint32(raw[offset : offset+4]))
+	offset += 4
+	return tenant, version, offset, nil
+}
+
+// seal cifra com AES-256-GCM e prefixa o nonce
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open decifra o resultado de seal
+func open(key, sealed, aad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("conteúdo cifrado truncado")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
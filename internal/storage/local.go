@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"zemdocs/internal/logger"
+)
+
+// localMetaDir diretório (dentro da raiz) com os metadados de cada objeto
+const localMetaDir = ".meta"
+
+// LocalStore implementação de Store no sistema de arquivos local, para
+// instalações pequenas e execução sem MinIO. Cada objeto é um arquivo sob a
+// raiz; tipo de conteúdo e metadados ficam em .meta/{chave}.json.
+type LocalStore struct {
+	root   string
+	signer *urlSigner
+}
+
+// localMeta metadados gravados ao lado de cada objeto
+type localMeta struct {
+	ContentType string            `json:"content_type"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocalStore cria o armazenamento local. A chave de assinatura dos links é obrigatória.
+func NewLocalStore(root, baseURL, signingKey string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de armazenamento: %w", err)
+	}
+
+	signer, err := newURLSigner(baseURL, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{
+		root:   root,
+		signer: signer,
+	}, nil
+}
+
+// Bucket retorna o diretório raiz, prefixado para diferenciá-lo de um bucket
+func (l *LocalStore) Bucket() string {
+	return "local:" + l.root
+}
+
+// Put grava um objeto de forma atômica (arquivo temporário + rename)
+func (l *LocalStore) Put(ctx context.Context, key string, data []byte, opts PutOptions) error {
+	objectPath, err := l.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := l.checkExisting(ctx, key, opts.Metadata[MetadataSHA256]); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(objectPath, data); err != nil {
+		return fmt.Errorf("erro ao gravar objeto: %w", err)
+	}
+
+	// Sem object lock no sistema de arquivos: o objeto retido fica somente leitura
+	// e Delete/Put conferem a retenção registrada nos metadados
+	if !opts.RetainUntil.IsZero() || opts.LegalHold {
+		if err := os.Chmod(objectPath, 0o444); err != nil {
+			return fmt.Errorf("erro ao proteger objeto: %w", err)
+		}
+	}
+
+	meta, err := json.Marshal(localMeta{ContentType: opts.ContentType, Metadata: putMetadata(opts)})
+	if err != nil {
+		return fmt.Errorf("erro ao serializar metadados: %w", err)
+	}
+	if err := writeFileAtomic(l.metaPath(key), meta); err != nil {
+		return fmt.Errorf("erro ao gravar metadados: %w", err)
+	}
+
+	logger.Database().Info().
+		Str("root", l.root).
+		Str("object", key).
+		Int("size", len(data)).
+		Msg("Objeto gravado no armazenamento local")
+
+	return nil
+}
+
+// Get lê um objeto
+func (l *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	objectPath, err := l.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler objeto: %w", err)
+	}
+	return data, nil
+}
+
+// Delete remove um objeto e seus metadados; remover chave inexistente não é erro
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	objectPath, err := l.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := l.checkExisting(ctx, key, ""); err != nil {
+		return err
+	}
+
+	if err := os.Remove(objectPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("erro ao remover objeto: %w", err)
+	}
+	if err := os.Remove(l.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("erro ao remover metadados: %w", err)
+	}
+
+	logger.Database().Info().
+		Str("root", l.root).
+		Str("object", key).
+		Msg("Objeto removido do armazenamento local")
+
+	return nil
+}
+
+// List lista as chaves com o prefixo informado
+func (l *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := l.walk(prefix, func(key string) {
+		keys = append(keys, key)
+	})
+	return keys, err
+}
+
+// ListPage lista até limit chaves com o prefixo, a partir da chave seguinte a
+// startAfter. A ordem de WalkDir (por diretório) não é a ordem lexicográfica das
+// chaves, por isso a árvore é percorrida inteira guardando apenas as limit
+// menores chaves posteriores a startAfter.
+func (l *LocalStore) ListPage(ctx context.Context, prefix, startAfter string, limit int) ([]string, error) {
+	var page []string
+	err := l.walk(prefix, func(key string) {
+		if key <= startAfter {
+			return
+		}
+		i, _ := slices.BinarySearch(page, key)
+		if i == limit {
+			return
+		}
+		page = slices.Insert(page, i, key)
+		if len(page) > limit {
+			page = page[:limit]
+		}
+	})
+	return page, err
+}
+
+// walk chama fn para cada chave com o prefixo, ignorando metadados e arquivos temporários
+func (l *LocalStore) walk(prefix string, fn func(key string)) error {
+	err := filepath.WalkDir(l.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == localMetaDir && filepath.Dir(p) == filepath.Clean(l.root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			fn(key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao listar objetos: %w", err)
+	}
+	return nil
+}
+
+// Stat retorna os metadados de um objeto
+func (l *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	objectPath, err := l.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := os.Stat(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar objeto: %w", err)
+	}
+
+	info := &ObjectInfo{
+		Key:          key,
+		Size:         fileInfo.Size(),
+		LastModified: fileInfo.ModTime(),
+		ETag:         localETag(fileInfo),
+	}
+
+	if raw, err := os.ReadFile(l.metaPath(key)); err == nil {
+		var meta localMeta
+		if err := json.Unmarshal(raw, &meta); err == nil {
+			info.ContentType = meta.ContentType
+			info.Metadata = meta.Metadata
+		}
+	}
+	info.readRetentionMetadata()
+
+	return info, nil
+}
+
+// checkExisting recusa a operação se o objeto existente ainda estiver retido
+func (l *LocalStore) checkExisting(ctx context.Context, key, newHash string) error {
+	info, err := l.Stat(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkRetention(info, newHash)
+}
+
+// PresignGet gera um link assinado com HMAC, servido por VerifyPresigned + Get
+func (l *LocalStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, err := l.objectPath(key); err != nil {
+		return "", err
+	}
+
+	return l.signer.presign(key, expiry), nil
+}
+
+// VerifyPresigned valida a assinatura e a validade de um link gerado por PresignGet
+func (l *LocalStore) VerifyPresigned(key, expires, signature string) error {
+	return l.signer.verify(key, expires, signature)
+}
+
+// objectPath converte a chave em caminho sob a raiz, rejeitando travessia de diretórios
+func (l *LocalStore) objectPath(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" || cleaned != key || cleaned == localMetaDir || strings.HasPrefix(cleaned, localMetaDir+"/") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(cleaned)), nil
+}
+
+func (l *LocalStore) metaPath(key string) string {
+	return filepath.Join(l.root, localMetaDir, filepath.FromSlash(key)+".json")
+}
+
+// writeFileAtomic grava em arquivo temporário no mesmo diretório e renomeia
+func writeFileAtomic(target string, data []byte) error {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// localETag identificador de versão derivado de tamanho e data de modificação
+func localETag(info fs.FileInfo) string {
+	sum := md5.Sum([]byte(fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"zemdocs/internal/logger"
@@ -13,13 +16,21 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
-// MinIOClient cliente para MinIO
+// MinIOClient implementação de Store para MinIO/S3
 type MinIOClient struct {
 	client     *minio.Client
 	bucketName string
+
+	// Bucket verificado na primeira gravação, não na construção
+	bucketMu    sync.Mutex
+	bucketReady bool
+
+	// Object lock (WORM): bucket criado com bloqueio habilitado e modo aplicado nas gravações
+	objectLock bool
+	lockMode   minio.RetentionMode
 }
 
-// NewMinIOClient cria uma nova instância do cliente MinIO
+// NewMinIOClient cria uma nova instância do cliente MinIO sem acessar a rede
 func NewMinIOClient(endpoint, accessKey, secretKey, bucketName string, useSSL bool) (*MinIOClient, error) {
 	client, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
@@ -29,94 +40,176 @@ func NewMinIOClient(endpoint, accessKey, secretKey, bucketName string, useSSL bo
 		return nil, fmt.Errorf("erro ao criar cliente MinIO: %w", err)
 	}
 
-	minioClient := &MinIOClient{
+	return &MinIOClient{
 		client:     client,
 		bucketName: bucketName,
-	}
+		lockMode:   minio.Governance,
+	}, nil
+}
 
-	// Criar bucket se não existir
-	if err := minioClient.ensureBucket(context.Background()); err != nil {
-		return nil, err
+// SetRetentionMode define o modo do object lock (GOVERNANCE ou COMPLIANCE).
+// Em COMPLIANCE nem o administrador do MinIO consegue antecipar a remoção.
+func (m *MinIOClient) SetRetentionMode(mode string) {
+	if retentionMode := minio.RetentionMode(strings.ToUpper(mode)); retentionMode.IsValid() {
+		m.lockMode = retentionMode
 	}
+}
 
-	return minioClient, nil
+// Bucket retorna o nome do bucket
+func (m *MinIOClient) Bucket() string {
+	return m.bucketName
 }
 
-// ensureBucket garante que o bucket existe
-func (m *MinIOClient) ensureBucket(ctx context.Context) error {
+// EnsureBucket garante que o bucket existe, criando-o se necessário
+func (m *MinIOClient) EnsureBucket(ctx context.Context) error {
+	m.bucketMu.Lock()
+	defer m.bucketMu.Unlock()
+
+	if m.bucketReady {
+		return nil
+	}
+
 	exists, err := m.client.BucketExists(ctx, m.bucketName)
 	if err != nil {
 		return fmt.Errorf("erro ao verificar bucket: %w", err)
 	}
 
 	if !exists {
-		err = m.client.MakeBucket(ctx, m.bucketName, minio.MakeBucketOptions{})
+		// Object lock só pode ser habilitado na criação do bucket
+		err = m.client.MakeBucket(ctx, m.bucketName, minio.MakeBucketOptions{ObjectLocking: true})
 		if err != nil {
 			return fmt.Errorf("erro ao criar bucket: %w", err)
 		}
 		logger.Database().Info().Str("bucket", m.bucketName).Msg("Bucket criado com sucesso")
+		m.objectLock = true
+	} else {
+		objectLock, _, _, _, err := m.client.GetObjectLockConfig(ctx, m.bucketName)
+		m.objectLock = err == nil && objectLock == "Enabled"
+		if !m.objectLock {
+			logger.Database().Warn().
+				Str("bucket", m.bucketName).
+				Msg("Bucket sem object lock; retenção garantida apenas pela aplicação")
+		}
 	}
 
+	m.bucketReady = true
 	return nil
 }
 
-// UploadXML faz upload de um XML para o MinIO
-func (m *MinIOClient) UploadXML(ctx context.Context, objectName string, xmlContent []byte) error {
-	reader := strings.NewReader(string(xmlContent))
+// Put grava um objeto no bucket
+func (m *MinIOClient) Put(ctx context.Context, key string, data []byte, opts PutOptions) error {
+	if err := m.EnsureBucket(ctx); err != nil {
+		return err
+	}
 
-	_, err := m.client.PutObject(ctx, m.bucketName, objectName, reader, int64(len(xmlContent)), minio.PutObjectOptions{
-		ContentType: "application/xml",
-		UserMetadata: map[string]string{
-			"upload-time": time.Now().Format(time.RFC3339),
-		},
-	})
+	if err := m.checkExisting(ctx, key, opts.Metadata[MetadataSHA256]); err != nil {
+		return err
+	}
+
+	putOpts := minio.PutObjectOptions{
+		ContentType:  opts.ContentType,
+		UserMetadata: putMetadata(opts),
+	}
+	if m.objectLock {
+		if !opts.RetainUntil.IsZero() {
+			putOpts.Mode = m.lockMode
+			putOpts.RetainUntilDate = opts.RetainUntil.UTC()
+		}
+		if opts.LegalHold {
+			putOpts.LegalHold = minio.LegalHoldEnabled
+		}
+	}
 
+	_, err := m.client.PutObject(ctx, m.bucketName, key, bytes.NewReader(data), int64(len(data)), putOpts)
 	if err != nil {
-		return fmt.Errorf("erro ao fazer upload do XML: %w", err)
+		return fmt.Errorf("erro ao enviar objeto: %w", err)
 	}
 
 	logger.Database().Info().
 		Str("bucket", m.bucketName).
-		Str("object", objectName).
-		Int("size", len(xmlContent)).
-		Msg("XML enviado para MinIO")
+		Str("object", key).
+		Int("size", len(data)).
+		Msg("Objeto enviado para MinIO")
 
 	return nil
 }
 
-// DownloadXML baixa um XML do MinIO
-func (m *MinIOClient) DownloadXML(ctx context.Context, objectName string) ([]byte, error) {
-	object, err := m.client.GetObject(ctx, m.bucketName, objectName, minio.GetObjectOptions{})
+// Get baixa um objeto do bucket
+func (m *MinIOClient) Get(ctx context.Context, key string) ([]byte, error) {
+	object, err := m.client.GetObject(ctx, m.bucketName, key, minio.GetObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("erro ao baixar XML: %w", err)
+		return nil, fmt.Errorf("erro ao baixar objeto: %w", m.mapError(err))
 	}
 	defer object.Close()
 
 	data, err := io.ReadAll(object)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao ler XML: %w", err)
+		return nil, fmt.Errorf("erro ao ler objeto: %w", m.mapError(err))
 	}
 
 	return data, nil
 }
 
-// DeleteXML remove um XML do MinIO
-func (m *MinIOClient) DeleteXML(ctx context.Context, objectName string) error {
-	err := m.client.RemoveObject(ctx, m.bucketName, objectName, minio.RemoveObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("erro ao deletar XML: %w", err)
+// Delete remove um objeto do bucket. Com object lock o bucket é versionado e a
+// remoção simples apenas criaria um marcador, mantendo o conteúdo; por isso,
+// depois de conferir a retenção, cada versão do objeto é removida pelo VersionID.
+func (m *MinIOClient) Delete(ctx context.Context, key string) error {
+	if err := m.EnsureBucket(ctx); err != nil {
+		return err
+	}
+
+	if err := m.checkExisting(ctx, key, ""); err != nil {
+		return err
+	}
+
+	if m.objectLock {
+		if err := m.removeVersions(ctx, key); err != nil {
+			return err
+		}
+	} else {
+		err := m.client.RemoveObject(ctx, m.bucketName, key, minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("erro ao remover objeto: %w", err)
+		}
 	}
 
 	logger.Database().Info().
 		Str("bucket", m.bucketName).
-		Str("object", objectName).
-		Msg("XML removido do MinIO")
+		Str("object", key).
+		Msg("Objeto removido do MinIO")
 
 	return nil
 }
 
-// ListXMLs lista XMLs no MinIO com prefixo
-func (m *MinIOClient) ListXMLs(ctx context.Context, prefix string) ([]string, error) {
+// removeVersions remove todas as versões e marcadores de remoção da chave. Uma
+// versão antiga ainda retida faz o MinIO recusar a remoção (AccessDenied).
+func (m *MinIOClient) removeVersions(ctx context.Context, key string) error {
+	for object := range m.client.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{
+		Prefix:       key,
+		WithVersions: true,
+		Recursive:    true,
+	}) {
+		if object.Err != nil {
+			return fmt.Errorf("erro ao listar versões do objeto: %w", object.Err)
+		}
+		// O prefixo também traz chaves mais longas iniciadas pela mesma sequência
+		if object.Key != key {
+			continue
+		}
+
+		err := m.client.RemoveObject(ctx, m.bucketName, key, minio.RemoveObjectOptions{VersionID: object.VersionID})
+		if minio.ToErrorResponse(err).Code == "AccessDenied" {
+			return fmt.Errorf("%w: versão %s de %s", ErrRetentionActive, object.VersionID, key)
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao remover versão do objeto: %w", err)
+		}
+	}
+	return nil
+}
+
+// List lista as chaves com o prefixo informado
+func (m *MinIOClient) List(ctx context.Context, prefix string) ([]string, error) {
 	var objects []string
 
 	for object := range m.client.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{
@@ -132,26 +225,88 @@ func (m *MinIOClient) ListXMLs(ctx context.Context, prefix string) ([]string, er
 	return objects, nil
 }
 
-// GenerateObjectName gera nome do objeto baseado na NFS-e
-func (m *MinIOClient) GenerateObjectName(numeroNfse, competencia string) string {
-	year := competencia[:4]
-	month := competencia[4:6]
-	return fmt.Sprintf("nfse/%s/%s/%s.xml", year, month, numeroNfse)
+// ListPage lista até limit chaves com o prefixo, a partir da chave seguinte a startAfter
+func (m *MinIOClient) ListPage(ctx context.Context, prefix, startAfter string, limit int) ([]string, error) {
+	// Cancelar a listagem encerra a paginação interna do cliente ao atingir o limite
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var objects []string
+	for object := range m.client.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Recursive:  true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("erro ao listar objetos: %w", object.Err)
+		}
+		objects = append(objects, object.Key)
+		if len(objects) == limit {
+			break
+		}
+	}
+
+	return objects, nil
 }
 
-// GenerateObjectNameWithCNPJ gera nome do objeto com CNPJ do prestador
-func (m *MinIOClient) GenerateObjectNameWithCNPJ(numeroNfse, competencia, cnpjPrestador string) string {
-	year := competencia[:4]
-	monthYear := competencia[4:6] + competencia[:4] // formato MMYYYY
-	return fmt.Sprintf("XML/NFS/%s/%s/%s/%s.xml", year, monthYear, cnpjPrestador, numeroNfse)
+// Stat retorna os metadados de um objeto
+func (m *MinIOClient) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := m.client.StatObject(ctx, m.bucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar objeto: %w", m.mapError(err))
+	}
+
+	objectInfo := &ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+		Metadata:     info.UserMetadata,
+	}
+	objectInfo.readRetentionMetadata()
+
+	// Retenção aplicada pelo object lock prevalece sobre a registrada nos metadados
+	if value := info.Metadata.Get("X-Amz-Object-Lock-Retain-Until-Date"); value != "" {
+		if retainUntil, err := time.Parse(time.RFC3339, value); err == nil {
+			objectInfo.RetainUntil = retainUntil
+			objectInfo.Locked = true
+		}
+	}
+	if strings.EqualFold(info.Metadata.Get("X-Amz-Object-Lock-Legal-Hold"), string(minio.LegalHoldEnabled)) {
+		objectInfo.LegalHold = true
+		objectInfo.Locked = true
+	}
+
+	return objectInfo, nil
 }
 
-// GetObjectURL gera URL pré-assinada para download
-func (m *MinIOClient) GetObjectURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
-	url, err := m.client.PresignedGetObject(ctx, m.bucketName, objectName, expiry, nil)
+// checkExisting recusa a operação se o objeto existente ainda estiver retido
+func (m *MinIOClient) checkExisting(ctx context.Context, key, newHash string) error {
+	info, err := m.Stat(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkRetention(info, newHash)
+}
+
+// PresignGet gera URL pré-assinada para download
+func (m *MinIOClient) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	url, err := m.client.PresignedGetObject(ctx, m.bucketName, key, expiry, nil)
 	if err != nil {
 		return "", fmt.Errorf("erro ao gerar URL: %w", err)
 	}
 
 	return url.String(), nil
 }
+
+// mapError converte "objeto inexistente" do MinIO em ErrObjectNotFound
+func (m *MinIOClient) mapError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrObjectNotFound
+	}
+	return err
+}
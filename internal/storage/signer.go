@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"zemdocs/internal/config"
+	"zemdocs/internal/logger"
+)
+
+var ErrInvalidSignature = errors.New("assinatura inválida ou expirada")
+
+// PresignVerifier armazenamento cujos links assinados são servidos pela própria API
+// (backend local e armazenamento criptografado) em vez de apontarem para o bucket
+type PresignVerifier interface {
+	VerifyPresigned(key, expires, signature string) error
+}
+
+// urlSigner gera e confere links assinados com HMAC-SHA256
+type urlSigner struct {
+	baseURL string
+	key     []byte
+}
+
+// newURLSigner cria o assinador. A chave é obrigatória: todas as réplicas precisam
+// compartilhá-la para aceitar os links umas das outras.
+func newURLSigner(baseURL, signingKey string) (*urlSigner, error) {
+	if signingKey == "" {
+		return nil, fmt.Errorf("STORAGE_LOCAL_SIGNING_KEY é obrigatório para links assinados")
+	}
+
+	return &urlSigner{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		key:     []byte(signingKey),
+	}, nil
+}
+
+// resolveSigningKey retorna a chave configurada ou, apenas com
+// STORAGE_LOCAL_SIGNING_KEY_EPHEMERAL, uma chave aleatória válida enquanto o processo rodar
+func resolveSigningKey(cfg config.StorageConfig) (string, error) {
+	if cfg.LocalSigningKey != "" || !cfg.EphemeralSigningKey {
+		return cfg.LocalSigningKey, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("erro ao gerar chave de assinatura: %w", err)
+	}
+	logger.Database().Warn().Msg("STORAGE_LOCAL_SIGNING_KEY_EPHEMERAL ativo; links assinados não valem entre réplicas nem sobrevivem a reinícios")
+	return hex.EncodeToString(key), nil
+}
+
+// presign monta o link assinado para a chave
+func (s *urlSigner) presign(key string, expiry time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	return fmt.Sprintf("%s/%s?expires=%s&signature=%s", s.baseURL, key, expires, s.sign(key, expires))
+}
+
+// verify valida a assinatura e a validade de um link gerado por presign
+func (s *urlSigner) verify(key, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+
+	expected, err := hex.DecodeString(s.sign(key, expires))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *urlSigner) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
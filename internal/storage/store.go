@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"zemdocs/internal/config"
+)
+
+var (
+	ErrObjectNotFound  = errors.New("objeto não encontrado")
+	ErrInvalidKey      = errors.New("chave de objeto inválida")
+	ErrHashMismatch    = errors.New("conteúdo do objeto não confere com o hash registrado")
+	ErrRetentionActive = errors.New("objeto sob retenção fiscal")
+)
+
+// Metadados gravados junto aos objetos
+const (
+	MetadataSHA256      = "sha256"       // SHA-256 do conteúdo
+	MetadataRetainUntil = "retain-until" // Fim da retenção (RFC 3339)
+	MetadataLegalHold   = "legal-hold"   // "on" enquanto houver bloqueio legal
+)
+
+// PutOptions opções de gravação de um objeto
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+
+	// Retenção: até RetainUntil (ou enquanto LegalHold) o objeto não pode ser
+	// removido nem sobrescrito com outro conteúdo
+	RetainUntil time.Time
+	LegalHold   bool
+
+	// Tenant dono do objeto; define a chave de dados quando a criptografia está ativa
+	Tenant string
+}
+
+// ObjectInfo metadados de um objeto armazenado
+type ObjectInfo struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"content_type"`
+	ETag         string            `json:"etag"`
+	LastModified time.Time         `json:"last_modified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+
+	RetainUntil time.Time `json:"retain_until,omitempty"`
+	LegalHold   bool      `json:"legal_hold"`
+	Locked      bool      `json:"locked"` // Retenção aplicada pelo próprio backend (object lock)
+}
+
+// Retained indica se o objeto ainda está protegido contra remoção
+func (o *ObjectInfo) Retained(now time.Time) bool {
+	return o.LegalHold || now.Before(o.RetainUntil)
+}
+
+// MetadataValue retorna um metadado ignorando maiúsculas/minúsculas
+// (o MinIO devolve as chaves canonizadas, ex.: "Sha256")
+func (o *ObjectInfo) MetadataValue(name string) string {
+	for k, v := range o.Metadata {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// readRetentionMetadata preenche a retenção a partir dos metadados gravados por Put
+func (o *ObjectInfo) readRetentionMetadata() {
+	if value := o.MetadataValue(MetadataRetainUntil); value != "" {
+		if retainUntil, err := time.Parse(time.RFC3339, value); err == nil {
+			o.RetainUntil = retainUntil
+		}
+	}
+	o.LegalHold = strings.EqualFold(o.MetadataValue(MetadataLegalHold), "on")
+}
+
+// putMetadata monta os metadados do objeto, incluindo a retenção
+func putMetadata(opts PutOptions) map[string]string {
+	metadata := map[string]string{
+		"upload-time": time.Now().Format(time.RFC3339),
+	}
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	if !opts.RetainUntil.IsZero() {
+		metadata[MetadataRetainUntil] = opts.RetainUntil.UTC().Format(time.RFC3339)
+	}
+	if opts.LegalHold {
+		metadata[MetadataLegalHold] = "on"
+	}
+	return metadata
+}
+
+// checkRetention recusa remover ou sobrescrever um objeto retido. Regravar o
+// mesmo conteúdo (mesmo hash) é permitido, para que reenvios sejam idempotentes.
+func checkRetention(info *ObjectInfo, newHash string) error {
+	if !info.Retained(time.Now()) {
+		return nil
+	}
+	if newHash != "" && strings.EqualFold(info.MetadataValue(MetadataSHA256), newHash) {
+		return nil
+	}
+	if info.LegalHold {
+		return fmt.Errorf("%w: %s (bloqueio legal)", ErrRetentionActive, info.Key)
+	}
+	return fmt.Errorf("%w: %s até %s", ErrRetentionActive, info.Key, info.RetainUntil.Format("02/01/2006"))
+}
+
+// ContentSHA256 calcula o SHA-256 (hex) de um conteúdo
+func ContentSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySHA256 confere o conteúdo com o hash esperado; hash vazio não é verificado
+func VerifySHA256(data []byte, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if actual := ContentSHA256(data); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w: esperado %s, obtido %s", ErrHashMismatch, expected, actual)
+	}
+	return nil
+}
+
+// Store armazenamento de objetos usado para os XMLs dos documentos.
+// Get e Stat retornam ErrObjectNotFound quando a chave não existe; Delete e Put
+// retornam ErrRetentionActive para objetos ainda sob retenção.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, opts PutOptions) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	// ListPage lista, em ordem lexicográfica, até limit chaves com o prefixo
+	// posteriores a startAfter (vazio para a primeira página)
+	ListPage(ctx context.Context, prefix, startAfter string, limit int) ([]string, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// Bucket identifica onde os objetos são gravados (nome do bucket ou diretório local)
+	Bucket() string
+}
+
+// NewStore cria o armazenamento configurado em STORAGE_BACKEND, cifrado quando
+// STORAGE_ENCRYPTION=true (as chaves de dados ficam em keyStore).
+// Nenhum acesso à rede é feito aqui; o bucket do MinIO é verificado na primeira gravação.
+func NewStore(cfg *config.Config, keyStore KeyStore) (Store, error) {
+	signingKey, err := resolveSigningKey(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+	store, err := newBackend(cfg, signingKey)
+	if err != nil || !cfg.Storage.Encryption {
+		return store, err
+	}
+
+	if keyStore == nil {
+		return nil, fmt.Errorf("criptografia em repouso requer o repositório de chaves")
+	}
+	masterKeys, err := LoadMasterKeys(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := newURLSigner(cfg.Storage.LocalBaseURL, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := NewEncryptedStore(store, masterKeys, keyStore, signer)
+	encrypted.SetAllowPlaintext(cfg.Storage.AllowPlaintext)
+	return encrypted, nil
+}
+
+func newBackend(cfg *config.Config, signingKey string) (Store, error) {
+	switch cfg.Storage.Backend {
+	case config.StorageBackendLocal:
+		return NewLocalStore(cfg.Storage.LocalPath, cfg.Storage.LocalBaseURL, signingKey)
+	case config.StorageBackendMinIO, "":
+		client, err := NewMinIOClient(
+			cfg.MinIO.Endpoint,
+			cfg.MinIO.AccessKey,
+			cfg.MinIO.SecretKey,
+			cfg.MinIO.BucketName,
+			cfg.MinIO.UseSSL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		client.SetRetentionMode(cfg.Retention.LockMode)
+		return client, nil
+	default:
+		return nil, fmt.Errorf("backend de armazenamento desconhecido: %s", cfg.Storage.Backend)
+	}
+}
+
+// EnsureBucket cria o bucket do MinIO se necessário; sem efeito no backend local
+func EnsureBucket(ctx context.Context, store Store) error {
+	if encrypted, ok := store.(*EncryptedStore); ok {
+		store = encrypted.inner
+	}
+	if client, ok := store.(*MinIOClient); ok {
+		return client.EnsureBucket(ctx)
+	}
+	return nil
+}
+
+// GenerateObjectName gera a chave legada do XML: nfse/{ano}/{mês}/{número}.xml
+func GenerateObjectName(numeroNfse, competencia string) string {
+	year := competencia[:4]
+	month := competencia[4:6]
+	return fmt.Sprintf("nfse/%s/%s/%s.xml", year, month, numeroNfse)
+}
+
+// GenerateObjectNameWithCNPJ gera a chave do XML com o CNPJ do prestador, no
+// layout anterior ao separado por escritório: XML/NFS/{ano}/{MMAAAA}/{cnpj}/{número}.xml
+func GenerateObjectNameWithCNPJ(numeroNfse, competencia, cnpjPrestador string) string {
+	year := competencia[:4]
+	monthYear := competencia[4:6] + competencia[:4] // formato MMYYYY
+	return fmt.Sprintf("XML/NFS/%s/%s/%s/%s.xml", year, monthYear, cnpjPrestador, numeroNfse)
+}
+
+// GenerateEscritorioObjectName gera a chave do XML no escritório dono do documento:
+// XML/{escritório}/NFS/{ano}/{MMAAAA}/{cnpj}/{número}.xml, sem o segmento do CNPJ
+// quando o prestador não é conhecido. A mesma NFS-e pode ser sincronizada por
+// mais de um escritório; cada um guarda a sua cópia, cifrada com a sua chave.
+func GenerateEscritorioObjectName(escritorioID *int64, numeroNfse, competencia, cnpjPrestador string) string {
+	year := competencia[:4]
+	monthYear := competencia[4:6] + competencia[:4] // formato MMYYYY
+	if cnpjPrestador == "" {
+		return fmt.Sprintf("XML/%s/NFS/%s/%s/%s.xml", EscritorioTenant(escritorioID), year, monthYear, numeroNfse)
+	}
+	return fmt.Sprintf("XML/%s/NFS/%s/%s/%s/%s.xml", EscritorioTenant(escritorioID), year, monthYear, cnpjPrestador, numeroNfse)
+}
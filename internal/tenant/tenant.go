@@ -0,0 +1,126 @@
+// Package tenant carrega no contexto o escritório de contabilidade (tenant) cujos
+// dados a operação pode ver. Os repositórios de dados dos escritórios recusam
+// consultas sem escopo: toda requisição recebe o escritório do token e os jobs,
+// comandos e rotinas internas declaram explicitamente o acesso de sistema.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/uptrace/bun"
+)
+
+var ErrNoScope = errors.New("operação sem escritório definido no contexto")
+
+// Scope escopo de acesso aos dados: um escritório ou o sistema (todos os escritórios)
+type Scope struct {
+	EscritorioID int64
+	System       bool
+}
+
+type scopeKey struct{}
+
+type connKey struct{}
+
+type afterCommitKey struct{}
+
+// afterCommit funções agendadas para depois do commit da transação da requisição
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context)
+}
+
+// WithEscritorio restringe as operações do contexto aos dados do escritório
+func WithEscritorio(ctx context.Context, escritorioID int64) context.Context {
+	return context.WithValue(ctx, scopeKey{}, Scope{EscritorioID: escritorioID})
+}
+
+// WithSystem libera o acesso aos dados de todos os escritórios (jobs, comandos e administração)
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, Scope{System: true})
+}
+
+// FromContext retorna o escopo do contexto ou ErrNoScope
+func FromContext(ctx context.Context) (Scope, error) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	if !ok || (!scope.System && scope.EscritorioID == 0) {
+		return Scope{}, ErrNoScope
+	}
+	return scope, nil
+}
+
+// EscritorioID retorna o escritório do contexto; nil no acesso de sistema ou sem escopo
+func EscritorioID(ctx context.Context) *int64 {
+	scope, err := FromContext(ctx)
+	if err != nil || scope.System {
+		return nil
+	}
+	id := scope.EscritorioID
+	return &id
+}
+
+// WithConn associa ao contexto a transação em que app.escritorio_id foi definido,
+// para que as políticas de row-level security do Postgres valham nas consultas
+func WithConn(ctx context.Context, conn bun.IDB) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// Conn retorna a transação do contexto ou, sem ela, o banco informado
+func Conn(ctx context.Context, db bun.IDB) bun.IDB {
+	if conn, ok := ctx.Value(connKey{}).(bun.IDB); ok {
+		return conn
+	}
+	return db
+}
+
+// Inherit aplica a ctx o escopo de from, sem a transação da requisição. Usado
+// pelos jobs disparados por uma requisição, que continuam depois de ela terminar.
+func Inherit(ctx, from context.Context) context.Context {
+	if scope, ok := from.Value(scopeKey{}).(Scope); ok {
+		return context.WithValue(ctx, scopeKey{}, scope)
+	}
+	return ctx
+}
+
+// WithoutConn descarta a transação da requisição, para gravações que precisam
+// ficar visíveis antes de a requisição terminar (ex.: lidas por um job em
+// segundo plano). O filtro dos repositórios continua valendo.
+func WithoutConn(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connKey{}, nil)
+}
+
+// WithAfterCommit prepara o contexto da transação da requisição para receber
+// funções agendadas por AfterCommit. A função retornada as executa e deve ser
+// chamada somente depois do commit.
+func WithAfterCommit(ctx context.Context) (context.Context, func()) {
+	hooks := &afterCommit{}
+	run := func() {
+		hooks.mu.Lock()
+		fns := hooks.fns
+		hooks.fns = nil
+		hooks.mu.Unlock()
+
+		// A transação já terminou e a resposta pode já ter sido enviada
+		runCtx := context.WithoutCancel(WithoutConn(ctx))
+		for _, fn := range fns {
+			fn(runCtx)
+		}
+	}
+	return context.WithValue(ctx, afterCommitKey{}, hooks), run
+}
+
+// AfterCommit agenda fn para depois do commit da transação da requisição, para
+// trabalhos que precisam enxergar as alterações já confirmadas (ex.: atualizar
+// agregados calculados por outra conexão). Sem transação da requisição no
+// contexto, fn executa imediatamente. Se a transação for desfeita, fn não executa.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommit); ok {
+		hooks.mu.Lock()
+		hooks.fns = append(hooks.fns, fn)
+		hooks.mu.Unlock()
+		return
+	}
+	fn(ctx)
+}
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// NFeParte emitente ou destinatário de uma NF-e
+type NFeParte struct {
+	CNPJ              string
+	CPF               string
+	Nome              string
+	NomeFantasia      string
+	InscricaoEstadual string
+	Logradouro        string
+	Numero            string
+	Bairro            string
+	Municipio         string
+	UF                string
+	CEP               string
+	Telefone          string
+}
+
+// NFeItem produto de uma NF-e
+type NFeItem struct {
+	Codigo        string
+	Descricao     string
+	NCM           string
+	CFOP          string
+	Unidade       string
+	Quantidade    decimal.Decimal
+	ValorUnitario decimal.Decimal
+	ValorTotal    decimal.Decimal
+}
+
+// NFeXMLData estrutura para extrair dados do XML da NF-e (nfeProc ou NFe)
+type NFeXMLData struct {
+	ChaveAcesso      string
+	Numero           string
+	Serie            string
+	Modelo           string
+	NaturezaOperacao string
+	TipoOperacao     string // 0 = entrada, 1 = saída
+	DataEmissao      time.Time
+	Protocolo        string
+	DataAutorizacao  time.Time
+
+	Emitente     NFeParte
+	Destinatario NFeParte
+	Itens        []NFeItem
+
+	BaseCalculoICMS decimal.Decimal
+	ValorICMS       decimal.Decimal
+	ValorProdutos   decimal.Decimal
+	ValorFrete      decimal.Decimal
+	ValorSeguro     decimal.Decimal
+	ValorDesconto   decimal.Decimal
+	ValorIPI        decimal.Decimal
+	ValorOutros     decimal.Decimal
+	ValorNota       decimal.Decimal
+
+	InformacoesComplementares string
+}
+
+type nfeEnderecoXML struct {
+	Logradouro string `xml:"xLgr"`
+	Numero     string `xml:"nro"`
+	Bairro     string `xml:"xBairro"`
+	Municipio  string `xml:"xMun"`
+	UF         string `xml:"UF"`
+	CEP        string `xml:"CEP"`
+	Telefone   string `xml:"fone"`
+}
+
+type nfeInfXML struct {
+	ID  string `xml:"Id,attr"`
+	Ide struct {
+		NaturezaOperacao string `xml:"natOp"`
+		Modelo           string `xml:"mod"`
+		Serie            string `xml:"serie"`
+		Numero           string `xml:"nNF"`
+		DataEmissao      string `xml:"dhEmi"`
+		TipoOperacao     string `xml:"tpNF"`
+	} `xml:"ide"`
+	Emit struct {
+		CNPJ         string         `xml:"CNPJ"`
+		CPF          string         `xml:"CPF"`
+		Nome         string         `xml:"xNome"`
+		NomeFantasia string         `xml:"xFant"`
+		IE           string         `xml:"IE"`
+		Endereco     nfeEnderecoXML `xml:"enderEmit"`
+	} `xml:"emit"`
+	Dest struct {
+		CNPJ     string         `xml:"CNPJ"`
+		CPF      string         `xml:"CPF"`
+		Nome     string         `xml:"xNome"`
+		IE       string         `xml:"IE"`
+		Endereco nfeEnderecoXML `xml:"enderDest"`
+	} `xml:"dest"`
+	Det []struct {
+		Prod struct {
+			Codigo        string `xml:"cProd"`
+			Descricao     string `xml:"xProd"`
+			NCM           string `xml:"NCM"`
+			CFOP          string `xml:"CFOP"`
+			Unidade       string `xml:"uCom"`
+			Quantidade    string `xml:"qCom"`
+			ValorUnitario string `xml:"vUnCom"`
+			ValorTotal    string `xml:"vProd"`
+		} `xml:"prod"`
+	} `xml:"det"`
+	Total struct {
+		ICMSTot struct {
+			BaseCalculo   string `xml:"vBC"`
+			ValorICMS     string `xml:"vICMS"`
+			ValorProdutos string `xml:"vProd"`
+			ValorFrete    string `xml:"vFrete"`
+			ValorSeguro   string `xml:"vSeg"`
+			ValorDesconto string `xml:"vDesc"`
+			ValorIPI      string `xml:"vIPI"`
+			ValorOutros   string `xml:"vOutro"`
+			ValorNota     string `xml:"vNF"`
+		} `xml:"ICMSTot"`
+	} `xml:"total"`
+	InfAdic struct {
+		InfCpl string `xml:"infCpl"`
+	} `xml:"infAdic"`
+}
+
+// ParseNFeXML extrai dados estruturados do XML da NF-e, com ou sem o protocolo de autorização
+func ParseNFeXML(xmlContent string) (*NFeXMLData, error) {
+	utf8Content, err := convertISO88591ToUTF8(xmlContent)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao converter encoding: %w", err)
+	}
+
+	// nfeProc: NF-e autorizada (NFe + protNFe)
+	var proc struct {
+		NFe struct {
+			InfNFe nfeInfXML `xml:"infNFe"`
+		} `xml:"NFe"`
+		ProtNFe struct {
+			InfProt struct {
+				Chave           string `xml:"chNFe"`
+				DataAutorizacao string `xml:"dhRecbto"`
+				Protocolo       string `xml:"nProt"`
+			} `xml:"infProt"`
+		} `xml:"protNFe"`
+	}
+	if err := xml.Unmarshal([]byte(utf8Content), &proc); err != nil {
+		return nil, fmt.Errorf("erro ao fazer parse do XML: %w", err)
+	}
+
+	inf := proc.NFe.InfNFe
+	if inf.ID == "" {
+		// NFe sem o envelope nfeProc
+		var nfe struct {
+			InfNFe nfeInfXML `xml:"infNFe"`
+		}
+		if err := xml.Unmarshal([]byte(utf8Content), &nfe); err != nil {
+			return nil, fmt.Errorf("erro ao fazer parse do XML: %w", err)
+		}
+		inf = nfe.InfNFe
+	}
+	if inf.ID == "" {
+		return nil, fmt.Errorf("XML não contém infNFe")
+	}
+
+	data := &NFeXMLData{
+		ChaveAcesso:      strings.TrimPrefix(inf.ID, "NFe"),
+		Numero:           inf.Ide.Numero,
+		Serie:            inf.Ide.Serie,
+		Modelo:           inf.Ide.Modelo,
+		NaturezaOperacao: inf.Ide.NaturezaOperacao,
+		TipoOperacao:     inf.Ide.TipoOperacao,
+		Protocolo:        proc.ProtNFe.InfProt.Protocolo,
+		Emitente: NFeParte{
+			CNPJ:              inf.Emit.CNPJ,
+			CPF:               inf.Emit.CPF,
+			Nome:              inf.Emit.Nome,
+			NomeFantasia:      inf.Emit.NomeFantasia,
+			InscricaoEstadual: inf.Emit.IE,
+		},
+		Destinatario: NFeParte{
+			CNPJ:              inf.Dest.CNPJ,
+			CPF:               inf.Dest.CPF,
+			Nome:              inf.Dest.Nome,
+			InscricaoEstadual: inf.Dest.IE,
+		},
+		InformacoesComplementares: strings.TrimSpace(inf.InfAdic.InfCpl),
+	}
+	data.Emitente.setEndereco(inf.Emit.Endereco)
+	data.Destinatario.setEndereco(inf.Dest.Endereco)
+
+	// Datas no formato 2024-08-29T11:47:15-03:00
+	if dt, err := time.Parse(time.RFC3339, inf.Ide.DataEmissao); err == nil {
+		data.DataEmissao = dt
+	}
+	if dt, err := time.Parse(time.RFC3339, proc.ProtNFe.InfProt.DataAutorizacao); err == nil {
+		data.DataAutorizacao = dt
+	}
+
+	for _, det := range inf.Det {
+		item := NFeItem{
+			Codigo:    det.Prod.Codigo,
+			Descricao: det.Prod.Descricao,
+			NCM:       det.Prod.NCM,
+			CFOP:      det.Prod.CFOP,
+			Unidade:   det.Prod.Unidade,
+		}
+		item.Quantidade, _ = parseDecimal(det.Prod.Quantidade)
+		item.ValorUnitario, _ = parseDecimal(det.Prod.ValorUnitario)
+		item.ValorTotal, _ = parseDecimal(det.Prod.ValorTotal)
+		data.Itens = append(data.Itens, item)
+	}
+
+	totais := inf.Total.ICMSTot
+	data.BaseCalculoICMS, _ = parseDecimal(totais.BaseCalculo)
+	data.ValorICMS, _ = parseDecimal(totais.ValorICMS)
+	data.ValorProdutos, _ = parseDecimal(totais.ValorProdutos)
+	data.ValorFrete, _ = parseDecimal(totais.ValorFrete)
+	data.ValorSeguro, _ = parseDecimal(totais.ValorSeguro)
+	data.ValorDesconto, _ = parseDecimal(totais.ValorDesconto)
+	data.ValorIPI, _ = parseDecimal(totais.ValorIPI)
+	data.ValorOutros, _ = parseDecimal(totais.ValorOutros)
+	data.ValorNota, _ = parseDecimal(totais.ValorNota)
+
+	return data, nil
+}
+
+func (p *NFeParte) setEndereco(endereco nfeEnderecoXML) {
+	p.Logradouro = endereco.Logradouro
+	p.Numero = endereco.Numero
+	p.Bairro = endereco.Bairro
+	p.Municipio = endereco.Municipio
+	p.UF = endereco.UF
+	p.CEP = endereco.CEP
+	p.Telefone = endereco.Telefone
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/shopspring/decimal"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/transform"
 )
@@ -60,17 +61,19 @@ type NFSeXMLData struct {
 	TipoRps                     int
 	DataEmissao                 time.Time
 	CodigoVerificacao           string
-	ValorServico                float64
-	ValorIss                    float64
-	ValorDeducoes               float64
-	ValorPis                    float64
-	ValorCofins                 float64
-	ValorInss                   float64
-	ValorIr                     float64
-	ValorCsll                   float64
-	OutrasRetencoes             float64
-	BaseCalculo                 float64
-	Aliquota                    float64
+	ValorServico                decimal.Decimal
+	ValorIss                    decimal.Decimal
+	IssRetido                   bool
+	ValorIssRetido              decimal.Decimal
+	ValorDeducoes               decimal.Decimal
+	ValorPis                    decimal.Decimal
+	ValorCofins                 decimal.Decimal
+	ValorInss                   decimal.Decimal
+	ValorIr                     decimal.Decimal
+	ValorCsll                   decimal.Decimal
+	OutrasRetencoes             decimal.Decimal
+	BaseCalculo                 decimal.Decimal
+	Aliquota                    decimal.Decimal
 	Discriminacao               string
 	CodigoServico               string
 	CodigoMunicipio             string
@@ -78,6 +81,11 @@ type NFSeXMLData struct {
 	CNPJPrestador               string
 	InscricaoMunicipalPrestador string
 	RazaoSocialPrestador        string
+	NomeFantasiaPrestador       string
+	EnderecoPrestador           string
+	NumeroPrestador             string
+	BairroPrestador             string
+	CEPPrestador                string
 	CNPJTomador                 string
 	InscricaoMunicipalTomador   string
 	RazaoSocialTomador          string
@@ -209,6 +217,8 @@ func ParseNFSeXML(xmlContent string) (*NFSeXMLData, error) {
 							Valores struct {
 								ValorServicos    string `xml:"ValorServicos"`
 								ValorIss         string `xml:"ValorIss"`
+								IssRetido        string `xml:"IssRetido"`
+								ValorIssRetido   string `xml:"ValorIssRetido"`
 								ValorDeducoes    string `xml:"ValorDeducoes"`
 								ValorPis         string `xml:"ValorPis"`
 								ValorCofins      string `xml:"ValorCofins"`
@@ -285,6 +295,11 @@ func ParseNFSeXML(xmlContent string) (*NFSeXMLData, error) {
 		CNPJPrestador:               infNfse.PrestadorServico.IdentificacaoPrestador.Cnpj,
 		InscricaoMunicipalPrestador: infNfse.PrestadorServico.IdentificacaoPrestador.InscricaoMunicipal,
 		RazaoSocialPrestador:        infNfse.PrestadorServico.RazaoSocial,
+		NomeFantasiaPrestador:       infNfse.PrestadorServico.NomeFantasia,
+		EnderecoPrestador:           infNfse.PrestadorServico.Endereco.Endereco,
+		NumeroPrestador:             infNfse.PrestadorServico.Endereco.Numero,
+		BairroPrestador:             infNfse.PrestadorServico.Endereco.Bairro,
+		CEPPrestador:                infNfse.PrestadorServico.Endereco.Cep,
 		CNPJTomador:                 infNfse.TomadorServico.IdentificacaoTomador.CpfCnpj.Cnpj,
 		InscricaoMunicipalTomador:   "", // Não presente na estrutura
 		RazaoSocialTomador:          infNfse.TomadorServico.RazaoSocial,
@@ -299,37 +314,45 @@ func ParseNFSeXML(xmlContent string) (*NFSeXMLData, error) {
 
 	// Converter valores numéricos usando a estrutura correta
 	valores := infNfse.Servico.Valores
-	if val, err := parseFloat(valores.ValorServicos); err == nil {
+	if val, err := parseDecimal(valores.ValorServicos); err == nil {
 		data.ValorServico = val
 	}
-	if val, err := parseFloat(valores.ValorIss); err == nil {
+	if val, err := parseDecimal(valores.ValorIss); err == nil {
 		data.ValorIss = val
 	}
-	if val, err := parseFloat(valores.ValorDeducoes); err == nil {
+	// IssRetido segue o ABRASF: 1 = retido pelo tomador, 2 = não retido
+	data.IssRetido = strings.TrimSpace(valores.IssRetido) == "1"
+	if val, err := parseDecimal(valores.ValorIssRetido); err == nil {
+		data.ValorIssRetido = val
+	}
+	if data.IssRetido && data.ValorIssRetido.IsZero() {
+		data.ValorIssRetido = data.ValorIss
+	}
+	if val, err := parseDecimal(valores.ValorDeducoes); err == nil {
 		data.ValorDeducoes = val
 	}
-	if val, err := parseFloat(valores.ValorPis); err == nil {
+	if val, err := parseDecimal(valores.ValorPis); err == nil {
 		data.ValorPis = val
 	}
-	if val, err := parseFloat(valores.ValorCofins); err == nil {
+	if val, err := parseDecimal(valores.ValorCofins); err == nil {
 		data.ValorCofins = val
 	}
-	if val, err := parseFloat(valores.ValorInss); err == nil {
+	if val, err := parseDecimal(valores.ValorInss); err == nil {
 		data.ValorInss = val
 	}
-	if val, err := parseFloat(valores.ValorIr); err == nil {
+	if val, err := parseDecimal(valores.ValorIr); err == nil {
 		data.ValorIr = val
 	}
-	if val, err := parseFloat(valores.ValorCsll); err == nil {
+	if val, err := parseDecimal(valores.ValorCsll); err == nil {
 		data.ValorCsll = val
 	}
-	if val, err := parseFloat(valores.OutrasRetencoes); err == nil {
+	if val, err := parseDecimal(valores.OutrasRetencoes); err == nil {
 		data.OutrasRetencoes = val
 	}
-	if val, err := parseFloat(valores.BaseCalculo); err == nil {
+	if val, err := parseDecimal(valores.BaseCalculo); err == nil {
 		data.BaseCalculo = val
 	}
-	if val, err := parseFloat(valores.Aliquota); err == nil {
+	if val, err := parseDecimal(valores.Aliquota); err == nil {
 		data.Aliquota = val
 	}
 
@@ -346,12 +369,13 @@ func ParseNFSeXML(xmlContent string) (*NFSeXMLData, error) {
 	return data, nil
 }
 
-// parseFloat converte string para float64, tratando vírgula como separador decimal
-func parseFloat(s string) (float64, error) {
+// parseDecimal converte string para decimal exato, tratando vírgula como separador decimal
+func parseDecimal(s string) (decimal.Decimal, error) {
+	s = strings.TrimSpace(s)
 	if s == "" {
-		return 0, nil
+		return decimal.Zero, nil
 	}
 	// Substituir vírgula por ponto
 	s = strings.ReplaceAll(s, ",", ".")
-	return strconv.ParseFloat(s, 64)
+	return decimal.NewFromString(s)
 }